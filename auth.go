@@ -0,0 +1,396 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"photofield/internal/auth"
+	"photofield/internal/collection"
+	"photofield/internal/event"
+	"photofield/internal/image"
+	"photofield/internal/openapi"
+	"photofield/internal/render"
+)
+
+const SESSION_COOKIE = "photofield_session"
+
+const (
+	PASSWORD_MAX_FAILURES   = 10
+	PASSWORD_FAILURE_WINDOW = time.Minute
+)
+
+var authConfig auth.Config
+
+// passwordLimiter limits the failed attempts of logging in and unlocking
+// shares per client address to make guessing passwords slow.
+var passwordLimiter = auth.NewLimiter(PASSWORD_MAX_FAILURES, PASSWORD_FAILURE_WINDOW)
+
+// authMiddleware authenticates the API requests by the session cookie or
+// the API token and checks the role of the user, if authentication is
+// enabled. Requests made with a share link are limited to the share either
+// way. The prefix is trimmed from the request paths.
+func authMiddleware(prefix string) func(next http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, prefix)
+			if token := shareToken(r); token != "" {
+				serveShare(w, r, next, token, path)
+				return
+			}
+			if !authConfig.Enable {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user, ok := authenticate(r)
+			if !ok {
+				if isPublicPath(r.Method, path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				problem(w, r, http.StatusUnauthorized, "Login required")
+				return
+			}
+			if !user.Role.Includes(requiredRole(r.Method, path)) {
+				problem(w, r, http.StatusForbidden, "Not allowed for the "+string(user.Role)+" role")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// authenticate returns the user of the API token in the Authorization
+// header, or of the session cookie otherwise.
+func authenticate(r *http.Request) (auth.User, bool) {
+	if imageSource == nil {
+		return auth.User{}, false
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return imageSource.GetTokenUser(auth.HashToken(strings.TrimSpace(token)))
+	}
+	cookie, err := r.Cookie(SESSION_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return auth.User{}, false
+	}
+	return imageSource.GetSessionUser(auth.HashToken(cookie.Value))
+}
+
+func isPublicPath(method string, path string) bool {
+	switch {
+	case method == http.MethodPost && path == "/auth/login":
+		return true
+	case method == http.MethodGet && path == "/capabilities":
+		return true
+	}
+	return false
+}
+
+// requiredRole returns the least privileged role allowed to make the
+// request. Reading is allowed to all users, changing files to editors and
+// changing the setup to admins.
+func requiredRole(method string, path string) auth.Role {
+	admin := path == "/users" || strings.HasPrefix(path, "/users/") ||
+		strings.HasPrefix(path, "/admin/") ||
+		strings.HasPrefix(path, "/metrics") ||
+		strings.HasPrefix(path, "/debug/")
+	if admin {
+		return auth.Admin
+	}
+	if path == "/shares" || strings.HasPrefix(path, "/shares/") {
+		return auth.Editor
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return auth.Viewer
+	}
+	switch {
+	case strings.HasPrefix(path, "/auth/"),
+		path == "/tokens", strings.HasPrefix(path, "/tokens/"):
+		return auth.Viewer
+	case path == "/scenes":
+		// Scenes are views of collections
+		return auth.Viewer
+	case path == "/tags",
+		strings.HasPrefix(path, "/tags/sys:select:") && strings.HasSuffix(path, "/files"):
+		// Selections, as only they can be created with POST /tags
+		return auth.Viewer
+	case strings.HasPrefix(path, "/collections"),
+		strings.HasPrefix(path, "/tasks"):
+		return auth.Admin
+	}
+	return auth.Editor
+}
+
+// restrictedUser returns the user of the request and true if they can
+// only access some of the collections. Without authentication all
+// collections are accessible, unless the request is made with a share link.
+func restrictedUser(r *http.Request) (auth.User, bool) {
+	if _, ok := auth.ShareFromContext(r.Context()); ok {
+		return auth.User{}, true
+	}
+	if !authConfig.Enable {
+		return auth.User{}, false
+	}
+	user, ok := auth.UserFromContext(r.Context())
+	return user, !ok || user.Role != auth.Admin
+}
+
+// canAccessCollection returns true if the user of the request is allowed to
+// access the collection. Virtual collections of saved searches need all of
+// their base collections to be allowed.
+func canAccessCollection(r *http.Request, c *collection.Collection) bool {
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		return c.Id == share.CollectionId
+	}
+	user, restricted := restrictedUser(r)
+	if !restricted {
+		return true
+	}
+	if c.SavedSearchId != 0 {
+		bases := c.Bases()
+		for _, base := range bases {
+			if !base.Allows(user.Name) {
+				return false
+			}
+		}
+		return len(bases) > 0
+	}
+	return user.Name != "" && c.Allows(user.Name)
+}
+
+// canAccessSavedSearch returns true if the user of the request is allowed to
+// access all the collections the saved search is sourced from.
+func canAccessSavedSearch(r *http.Request, s image.SavedSearch) bool {
+	if _, restricted := restrictedUser(r); !restricted {
+		return true
+	}
+	c, ok := getSavedSearchCollection(s)
+	return ok && canAccessCollection(r, &c)
+}
+
+// canAccessPath returns true if the file at the path is in a collection
+// the user of the request is allowed to access.
+func canAccessPath(r *http.Request, path string) bool {
+	if _, restricted := restrictedUser(r); !restricted {
+		return true
+	}
+	if _, ok := auth.ShareFromContext(r.Context()); ok {
+		// Shares only include some of the files of their collection, see
+		// canAccessFile
+		return false
+	}
+	for i := range collections {
+		c := &collections[i]
+		if !canAccessCollection(r, c) {
+			continue
+		}
+		if c.PathFilter().MatchPath(c.Dirs, path, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessFile returns true if the file with the id is in a collection the
+// user of the request is allowed to access.
+func canAccessFile(r *http.Request, id image.ImageId) bool {
+	if _, restricted := restrictedUser(r); !restricted {
+		return true
+	}
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		return shareContainsFile(r, share, id)
+	}
+	path, err := imageSource.GetImagePath(id)
+	if err != nil {
+		return false
+	}
+	return canAccessPath(r, path)
+}
+
+// allowedFileIds returns the ids of the files the user of the request is
+// allowed to access.
+func allowedFileIds(r *http.Request, ids image.Ids) image.Ids {
+	if _, restricted := restrictedUser(r); !restricted {
+		return ids
+	}
+	allowed := image.NewIds()
+	for _, id := range ids.IntSlice() {
+		if canAccessFile(r, image.ImageId(id)) {
+			allowed.AddInt(id)
+		}
+	}
+	return allowed
+}
+
+// canAccessEvent returns true if the user of the request is allowed to
+// receive the event, as events about a collection are only sent to the
+// users allowed to access it.
+func canAccessEvent(r *http.Request, e event.Event) bool {
+	return canAccessCollectionId(r, e.CollectionId)
+}
+
+// canAccessCollectionId returns true if the user of the request is allowed
+// to access the collection with the id, or if the id is empty as the
+// resource is not about a single collection, e.g. of events and tasks.
+func canAccessCollectionId(r *http.Request, id string) bool {
+	if id == "" {
+		return true
+	}
+	if _, restricted := restrictedUser(r); !restricted {
+		return true
+	}
+	c := getCollectionById(id)
+	return c != nil && canAccessCollection(r, c)
+}
+
+// allowedPerson returns the person with only the faces in the files the
+// user of the request is allowed to access counted, or false if there are
+// none, so that restricted users only see the persons of their collections.
+func allowedPerson(r *http.Request, p image.Person) (image.Person, bool) {
+	if _, restricted := restrictedUser(r); !restricted {
+		return p, true
+	}
+	faces := allowedFaces(r, imageSource.ListPersonFaces(p.Id))
+	if len(faces) == 0 {
+		return p, false
+	}
+	p.FaceCount = len(faces)
+	coverAllowed := false
+	for _, f := range faces {
+		if int64(f.Id) == p.CoverFaceId {
+			coverAllowed = true
+			break
+		}
+	}
+	if !coverAllowed {
+		// Faces are ordered by confidence
+		p.CoverFaceId = int64(faces[0].Id)
+	}
+	return p, true
+}
+
+// allowedFaces returns the faces in the files the user of the request is
+// allowed to access.
+func allowedFaces(r *http.Request, faces []image.FaceInfo) []image.FaceInfo {
+	if _, restricted := restrictedUser(r); !restricted {
+		return faces
+	}
+	allowed := make([]image.FaceInfo, 0, len(faces))
+	for _, f := range faces {
+		if canAccessFile(r, f.FileId) {
+			allowed = append(allowed, f)
+		}
+	}
+	return allowed
+}
+
+// clientAddr returns the address of the client of the request without the
+// port.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowPasswordAttempt returns true if the client of the request has
+// password attempts left, responding with 429 Too Many Requests otherwise.
+func allowPasswordAttempt(w http.ResponseWriter, r *http.Request) bool {
+	wait, ok := passwordLimiter.Allow(clientAddr(r))
+	if ok {
+		return true
+	}
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	problem(w, r, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+	return false
+}
+
+// getAllowedSceneById returns the scene if the user of the request is allowed
+// to access its collection, as scenes are shared by all users.
+func getAllowedSceneById(r *http.Request, id openapi.SceneId) *render.Scene {
+	scene := sceneSource.GetSceneById(string(id), imageSource)
+	if scene == nil {
+		return nil
+	}
+	if _, restricted := restrictedUser(r); !restricted {
+		return scene
+	}
+	config, ok := sceneSource.GetSceneConfig(string(id))
+	if !ok || config.Collection == nil {
+		return nil
+	}
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		if !shareScene(share, config) {
+			return nil
+		}
+		return scene
+	}
+	// Check the current allow-list of the collection, as it might have
+	// changed since the scene was created
+	c := getCollectionById(config.Collection.Id)
+	if c == nil {
+		return nil
+	}
+	if !canAccessCollection(r, c) {
+		return nil
+	}
+	return scene
+}
+
+// isSecureRequest returns true if the request was made over HTTPS, directly
+// or through a reverse proxy.
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// setSessionCookie sets the cookie of the session token, or removes it if
+// the token is empty.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     SESSION_COOKIE,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// ensureAdminUser adds the admin user with the password of the
+// PHOTOFIELD_ADMIN_PASSWORD environment variable if there are no users yet,
+// so that the first login is possible.
+func ensureAdminUser() {
+	if !authConfig.Enable || imageSource == nil {
+		return
+	}
+	if len(imageSource.ListUsers()) > 0 {
+		return
+	}
+	password := os.Getenv("PHOTOFIELD_ADMIN_PASSWORD")
+	if password == "" {
+		log.Printf("auth enabled, but there are no users, set PHOTOFIELD_ADMIN_PASSWORD to add the admin user")
+		return
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("auth unable to add admin user: %v", err)
+		return
+	}
+	if _, err := imageSource.AddUser("admin", hash, auth.Admin); err != nil {
+		log.Printf("auth unable to add admin user: %v", err)
+		return
+	}
+	log.Printf("auth added admin user")
+}
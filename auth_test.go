@@ -0,0 +1,196 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photofield/internal/auth"
+	"photofield/internal/collection"
+	"photofield/internal/image"
+)
+
+func TestUserSessionsAndTokens(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+	<-db.CommitBarrier()
+
+	alice, err := db.AddUser("alice", "hash", auth.Viewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddUser("alice", "hash", auth.Admin); err != image.ErrExists {
+		t.Errorf("duplicate user error %v, want %v", err, image.ErrExists)
+	}
+
+	session := auth.HashToken("session")
+	if err := db.AddUserSession(alice.Id, session, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expired := auth.HashToken("expired")
+	if err := db.AddUserSession(alice.Id, expired, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := db.GetSessionUser(session); !ok || user.Name != "alice" {
+		t.Errorf("session user %v %v, want alice", user, ok)
+	}
+	if _, ok := db.GetSessionUser(expired); ok {
+		t.Error("expired session accepted")
+	}
+
+	token, err := db.AddUserToken(alice.Id, "script", auth.HashToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := db.GetTokenUser(auth.HashToken("secret")); !ok || user.Id != alice.Id {
+		t.Errorf("token user %v %v, want alice", user, ok)
+	}
+	if tokens := db.ListUserTokens(alice.Id); len(tokens) != 1 || tokens[0].Name != "script" {
+		t.Errorf("tokens %v, want script", tokens)
+	}
+
+	if err := db.SetUserPassword(alice.Id, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.GetSessionUser(session); ok {
+		t.Error("session accepted after password change")
+	}
+	if _, hash, _ := db.GetUserByName("alice"); hash != "other" {
+		t.Errorf("password hash %q, want other", hash)
+	}
+
+	if err := db.SetUserRole(alice.Id, auth.Editor); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := db.GetUser(alice.Id); user.Role != auth.Editor {
+		t.Errorf("role %s, want %s", user.Role, auth.Editor)
+	}
+
+	if err := db.DeleteUserToken(alice.Id+1, token.Id); err != image.ErrNotFound {
+		t.Errorf("token of other user deleted, error %v", err)
+	}
+	if err := db.DeleteUser(alice.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.GetTokenUser(auth.HashToken("secret")); ok {
+		t.Error("token accepted after user deletion")
+	}
+	if users := db.ListUsers(); len(users) != 0 {
+		t.Errorf("users %v, want none", users)
+	}
+}
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		role   auth.Role
+	}{
+		{http.MethodGet, "/collections", auth.Viewer},
+		{http.MethodGet, "/users", auth.Admin},
+		{http.MethodPost, "/auth/logout", auth.Viewer},
+		{http.MethodPost, "/scenes", auth.Viewer},
+		{http.MethodPost, "/tags", auth.Viewer},
+		{http.MethodPost, "/tags/sys:select:abc/files", auth.Viewer},
+		{http.MethodPost, "/tags/vacation/files", auth.Editor},
+		{http.MethodPost, "/files/rating", auth.Editor},
+		{http.MethodPatch, "/collections/photos", auth.Admin},
+		{http.MethodPost, "/tasks", auth.Admin},
+		{http.MethodPost, "/admin/backup", auth.Admin},
+		{http.MethodGet, "/debug/pprof/heap", auth.Admin},
+	}
+	for _, tt := range tests {
+		if role := requiredRole(tt.method, tt.path); role != tt.role {
+			t.Errorf("%s %s role %s, want %s", tt.method, tt.path, role, tt.role)
+		}
+	}
+}
+
+func TestCollectionAllows(t *testing.T) {
+	open := collection.Collection{}
+	if !open.Allows("anyone") {
+		t.Error("collection without allow-list rejected user")
+	}
+	family := collection.Collection{Allow: []string{"alice"}}
+	if !family.Allows("alice") || family.Allows("bob") {
+		t.Error("allow-list not applied")
+	}
+}
+
+func TestCanAccessCollectionId(t *testing.T) {
+	prevAuth, prevCollections := authConfig, collections
+	defer func() {
+		authConfig, collections = prevAuth, prevCollections
+	}()
+	authConfig = auth.Config{Enable: true}
+	collections = []collection.Collection{
+		{Id: "family", Allow: []string{"alice"}},
+	}
+
+	request := func(user auth.User) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+		return r.WithContext(auth.WithUser(r.Context(), user))
+	}
+	alice := request(auth.User{Name: "alice", Role: auth.Viewer})
+	bob := request(auth.User{Name: "bob", Role: auth.Viewer})
+	admin := request(auth.User{Name: "root", Role: auth.Admin})
+
+	cases := []struct {
+		r    *http.Request
+		id   string
+		want bool
+	}{
+		{alice, "family", true},
+		{bob, "family", false},
+		{admin, "family", true},
+		{bob, "", true},
+		{bob, "missing", false},
+	}
+	for _, c := range cases {
+		user, _ := auth.UserFromContext(c.r.Context())
+		if got := canAccessCollectionId(c.r, c.id); got != c.want {
+			t.Errorf("canAccessCollectionId(%s, %q) = %v, want %v", user.Name, c.id, got, c.want)
+		}
+	}
+}
+
+func TestCanAccessSavedSearch(t *testing.T) {
+	prevAuth, prevCollections := authConfig, collections
+	defer func() {
+		authConfig, collections = prevAuth, prevCollections
+	}()
+	authConfig = auth.Config{Enable: true}
+	collections = []collection.Collection{
+		{Id: "family", Allow: []string{"alice"}},
+		{Id: "public"},
+	}
+
+	request := func(user auth.User) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/saved-searches", nil)
+		return r.WithContext(auth.WithUser(r.Context(), user))
+	}
+	alice := request(auth.User{Name: "alice", Role: auth.Viewer})
+	bob := request(auth.User{Name: "bob", Role: auth.Viewer})
+	admin := request(auth.User{Name: "root", Role: auth.Admin})
+
+	cases := []struct {
+		r            *http.Request
+		collectionId string
+		want         bool
+	}{
+		{alice, "family", true},
+		{bob, "family", false},
+		{bob, "public", true},
+		{alice, "", true},
+		{bob, "", false},
+		{admin, "", true},
+	}
+	for _, c := range cases {
+		user, _ := auth.UserFromContext(c.r.Context())
+		s := image.SavedSearch{Id: 1, Search: "iso:>1600", CollectionId: c.collectionId}
+		if got := canAccessSavedSearch(c.r, s); got != c.want {
+			t.Errorf("canAccessSavedSearch(%s, %q) = %v, want %v", user.Name, c.collectionId, got, c.want)
+		}
+	}
+}
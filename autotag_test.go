@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/search"
+	"photofield/internal/tag"
+	"photofield/internal/task"
+)
+
+func TestIndexAutotag(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	dir := "/photos/"
+	dirs := []string{dir}
+	embeddings := map[string][]float32{
+		"beach-1.jpg":  {1, 0.1, 0, 0},
+		"beach-2.jpg":  {1, 0, 0.1, 0},
+		"beach-3.jpg":  {1, 0.1, 0.1, 0},
+		"forest-1.jpg": {0, 1, 0.1, 0},
+		"forest-2.jpg": {0.1, 1, 0, 0},
+		"forest-3.jpg": {0, 1, 0, 0.1},
+		"sea.jpg":      {1, 0.05, 0.05, 0},
+		"trees.jpg":    {0.05, 1, 0.05, 0},
+		"city.jpg":     {0, 0, 0, 1},
+	}
+	for name := range embeddings {
+		db.Write(dir+name, image.Info{}, image.AppendPath)
+	}
+	<-db.CommitBarrier()
+	ids := make(map[string]image.ImageId)
+	for ip := range db.ListIdPaths(dirs, 0) {
+		name := filepath.Base(ip.Path)
+		ids[name] = ip.Id
+		db.WriteAI(ip.Id, testEmbedding(embeddings[name]...))
+	}
+	<-db.CommitBarrier()
+
+	addTag := func(name string, files ...string) tag.Tag {
+		t.Helper()
+		done, _ := db.AddTag(name)
+		<-done
+		tg, ok := db.GetTagByName(name)
+		if !ok {
+			t.Fatalf("tag %s not found", name)
+		}
+		fileIds := image.NewIds()
+		for _, f := range files {
+			fileIds.AddInt(int(ids[f]))
+		}
+		db.AddTagIds(tg.Id, fileIds)
+		if err := db.SetTagAuto(tg, true); err != nil {
+			t.Fatal(err)
+		}
+		tg, _ = db.GetTagByName(name)
+		if !tg.Auto {
+			t.Fatalf("tag %s is not auto", name)
+		}
+		return tg
+	}
+	beach := addTag("beach", "beach-1.jpg", "beach-2.jpg", "beach-3.jpg")
+	addTag("forest", "forest-1.jpg", "forest-2.jpg", "forest-3.jpg")
+
+	cfg := pipeline.Config{
+		DB:                   db,
+		AutotagNeighbors:     3,
+		AutotagMinConfidence: 0.8,
+	}
+	autotag := func() {
+		t.Helper()
+		if err := pipeline.RunAutotag(context.Background(), cfg, task.NewAutotagTask("test", "Test", dirs)); err != nil {
+			t.Fatalf("index autotag: %v", err)
+		}
+	}
+	autotag()
+
+	query := func(q string) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression: %v", err)
+		}
+		results, _ := db.List(dirs, image.ListOptions{Expression: expr})
+		var names []string
+		for result := range results {
+			path, _ := db.GetPathFromId(result.Id)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got, want := query("tag:suggest:beach"), []string{"sea.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:suggest:beach = %v, want %v", got, want)
+	}
+	if got, want := query("tag:suggest:forest"), []string{"trees.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:suggest:forest = %v, want %v", got, want)
+	}
+
+	suggestions := db.ListTagSuggestions(beach.Name, 10)
+	if len(suggestions) != 1 || suggestions[0].FileId != ids["sea.jpg"] || suggestions[0].Confidence < 0.8 {
+		t.Fatalf("beach suggestions = %+v, want sea.jpg", suggestions)
+	}
+
+	if err := db.AcceptTagSuggestions(beach, db.GetTagSuggestionIds(beach.Name, 0, false)); err != nil {
+		t.Fatal(err)
+	}
+	if got := query("tag:beach"); !slices.Contains(got, "sea.jpg") {
+		t.Errorf("tag:beach after accepting = %v, want sea.jpg", got)
+	}
+	if got := query("tag:suggest:beach"); len(got) != 0 {
+		t.Errorf("tag:suggest:beach after accepting = %v, want none", got)
+	}
+
+	forest, _ := db.GetTagByName("forest")
+	if err := db.RejectTagSuggestions(forest, db.GetTagSuggestionIds(forest.Name, 0, false)); err != nil {
+		t.Fatal(err)
+	}
+	autotag()
+	if got := query("tag:suggest:forest"); len(got) != 0 {
+		t.Errorf("tag:suggest:forest after rejecting = %v, want none", got)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+	"photofield/internal/tag"
+)
+
+func TestListBooleanQualifiers(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path    string
+		created time.Time
+		tags    []string
+	}{
+		{"/photos/beach-2021.jpg", time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC), []string{"beach"}},
+		{"/photos/lake-2021.jpg", time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC), []string{"lake"}},
+		{"/photos/beach-2022.jpg", time.Date(2022, 5, 3, 12, 0, 0, 0, time.UTC), []string{"beach", "family"}},
+		{"/photos/city-2021.png", time.Date(2021, 12, 31, 12, 0, 0, 0, time.UTC), nil},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		if err := db.Write(f.path, image.Info{DateTime: f.created}, image.UpdateMeta); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	<-db.CommitBarrier()
+
+	ids := make(map[string]image.ImageId)
+	for ip := range db.ListIdPaths([]string{"/photos"}, 0) {
+		ids[ip.Path] = ip.Id
+	}
+	for _, f := range files {
+		var tags []tag.Tag
+		for _, name := range f.tags {
+			tags = append(tags, tag.Tag{Name: name})
+		}
+		if err := db.WriteTags(ids[f.path], tags); err != nil {
+			t.Fatalf("write tags %s: %v", f.path, err)
+		}
+	}
+	<-db.CommitBarrier()
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect []string
+	}{
+		{
+			name:   "or",
+			query:  "tag:beach OR tag:lake",
+			expect: []string{"/photos/beach-2021.jpg", "/photos/beach-2022.jpg", "/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "group and created",
+			query:  "(tag:beach OR tag:lake) created:2021",
+			expect: []string{"/photos/beach-2021.jpg", "/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "explicit and",
+			query:  "tag:beach AND tag:family",
+			expect: []string{"/photos/beach-2022.jpg"},
+		},
+		{
+			name:   "negated tag",
+			query:  "NOT tag:beach",
+			expect: []string{"/photos/city-2021.png", "/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "negated group",
+			query:  "NOT (tag:beach OR filename:*.png)",
+			expect: []string{"/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "created alternatives",
+			query:  "created:2022 OR created:2021-08",
+			expect: []string{"/photos/beach-2022.jpg", "/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "wildcard month",
+			query:  "created:*-05-* OR created:*-08-*",
+			expect: []string{"/photos/beach-2022.jpg", "/photos/lake-2021.jpg"},
+		},
+		{
+			name:   "wildcard end of year",
+			query:  "created:*-12-31",
+			expect: []string{"/photos/city-2021.png"},
+		},
+		{
+			name:   "words do not filter",
+			query:  "sunset OR tag:lake",
+			expect: []string{"/photos/beach-2021.jpg", "/photos/beach-2022.jpg", "/photos/city-2021.png", "/photos/lake-2021.jpg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := search.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.query, err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression %q: %v", tc.query, err)
+			}
+			paths := collectListedPaths(t, db, expr)
+			if !slices.Equal(paths, tc.expect) {
+				t.Fatalf("query %q paths = %v, want %v", tc.query, paths, tc.expect)
+			}
+		})
+	}
+}
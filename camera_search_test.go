@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+)
+
+func TestListCameraQualifiers(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path   string
+		camera *image.Camera
+	}{
+		{"/photos/night.jpg", &image.Camera{ISO: 6400, Aperture: 1.8, ExposureTime: 1. / 30, FocalLength35: 35, Lens: "RF35mm F1.8 MACRO IS STM"}},
+		{"/photos/portrait.jpg", &image.Camera{ISO: 200, Aperture: 2.8, ExposureTime: 1. / 250, FocalLength35: 85, Lens: "RF24-105mm F4 L IS USM"}},
+		{"/photos/landscape.jpg", &image.Camera{ISO: 100, Aperture: 8, ExposureTime: 1. / 60, FocalLength35: 24, Lens: "RF24-105mm F4 L IS USM"}},
+		{"/photos/scan.png", nil},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		if err := db.Write(f.path, image.Info{Camera: f.camera}, image.UpdateMeta); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	<-db.CommitBarrier()
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect []string
+	}{
+		{
+			name:   "iso greater than",
+			query:  "iso:>1600",
+			expect: []string{"/photos/night.jpg"},
+		},
+		{
+			name:   "iso at most",
+			query:  "iso:<=200",
+			expect: []string{"/photos/landscape.jpg", "/photos/portrait.jpg"},
+		},
+		{
+			name:   "aperture less than",
+			query:  "f:<2.8",
+			expect: []string{"/photos/night.jpg"},
+		},
+		{
+			name:   "aperture exact",
+			query:  "f:2.8",
+			expect: []string{"/photos/portrait.jpg"},
+		},
+		{
+			name:   "shutter fraction",
+			query:  "shutter:1/60",
+			expect: []string{"/photos/landscape.jpg"},
+		},
+		{
+			name:   "focal range",
+			query:  "focal:24..70",
+			expect: []string{"/photos/landscape.jpg", "/photos/night.jpg"},
+		},
+		{
+			name:   "lens",
+			query:  `lens:"24-105mm F4"`,
+			expect: []string{"/photos/landscape.jpg", "/photos/portrait.jpg"},
+		},
+		{
+			name:   "negated includes unknown",
+			query:  "NOT iso:>1600",
+			expect: []string{"/photos/landscape.jpg", "/photos/portrait.jpg", "/photos/scan.png"},
+		},
+		{
+			name:   "combined",
+			query:  "lens:RF24-105* iso:<200 OR f:<2",
+			expect: []string{"/photos/landscape.jpg", "/photos/night.jpg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := search.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.query, err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression %q: %v", tc.query, err)
+			}
+			paths := collectListedPaths(t, db, expr)
+			if !slices.Equal(paths, tc.expect) {
+				t.Fatalf("query %q paths = %v, want %v", tc.query, paths, tc.expect)
+			}
+		})
+	}
+}
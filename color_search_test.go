@@ -0,0 +1,151 @@
+package main
+
+import (
+	"image/color"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+)
+
+func TestListColor(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path  string
+		color color.RGBA
+	}{
+		{"/photos/sky.jpg", color.RGBA{R: 0x33, G: 0x66, B: 0xff, A: 0xff}},
+		{"/photos/sea.jpg", color.RGBA{R: 0x28, G: 0x5a, B: 0xf0, A: 0xff}},
+		{"/photos/forest.jpg", color.RGBA{R: 0x22, G: 0x8b, B: 0x22, A: 0xff}},
+		{"/photos/sunset.jpg", color.RGBA{R: 0xff, G: 0x45, B: 0x00, A: 0xff}},
+		{"/photos/snow.jpg", color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}},
+		{"/photos/night.jpg", color.RGBA{R: 0x10, G: 0x10, B: 0x10, A: 0xff}},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		info := image.Info{}
+		info.SetColorRGBA(f.color)
+		if err := db.Write(f.path, info, image.UpdateColor); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	if err := db.Write("/photos/unindexed.jpg", image.Info{}, image.AppendPath); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-db.CommitBarrier()
+
+	list := func(t *testing.T, q string, order image.ListOrder) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse %q: %v", q, err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression %q: %v", q, err)
+		}
+		var paths []string
+		results, _ := db.List([]string{"/photos"}, image.ListOptions{
+			Expression: expr,
+			OrderBy:    order,
+		})
+		for result := range results {
+			path, ok := db.GetPathFromId(result.Id)
+			if !ok {
+				t.Fatalf("missing path for image id %d", result.Id)
+			}
+			paths = append(paths, path)
+		}
+		return paths
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		paths := list(t, "color:#3366ff", image.None)
+		slices.Sort(paths)
+		expect := []string{"/photos/sea.jpg", "/photos/sky.jpg"}
+		if !slices.Equal(paths, expect) {
+			t.Fatalf("paths = %v, want %v", paths, expect)
+		}
+	})
+
+	t.Run("negated", func(t *testing.T) {
+		paths := list(t, "NOT color:#3366ff", image.None)
+		slices.Sort(paths)
+		expect := []string{
+			"/photos/forest.jpg",
+			"/photos/night.jpg",
+			"/photos/snow.jpg",
+			"/photos/sunset.jpg",
+			"/photos/unindexed.jpg",
+		}
+		if !slices.Equal(paths, expect) {
+			t.Fatalf("paths = %v, want %v", paths, expect)
+		}
+	})
+
+	t.Run("or", func(t *testing.T) {
+		paths := list(t, "color:#ff4500 OR filename:snow.jpg", image.None)
+		slices.Sort(paths)
+		expect := []string{"/photos/snow.jpg", "/photos/sunset.jpg"}
+		if !slices.Equal(paths, expect) {
+			t.Fatalf("paths = %v, want %v", paths, expect)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		// The limit applies to the matching files, not the first ones
+		query, _ := search.Parse("color:#ff4500")
+		expr, _ := query.Expression()
+		results, _ := db.List([]string{"/photos"}, image.ListOptions{
+			Expression: expr,
+			Limit:      1,
+		})
+		n := 0
+		for range results {
+			n++
+		}
+		if n != 1 {
+			t.Fatalf("listed %d files, want 1", n)
+		}
+	})
+
+	t.Run("rank by distance", func(t *testing.T) {
+		paths := list(t, "color:#2a5cf2,200", image.SimilarityDesc)
+		if len(paths) != len(files) {
+			t.Fatalf("paths = %v, want all %d colored files", paths, len(files))
+		}
+		expect := []string{"/photos/sea.jpg", "/photos/sky.jpg"}
+		if !slices.Equal(paths[:2], expect) {
+			t.Fatalf("paths = %v, want to start with %v", paths, expect)
+		}
+	})
+
+	t.Run("hue", func(t *testing.T) {
+		paths := list(t, "", image.HueAsc)
+		expect := []string{
+			"/photos/sunset.jpg",
+			"/photos/forest.jpg",
+			"/photos/sky.jpg",
+			"/photos/sea.jpg",
+			"/photos/unindexed.jpg",
+			"/photos/night.jpg",
+			"/photos/snow.jpg",
+		}
+		if !slices.Equal(paths, expect) {
+			t.Fatalf("paths = %v, want %v", paths, expect)
+		}
+	})
+
+	t.Run("lightness", func(t *testing.T) {
+		paths := list(t, "", image.LightnessDesc)
+		if paths[0] != "/photos/snow.jpg" || paths[len(paths)-1] != "/photos/unindexed.jpg" {
+			t.Fatalf("paths = %v, want snow first and unindexed last", paths)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"photofield/internal/ai"
+	"photofield/internal/auth"
 	"photofield/internal/collection"
 	"photofield/internal/fs"
 	"photofield/internal/geo"
@@ -13,6 +14,7 @@ import (
 	"photofield/internal/layout"
 	"photofield/internal/render"
 	"photofield/internal/tag"
+	"slices"
 
 	"github.com/goccy/go-yaml"
 	"github.com/imdario/mergo"
@@ -28,11 +30,14 @@ type AppConfig struct {
 	Geo           geo.Config              `json:"geo"`
 	Tags          tag.Config              `json:"tags"`
 	TileRequests  TileRequestConfig       `json:"tile_requests"`
+	Auth          auth.Config             `json:"auth"`
 }
 
 var CONFIG_FILENAME = "configuration.yaml"
 
-func watchConfig(dataDir string, callback func(appConfig *AppConfig)) {
+// watchConfig loads the config and reloads it when it changes. The returned
+// function reloads it on demand, returning once the callback is done.
+func watchConfig(dataDir string, callback func(appConfig *AppConfig)) (reload func()) {
 	w, err := fs.NewFileWatcher(filepath.Join(dataDir, CONFIG_FILENAME))
 	if err != nil {
 		log.Fatalln("Unable to watch config", err)
@@ -59,10 +64,16 @@ func watchConfig(dataDir string, callback func(appConfig *AppConfig)) {
 	}
 
 	reloadConfig()
+	reloads := make(chan chan struct{})
 	go func() {
 		defer w.Close()
 		for {
 			select {
+			case done := <-reloads:
+				log.Println("managed collections changed, reloading")
+				reloadConfig()
+				close(done)
+				continue
 			case <-w.Events:
 				log.Println("config changed, reloading")
 			case e := <-collectionsChanged:
@@ -97,6 +108,11 @@ func watchConfig(dataDir string, callback func(appConfig *AppConfig)) {
 			reloadConfig()
 		}
 	}()
+	return func() {
+		done := make(chan struct{})
+		reloads <- done
+		<-done
+	}
 }
 
 func initDefaults() {
@@ -123,6 +139,12 @@ func loadConfig(dataDir string) (*AppConfig, error) {
 		appConfig = defaults
 	}
 
+	managed, err := loadManagedCollections(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load managed collections: %w", err)
+	}
+	appConfig.Collections = append(slices.Clone(appConfig.Collections), managed.Collections...)
+
 	// Expand collections
 	collections := make([]collection.Collection, 0, len(appConfig.Collections))
 	expandedDirs := make(map[string]bool) // Track deduplicated dirs
@@ -157,6 +179,9 @@ func loadConfig(dataDir string) (*AppConfig, error) {
 		}
 		collectionsMap[collections[i].Id] = i
 	}
+	collections = slices.DeleteFunc(collections, func(c collection.Collection) bool {
+		return slices.Contains(managed.Deleted, c.Id)
+	})
 	appConfig.Collections = collections
 
 	appConfig.Media.AI = appConfig.AI
@@ -0,0 +1,11 @@
+DROP INDEX idx_infos_lens;
+DROP INDEX idx_infos_focal_length_35;
+DROP INDEX idx_infos_exposure_time;
+DROP INDEX idx_infos_aperture;
+DROP INDEX idx_infos_iso;
+
+ALTER TABLE infos DROP COLUMN lens;
+ALTER TABLE infos DROP COLUMN focal_length_35;
+ALTER TABLE infos DROP COLUMN exposure_time;
+ALTER TABLE infos DROP COLUMN aperture;
+ALTER TABLE infos DROP COLUMN iso;
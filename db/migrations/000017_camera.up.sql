@@ -0,0 +1,11 @@
+ALTER TABLE infos ADD COLUMN iso INTEGER;
+ALTER TABLE infos ADD COLUMN aperture REAL;
+ALTER TABLE infos ADD COLUMN exposure_time REAL;
+ALTER TABLE infos ADD COLUMN focal_length_35 REAL;
+ALTER TABLE infos ADD COLUMN lens TEXT;
+
+CREATE INDEX idx_infos_iso ON infos(iso);
+CREATE INDEX idx_infos_aperture ON infos(aperture);
+CREATE INDEX idx_infos_exposure_time ON infos(exposure_time);
+CREATE INDEX idx_infos_focal_length_35 ON infos(focal_length_35);
+CREATE INDEX idx_infos_lens ON infos(lens);
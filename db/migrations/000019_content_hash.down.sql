@@ -0,0 +1,4 @@
+DROP INDEX idx_infos_hash_partial;
+
+ALTER TABLE infos DROP COLUMN hash_full;
+ALTER TABLE infos DROP COLUMN hash_partial;
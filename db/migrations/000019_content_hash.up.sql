@@ -0,0 +1,4 @@
+ALTER TABLE infos ADD COLUMN hash_partial INTEGER;
+ALTER TABLE infos ADD COLUMN hash_full BLOB;
+
+CREATE INDEX idx_infos_hash_partial ON infos(hash_partial);
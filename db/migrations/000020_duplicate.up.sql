@@ -0,0 +1,7 @@
+CREATE TABLE duplicate (
+    file_id INTEGER PRIMARY KEY REFERENCES infos(id),
+    group_id INTEGER NOT NULL,
+    exact INTEGER NOT NULL
+);
+
+CREATE INDEX idx_duplicate_group_id ON duplicate(group_id);
@@ -0,0 +1,2 @@
+DROP TABLE task_error;
+DROP TABLE task_run;
@@ -0,0 +1,26 @@
+CREATE TABLE task_run (
+    id INTEGER PRIMARY KEY,
+    task_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    collection_id TEXT NOT NULL,
+    started_at_ms INTEGER NOT NULL,
+    finished_at_ms INTEGER,
+    done INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX idx_task_run_task_id ON task_run(task_id);
+CREATE INDEX idx_task_run_finished_at_ms ON task_run(finished_at_ms);
+
+CREATE TABLE task_error (
+    id INTEGER PRIMARY KEY,
+    task_run_id INTEGER NOT NULL REFERENCES task_run(id),
+    file_id INTEGER,
+    path TEXT NOT NULL,
+    error TEXT NOT NULL,
+    created_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX idx_task_error_task_run_id ON task_error(task_run_id);
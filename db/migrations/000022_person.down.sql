@@ -0,0 +1,5 @@
+DROP INDEX idx_face_person_id;
+
+ALTER TABLE face DROP COLUMN person_id;
+
+DROP TABLE person;
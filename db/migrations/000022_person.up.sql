@@ -0,0 +1,13 @@
+CREATE TABLE person (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    hidden INTEGER NOT NULL DEFAULT 0,
+    created_at_ms INTEGER NOT NULL,
+    updated_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX idx_person_name ON person(name COLLATE NOCASE);
+
+ALTER TABLE face ADD COLUMN person_id INTEGER REFERENCES person(id);
+
+CREATE INDEX idx_face_person_id ON face(person_id);
@@ -0,0 +1,3 @@
+DROP TABLE tag_suggestion;
+
+ALTER TABLE tag DROP COLUMN auto;
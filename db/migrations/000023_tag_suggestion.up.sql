@@ -0,0 +1,13 @@
+ALTER TABLE tag ADD COLUMN auto BOOLEAN NOT NULL DEFAULT 0;
+
+-- Suggestions refer to tags by name, as every change of a tag adds a new
+-- version of it with a new id
+CREATE TABLE tag_suggestion (
+    tag_name TEXT NOT NULL,
+    file_id INTEGER NOT NULL REFERENCES infos(id),
+    confidence REAL NOT NULL,
+    rejected BOOLEAN NOT NULL DEFAULT 0,
+    PRIMARY KEY (tag_name, file_id)
+);
+
+CREATE INDEX idx_tag_suggestion_file_id ON tag_suggestion(file_id);
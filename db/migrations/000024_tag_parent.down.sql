@@ -0,0 +1,3 @@
+DROP INDEX idx_tag_parent_id;
+
+ALTER TABLE tag DROP COLUMN parent_id;
@@ -0,0 +1,5 @@
+-- Parent of hierarchical tags, e.g. places/europe for places/europe/slovenia.
+-- Refers to the active version of the parent tag.
+ALTER TABLE tag ADD COLUMN parent_id INTEGER;
+
+CREATE INDEX idx_tag_parent_id ON tag(parent_id);
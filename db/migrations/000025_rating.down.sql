@@ -0,0 +1 @@
+ALTER TABLE infos DROP COLUMN rating;
@@ -0,0 +1,2 @@
+-- Rating from 1 to 5, -1 if rejected, NULL if unrated
+ALTER TABLE infos ADD COLUMN rating INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE infos DROP COLUMN favorite;
+ALTER TABLE infos DROP COLUMN label;
@@ -0,0 +1,3 @@
+-- Color label, one of red, yellow, green, blue or purple, NULL if unlabeled
+ALTER TABLE infos ADD COLUMN label TEXT;
+ALTER TABLE infos ADD COLUMN favorite BOOLEAN NOT NULL DEFAULT 0;
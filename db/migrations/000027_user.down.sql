@@ -0,0 +1,7 @@
+DROP INDEX idx_user_token_user_id;
+DROP TABLE user_token;
+
+DROP INDEX idx_user_session_user_id;
+DROP TABLE user_session;
+
+DROP TABLE user;
@@ -0,0 +1,29 @@
+-- Users of the app when authentication is enabled.
+-- password_hash is encoded with its algorithm and parameters, see internal/auth.
+CREATE TABLE user (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at_ms INTEGER NOT NULL
+);
+
+-- Login sessions and API tokens only store the SHA-256 hash of the token.
+CREATE TABLE user_session (
+    token_hash BLOB PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at_ms INTEGER NOT NULL,
+    expires_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX idx_user_session_user_id ON user_session(user_id);
+
+CREATE TABLE user_token (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    token_hash BLOB NOT NULL UNIQUE,
+    created_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX idx_user_token_user_id ON user_token(user_id);
@@ -0,0 +1,13 @@
+-- Public share links of a collection, optionally narrowed down by a search
+-- or a selection tag. Only the SHA-256 hash of the token is stored.
+CREATE TABLE share (
+    id INTEGER PRIMARY KEY,
+    token_hash BLOB NOT NULL UNIQUE,
+    collection_id TEXT NOT NULL,
+    search TEXT NOT NULL DEFAULT '',
+    tag_id TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    download INTEGER NOT NULL DEFAULT 0,
+    created_at_ms INTEGER NOT NULL,
+    expires_at_ms INTEGER NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX idx_tag_name;
@@ -0,0 +1,2 @@
+-- Speeds up looking up tags and their descendants by name.
+CREATE INDEX idx_tag_name ON tag(name);
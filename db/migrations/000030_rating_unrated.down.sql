@@ -0,0 +1 @@
+-- Rejected files cannot be told apart from unrated ones anymore
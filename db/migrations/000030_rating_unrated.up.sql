@@ -0,0 +1,2 @@
+-- Ratings are from 1 to 5, so files rejected with -1 become unrated
+UPDATE infos SET rating = NULL WHERE rating < 1;
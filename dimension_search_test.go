@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+)
+
+func TestListDimensionQualifiers(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path string
+		info image.Info
+	}{
+		{"/photos/landscape.jpg", image.Info{Width: 6000, Height: 4000, Orientation: image.Normal}},
+		{"/photos/portrait.jpg", image.Info{Width: 4000, Height: 6000, Orientation: image.Rotate90}},
+		{"/photos/pano.jpg", image.Info{Width: 12000, Height: 3000, Orientation: image.Normal}},
+		{"/photos/square.jpg", image.Info{Width: 1000, Height: 1010, Orientation: image.MirrorHorizontal}},
+		{"/photos/scan.png", image.Info{Width: 640, Height: 480}},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		if err := db.Write(f.path, f.info, image.UpdateMeta); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	if err := db.Write("/photos/unindexed.jpg", image.Info{}, image.AppendPath); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-db.CommitBarrier()
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect []string
+	}{
+		{
+			name:   "width",
+			query:  "width:>4000",
+			expect: []string{"/photos/landscape.jpg", "/photos/pano.jpg"},
+		},
+		{
+			name:   "height range",
+			query:  "height:1000..4000",
+			expect: []string{"/photos/landscape.jpg", "/photos/pano.jpg", "/photos/square.jpg"},
+		},
+		{
+			name:   "megapixels",
+			query:  "mp:>20",
+			expect: []string{"/photos/landscape.jpg", "/photos/pano.jpg", "/photos/portrait.jpg"},
+		},
+		{
+			name:   "low resolution",
+			query:  "mp:<1",
+			expect: []string{"/photos/scan.png"},
+		},
+		{
+			name:   "portrait",
+			query:  "aspect:portrait",
+			expect: []string{"/photos/portrait.jpg"},
+		},
+		{
+			name:   "landscape",
+			query:  "aspect:landscape",
+			expect: []string{"/photos/landscape.jpg", "/photos/pano.jpg", "/photos/scan.png"},
+		},
+		{
+			name:   "square",
+			query:  "aspect:square",
+			expect: []string{"/photos/square.jpg"},
+		},
+		{
+			name:   "pano",
+			query:  "aspect:pano",
+			expect: []string{"/photos/pano.jpg"},
+		},
+		{
+			name:   "ratio",
+			query:  "aspect:4/3",
+			expect: []string{"/photos/scan.png"},
+		},
+		{
+			name:   "rotated",
+			query:  "orientation:rotated",
+			expect: []string{"/photos/portrait.jpg"},
+		},
+		{
+			name:   "not rotated",
+			query:  "NOT orientation:rotated",
+			expect: []string{"/photos/landscape.jpg", "/photos/pano.jpg", "/photos/scan.png", "/photos/square.jpg", "/photos/unindexed.jpg"},
+		},
+		{
+			name:   "mirrored",
+			query:  "orientation:mirrored",
+			expect: []string{"/photos/square.jpg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := search.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.query, err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression %q: %v", tc.query, err)
+			}
+			paths := collectListedPaths(t, db, expr)
+			if !slices.Equal(paths, tc.expect) {
+				t.Fatalf("query %q paths = %v, want %v", tc.query, paths, tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"photofield/internal/image"
+
+	"github.com/gosimple/slug"
+)
+
+// downloadSize returns the name of the resized variant to download, if any.
+func downloadSize(size *string) (string, error) {
+	if size == nil || *size == "" {
+		return "", nil
+	}
+	if !imageSource.HasReader(*size) {
+		return "", fmt.Errorf("unknown size %s", *size)
+	}
+	return *size, nil
+}
+
+// tagZipFilename returns the name of the archive of the tag, as tag names
+// can contain characters not allowed in filenames.
+func tagZipFilename(name string) string {
+	if strings.HasPrefix(name, "sys:select:") {
+		return "selection.zip"
+	}
+	return slug.Make(name) + ".zip"
+}
+
+// writeZip streams the files as a ZIP archive to the response. The files are
+// stored without compression, as photos and videos are compressed already,
+// so the download starts right away and needs no temp files. Large archives
+// switch to ZIP64 as needed.
+//
+// If size is set, the files are read from the resized variant of the source
+// with the name, falling back to the originals for files without it.
+func writeZip(w http.ResponseWriter, r *http.Request, filename string, ids []image.ImageId, size string) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filename,
+	}))
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	names := make(map[string]int)
+	for _, id := range ids {
+		if err := r.Context().Err(); err != nil {
+			log.Printf("zip %s canceled: %v", filename, err)
+			return
+		}
+		if err := writeZipImage(zw, names, id, size); err != nil {
+			// The archive is broken at this point, so stop writing it
+			log.Printf("zip %s unable to write file %d: %v", filename, id, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("zip %s unable to close: %v", filename, err)
+	}
+}
+
+// writeZipImage writes the file of the image to the archive, skipping it if
+// it cannot be opened.
+func writeZipImage(zw *zip.Writer, names map[string]int, id image.ImageId, size string) error {
+	path, err := imageSource.GetImagePath(id)
+	if err != nil {
+		log.Printf("zip skipping file %d: %v", id, err)
+		return nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		log.Printf("zip skipping file %d: %v", id, err)
+		return nil
+	}
+
+	if size != "" {
+		written := false
+		var werr error
+		imageSource.GetImageReader(id, size, func(rs io.ReadSeeker, err error) {
+			if err != nil {
+				return
+			}
+			written = true
+			name, err := variantFilename(filepath.Base(path), rs)
+			if err != nil {
+				werr = err
+				return
+			}
+			werr = writeZipFile(zw, uniqueZipName(names, name), stat.ModTime(), rs)
+		})
+		if written {
+			return werr
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("zip skipping file %d: %v", id, err)
+		return nil
+	}
+	defer f.Close()
+	return writeZipFile(zw, uniqueZipName(names, filepath.Base(path)), stat.ModTime(), f)
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, r io.Reader) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// uniqueZipName returns the name, numbered if it was returned before, as
+// files of different dirs can have the same name.
+func uniqueZipName(names map[string]int, name string) string {
+	key := strings.ToLower(name)
+	names[key]++
+	n := names[key]
+	if n == 1 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	unique := fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+	if _, ok := names[strings.ToLower(unique)]; ok {
+		return uniqueZipName(names, unique)
+	}
+	names[strings.ToLower(unique)] = 1
+	return unique
+}
+
+// variantFilename returns the name of the original file with the extension
+// of the format of the variant, e.g. a JPEG thumbnail of a PNG.
+func variantFilename(name string, rs io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(rs, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	var ext string
+	switch http.DetectContentType(head[:n]) {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/png":
+		ext = ".png"
+	case "image/webp":
+		ext = ".webp"
+	case "image/gif":
+		ext = ".gif"
+	case "video/mp4":
+		ext = ".mp4"
+	default:
+		return name, nil
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ext, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestUniqueZipName(t *testing.T) {
+	names := make(map[string]int)
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"a.jpg", "a.jpg"},
+		{"b.jpg", "b.jpg"},
+		{"a.jpg", "a (2).jpg"},
+		{"A.JPG", "A (3).JPG"},
+		{"a (2).jpg", "a (2) (2).jpg"},
+	} {
+		if got := uniqueZipName(names, tt.name); got != tt.want {
+			t.Errorf("unique name of %s %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVariantFilename(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	rs := bytes.NewReader(b.Bytes())
+	name, err := variantFilename("photo.heic", rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "photo.png" {
+		t.Errorf("variant filename %q, want photo.png", name)
+	}
+	if pos, _ := rs.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("reader at %d, want rewound to 0", pos)
+	}
+
+	name, err = variantFilename("notes.txt", bytes.NewReader([]byte("text")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "notes.txt" {
+		t.Errorf("variant filename %q, want unchanged", name)
+	}
+}
+
+func TestWriteZipFile(t *testing.T) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	files := map[string]string{
+		"a.jpg": "first",
+		"b.jpg": "second",
+	}
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		if err := writeZipFile(zw, name, modified, bytes.NewReader([]byte(files[name]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != len(files) {
+		t.Fatalf("files %d, want %d", len(zr.File), len(files))
+	}
+	for _, f := range zr.File {
+		if f.Method != zip.Store {
+			t.Errorf("%s method %d, want store", f.Name, f.Method)
+		}
+		if !f.Modified.Equal(modified) {
+			t.Errorf("%s modified %v, want %v", f.Name, f.Modified, modified)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != files[f.Name] {
+			t.Errorf("%s content %q, want %q", f.Name, content, files[f.Name])
+		}
+	}
+}
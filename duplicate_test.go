@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"photofield/internal/ai"
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/search"
+	"photofield/internal/task"
+
+	"github.com/x448/float16"
+)
+
+func testEmbedding(v ...float32) ai.Embedding {
+	norm := float32(0)
+	for _, f := range v {
+		norm += f * f
+	}
+	invnorm := float32(1 / math.Sqrt(float64(norm)))
+	bytes := make([]byte, 2*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint16(bytes[2*i:], float16.Fromfloat32(f).Bits())
+	}
+	return ai.FromRaw(bytes, float16.Fromfloat32(invnorm).Bits())
+}
+
+func TestIndexDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	rnd := rand.New(rand.NewSource(1))
+	content := func(size int) []byte {
+		b := make([]byte, size)
+		rnd.Read(b)
+		return b
+	}
+	same := content(2000)
+	files := map[string][]byte{
+		"original.jpg": same,
+		"copy.jpg":     same,
+		"burst-1.jpg":  content(2000),
+		"burst-2.jpg":  content(2000),
+		"other.jpg":    content(2000),
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{dir + string(filepath.Separator)}
+	cfg := pipeline.Config{
+		DB:                  db,
+		Extensions:          []string{".jpg"},
+		DuplicateSimilarity: 0.95,
+	}
+	if err := pipeline.RunFiles(context.Background(), cfg, task.NewFilesTask("test", "Test", dirs, 0)); err != nil {
+		t.Fatalf("index files: %v", err)
+	}
+
+	ids := make(map[string]image.ImageId)
+	for ip := range db.ListIdPaths(dirs, 0) {
+		ids[filepath.Base(ip.Path)] = ip.Id
+	}
+	db.WriteAI(ids["burst-1.jpg"], testEmbedding(1, 0, 0, 0))
+	db.WriteAI(ids["burst-2.jpg"], testEmbedding(0.98, 0.2, 0, 0))
+	db.WriteAI(ids["other.jpg"], testEmbedding(0, 0, 1, 0))
+	<-db.CommitBarrier()
+
+	if err := pipeline.RunDuplicates(context.Background(), cfg, task.NewDuplicatesTask("test", "Test", dirs)); err != nil {
+		t.Fatalf("index duplicates: %v", err)
+	}
+
+	// The file with the lowest id of each group is the one to keep
+	extra := func(a, b string) string {
+		if ids[a] > ids[b] {
+			return a
+		}
+		return b
+	}
+
+	testCases := []struct {
+		query  string
+		expect []string
+	}{
+		{"dup:any", []string{"burst-1.jpg", "burst-2.jpg", "copy.jpg", "original.jpg"}},
+		{"dup:exact", []string{"copy.jpg", "original.jpg"}},
+		{"dup:near", []string{"burst-1.jpg", "burst-2.jpg"}},
+		{"dup:extra", []string{extra("burst-1.jpg", "burst-2.jpg"), extra("original.jpg", "copy.jpg")}},
+		{"NOT dup:any", []string{"other.jpg"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := search.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression: %v", err)
+			}
+			results, _ := db.List(dirs, image.ListOptions{Expression: expr})
+			var names []string
+			for result := range results {
+				path, ok := db.GetPathFromId(result.Id)
+				if !ok {
+					t.Fatalf("missing path for image id %d", result.Id)
+				}
+				names = append(names, filepath.Base(path))
+			}
+			slices.Sort(names)
+			expect := slices.Clone(tc.expect)
+			slices.Sort(expect)
+			if !slices.Equal(names, expect) {
+				t.Fatalf("%s = %v, want %v", tc.query, names, expect)
+			}
+		})
+	}
+
+	db.Delete(ids["copy.jpg"])
+	<-db.CommitBarrier()
+	duplicates := db.ListDuplicates(dirs)
+	if _, ok := duplicates[ids["copy.jpg"]]; ok {
+		t.Errorf("deleted file is still a duplicate")
+	}
+	if len(duplicates) != 3 {
+		t.Errorf("duplicates after delete = %v, want 3", duplicates)
+	}
+}
+
+func TestIndexDuplicatesEmbeddingIndex(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	// Near duplicates further apart in time than the files compared without
+	// the embedding index
+	dir := "/photos/"
+	dirs := []string{dir}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		db.Write(fmt.Sprintf("%s%03d.jpg", dir, i), image.Info{DateTime: start.Add(time.Duration(i) * time.Hour)}, image.AppendPath)
+	}
+	<-db.CommitBarrier()
+
+	rnd := rand.New(rand.NewSource(1))
+	ids := make(map[string]image.ImageId)
+	for ip := range db.ListIdPaths(dirs, 0) {
+		ids[filepath.Base(ip.Path)] = ip.Id
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = float32(rnd.NormFloat64())
+		}
+		db.WriteAI(ip.Id, testEmbedding(v...))
+	}
+	db.WriteAI(ids["000.jpg"], testEmbedding(1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0))
+	db.WriteAI(ids["029.jpg"], testEmbedding(0.98, 0.2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0))
+	<-db.CommitBarrier()
+
+	db.EnableEmbeddingIndexes(image.EmbeddingIndexConfig{Enable: true})
+	db.LoadEmbeddingIndexes()
+
+	cfg := pipeline.Config{
+		DB:                  db,
+		DuplicateSimilarity: 0.95,
+	}
+	if err := pipeline.RunDuplicates(context.Background(), cfg, task.NewDuplicatesTask("test", "Test", dirs)); err != nil {
+		t.Fatalf("index duplicates: %v", err)
+	}
+
+	duplicates := db.ListDuplicates(dirs)
+	first, ok := duplicates[ids["000.jpg"]]
+	if !ok {
+		t.Fatalf("duplicates = %v, want 000.jpg", duplicates)
+	}
+	last, ok := duplicates[ids["029.jpg"]]
+	if !ok || last.GroupId != first.GroupId {
+		t.Fatalf("duplicates = %v, want 000.jpg and 029.jpg grouped", duplicates)
+	}
+}
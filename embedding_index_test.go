@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photofield/internal/ai"
+	"photofield/internal/image"
+	"photofield/internal/search"
+)
+
+func TestEmbeddingIndexSearch(t *testing.T) {
+	dataDir := t.TempDir()
+	dbPath := filepath.Join(dataDir, "photofield.cache.db")
+	db := image.NewDatabase(dbPath, migrations)
+
+	rnd := rand.New(rand.NewSource(1))
+	randomEmbedding := func() (ai.Embedding, []float32) {
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = float32(rnd.NormFloat64())
+		}
+		return testEmbedding(v...), v
+	}
+
+	dir := "/photos/"
+	dirs := []string{dir}
+	for i := 0; i < 300; i++ {
+		db.Write(fmt.Sprintf("%s%03d.jpg", dir, i), image.Info{}, image.AppendPath)
+	}
+	<-db.CommitBarrier()
+	vecs := make(map[image.ImageId][]float32)
+	for ip := range db.ListIdPaths(dirs, 0) {
+		emb, v := randomEmbedding()
+		vecs[ip.Id] = v
+		db.WriteAI(ip.Id, emb)
+	}
+	<-db.CommitBarrier()
+
+	config := image.EmbeddingIndexConfig{Enable: true, Results: 10}
+	db.EnableEmbeddingIndexes(config)
+	db.LoadEmbeddingIndexes()
+
+	similar := func(db *image.Database, emb ai.Embedding) []image.ImageId {
+		t.Helper()
+		infos, _ := db.List(dirs, image.ListOptions{
+			OrderBy:        image.SimilarityDesc,
+			ImageEmbedding: emb,
+		})
+		ids := make([]image.ImageId, 0)
+		for info := range infos {
+			ids = append(ids, info.Id)
+		}
+		return ids
+	}
+
+	var queryId image.ImageId
+	for id := range vecs {
+		queryId = id
+		break
+	}
+	ids := similar(db, testEmbedding(vecs[queryId]...))
+	if len(ids) != config.Results {
+		t.Fatalf("listed %d files, want %d most similar", len(ids), config.Results)
+	}
+	if ids[0] != queryId {
+		t.Errorf("most similar = %d, want %d", ids[0], queryId)
+	}
+
+	// Conditions apply before the most similar files are limited
+	query, err := search.Parse("filename:*0.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := query.Expression()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip := range db.ListIdPaths(dirs, 0) {
+		if strings.HasSuffix(ip.Path, "0.jpg") {
+			queryId = ip.Id
+			break
+		}
+	}
+	infos, _ := db.List(dirs, image.ListOptions{
+		OrderBy:        image.SimilarityDesc,
+		ImageEmbedding: testEmbedding(vecs[queryId]...),
+		Expression:     expr,
+		Limit:          config.Results,
+	})
+	var paths []string
+	for info := range infos {
+		path, _ := db.GetPathFromId(info.Id)
+		paths = append(paths, path)
+		if !strings.HasSuffix(path, "0.jpg") {
+			t.Errorf("listed %s, want only files matching the condition", path)
+		}
+	}
+	if len(paths) != config.Results {
+		t.Errorf("listed %d files with condition, want %d", len(paths), config.Results)
+	}
+
+	// Files indexed later are searchable right away
+	db.Write(dir+"new.jpg", image.Info{}, image.AppendPath)
+	<-db.CommitBarrier()
+	newEmb, newVec := randomEmbedding()
+	for ip := range db.ListIdPaths(dirs, 0) {
+		if filepath.Base(ip.Path) == "new.jpg" {
+			db.WriteAI(ip.Id, newEmb)
+			queryId = ip.Id
+		}
+	}
+	<-db.CommitBarrier()
+	if ids := similar(db, testEmbedding(newVec...)); len(ids) == 0 || ids[0] != queryId {
+		t.Errorf("most similar = %v, want new file %d first", ids, queryId)
+	}
+	db.Close()
+
+	// The index is saved next to the database and loaded on the next start
+	if _, err := os.Stat(filepath.Join(dataDir, "photofield.cache.clip.hnsw")); err != nil {
+		t.Fatal(err)
+	}
+	db = image.NewDatabase(dbPath, migrations)
+	defer db.Close()
+	db.EnableEmbeddingIndexes(config)
+	db.LoadEmbeddingIndexes()
+	if ids := similar(db, testEmbedding(newVec...)); len(ids) != config.Results || ids[0] != queryId {
+		t.Errorf("most similar after reload = %v, want new file %d first", ids, queryId)
+	}
+
+	// Small collections compare all embeddings
+	db.EnableEmbeddingIndexes(image.EmbeddingIndexConfig{Enable: true, MinFiles: 1000, Results: 10})
+	db.LoadEmbeddingIndexes()
+	if ids := similar(db, testEmbedding(newVec...)); len(ids) != len(vecs)+1 {
+		t.Errorf("listed %d files, want all %d", len(ids), len(vecs)+1)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"photofield/internal/collection"
+	"photofield/internal/event"
+	"photofield/internal/render"
+	"photofield/internal/scene"
+	"photofield/internal/tag"
+	inttask "photofield/internal/task"
+)
+
+const (
+	EVENT_BUFFER             = 256
+	EVENT_TASK_INTERVAL      = 500 * time.Millisecond
+	EVENT_KEEPALIVE_INTERVAL = 30 * time.Second
+)
+
+// eventHub broadcasts the changes of tasks, collections, tags and scenes to
+// the clients of GET /events.
+var eventHub = event.NewHub(EVENT_BUFFER)
+
+type TaskFinishedEvent struct {
+	Id           string `json:"id"`
+	Type         string `json:"type"`
+	CollectionId string `json:"collection_id"`
+}
+
+type CollectionEvent struct {
+	Id          string `json:"id"`
+	UpdatedAtMs int64  `json:"updated_at_ms"`
+}
+
+type TagEvent struct {
+	Id          string `json:"id"`
+	UpdatedAtMs int64  `json:"updated_at_ms"`
+}
+
+type SceneEvent struct {
+	Id           string `json:"id"`
+	CollectionId string `json:"collection_id"`
+	Loading      bool   `json:"loading"`
+	FileCount    int    `json:"file_count"`
+	Error        string `json:"error,omitempty"`
+}
+
+// handleEvents publishes the changes of the scenes and the pipeline tasks.
+// Tag updates are published per image source, see applyConfig, and
+// collection invalidations by invalidateCollection.
+func handleEvents() {
+	sceneSource.HandleLoads(publishSceneLoad)
+	go publishTaskProgress(EVENT_TASK_INTERVAL)
+}
+
+// invalidateCollection invalidates the collection, so that its scenes are
+// reloaded, and publishes the invalidation.
+func invalidateCollection(c *collection.Collection) {
+	c.Invalidate()
+	publishCollectionInvalidation(c)
+}
+
+func publishCollectionInvalidation(c *collection.Collection) {
+	eventHub.Publish(event.Event{
+		Type:         "collection",
+		CollectionId: c.Id,
+		Data: CollectionEvent{
+			Id:          c.Id,
+			UpdatedAtMs: c.UpdatedAt().UnixMilli(),
+		},
+	})
+}
+
+func publishSceneLoad(s *render.Scene, config scene.SceneConfig) {
+	eventHub.Publish(event.Event{
+		Type:         "scene",
+		CollectionId: config.Collection.Id,
+		Data: SceneEvent{
+			Id:           s.Id,
+			CollectionId: config.Collection.Id,
+			Loading:      s.Loading,
+			FileCount:    s.FileCount,
+			Error:        s.Error,
+		},
+	})
+}
+
+// publishTagUpdate publishes the update of the files of a tag. Selections
+// are only published to the clients allowed to access their collection.
+func publishTagUpdate(id tag.Id, name string, updatedAt time.Time) {
+	if eventHub.Len() == 0 {
+		return
+	}
+	collectionId, _ := tag.SelectionCollectionId(name)
+	eventHub.Publish(event.Event{
+		Type:         "tag",
+		CollectionId: collectionId,
+		Data: TagEvent{
+			Id:          name,
+			UpdatedAtMs: updatedAt.UnixMilli(),
+		},
+	})
+}
+
+// publishTaskProgress periodically publishes the pipeline tasks that made
+// progress since the last tick and the ones that finished, while there are
+// clients to receive them.
+func publishTaskProgress(interval time.Duration) {
+	last := make(map[string]Task)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if eventHub.Len() == 0 || pipelineCoordinator == nil {
+			clear(last)
+			continue
+		}
+		last = publishTaskDeltas(last, pipelineCoordinator.List())
+	}
+}
+
+// publishTaskDeltas publishes the tasks that changed from the last ones and
+// the last ones that are no longer active, returning the current tasks.
+func publishTaskDeltas(last map[string]Task, tasks []*inttask.Task) map[string]Task {
+	current := make(map[string]Task, len(tasks))
+	for _, pt := range tasks {
+		t := pipelineTaskItem(pt)
+		current[t.Id] = *t
+		prev, ok := last[t.Id]
+		if ok && prev.Done == t.Done && prev.Total == t.Total && prev.RunId == t.RunId {
+			continue
+		}
+		eventHub.Publish(event.Event{
+			Type:         "task",
+			CollectionId: t.CollectionId,
+			Data:         t,
+		})
+	}
+	for id, t := range last {
+		if _, ok := current[id]; ok {
+			continue
+		}
+		eventHub.Publish(event.Event{
+			Type:         "task_finished",
+			CollectionId: t.CollectionId,
+			Data: TaskFinishedEvent{
+				Id:           t.Id,
+				Type:         t.Type,
+				CollectionId: t.CollectionId,
+			},
+		})
+	}
+	return current
+}
+
+func (*Api) GetEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	events, unsubscribe := eventHub.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disable response buffering of nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepalive := time.NewTicker(EVENT_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-events:
+			if !ok {
+				// Fell behind, the client reconnects and reloads
+				return
+			}
+			if !canAccessEvent(r, e) {
+				continue
+			}
+			if err := event.Write(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
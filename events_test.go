@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photofield/internal/event"
+	"photofield/internal/image"
+	"photofield/internal/tag"
+	inttask "photofield/internal/task"
+)
+
+func TestPublishTaskDeltas(t *testing.T) {
+	events, unsubscribe := eventHub.Subscribe()
+	defer unsubscribe()
+
+	next := func() event.Event {
+		select {
+		case e := <-events:
+			return e
+		default:
+			return event.Event{}
+		}
+	}
+
+	pt := inttask.New(inttask.TypeIndexFiles, "files-vacation", "Indexing files", "vacation")
+	pt.SetTotal(10)
+	last := publishTaskDeltas(nil, []*inttask.Task{pt})
+	e := next()
+	if e.Type != "task" || e.CollectionId != "vacation" || e.Data.(*Task).Total != 10 {
+		t.Errorf("event %+v, want task with total 10", e)
+	}
+
+	last = publishTaskDeltas(last, []*inttask.Task{pt})
+	if e := next(); e.Type != "" {
+		t.Errorf("event %+v for unchanged task", e)
+	}
+
+	pt.AddDone(3)
+	last = publishTaskDeltas(last, []*inttask.Task{pt})
+	if e := next(); e.Type != "task" || e.Data.(*Task).Done != 3 || e.Data.(*Task).Pending != 7 {
+		t.Errorf("event %+v, want task with 3 done", e)
+	}
+
+	publishTaskDeltas(last, nil)
+	e = next()
+	if e.Type != "task_finished" || e.Data.(TaskFinishedEvent).Id != "files-vacation" {
+		t.Errorf("event %+v, want finished task", e)
+	}
+}
+
+func TestTagUpdates(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+	<-db.CommitBarrier()
+
+	type update struct {
+		id        tag.Id
+		name      string
+		updatedAt time.Time
+	}
+	updates := make(chan update, 10)
+	db.HandleTagUpdates(func(id tag.Id, name string, updatedAt time.Time) {
+		updates <- update{id, name, updatedAt}
+	})
+
+	done, err := db.AddTag("beach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	tg, ok := db.GetTagByName("beach")
+	if !ok {
+		t.Fatal("tag not found")
+	}
+	ids := image.NewIds()
+	ids.AddInt(1)
+	for _, change := range []func(tag.Id, image.Ids) time.Time{db.AddTagIds, db.RemoveTagIds} {
+		updatedAt := change(tg.Id, ids)
+		select {
+		case u := <-updates:
+			if !u.updatedAt.Equal(updatedAt) {
+				t.Errorf("updated at %v, want %v", u.updatedAt, updatedAt)
+			}
+			if u.name != "beach" {
+				t.Errorf("updated tag name %q, want beach", u.name)
+			}
+			got, ok := db.GetTag(u.id)
+			if !ok || got.Name != "beach" {
+				t.Errorf("tag %+v, want beach", got)
+			}
+			tg = got
+		case <-time.After(5 * time.Second):
+			t.Fatal("tag update not handled")
+		}
+	}
+}
+
+func TestSelectionCollectionId(t *testing.T) {
+	selection, err := tag.NewSelection("vacation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := tag.SelectionCollectionId(selection.Name); !ok || id != "vacation" {
+		t.Errorf("collection id %q, want vacation", id)
+	}
+	if _, ok := tag.SelectionCollectionId("beach"); ok {
+		t.Error("collection id of a regular tag")
+	}
+}
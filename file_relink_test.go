@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/task"
+)
+
+func TestIndexFilesRelinksMovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	rnd := rand.New(rand.NewSource(1))
+	content := func(size int) []byte {
+		b := make([]byte, size)
+		rnd.Read(b)
+		return b
+	}
+	write := func(path string, b []byte) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	move := func(from, to string) {
+		t.Helper()
+		to = filepath.Join(dir, to)
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, from), to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := func() map[string]image.ImageId {
+		t.Helper()
+		cfg := pipeline.Config{
+			DB:          db,
+			Extensions:  []string{".jpg"},
+			ContentHash: true,
+		}
+		dirs := []string{dir + string(filepath.Separator)}
+		tsk := task.NewFilesTask("test", "Test", dirs, 0)
+		if err := pipeline.RunFiles(context.Background(), cfg, tsk); err != nil {
+			t.Fatalf("index files: %v", err)
+		}
+		ids := make(map[string]image.ImageId)
+		for ip := range db.ListIdPaths(dirs, 0) {
+			rel, err := filepath.Rel(dir, ip.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[filepath.ToSlash(rel)] = ip.Id
+		}
+		return ids
+	}
+
+	dup := content(300 * 1024)
+	write("a/large.jpg", content(500*1024))
+	write("a/small.jpg", content(1000))
+	write("a/removed.jpg", content(2000))
+	write("dup/1.jpg", dup)
+	write("dup/2.jpg", dup)
+	before := index()
+	if len(before) != 5 {
+		t.Fatalf("indexed %v, want 5 files", before)
+	}
+
+	move("a/large.jpg", "b/large-renamed.jpg")
+	move("a/small.jpg", "a/small-renamed.jpg")
+	move("dup/1.jpg", "moved/1.jpg")
+	if err := os.Remove(filepath.Join(dir, "a/removed.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	after := index()
+
+	expect := map[string]image.ImageId{
+		"b/large-renamed.jpg": before["a/large.jpg"],
+		"a/small-renamed.jpg": before["a/small.jpg"],
+		"moved/1.jpg":         before["dup/1.jpg"],
+		"dup/2.jpg":           before["dup/2.jpg"],
+	}
+	if len(after) != len(expect) {
+		t.Fatalf("indexed %v, want %v", after, expect)
+	}
+	for path, id := range expect {
+		if after[path] != id {
+			t.Errorf("%s has id %d, want %d", path, after[path], id)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// testPlaces resolves place names to bounds that are usually looked up by
+// geocoding.
+type testPlaces map[string][]search.Bounds
+
+func (p testPlaces) Bounds(ctx context.Context, name string) ([]s2.Rect, error) {
+	rects := make([]s2.Rect, 0, len(p[name]))
+	for _, b := range p[name] {
+		rects = append(rects, s2.Rect{
+			Lat: r1.Interval{Lo: b.MinLat * math.Pi / 180, Hi: b.MaxLat * math.Pi / 180},
+			Lng: s1.IntervalFromEndpoints(b.MinLng*math.Pi/180, b.MaxLng*math.Pi/180),
+		})
+	}
+	return rects, nil
+}
+
+func TestListGeoQualifiers(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path    string
+		latlng  s2.LatLng
+		created time.Time
+	}{
+		{"/photos/ljubljana-castle.jpg", s2.LatLngFromDegrees(46.0489, 14.5087), time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{"/photos/ljubljana-tivoli.jpg", s2.LatLngFromDegrees(46.0546, 14.4961), time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{"/photos/bled.jpg", s2.LatLngFromDegrees(46.3636, 14.0938), time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)},
+		{"/photos/fiji.jpg", s2.LatLngFromDegrees(-17.7134, 178.065), time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)},
+		{"/photos/scan.png", image.NaNLatLng(), time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		if err := db.Write(f.path, image.Info{LatLng: f.latlng, DateTime: f.created}, image.UpdateMeta); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	<-db.CommitBarrier()
+
+	db.EnablePlaces(testPlaces{
+		"Ljubljana": {{MinLat: 45.95, MinLng: 14.35, MaxLat: 46.15, MaxLng: 14.75}},
+		"Fiji":      {{MinLat: -21, MinLng: 177, MaxLat: -12, MaxLng: -178}},
+	})
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect []string
+	}{
+		{
+			name:   "near",
+			query:  "near:46.05,14.5,5km",
+			expect: []string{"/photos/ljubljana-castle.jpg", "/photos/ljubljana-tivoli.jpg"},
+		},
+		{
+			name:   "near small radius",
+			query:  "near:46.0489,14.5087,300m",
+			expect: []string{"/photos/ljubljana-castle.jpg"},
+		},
+		{
+			name:   "near across antimeridian",
+			query:  "near:-17.7,-179.9,300km",
+			expect: []string{"/photos/fiji.jpg"},
+		},
+		{
+			name:   "bbox",
+			query:  "bbox:45.4,13.4,46.9,16.6",
+			expect: []string{"/photos/bled.jpg", "/photos/ljubljana-castle.jpg", "/photos/ljubljana-tivoli.jpg"},
+		},
+		{
+			name:   "bbox across antimeridian",
+			query:  "bbox:-21,177,-12,-178",
+			expect: []string{"/photos/fiji.jpg"},
+		},
+		{
+			name:   "place and created",
+			query:  `place:"Ljubljana" created:2021`,
+			expect: []string{"/photos/ljubljana-castle.jpg"},
+		},
+		{
+			name:   "place alternatives",
+			query:  "place:Fiji OR near:46.36,14.09,2km",
+			expect: []string{"/photos/bled.jpg", "/photos/fiji.jpg"},
+		},
+		{
+			name:   "place not found",
+			query:  "place:Atlantis",
+			expect: nil,
+		},
+		{
+			name:   "negated includes unknown",
+			query:  "NOT bbox:45.4,13.4,46.9,16.6",
+			expect: []string{"/photos/fiji.jpg", "/photos/scan.png"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := search.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.query, err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression %q: %v", tc.query, err)
+			}
+			paths := collectListedPaths(t, db, expr)
+			if !slices.Equal(paths, tc.expect) {
+				t.Fatalf("query %q paths = %v, want %v", tc.query, paths, tc.expect)
+			}
+		})
+	}
+}
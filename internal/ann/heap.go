@@ -0,0 +1,44 @@
+package ann
+
+import "sort"
+
+type candidate struct {
+	node uint32
+	sim  float32
+}
+
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(i, j int) bool {
+		return c[i].sim > c[j].sim
+	})
+}
+
+// bestFirst is a heap of candidates with the most similar one on top.
+type bestFirst []candidate
+
+func (h bestFirst) Len() int           { return len(h) }
+func (h bestFirst) Less(i, j int) bool { return h[i].sim > h[j].sim }
+func (h bestFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *bestFirst) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *bestFirst) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// worstFirst is a heap of candidates with the least similar one on top.
+type worstFirst []candidate
+
+func (h worstFirst) Len() int           { return len(h) }
+func (h worstFirst) Less(i, j int) bool { return h[i].sim < h[j].sim }
+func (h worstFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *worstFirst) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *worstFirst) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,366 @@
+// Package ann finds approximately nearest neighbours of vectors without
+// comparing the query to all of them.
+package ann
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+var ErrDimensions = errors.New("vector dimensions do not match the index")
+
+// Index is a hierarchical navigable small world (HNSW) graph of vectors
+// compared by cosine similarity. Vectors are normalized and quantized to
+// 8 bits, so similarities are approximate and should be recomputed exactly
+// for the results if they matter.
+type Index struct {
+	mutex          sync.RWMutex
+	dims           int
+	m              int
+	efConstruction int
+	levelMul       float64
+	rnd            *rand.Rand
+
+	nodes    []node
+	ids      map[int64]uint32
+	entry    uint32
+	maxLevel int
+	deleted  int
+}
+
+type node struct {
+	id      int64
+	vec     []int8
+	scale   float32
+	links   [][]uint32
+	deleted bool
+}
+
+// Result is an indexed vector similar to the query.
+type Result struct {
+	Id         int64
+	Similarity float32
+}
+
+// New creates an empty index, where m is the number of links of each vector
+// in the graph and efConstruction the number of candidates considered while
+// linking them. Higher values improve the accuracy at the cost of memory and
+// indexing time.
+func New(m int, efConstruction int) *Index {
+	if m < 2 {
+		m = 2
+	}
+	if efConstruction < m {
+		efConstruction = m
+	}
+	return &Index{
+		m:              m,
+		efConstruction: efConstruction,
+		levelMul:       1 / math.Log(float64(m)),
+		rnd:            rand.New(rand.NewSource(1)),
+		ids:            make(map[int64]uint32),
+	}
+}
+
+// Len returns the number of vectors in the index.
+func (idx *Index) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.ids)
+}
+
+// Deleted returns the number of removed vectors still linked in the graph.
+// The index should be rebuilt if these make up a large part of it.
+func (idx *Index) Deleted() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return idx.deleted
+}
+
+// Has returns true if a vector with the id is in the index.
+func (idx *Index) Has(id int64) bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	_, ok := idx.ids[id]
+	return ok
+}
+
+func quantize(vec []float32) ([]int8, float32) {
+	norm := float32(0)
+	maxAbs := float32(0)
+	for _, v := range vec {
+		norm += v * v
+		if v < 0 {
+			v = -v
+		}
+		if v > maxAbs {
+			maxAbs = v
+		}
+	}
+	if norm == 0 {
+		return nil, 0
+	}
+	norm = float32(math.Sqrt(float64(norm)))
+	q := make([]int8, len(vec))
+	for i, v := range vec {
+		q[i] = int8(math.Round(float64(v / maxAbs * 127)))
+	}
+	return q, maxAbs / 127 / norm
+}
+
+func dot(a []int8, b []int8) int32 {
+	sum := int32(0)
+	for i := range a {
+		sum += int32(a[i]) * int32(b[i])
+	}
+	return sum
+}
+
+type query struct {
+	vec   []int8
+	scale float32
+}
+
+func (idx *Index) similarity(q query, n uint32) float32 {
+	nd := &idx.nodes[n]
+	return float32(dot(q.vec, nd.vec)) * q.scale * nd.scale
+}
+
+func (idx *Index) maxLinks(level int) int {
+	if level == 0 {
+		return idx.m * 2
+	}
+	return idx.m
+}
+
+func (idx *Index) randomLevel() int {
+	return int(-math.Log(1-idx.rnd.Float64()) * idx.levelMul)
+}
+
+// Add indexes the vector with the id, replacing any previous vector with the
+// same id.
+func (idx *Index) Add(id int64, vec []float32) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if idx.dims == 0 {
+		idx.dims = len(vec)
+	}
+	if len(vec) != idx.dims {
+		return fmt.Errorf("%w: %d, expected %d", ErrDimensions, len(vec), idx.dims)
+	}
+	qvec, scale := quantize(vec)
+	if qvec == nil {
+		return fmt.Errorf("unable to index zero vector %d", id)
+	}
+	idx.remove(id)
+
+	level := idx.randomLevel()
+	n := uint32(len(idx.nodes))
+	idx.nodes = append(idx.nodes, node{
+		id:    id,
+		vec:   qvec,
+		scale: scale,
+		links: make([][]uint32, level+1),
+	})
+	idx.ids[id] = n
+
+	if n == 0 {
+		idx.entry = n
+		idx.maxLevel = level
+		return nil
+	}
+
+	q := query{vec: qvec, scale: scale}
+	ep := []candidate{{node: idx.entry, sim: idx.similarity(q, idx.entry)}}
+	for l := idx.maxLevel; l > level; l-- {
+		ep = idx.searchLayer(q, ep, 1, l)[:1]
+	}
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayer(q, ep, idx.efConstruction, l)
+		neighbors := idx.selectNeighbors(candidates, idx.m)
+		links := make([]uint32, len(neighbors), idx.maxLinks(l))
+		for i, c := range neighbors {
+			links[i] = c.node
+		}
+		idx.nodes[n].links[l] = links
+		for _, c := range neighbors {
+			idx.link(c.node, n, l)
+		}
+		ep = candidates
+	}
+	if level > idx.maxLevel {
+		idx.entry = n
+		idx.maxLevel = level
+	}
+	return nil
+}
+
+// link adds a link from one node to another, pruning the links of the node
+// if it has too many.
+func (idx *Index) link(from uint32, to uint32, level int) {
+	nd := &idx.nodes[from]
+	nd.links[level] = append(nd.links[level], to)
+	max := idx.maxLinks(level)
+	if len(nd.links[level]) <= max {
+		return
+	}
+	q := query{vec: nd.vec, scale: nd.scale}
+	candidates := make([]candidate, len(nd.links[level]))
+	for i, l := range nd.links[level] {
+		candidates[i] = candidate{node: l, sim: idx.similarity(q, l)}
+	}
+	sortCandidates(candidates)
+	selected := idx.selectNeighbors(candidates, max)
+	links := nd.links[level][:0]
+	for _, c := range selected {
+		links = append(links, c.node)
+	}
+	nd.links[level] = links
+}
+
+// selectNeighbors picks up to max of the candidates sorted by similarity,
+// preferring the ones that are more similar to the query than to the already
+// picked ones, so that the links lead in different directions.
+func (idx *Index) selectNeighbors(candidates []candidate, max int) []candidate {
+	if len(candidates) <= max {
+		return candidates
+	}
+	selected := make([]candidate, 0, max)
+	skipped := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if len(selected) >= max {
+			break
+		}
+		nd := &idx.nodes[c.node]
+		q := query{vec: nd.vec, scale: nd.scale}
+		diverse := true
+		for _, s := range selected {
+			if idx.similarity(q, s.node) > c.sim {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	for _, c := range skipped {
+		if len(selected) >= max {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// searchLayer returns up to ef nodes most similar to the query on the level
+// found by following the links from the entry points, most similar first.
+func (idx *Index) searchLayer(q query, entries []candidate, ef int, level int) []candidate {
+	visited := make(map[uint32]struct{}, ef*4)
+	explore := make(bestFirst, 0, ef)
+	results := make(worstFirst, 0, ef+1)
+	for _, e := range entries {
+		visited[e.node] = struct{}{}
+		heap.Push(&explore, e)
+		heap.Push(&results, e)
+		if len(results) > ef {
+			heap.Pop(&results)
+		}
+	}
+
+	for len(explore) > 0 {
+		c := heap.Pop(&explore).(candidate)
+		if len(results) >= ef && c.sim < results[0].sim {
+			break
+		}
+		for _, n := range idx.nodes[c.node].links[level] {
+			if _, ok := visited[n]; ok {
+				continue
+			}
+			visited[n] = struct{}{}
+			sim := idx.similarity(q, n)
+			if len(results) < ef || sim > results[0].sim {
+				nc := candidate{node: n, sim: sim}
+				heap.Push(&explore, nc)
+				heap.Push(&results, nc)
+				if len(results) > ef {
+					heap.Pop(&results)
+				}
+			}
+		}
+	}
+
+	out := []candidate(results)
+	sortCandidates(out)
+	return out
+}
+
+// Remove removes the vector with the id from the index. The vector stays in
+// the graph to keep it connected, but is no longer returned by searches.
+func (idx *Index) Remove(id int64) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int64) {
+	n, ok := idx.ids[id]
+	if !ok {
+		return
+	}
+	idx.nodes[n].deleted = true
+	delete(idx.ids, id)
+	idx.deleted++
+}
+
+// Search returns up to k indexed vectors most similar to the vector, most
+// similar first. Higher ef values find the actual nearest neighbours more
+// reliably, but take longer.
+func (idx *Index) Search(vec []float32, k int, ef int) ([]Result, error) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	if len(idx.ids) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if len(vec) != idx.dims {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrDimensions, len(vec), idx.dims)
+	}
+	qvec, scale := quantize(vec)
+	if qvec == nil {
+		return nil, nil
+	}
+	q := query{vec: qvec, scale: scale}
+
+	if ef < k {
+		ef = k
+	}
+	// Removed vectors take up some of the results
+	ef += ef * idx.deleted / len(idx.nodes)
+
+	ep := []candidate{{node: idx.entry, sim: idx.similarity(q, idx.entry)}}
+	for l := idx.maxLevel; l > 0; l-- {
+		ep = idx.searchLayer(q, ep, 1, l)[:1]
+	}
+	candidates := idx.searchLayer(q, ep, ef, 0)
+
+	results := make([]Result, 0, min(k, len(candidates)))
+	for _, c := range candidates {
+		nd := &idx.nodes[c.node]
+		if nd.deleted {
+			continue
+		}
+		results = append(results, Result{Id: nd.id, Similarity: c.sim})
+		if len(results) >= k {
+			break
+		}
+	}
+	return results, nil
+}
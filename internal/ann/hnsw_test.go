@@ -0,0 +1,164 @@
+package ann
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func randomVectors(rnd *rand.Rand, count int, dims int) [][]float32 {
+	vecs := make([][]float32, count)
+	for i := range vecs {
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = float32(rnd.NormFloat64())
+		}
+		vecs[i] = v
+	}
+	return vecs
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+func exactSearch(vecs [][]float32, deleted map[int64]bool, q []float32, k int) []int64 {
+	results := make([]Result, 0, len(vecs))
+	for i, v := range vecs {
+		if deleted[int64(i)] {
+			continue
+		}
+		results = append(results, Result{Id: int64(i), Similarity: cosine(q, v)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+	ids := make([]int64, k)
+	for i := range ids {
+		ids[i] = results[i].Id
+	}
+	return ids
+}
+
+func recall(t *testing.T, idx *Index, vecs [][]float32, deleted map[int64]bool, queries [][]float32, k int) float64 {
+	t.Helper()
+	found := 0
+	for _, q := range queries {
+		results, err := idx.Search(q, k, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := make(map[int64]bool)
+		for _, id := range exactSearch(vecs, deleted, q, k) {
+			expected[id] = true
+		}
+		for _, r := range results {
+			if deleted[r.Id] {
+				t.Fatalf("removed vector %d returned", r.Id)
+			}
+			if expected[r.Id] {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(len(queries)*k)
+}
+
+func TestSearchRecall(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	vecs := randomVectors(rnd, 2000, 32)
+	queries := randomVectors(rnd, 50, 32)
+
+	idx := New(16, 100)
+	for i, v := range vecs {
+		if err := idx.Add(int64(i), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if idx.Len() != len(vecs) {
+		t.Fatalf("len = %d, want %d", idx.Len(), len(vecs))
+	}
+
+	if r := recall(t, idx, vecs, nil, queries, 10); r < 0.9 {
+		t.Errorf("recall = %.3f, want >= 0.9", r)
+	}
+
+	results, err := idx.Search(vecs[42], 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Id != 42 || results[0].Similarity < 0.99 {
+		t.Errorf("self search = %+v, want 42 with similarity ~1", results)
+	}
+
+	deleted := make(map[int64]bool)
+	for i := 0; i < len(vecs); i += 3 {
+		idx.Remove(int64(i))
+		deleted[int64(i)] = true
+	}
+	if idx.Len() != len(vecs)-len(deleted) || idx.Deleted() != len(deleted) {
+		t.Fatalf("len = %d, deleted = %d", idx.Len(), idx.Deleted())
+	}
+	if r := recall(t, idx, vecs, deleted, queries, 10); r < 0.9 {
+		t.Errorf("recall after removing = %.3f, want >= 0.9", r)
+	}
+
+	if err := idx.Add(1, make([]float32, 8)); err == nil {
+		t.Error("adding vector with different dimensions did not fail")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	vecs := randomVectors(rnd, 500, 16)
+
+	idx := New(8, 50)
+	for i, v := range vecs {
+		if err := idx.Add(int64(i+1000), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx.Remove(1000)
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != idx.Len() || loaded.Deleted() != 1 || loaded.Has(1000) || !loaded.Has(1001) {
+		t.Fatalf("loaded len = %d, deleted = %d", loaded.Len(), loaded.Deleted())
+	}
+
+	for _, q := range randomVectors(rnd, 10, 16) {
+		a, _ := idx.Search(q, 5, 32)
+		b, _ := loaded.Search(q, 5, 32)
+		if len(a) != len(b) {
+			t.Fatalf("results %v, loaded %v", a, b)
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("results %v, loaded %v", a, b)
+			}
+		}
+	}
+
+	// Loaded indexes can still be added to
+	if err := loaded.Add(5000, vecs[0]); err != nil {
+		t.Fatal(err)
+	}
+	results, _ := loaded.Search(vecs[0], 2, 16)
+	if len(results) != 2 {
+		t.Fatalf("results = %v", results)
+	}
+}
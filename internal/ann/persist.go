@@ -0,0 +1,172 @@
+package ann
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+var magic = [4]byte{'H', 'N', 'S', 'W'}
+
+const version = 1
+
+var ErrFormat = errors.New("unsupported index file format")
+
+type header struct {
+	Magic          [4]byte
+	Version        uint32
+	Dims           uint32
+	M              uint32
+	EfConstruction uint32
+	Nodes          uint32
+	Entry          uint32
+	MaxLevel       uint32
+}
+
+type nodeHeader struct {
+	Id      int64
+	Scale   float32
+	Deleted bool
+	Levels  uint8
+}
+
+// Save writes the index to the file at the path, replacing it only once
+// the whole index is written.
+func (idx *Index) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	err = idx.write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (idx *Index) write(w io.Writer) error {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	h := header{
+		Magic:          magic,
+		Version:        version,
+		Dims:           uint32(idx.dims),
+		M:              uint32(idx.m),
+		EfConstruction: uint32(idx.efConstruction),
+		Nodes:          uint32(len(idx.nodes)),
+		Entry:          idx.entry,
+		MaxLevel:       uint32(idx.maxLevel),
+	}
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return err
+	}
+	for _, nd := range idx.nodes {
+		nh := nodeHeader{
+			Id:      nd.id,
+			Scale:   nd.scale,
+			Deleted: nd.deleted,
+			Levels:  uint8(len(nd.links)),
+		}
+		if err := binary.Write(w, binary.LittleEndian, nh); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, nd.vec); err != nil {
+			return err
+		}
+		for _, links := range nd.links {
+			if err := binary.Write(w, binary.LittleEndian, uint32(len(links))); err != nil {
+				return err
+			}
+			if err := binary.Write(w, binary.LittleEndian, links); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads an index saved to the file at the path.
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(bufio.NewReaderSize(f, 1<<20))
+}
+
+func read(r io.Reader) (*Index, error) {
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if h.Magic != magic || h.Version != version {
+		return nil, ErrFormat
+	}
+
+	idx := New(int(h.M), int(h.EfConstruction))
+	idx.dims = int(h.Dims)
+	idx.entry = h.Entry
+	idx.maxLevel = int(h.MaxLevel)
+	idx.rnd = rand.New(rand.NewSource(int64(h.Nodes)))
+	idx.nodes = make([]node, h.Nodes)
+	for i := range idx.nodes {
+		var nh nodeHeader
+		if err := binary.Read(r, binary.LittleEndian, &nh); err != nil {
+			return nil, err
+		}
+		nd := node{
+			id:      nh.Id,
+			scale:   nh.Scale,
+			deleted: nh.Deleted,
+			vec:     make([]int8, idx.dims),
+			links:   make([][]uint32, nh.Levels),
+		}
+		if err := binary.Read(r, binary.LittleEndian, nd.vec); err != nil {
+			return nil, err
+		}
+		for l := range nd.links {
+			var count uint32
+			if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+				return nil, err
+			}
+			if count > uint32(idx.maxLinks(l)) {
+				return nil, fmt.Errorf("%w: %d links on level %d", ErrFormat, count, l)
+			}
+			links := make([]uint32, count, idx.maxLinks(l))
+			if err := binary.Read(r, binary.LittleEndian, links); err != nil {
+				return nil, err
+			}
+			for _, n := range links {
+				if n >= h.Nodes {
+					return nil, fmt.Errorf("%w: link to %d of %d nodes", ErrFormat, n, h.Nodes)
+				}
+			}
+			nd.links[l] = links
+		}
+		if nd.deleted {
+			idx.deleted++
+		} else {
+			idx.ids[nd.id] = uint32(i)
+		}
+		idx.nodes[i] = nd
+	}
+	if len(idx.nodes) > 0 && int(idx.entry) >= len(idx.nodes) {
+		return nil, fmt.Errorf("%w: entry %d of %d nodes", ErrFormat, idx.entry, len(idx.nodes))
+	}
+	return idx, nil
+}
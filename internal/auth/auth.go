@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Enable requires users to log in or use an API token
+	Enable bool `json:"enable"`
+	// SessionDays is the number of days users stay logged in
+	SessionDays int `json:"session_days"`
+}
+
+// SessionDuration returns how long login sessions last.
+func (config Config) SessionDuration() time.Duration {
+	days := config.SessionDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Role grants users access to the API, each role including the previous ones.
+type Role string
+
+const (
+	// Viewer can browse the collections they are allowed to
+	Viewer Role = "viewer"
+	// Editor can also tag, rate and label files, and manage saved searches
+	// and persons
+	Editor Role = "editor"
+	// Admin can also manage collections, users and tasks, and access all
+	// collections
+	Admin Role = "admin"
+)
+
+// Roles are the valid roles from the least to the most privileged.
+var Roles = []Role{Viewer, Editor, Admin}
+
+func (role Role) Valid() bool {
+	return slices.Contains(Roles, role)
+}
+
+// Includes returns true if the role grants at least the access of the other
+// role.
+func (role Role) Includes(other Role) bool {
+	return slices.Index(Roles, role) >= slices.Index(Roles, other) && role.Valid()
+}
+
+// User is an account of the app, see image.Database for how they are stored.
+type User struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Token is an API token of a user for scripts, only shown when created.
+type Token struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type contextKey struct{}
+
+// WithUser returns the context of a request authenticated as the user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the user the request was authenticated as.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
+
+const (
+	passwordAlgorithm  = "pbkdf2-sha256"
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+	tokenLength        = 32
+)
+
+// PasswordIterations of PBKDF2 as recommended by OWASP for SHA-256
+var PasswordIterations = 600000
+
+// MinPasswordLength is the min number of characters of passwords
+const MinPasswordLength = 8
+
+var ErrPasswordTooShort = fmt.Errorf("password needs at least %d characters", MinPasswordLength)
+
+// HashPassword returns the salted hash of the password along with the
+// parameters needed to check it, e.g. pbkdf2-sha256$600000$salt$key.
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, PasswordIterations, passwordKeyLength)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		passwordAlgorithm,
+		strconv.Itoa(PasswordIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// CheckPassword returns true if the password matches the hash returned by
+// HashPassword. Only a few passwords are checked at the same time, the
+// others wait for their turn.
+func CheckPassword(hash string, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordAlgorithm {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	passwordChecks <- struct{}{}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	<-passwordChecks
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// NewToken returns a random token for sessions and API tokens.
+func NewToken() (string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hash of the token stored instead of the token, so
+// that the tokens cannot be used even if the database leaks.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+var ErrInvalidName = errors.New("name needs to be 1 to 64 characters without spaces")
+
+// ValidName returns an error if the user name is not valid.
+func ValidName(name string) error {
+	if name == "" || len(name) > 64 || strings.ContainsFunc(name, func(r rune) bool {
+		return r <= ' '
+	}) {
+		return ErrInvalidName
+	}
+	return nil
+}
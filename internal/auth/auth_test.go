@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHashPassword(t *testing.T) {
+	PasswordIterations = 1000
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(hash, "correct horse") {
+		t.Error("correct password rejected")
+	}
+	if CheckPassword(hash, "wrong horse") {
+		t.Error("wrong password accepted")
+	}
+	if CheckPassword("", "correct horse") {
+		t.Error("empty hash accepted")
+	}
+
+	other, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("hashes of the same password are equal, want different salts")
+	}
+
+	if _, err := HashPassword("short"); err != ErrPasswordTooShort {
+		t.Errorf("short password error %v, want %v", err, ErrPasswordTooShort)
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role     Role
+		other    Role
+		includes bool
+	}{
+		{Admin, Viewer, true},
+		{Admin, Admin, true},
+		{Editor, Viewer, true},
+		{Editor, Admin, false},
+		{Viewer, Editor, false},
+		{Role("nope"), Viewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Includes(tt.other); got != tt.includes {
+			t.Errorf("%s includes %s = %v, want %v", tt.role, tt.other, got, tt.includes)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.Allow("a"); !ok {
+			t.Fatalf("attempt %d rejected", i)
+		}
+		l.Fail("a")
+	}
+	if wait, ok := l.Allow("a"); ok || wait != time.Minute {
+		t.Errorf("allow after max failures = %v %v, want %v false", wait, ok, time.Minute)
+	}
+	if _, ok := l.Allow("b"); !ok {
+		t.Error("other key rejected")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := l.Allow("a"); !ok {
+		t.Error("attempt after window rejected")
+	}
+
+	l.Fail("a")
+	l.Fail("a")
+	l.Reset("a")
+	if _, ok := l.Allow("a"); !ok {
+		t.Error("attempt after reset rejected")
+	}
+}
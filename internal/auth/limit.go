@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// passwordChecks caps the number of passwords hashed at the same time, so
+// that concurrent attempts cannot use up all of the CPU.
+var passwordChecks = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+// Limiter limits the failed password attempts per key, e.g. the address of
+// the client, to a max number within a window of time. The window starts
+// with the first failed attempt and the attempts are allowed again once it
+// passes.
+type Limiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]failures
+}
+
+type failures struct {
+	count int
+	since time.Time
+}
+
+func NewLimiter(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:      max,
+		window:   window,
+		now:      time.Now,
+		failures: make(map[string]failures),
+	}
+}
+
+// Allow returns true if the key has attempts left, or how long to wait
+// until it does otherwise.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[key]
+	if !ok || f.count < l.max {
+		return 0, true
+	}
+	wait := f.since.Add(l.window).Sub(l.now())
+	if wait <= 0 {
+		delete(l.failures, key)
+		return 0, true
+	}
+	return wait, false
+}
+
+// Fail records a failed attempt of the key.
+func (l *Limiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.since) >= l.window {
+		f = failures{since: now}
+	}
+	f.count++
+	l.failures[key] = f
+	if len(l.failures) > 1000 {
+		l.prune(now)
+	}
+}
+
+// Reset forgets the failed attempts of the key, e.g. after a successful one.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+func (l *Limiter) prune(now time.Time) {
+	for key, f := range l.failures {
+		if now.Sub(f.since) >= l.window {
+			delete(l.failures, key)
+		}
+	}
+}
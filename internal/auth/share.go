@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+// Share is a public link to the files of a collection, narrowed down by a
+// search or a selection tag, see image.Database for how they are stored.
+type Share struct {
+	Id           int64     `json:"id"`
+	CollectionId string    `json:"collection_id"`
+	Search       string    `json:"search,omitempty"`
+	TagId        string    `json:"tag_id,omitempty"`
+	Download     bool      `json:"download"`
+	Password     bool      `json:"password"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	PasswordHash string `json:"-"`
+}
+
+// SceneSearch returns the search of the scenes showing the files of the
+// share.
+func (share Share) SceneSearch() string {
+	if share.TagId != "" {
+		return "tag:" + share.TagId
+	}
+	return share.Search
+}
+
+// UnlockProof returns the value proving that the password of the share was
+// entered for the token. It changes along with the password.
+func (share Share) UnlockProof(token string) string {
+	sum := sha256.Sum256([]byte(token + "$" + share.PasswordHash))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type shareContextKey struct{}
+
+// WithShare returns the context of a request made with the share link.
+func WithShare(ctx context.Context, share Share) context.Context {
+	return context.WithValue(ctx, shareContextKey{}, share)
+}
+
+// ShareFromContext returns the share the request was made with.
+func ShareFromContext(ctx context.Context) (Share, bool) {
+	share, ok := ctx.Value(shareContextKey{}).(Share)
+	return share, ok
+}
@@ -1,11 +1,14 @@
 package collection
 
 import (
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	"photofield/internal/image"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +28,111 @@ type Collection struct {
 	IndexedAt     *time.Time `json:"indexed_at,omitempty"`
 	IndexedCount  int        `json:"indexed_count"`
 	InvalidatedAt *time.Time `json:"-"`
+	Schedule      *Schedule  `json:"schedule,omitempty"`
+	Watch         bool       `json:"watch"`
+	// Xmp syncs the tags of the files with the keywords of their XMP
+	// metadata and sidecars
+	Xmp bool `json:"xmp"`
+	// Include and Exclude are glob patterns of the paths of the files
+	// relative to the dirs, see image.PathFilter
+	Include    []string `json:"include,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	SkipHidden bool     `json:"skip_hidden"`
+	MaxDepth   int      `json:"max_depth,omitempty"`
+	// Allow are the names of the users allowed to access the collection if
+	// authentication is enabled, all users if empty
+	Allow []string `json:"allow,omitempty"`
+
+	// Search narrows down the files of the dirs, only set for virtual
+	// collections of saved searches
+	Search        string `json:"search,omitempty"`
+	SavedSearchId int64  `json:"saved_search_id,omitempty"`
+
+	// Collections the dirs are sourced from, so that their updates also
+	// update the virtual collection
+	bases []*Collection
+}
+
+// Schedule indexes a collection periodically in the background.
+type Schedule struct {
+	// Index is a cron expression of when to index, e.g. "0 3 * * *"
+	Index string `json:"index"`
+	// Stages to index, files, metadata, contents and faces by default
+	Stages []string `json:"stages,omitempty"`
+}
+
+// ScheduleStages are the valid stages of a schedule.
+var ScheduleStages = []string{"files", "metadata", "contents", "faces", "duplicates"}
+
+// savedSearchPrefix prefixes the ids of saved search collections, the colon
+// cannot appear in the slugs of configured collection names.
+const savedSearchPrefix = "search:"
+
+// FromSavedSearch returns a virtual collection of the files matching the
+// saved search in the dirs of the base collections.
+func FromSavedSearch(s image.SavedSearch, bases []*Collection) Collection {
+	collection := Collection{
+		Id:            SavedSearchCollectionId(s.Id),
+		Name:          s.Name,
+		Search:        s.Search,
+		SavedSearchId: s.Id,
+		InvalidatedAt: &s.UpdatedAt,
+		bases:         bases,
+	}
+	if len(bases) == 1 {
+		base := bases[0]
+		collection.Layout = base.Layout
+		collection.Sort = base.Sort
+		collection.Limit = base.Limit
+		collection.IndexLimit = base.IndexLimit
+		collection.Include = base.Include
+		collection.Exclude = base.Exclude
+		collection.SkipHidden = base.SkipHidden
+		collection.MaxDepth = base.MaxDepth
+	}
+	seen := make(map[string]bool)
+	for _, base := range bases {
+		for _, dir := range base.Dirs {
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			collection.Dirs = append(collection.Dirs, dir)
+		}
+	}
+	return collection
+}
+
+// SavedSearchCollectionId returns the id of the virtual collection of a
+// saved search.
+func SavedSearchCollectionId(id int64) string {
+	return savedSearchPrefix + strconv.FormatInt(id, 10)
+}
+
+// ParseSavedSearchCollectionId returns the saved search id of a virtual
+// collection id.
+func ParseSavedSearchCollectionId(id string) (int64, bool) {
+	s, ok := strings.CutPrefix(id, savedSearchPrefix)
+	if !ok {
+		return 0, false
+	}
+	savedId, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return savedId, true
+}
+
+// SearchWith returns the search of the collection combined with the provided
+// one, so that both need to match.
+func (collection *Collection) SearchWith(search string) string {
+	if collection.Search == "" {
+		return search
+	}
+	if search == "" {
+		return collection.Search
+	}
+	return "(" + collection.Search + ") (" + search + ")"
 }
 
 func (collection *Collection) MakeValid() {
@@ -47,6 +155,34 @@ func (collection *Collection) MakeValid() {
 	}
 }
 
+// Allows returns true if the user with the name is allowed to access the
+// collection.
+func (collection *Collection) Allows(name string) bool {
+	return len(collection.Allow) == 0 || slices.Contains(collection.Allow, name)
+}
+
+// Bases returns the collections the dirs of a virtual collection are
+// sourced from.
+func (collection *Collection) Bases() []*Collection {
+	return collection.bases
+}
+
+// PathFilter returns the filter of the files of the dirs of the collection,
+// excluding image.DefaultExclude unless the collection has its own exclude
+// patterns.
+func (collection *Collection) PathFilter() image.PathFilter {
+	exclude := collection.Exclude
+	if exclude == nil {
+		exclude = image.DefaultExclude
+	}
+	return image.PathFilter{
+		Include:    collection.Include,
+		Exclude:    exclude,
+		SkipHidden: collection.SkipHidden,
+		MaxDepth:   collection.MaxDepth,
+	}
+}
+
 func (collection *Collection) Invalidate() {
 	now := time.Now()
 	collection.InvalidatedAt = &now
@@ -60,11 +196,19 @@ func (collection *Collection) UpdatedAt() time.Time {
 	if collection.IndexedAt != nil && collection.IndexedAt.After(updatedAt) {
 		updatedAt = *collection.IndexedAt
 	}
+	for _, base := range collection.bases {
+		if baseUpdatedAt := base.UpdatedAt(); baseUpdatedAt.After(updatedAt) {
+			updatedAt = baseUpdatedAt
+		}
+	}
 	return updatedAt
 }
 
 func (collection *Collection) Expand() []Collection {
 	collections := make([]Collection, 0)
+	// Expanded collections apply the depth relative to their own dir
+	subdirs := collection.PathFilter()
+	subdirs.MaxDepth = 0
 	for _, collectionDir := range collection.Dirs {
 		dir, err := os.Open(collectionDir)
 		if err != nil {
@@ -78,11 +222,22 @@ func (collection *Collection) Expand() []Collection {
 				continue
 			}
 			name := entry.Name()
+			if !subdirs.Match(name, true) {
+				continue
+			}
 			child := Collection{
 				Name:       name,
 				Dirs:       []string{filepath.Join(collectionDir, name)},
 				Limit:      collection.Limit,
 				IndexLimit: collection.IndexLimit,
+				Schedule:   collection.Schedule,
+				Watch:      collection.Watch,
+				Xmp:        collection.Xmp,
+				Include:    collection.Include,
+				Exclude:    collection.Exclude,
+				SkipHidden: collection.SkipHidden,
+				MaxDepth:   collection.MaxDepth,
+				Allow:      collection.Allow,
 			}
 			child.MakeValid()
 			collections = append(collections, child)
@@ -112,11 +267,39 @@ func (collection *Collection) UpdateIndexedAt(source *image.Source) {
 	collection.IndexedAt = earliestIndex
 }
 
+// ChangedSince returns true if files were added, removed or renamed in any of
+// the dirs or their subdirs after t, as told by the modification times of the
+// dirs.
+func (collection *Collection) ChangedSince(t time.Time) bool {
+	for _, dir := range collection.Dirs {
+		changed := false
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if info.ModTime().After(t) {
+				changed = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if changed {
+			return true
+		}
+	}
+	return false
+}
+
 func (collection *Collection) UpdateIndexedCount(source *image.Source) {
 	collection.IndexedCount = source.GetDirsCount(collection.Dirs)
 }
 
 func (collection *Collection) GetInfos(source *image.Source, options image.ListOptions) (<-chan image.SourcedInfo, image.Dependencies) {
+	options.Filter = collection.PathFilter()
 	return source.ListInfos(collection.Dirs, options)
 }
 
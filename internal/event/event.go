@@ -0,0 +1,96 @@
+// Package event broadcasts changes on the server to the subscribed clients,
+// e.g. of the GET /events Server-Sent Events stream.
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Event is a change to notify the clients about.
+type Event struct {
+	// Type of the event, sent as the SSE event name
+	Type string
+	// Id of the collection the event is about, empty if the event is not
+	// about a single collection, used to only send events of the
+	// collections the client is allowed to access
+	CollectionId string
+	// Data of the event, sent encoded as JSON
+	Data any
+}
+
+// Hub publishes events to all of its subscribers without blocking. The
+// subscribers that fall behind by more than the buffer size are
+// unsubscribed, so that the clients reconnect and reload their state
+// instead of silently missing events.
+type Hub struct {
+	size int
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+}
+
+func NewHub(size int) *Hub {
+	return &Hub{
+		size: size,
+		subs: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the published events and a function
+// to unsubscribe. The channel is closed once unsubscribed.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, h.size)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.unsubscribe(ch)
+	}
+}
+
+func (h *Hub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; !ok {
+		return
+	}
+	delete(h.subs, ch)
+	close(ch)
+}
+
+// Len returns the number of subscribers, e.g. to skip preparing events
+// no one would receive.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Publish sends the event to all subscribers.
+func (h *Hub) Publish(e Event) {
+	var behind []chan Event
+	h.mu.RLock()
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			behind = append(behind, ch)
+		}
+	}
+	h.mu.RUnlock()
+	for _, ch := range behind {
+		h.unsubscribe(ch)
+	}
+}
+
+// Write writes the event in the text/event-stream format.
+func Write(w io.Writer, e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
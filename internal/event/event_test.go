@@ -0,0 +1,57 @@
+package event
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub(1)
+	a, unsubscribeA := hub.Subscribe()
+	b, unsubscribeB := hub.Subscribe()
+	defer unsubscribeB()
+	if hub.Len() != 2 {
+		t.Fatalf("subscribers %d, want 2", hub.Len())
+	}
+
+	hub.Publish(Event{Type: "tag"})
+	for _, ch := range []<-chan Event{a, b} {
+		if e := <-ch; e.Type != "tag" {
+			t.Errorf("event %s, want tag", e.Type)
+		}
+	}
+
+	unsubscribeA()
+	unsubscribeA()
+	if _, ok := <-a; ok {
+		t.Error("channel open after unsubscribing")
+	}
+
+	// The second event does not fit the buffer
+	hub.Publish(Event{Type: "first"})
+	hub.Publish(Event{Type: "second"})
+	if e := <-b; e.Type != "first" {
+		t.Errorf("event %s, want first", e.Type)
+	}
+	if _, ok := <-b; ok {
+		t.Error("subscriber behind not unsubscribed")
+	}
+	if hub.Len() != 0 {
+		t.Errorf("subscribers %d, want 0", hub.Len())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var sb strings.Builder
+	err := Write(&sb, Event{
+		Type: "tag",
+		Data: map[string]any{"id": "beach", "updated_at_ms": 1700000000000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "event: tag\ndata: {\"id\":\"beach\",\"updated_at_ms\":1700000000000}\n\n"
+	if sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 	"github.com/smilyorg/tinygpkg/gpkg"
 	"modernc.org/sqlite/vfs"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
 )
 
 var ErrNotAvailable = fmt.Errorf("geopackage not available")
@@ -31,6 +35,8 @@ type Geo struct {
 	uri    string
 	fs     *vfs.FS
 	gp     *gpkg.GeoPackage
+	pool   *sqlitex.Pool
+	table  string
 }
 
 // New creates a new Geo
@@ -82,6 +88,22 @@ func New(config Config, fs embed.FS) (*Geo, error) {
 	}
 	g.gp = gp
 
+	// Open a separate pool for looking up features by name, as the
+	// geopackage only supports reverse geocoding
+	g.pool, err = sqlitex.Open(g.uri, sqlite.OpenReadOnly|sqlite.OpenURI, 2)
+	if err != nil {
+		g.Close()
+		return nil, fmt.Errorf("error opening geopackage pool: %w", err)
+	}
+	g.table = g.config.GeoPackage.Table
+	if g.table == "" {
+		g.table, err = g.firstTable()
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+	}
+
 	// Set up the geometry cache, this prevents having to re-parse the geometry
 	// for every request
 	c, err := NewCache()
@@ -121,10 +143,84 @@ func (g *Geo) ReverseGeocode(ctx context.Context, l s2.LatLng) (string, error) {
 	return cols[0], nil
 }
 
+// Bounds returns the bounding boxes of all the features with the given name,
+// compared case-insensitively.
+//
+// If reverse geocoding is disabled, it will return ErrNotAvailable.
+func (g *Geo) Bounds(ctx context.Context, name string) ([]s2.Rect, error) {
+	if !g.Available() {
+		return nil, ErrNotAvailable
+	}
+	conn := g.pool.Get(ctx)
+	if conn == nil {
+		return nil, ctx.Err()
+	}
+	defer g.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT miny, minx, maxy, maxx
+		FROM rtree_` + g.table + `_geom
+		WHERE id IN (
+			SELECT fid
+			FROM ` + g.table + `
+			WHERE ` + g.config.GeoPackage.NameCol + ` = ? COLLATE NOCASE
+		)`)
+	defer stmt.Reset()
+
+	stmt.BindText(1, name)
+
+	var rects []s2.Rect
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			return nil, fmt.Errorf("error finding feature bounds: %w", err)
+		} else if !exists {
+			break
+		}
+		rects = append(rects, s2.Rect{
+			Lat: r1.Interval{
+				Lo: (s1.Angle(stmt.ColumnFloat(0)) * s1.Degree).Radians(),
+				Hi: (s1.Angle(stmt.ColumnFloat(2)) * s1.Degree).Radians(),
+			},
+			Lng: s1.IntervalFromEndpoints(
+				(s1.Angle(stmt.ColumnFloat(1)) * s1.Degree).Radians(),
+				(s1.Angle(stmt.ColumnFloat(3)) * s1.Degree).Radians(),
+			),
+		})
+	}
+	return rects, nil
+}
+
+func (g *Geo) firstTable() (string, error) {
+	conn := g.pool.Get(context.Background())
+	defer g.pool.Put(conn)
+
+	var table string
+	err := sqlitex.Execute(conn, `SELECT table_name FROM gpkg_contents LIMIT 1`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			table = stmt.ColumnText(0)
+			return nil
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error finding geopackage table: %w", err)
+	}
+	if table == "" {
+		return "", fmt.Errorf("error finding geopackage table: no table found")
+	}
+	return table, nil
+}
+
 func (g *Geo) Close() error {
 	if g == nil {
 		return nil
 	}
+	if g.pool != nil {
+		err := g.pool.Close()
+		if err != nil {
+			return fmt.Errorf("error closing geopackage pool: %w", err)
+		}
+		g.pool = nil
+	}
 	if g.gp != nil {
 		c, ok := g.gp.Cache.(*Cache)
 		if !ok {
@@ -0,0 +1,93 @@
+package geo
+
+import (
+	"context"
+	"embed"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// writeTestGeoPackage writes a minimal geopackage with only the tables and
+// columns used to look up features by name.
+func writeTestGeoPackage(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.gpkg")
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = sqlitex.ExecuteScript(conn, `
+		CREATE TABLE gpkg_contents (table_name TEXT);
+		INSERT INTO gpkg_contents VALUES ('areas');
+		CREATE TABLE areas (fid INTEGER PRIMARY KEY, geom BLOB, name TEXT);
+		CREATE TABLE rtree_areas_geom (id INTEGER PRIMARY KEY, minx REAL, maxx REAL, miny REAL, maxy REAL);
+		INSERT INTO areas VALUES (1, NULL, 'Ljubljana'), (2, NULL, 'Fiji'), (3, NULL, 'Maribor');
+		INSERT INTO rtree_areas_geom VALUES
+			(1, 14.4, 14.7, 45.9, 46.2),
+			(2, 177, -178, -21, -12),
+			(3, 15.5, 15.8, 46.4, 46.7);
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBounds(t *testing.T) {
+	g, err := New(Config{
+		ReverseGeocode: true,
+		GeoPackage: GeoPackageConfig{
+			Path:    writeTestGeoPackage(t),
+			NameCol: "name",
+		},
+	}, embed.FS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	rects, err := g.Bounds(context.Background(), "ljubljana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rects) != 1 {
+		t.Fatalf("expected 1 rect, got %d", len(rects))
+	}
+	lo, hi := rects[0].Lo(), rects[0].Hi()
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if !near(lo.Lat.Degrees(), 45.9) || !near(lo.Lng.Degrees(), 14.4) || !near(hi.Lat.Degrees(), 46.2) || !near(hi.Lng.Degrees(), 14.7) {
+		t.Errorf("unexpected bounds %v %v", lo, hi)
+	}
+
+	rects, err = g.Bounds(context.Background(), "Fiji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rects) != 1 || !rects[0].Lng.IsInverted() {
+		t.Errorf("expected antimeridian crossing bounds, got %v", rects)
+	}
+
+	rects, err = g.Bounds(context.Background(), "Atlantis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rects) != 0 {
+		t.Errorf("expected no bounds, got %v", rects)
+	}
+}
+
+func TestBoundsNotAvailable(t *testing.T) {
+	g, err := New(Config{}, embed.FS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Bounds(context.Background(), "Ljubljana")
+	if err != ErrNotAvailable {
+		t.Errorf("expected ErrNotAvailable, got %v", err)
+	}
+}
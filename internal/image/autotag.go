@@ -0,0 +1,363 @@
+package image
+
+import (
+	"context"
+	"log"
+
+	"photofield/internal/ai"
+	"photofield/internal/metrics"
+	"photofield/internal/tag"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// SuggestPrefix is the namespace of the tags with the files suggested for
+// each auto tag, e.g. suggest:beach for beach.
+const SuggestPrefix = "suggest:"
+
+// TagSuggestion is a tag suggested for a file by classifying its AI
+// embedding by the tags of the most similar tagged files.
+type TagSuggestion struct {
+	Tag        string  `json:"-"`
+	FileId     ImageId `json:"file_id"`
+	Confidence float32 `json:"confidence"`
+}
+
+// TagClassifier classifies AI embeddings by the tags of the most similar
+// tagged files.
+type TagClassifier struct {
+	images []taggedImage
+	k      int
+}
+
+// SetTagAuto sets whether the tag is suggested for similar files. The
+// pending suggestions of tags that are no longer auto are removed.
+func (source *Database) SetTagAuto(t tag.Tag, auto bool) error {
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE tag
+		SET auto = ?
+		WHERE name = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindBool(1, auto)
+		stmt.BindText(2, t.Name)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		if auto {
+			return nil
+		}
+
+		remove := conn.Prep(`
+		DELETE FROM tag_suggestion
+		WHERE tag_name = ? AND NOT rejected;`)
+		defer remove.Reset()
+
+		remove.BindText(1, t.Name)
+		_, err := remove.Step()
+		return err
+	})
+	if err != nil || auto {
+		return err
+	}
+	return source.SyncSuggestTag(t)
+}
+
+// ListAutoTags lists the tags suggested for similar files.
+func (source *Database) ListAutoTags() []tag.Tag {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, updated_at_ms
+	FROM tag
+	WHERE auto = true AND active = true
+	ORDER BY name ASC;`)
+	defer stmt.Reset()
+
+	tags := make([]tag.Tag, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing auto tags: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		tags = append(tags, tag.Tag{
+			Id:        tag.Id(stmt.ColumnInt(0)),
+			Name:      stmt.ColumnText(1),
+			UpdatedAt: fromUnixMs(stmt.ColumnInt64(2)),
+			Auto:      true,
+		})
+	}
+	return tags
+}
+
+// NewTagClassifier creates a classifier from the AI embeddings of the files
+// with the tags, considering the k most similar of them for each embedding.
+func (source *Database) NewTagClassifier(ids []tag.Id, k int) *TagClassifier {
+	defer metrics.Elapsed("tag classifier")()
+
+	c := &TagClassifier{k: k}
+	for _, id := range ids {
+		for r := range source.ListTagRanges(id) {
+			for i := range r.Chan() {
+				emb, err := source.GetImageEmbedding(ImageId(i))
+				if err != nil {
+					continue
+				}
+				c.images = append(c.images, taggedImage{
+					Id:         ImageId(i),
+					TagId:      id,
+					Emb:        emb.Float32(),
+					EmbInvNorm: emb.InvNormFloat32(),
+					Weight:     1,
+				})
+			}
+		}
+	}
+	return c
+}
+
+// Len returns the number of tagged files the classifier compares to.
+func (c *TagClassifier) Len() int {
+	return len(c.images)
+}
+
+// Classify returns the tag of most of the most similar tagged files and the
+// confidence of the classification from 0 to 1.
+func (c *TagClassifier) Classify(emb ai.Embedding) (tag.Id, float32) {
+	id, confidence, err := classifyEmbedding(c.images, emb, c.k)
+	if err != nil {
+		return 0, 0
+	}
+	return id, confidence
+}
+
+// WriteTagSuggestions replaces the pending suggestions of the tags for the
+// files in the dirs. Rejected suggestions are kept, so that they are not
+// suggested again.
+func (source *Database) WriteTagSuggestions(dirs []string, tags []string, suggestions []TagSuggestion) error {
+	return source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT write_tag_suggestions;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO write_tag_suggestions;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE write_tag_suggestions;", nil)
+		}()
+
+		sql := `
+			DELETE FROM tag_suggestion
+			WHERE tag_name = ? AND NOT rejected AND file_id IN (
+				SELECT id
+				FROM infos
+				WHERE path_prefix_id IN (
+					SELECT id
+					FROM prefix
+					WHERE
+		`
+		for i := range dirs {
+			sql += `str LIKE ? `
+			if i < len(dirs)-1 {
+				sql += "OR "
+			}
+		}
+		sql += `
+				)
+			);`
+
+		remove := conn.Prep(sql)
+		defer remove.Reset()
+		for _, name := range tags {
+			remove.BindText(1, name)
+			for i, dir := range dirs {
+				remove.BindText(i+2, dir+"%")
+			}
+			if _, err := remove.Step(); err != nil {
+				return err
+			}
+			if err := remove.Reset(); err != nil {
+				return err
+			}
+		}
+
+		insert := conn.Prep(`
+		INSERT OR IGNORE INTO tag_suggestion(tag_name, file_id, confidence)
+		VALUES (?, ?, ?);`)
+		defer insert.Reset()
+
+		for _, s := range suggestions {
+			insert.BindText(1, s.Tag)
+			insert.BindInt64(2, int64(s.FileId))
+			insert.BindFloat(3, float64(s.Confidence))
+			if _, err := insert.Step(); err != nil {
+				return err
+			}
+			if err := insert.Reset(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListTagSuggestions lists the pending suggestions of the tag, most
+// confident first.
+func (source *Database) ListTagSuggestions(name string, limit int) []TagSuggestion {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT file_id, confidence
+	FROM tag_suggestion
+	WHERE tag_name = ? AND NOT rejected
+	ORDER BY confidence DESC, file_id ASC
+	LIMIT ?;`)
+	defer stmt.Reset()
+
+	stmt.BindText(1, name)
+	stmt.BindInt64(2, int64(limit))
+
+	suggestions := make([]TagSuggestion, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing tag suggestions: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		suggestions = append(suggestions, TagSuggestion{
+			Tag:        name,
+			FileId:     ImageId(stmt.ColumnInt64(0)),
+			Confidence: float32(stmt.ColumnFloat(1)),
+		})
+	}
+	return suggestions
+}
+
+// GetTagSuggestionIds returns the files the tag is suggested for with at
+// least the confidence, or that it was suggested and rejected for.
+func (source *Database) GetTagSuggestionIds(name string, minConfidence float32, rejected bool) Ids {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT file_id
+	FROM tag_suggestion
+	WHERE tag_name = ? AND confidence >= ? AND rejected = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindText(1, name)
+	stmt.BindFloat(2, float64(minConfidence))
+	stmt.BindBool(3, rejected)
+
+	ids := NewIds()
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing tag suggestions: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		ids.AddInt(stmt.ColumnInt(0))
+	}
+	return ids
+}
+
+// AcceptTagSuggestions adds the tag to the files it is suggested for and
+// removes the suggestions.
+func (source *Database) AcceptTagSuggestions(t tag.Tag, ids Ids) error {
+	source.AddTagIds(t.Id, ids)
+	if err := source.resolveTagSuggestions(t.Name, ids, false); err != nil {
+		return err
+	}
+	return source.SyncSuggestTag(t)
+}
+
+// RejectTagSuggestions marks the suggestions of the tag for the files as
+// rejected, so that they are not suggested again.
+func (source *Database) RejectTagSuggestions(t tag.Tag, ids Ids) error {
+	if err := source.resolveTagSuggestions(t.Name, ids, true); err != nil {
+		return err
+	}
+	return source.SyncSuggestTag(t)
+}
+
+func (source *Database) resolveTagSuggestions(name string, ids Ids, reject bool) error {
+	return source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT resolve_tag_suggestions;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO resolve_tag_suggestions;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE resolve_tag_suggestions;", nil)
+		}()
+
+		sql := `DELETE FROM tag_suggestion WHERE tag_name = ? AND file_id = ?;`
+		if reject {
+			sql = `UPDATE tag_suggestion SET rejected = true WHERE tag_name = ? AND file_id = ?;`
+		}
+		stmt := conn.Prep(sql)
+		defer stmt.Reset()
+
+		for _, fileId := range ids.IntSlice() {
+			stmt.BindText(1, name)
+			stmt.BindInt64(2, int64(fileId))
+			if _, err := stmt.Step(); err != nil {
+				return err
+			}
+			if err := stmt.Reset(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SyncSuggestTag updates the files of the suggest: tag of the tag to the
+// files with pending suggestions, so that they can be searched for and
+// browsed like any other tag.
+func (source *Database) SyncSuggestTag(t tag.Tag) error {
+	want := source.GetTagSuggestionIds(t.Name, 0, false)
+	name := SuggestPrefix + t.Name
+	st, ok := source.GetTagByName(name)
+	if !ok && want.Len() == 0 {
+		return nil
+	}
+	if !ok {
+		done, err := source.AddTag(name)
+		if err != nil {
+			return err
+		}
+		<-done
+		st, ok = source.GetTagByName(name)
+		if !ok {
+			return ErrNotFound
+		}
+	}
+
+	have := source.GetTagImageIds(st.Id)
+
+	remove := have.Clone()
+	remove.SubtractTree(want)
+	if remove.Len() > 0 {
+		source.RemoveTagIds(st.Id, remove)
+	}
+
+	add := want.Clone()
+	add.SubtractTree(have)
+	if add.Len() > 0 {
+		source.AddTagIds(st.Id, add)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package image
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"photofield/internal/metrics"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// Backup writes a consistent copy of the database to the path using the
+// SQLite online backup API, so that it can run while the server is in use.
+// The copy is written next to the path first and renamed once complete.
+func (source *Database) Backup(path string) error {
+	// Include the writes pending in the open transaction
+	<-source.CommitBarrier()
+
+	log.Printf("database backup to %s", path)
+	defer metrics.Elapsed("database backup")()
+
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err := source.backupTo(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (source *Database) backupTo(path string) error {
+	dst, err := sqlite.OpenConn(path, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	src := source.pool.Get(context.TODO())
+	defer source.pool.Put(src)
+
+	backup, err := sqlite.NewBackup(dst, "main", src, "main")
+	if err != nil {
+		return err
+	}
+
+	// Copying all pages in a single step keeps a read transaction open for
+	// the duration, so that concurrent writes do not restart the backup.
+	_, err = backup.Step(-1)
+	if cerr := backup.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -3,8 +3,10 @@ package image
 import (
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/EdlinOrg/prominentcolor"
+	"zombiezen.com/go/sqlite"
 )
 
 func extractProminentColor(img image.Image) (color.RGBA, error) {
@@ -23,3 +25,83 @@ func extractProminentColor(img image.Image) (color.RGBA, error) {
 		B: uint8(promColor.Color.B),
 	}, nil
 }
+
+// Lab is a color in the CIE L*a*b* color space with the D65 white point.
+type Lab struct {
+	L, A, B float64
+}
+
+// LabFromRGB converts an sRGB color to Lab.
+func LabFromRGB(c color.RGBA) Lab {
+	r := srgbToLinear(c.R)
+	g := srgbToLinear(c.G)
+	b := srgbToLinear(c.B)
+
+	// Linear sRGB to XYZ, normalized to the D65 white point
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / 1.00000
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return Lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+// DeltaE returns the CIE76 perceptual distance between two colors, where
+// about 2.3 is just noticeable and 100 is the distance between black and
+// white.
+func (lab Lab) DeltaE(other Lab) float64 {
+	dl := lab.L - other.L
+	da := lab.A - other.A
+	db := lab.B - other.B
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+// Chroma returns the colorfulness of the color.
+func (lab Lab) Chroma() float64 {
+	return math.Hypot(lab.A, lab.B)
+}
+
+// Hue returns the hue angle of the color in degrees in the [0, 360) range.
+func (lab Lab) Hue() float64 {
+	h := math.Atan2(lab.B, lab.A) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func srgbToLinear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const delta = 6. / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4./29
+}
+
+// colorDeltaEFunc is the color_delta_e(color, l, a, b) SQL function
+// returning the distance of the prominent color of a file from the Lab
+// color, so that the color: qualifier can be filtered in SQL.
+var colorDeltaEFunc = &sqlite.FunctionImpl{
+	NArgs:         4,
+	Deterministic: true,
+	Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+		if args[0].Type() == sqlite.TypeNull {
+			return sqlite.Value{}, nil
+		}
+		info := Info{Color: uint32(args[0].Int64())}
+		lab := Lab{L: args[1].Float(), A: args[2].Float(), B: args[3].Float()}
+		return sqlite.FloatValue(lab.DeltaE(LabFromRGB(info.GetColor()))), nil
+	},
+}
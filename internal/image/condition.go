@@ -0,0 +1,376 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"photofield/internal/geo"
+	"photofield/internal/search"
+
+	"github.com/golang/geo/s2"
+	"zombiezen.com/go/sqlite"
+)
+
+// conditionSQL is a search condition compiled into an SQL predicate over the
+// infos table.
+//
+// Parameters are named, so that the predicate can be repeated across the
+// UNION ALL branches of a listing while being bound only once. They are
+// numbered in the order they appear in the predicate.
+type conditionSQL struct {
+	where  strings.Builder
+	params []any
+}
+
+// PlaceBounds looks up the bounds of the features with a name, e.g.
+// geo.Geo.
+type PlaceBounds interface {
+	Bounds(ctx context.Context, name string) ([]s2.Rect, error)
+}
+
+// EnablePlaces enables listing by the place: qualifiers, which are resolved
+// to the bounds of the features with the same name.
+func (source *Database) EnablePlaces(places PlaceBounds) {
+	source.places = places
+}
+
+// ResolvePlaces geocodes the place: qualifiers of the condition to the bounds
+// of the features with the same name, so that they can be listed. Listing
+// resolves them as well, this returns the errors for the caller to show.
+func (source *Source) ResolvePlaces(ctx context.Context, cond *search.Condition) error {
+	return source.database.resolvePlaces(ctx, cond)
+}
+
+func (source *Database) resolvePlaces(ctx context.Context, cond *search.Condition) error {
+	for _, leaf := range cond.Leaves("place") {
+		place := leaf.Value.(search.Place)
+		if place.Resolved {
+			continue
+		}
+		if source.places == nil {
+			return fmt.Errorf("place %q: %w", place.Name, geo.ErrNotAvailable)
+		}
+		rects, err := source.places.Bounds(ctx, place.Name)
+		if err != nil {
+			return fmt.Errorf("place %q: %w", place.Name, err)
+		}
+		if len(rects) == 0 {
+			return fmt.Errorf("place %q: %w", place.Name, ErrNotFound)
+		}
+		place.Bounds = place.Bounds[:0]
+		for _, r := range rects {
+			place.Bounds = append(place.Bounds, search.Bounds{
+				MinLat: r.Lo().Lat.Degrees(),
+				MinLng: r.Lo().Lng.Degrees(),
+				MaxLat: r.Hi().Lat.Degrees(),
+				MaxLng: r.Hi().Lng.Degrees(),
+			})
+		}
+		place.Resolved = true
+		leaf.Value = place
+	}
+	return nil
+}
+
+func newConditionSQL(cond *search.Condition) (*conditionSQL, error) {
+	c := &conditionSQL{}
+	if cond == nil {
+		c.where.WriteString("true")
+		return c, nil
+	}
+	err := c.write(cond)
+	return c, err
+}
+
+func (c *conditionSQL) String() string {
+	return c.where.String()
+}
+
+func (c *conditionSQL) param(value any) string {
+	name := fmt.Sprintf(":cond%d", len(c.params))
+	c.params = append(c.params, value)
+	return name
+}
+
+// bind binds the parameters starting at the given index and returns the
+// index after the last one.
+func (c *conditionSQL) bind(stmt *sqlite.Stmt, bindIndex int) int {
+	for _, p := range c.params {
+		switch v := p.(type) {
+		case string:
+			stmt.BindText(bindIndex, v)
+		case int64:
+			stmt.BindInt64(bindIndex, v)
+		case float64:
+			stmt.BindFloat(bindIndex, v)
+		default:
+			panic(fmt.Sprintf("unsupported condition parameter type %T", p))
+		}
+		bindIndex++
+	}
+	return bindIndex
+}
+
+func (c *conditionSQL) write(cond *search.Condition) error {
+	if cond.Not {
+		c.where.WriteString("NOT ")
+	}
+	c.where.WriteString("(")
+	defer c.where.WriteString(")")
+
+	if !cond.IsLeaf() {
+		for i, child := range cond.Children {
+			if i > 0 {
+				c.where.WriteString(" ")
+				c.where.WriteString(string(cond.Op))
+				c.where.WriteString(" ")
+			}
+			if err := c.write(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch cond.Key {
+	case "tag":
+		// Hierarchical tags match their descendants as well
+		c.where.WriteString(`EXISTS (
+			SELECT 1
+			FROM infos_tag
+			WHERE tag_id IN (
+				SELECT id
+				FROM tag
+				WHERE active = true
+				AND ` + tagSubtreeSQL(c.param(cond.Value.(search.String).Value)) + `
+			)
+			AND infos.id BETWEEN infos_tag.file_id AND infos_tag.file_id + infos_tag.len
+		)`)
+
+	case "filename":
+		c.where.WriteString(`filename LIKE ` + c.param(filenameToLikePattern(cond.Value.(search.String).Value)) + ` ESCAPE '\'`)
+
+	case "created":
+		c.writeDateRange(cond.Value.(search.DateRange))
+
+	case "iso", "f", "shutter", "focal":
+		c.writeNumberRange(cameraColumns[cond.Key], cond.Value.(search.NumberRange))
+
+	case "lens":
+		c.where.WriteString(`lens IS NOT NULL AND lens LIKE ` + c.param(filenameToLikePattern(cond.Value.(search.String).Value)) + ` ESCAPE '\'`)
+
+	case "color":
+		c.writeColor(cond.Value.(search.Color))
+
+	case "width", "height":
+		c.where.WriteString(knownSizeSQL + ` AND `)
+		c.writeNumberRange(cond.Key, cond.Value.(search.NumberRange))
+
+	case "mp":
+		c.where.WriteString(knownSizeSQL + ` AND `)
+		c.writeNumberRange(`width * height / 1e6`, cond.Value.(search.NumberRange))
+
+	case "aspect":
+		c.where.WriteString(knownSizeSQL + ` AND `)
+		switch v := cond.Value.(type) {
+		case search.NumberRange:
+			c.writeNumberRange(`CAST(width AS REAL) / height`, v)
+		case search.String:
+			c.where.WriteString(aspectSQL[v.Value])
+		}
+
+	case "orientation":
+		c.where.WriteString(orientationSQL[cond.Value.(search.String).Value])
+
+	case "near":
+		c.writeNear(cond.Value.(search.Near))
+
+	case "bbox":
+		c.writeBounds([]search.Bounds{cond.Value.(search.BBox).Bounds})
+
+	case "place":
+		place := cond.Value.(search.Place)
+		if !place.Resolved {
+			return fmt.Errorf("place %q not resolved", place.Name)
+		}
+		c.writeBounds(place.Bounds)
+
+	case "dup":
+		c.where.WriteString(duplicateSQL[cond.Value.(search.String).Value])
+
+	case "person":
+		c.where.WriteString(`infos.id IN (
+			SELECT face.file_id
+			FROM face
+			JOIN person ON person.id = face.person_id
+			WHERE person.name = ` + c.param(cond.Value.(search.String).Value) + ` COLLATE NOCASE
+		)`)
+
+	case "rating":
+		// Unrated files have a rating of 0, so that rating:0 matches them
+		c.writeNumberRange(`coalesce(rating, 0)`, cond.Value.(search.NumberRange))
+
+	case "label":
+		c.where.WriteString(`label = ` + c.param(cond.Value.(search.String).Value))
+
+	case "is":
+		c.where.WriteString(isSQL[cond.Value.(search.String).Value])
+
+	default:
+		return fmt.Errorf("unsupported condition qualifier %q", cond.Key)
+	}
+	return nil
+}
+
+// cameraColumns are the infos columns of the numeric camera qualifiers.
+var cameraColumns = map[string]string{
+	"iso":     "iso",
+	"f":       "aperture",
+	"shutter": "exposure_time",
+	"focal":   "focal_length_35",
+}
+
+// knownSizeSQL excludes files with dimensions missing, e.g. before their
+// metadata is indexed.
+const knownSizeSQL = `width IS NOT NULL AND height IS NOT NULL AND width > 0 AND height > 0`
+
+// aspectSQL are the predicates of the named aspect: shapes. Square allows
+// for a few percent of difference between the sides and panoramas are at
+// least twice as long as they are wide in either direction.
+var aspectSQL = map[string]string{
+	"portrait":  `height > width * 1.05`,
+	"landscape": `width > height * 1.05`,
+	"square":    `width <= height * 1.05 AND height <= width * 1.05`,
+	"pano":      `(width >= height * 2 OR height >= width * 2)`,
+}
+
+// orientationSQL are the predicates of the orientation: values, see
+// Orientation for the EXIF values.
+var orientationSQL = map[string]string{
+	"normal":   `coalesce(orientation, 1) IN (0, 1)`,
+	"rotated":  `coalesce(orientation, 1) IN (3, 5, 6, 7, 8)`,
+	"mirrored": `coalesce(orientation, 1) IN (2, 4, 5, 7)`,
+}
+
+// duplicateSQL are the predicates of the dup: values, see
+// Database.WriteDuplicates for how the groups are stored.
+var duplicateSQL = map[string]string{
+	"any":   `infos.id IN (SELECT file_id FROM duplicate)`,
+	"exact": `infos.id IN (SELECT file_id FROM duplicate WHERE exact)`,
+	"near":  `infos.id IN (SELECT file_id FROM duplicate WHERE NOT exact)`,
+	"extra": `infos.id IN (SELECT file_id FROM duplicate WHERE file_id != group_id)`,
+}
+
+// isSQL are the predicates of the is: values.
+var isSQL = map[string]string{
+	"favorite": `favorite`,
+}
+
+// writeNumberRange matches a nullable numeric column, files with unknown
+// values never match, so that they are included when the range is negated.
+func (c *conditionSQL) writeNumberRange(column string, r search.NumberRange) {
+	preds := []string{column + ` IS NOT NULL`}
+	if r.HasFrom {
+		op := ` >= `
+		if r.FromExcluded {
+			op = ` > `
+		}
+		preds = append(preds, column+op+c.param(r.From))
+	}
+	if r.HasTo {
+		op := ` <= `
+		if r.ToExcluded {
+			op = ` < `
+		}
+		preds = append(preds, column+op+c.param(r.To))
+	}
+	c.where.WriteString(strings.Join(preds, " AND "))
+}
+
+// writeColor matches the prominent colors within the max distance of the
+// color, files without one extracted yet never match.
+func (c *conditionSQL) writeColor(col search.Color) {
+	lab := LabFromRGB(color.RGBA{R: col.R, G: col.G, B: col.B, A: 0xFF})
+	c.where.WriteString(`color IS NOT NULL AND (color >> 24) & 255 != 0 AND ` +
+		`color_delta_e(color, ` + c.param(lab.L) + `, ` + c.param(lab.A) + `, ` + c.param(lab.B) + `) <= ` + c.param(col.MaxDistance))
+}
+
+// writeNear matches locations within the radius using the equirectangular
+// approximation, which is accurate enough for the distances of interest.
+func (c *conditionSQL) writeNear(n search.Near) {
+	radiusDeg := n.RadiusKm / earthRadiusKm * 180 / math.Pi
+	// Parameters are declared in the order they appear in the predicate
+	latFrom := c.param(n.Lat - radiusDeg)
+	latTo := c.param(n.Lat + radiusDeg)
+	lat := c.param(n.Lat)
+	lng := c.param(n.Lng)
+	lngScale := c.param(math.Cos(n.Lat * math.Pi / 180))
+	radiusSq := c.param(radiusDeg * radiusDeg)
+	dlng := `min(abs(longitude - ` + lng + `), 360 - abs(longitude - ` + lng + `)) * ` + lngScale
+	c.where.WriteString(`latitude IS NOT NULL AND longitude IS NOT NULL AND ` +
+		`latitude BETWEEN ` + latFrom + ` AND ` + latTo + ` AND ` +
+		`(latitude - ` + lat + `) * (latitude - ` + lat + `) + ` + dlng + ` * ` + dlng + ` <= ` + radiusSq)
+}
+
+// writeBounds matches locations within any of the bounds.
+func (c *conditionSQL) writeBounds(bounds []search.Bounds) {
+	if len(bounds) == 0 {
+		c.where.WriteString("false")
+		return
+	}
+	preds := make([]string, 0, len(bounds))
+	for _, b := range bounds {
+		pred := `latitude BETWEEN ` + c.param(b.MinLat) + ` AND ` + c.param(b.MaxLat) + ` AND `
+		if b.MinLng <= b.MaxLng {
+			pred += `longitude BETWEEN ` + c.param(b.MinLng) + ` AND ` + c.param(b.MaxLng)
+		} else {
+			// Crosses the antimeridian
+			pred += `(longitude >= ` + c.param(b.MinLng) + ` OR longitude <= ` + c.param(b.MaxLng) + `)`
+		}
+		preds = append(preds, `(`+pred+`)`)
+	}
+	c.where.WriteString(`latitude IS NOT NULL AND longitude IS NOT NULL AND (` + strings.Join(preds, " OR ") + `)`)
+}
+
+// localDateSQL formats the local creation time of a file with the given
+// strftime format.
+func localDateSQL(format string) string {
+	return fmt.Sprintf(`strftime('%s', created_at_unix + created_at_tz_offset * 60, 'unixepoch')`, format)
+}
+
+func (c *conditionSQL) writeDateRange(r search.DateRange) {
+	preds := make([]string, 0, 4)
+	if !r.From.IsZero() {
+		preds = append(preds, `created_at_unix >= `+c.param(r.From.Unix()))
+	}
+	if !r.To.IsZero() {
+		preds = append(preds, `created_at_unix < `+c.param(r.To.Unix()))
+	}
+	// Partial wildcards match the remaining date components of the local
+	// time, e.g. created:*-05-* matches all the days in May of any year
+	fromPartial := r.FromWildcard.Any() && !r.FromWildcard.All()
+	toPartial := r.ToWildcard.Any() && !r.ToWildcard.All()
+	fromLayout, fromFormat := r.FromWildcard.Layouts()
+	toLayout, toFormat := r.ToWildcard.Layouts()
+	from := r.From.Format(fromLayout)
+	to := r.To.Format(toLayout)
+	if fromPartial && toPartial && fromFormat == toFormat && from >= to {
+		// The end wrapped around, e.g. created:*-12-31 ends on 01-01
+		preds = append(preds, `(`+localDateSQL(fromFormat)+` >= `+c.param(from)+` OR `+localDateSQL(toFormat)+` < `+c.param(to)+`)`)
+	} else {
+		if fromPartial {
+			preds = append(preds, localDateSQL(fromFormat)+` >= `+c.param(from))
+		}
+		if toPartial {
+			preds = append(preds, localDateSQL(toFormat)+` < `+c.param(to))
+		}
+	}
+	if len(preds) == 0 {
+		c.where.WriteString("true")
+		return
+	}
+	c.where.WriteString(strings.Join(preds, " AND "))
+}
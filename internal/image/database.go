@@ -5,13 +5,14 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"image/color"
 	"log"
 	"net/http"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"photofield/internal/ai"
@@ -50,6 +51,10 @@ const (
 	ShuffleMonthly
 	SimilarityDesc
 	SimilarityAsc
+	HueAsc
+	HueDesc
+	LightnessAsc
+	LightnessDesc
 )
 
 func isSimilarityOrder(order ListOrder) bool {
@@ -69,10 +74,21 @@ type ListOptions struct {
 	ImageEmbedding ai.Embedding
 	FaceEmbedding  ai.Embedding
 	Extensions     []string
+	Filter         PathFilter
 	Batch          int
+
+	// Dirs of the prefixes relative to the listed dirs, only set if the
+	// files are filtered
+	prefixDirs map[int64]string
+
+	// JSON array of the ids of the files or faces to compare to the
+	// embedding, found by the embedding index, empty to compare all
+	similarIds string
 }
 
 type DirsFunc func(dirs []string)
+type IdsFunc func(ids []ImageId)
+type TagFunc func(id tag.Id, name string, updatedAt time.Time)
 type stringSet map[string]struct{}
 
 func (s *stringSet) Add(str string) {
@@ -97,6 +113,15 @@ type Database struct {
 	pending          chan *InfoWrite
 	transactionMutex sync.RWMutex
 	dirUpdateFuncs   []DirsFunc
+	relinkFuncs      []IdsFunc
+	tagUpdateFuncs   []TagFunc
+	indexConfig      EmbeddingIndexConfig
+	clipIndex        *embeddingIndex
+	faceIndex        *embeddingIndex
+	places           PlaceBounds
+	// metadataUpdatedAt is the last time the ratings, labels or favorites
+	// of any files were set in Unix milliseconds, see UpdateStaleness
+	metadataUpdatedAt atomic.Int64
 }
 
 type InfoWriteType int32
@@ -115,6 +140,10 @@ const (
 	RemoveTagIds  InfoWriteType = iota
 	InvertTagIds  InfoWriteType = iota
 	CompactTagIds InfoWriteType = iota
+	UpdateHash    InfoWriteType = iota
+	UpdateRating  InfoWriteType = iota
+	Relink        InfoWriteType = iota
+	AddTaskError  InfoWriteType = iota
 	CommitBarrier InfoWriteType = iota
 )
 
@@ -125,10 +154,21 @@ type InfoWrite struct {
 	Faces     []ai.Face
 	Type      InfoWriteType
 	Ids       Ids
+	Hash      ContentHash
+	Rating    int
+	TaskError TaskError
 	Done      chan any
 	Info
 }
 
+// ContentHash identifies a file by its contents, so that it can be found
+// again after it is moved or renamed. Full is only set if the partial hash
+// collides with the partial hash of another file.
+type ContentHash struct {
+	Partial int64
+	Full    []byte
+}
+
 type InfoExistence struct {
 	SizeNull        bool
 	OrientationNull bool
@@ -198,7 +238,10 @@ func NewDatabase(path string, migrations embed.FS) *Database {
 	source.poolSize = 100
 	source.migrate(migrations)
 
-	source.pool, err = sqlitex.Open(source.path, 0, source.poolSize)
+	source.pool, err = sqlitex.NewPool(source.path, sqlitex.PoolOptions{
+		PoolSize:    source.poolSize,
+		PrepareConn: prepareConn,
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -206,14 +249,32 @@ func NewDatabase(path string, migrations embed.FS) *Database {
 	source.pending = make(chan *InfoWrite, 10000)
 	go source.writePendingInfosSqlite()
 
+	if err := source.linkTagParents(); err != nil {
+		log.Printf("Unable to link tag parents: %s\n", err.Error())
+	}
+
 	return &source
 }
 
+// prepareConn registers the SQL functions used by the listings on the
+// connections of the pool.
+func prepareConn(conn *sqlite.Conn) error {
+	return conn.CreateFunction("color_delta_e", colorDeltaEFunc)
+}
+
 func (source *Database) Close() {
 	if source == nil {
 		return
 	}
 	source.dirUpdateFuncs = nil
+	source.relinkFuncs = nil
+	source.tagUpdateFuncs = nil
+	if source.clipIndex != nil {
+		source.clipIndex.save()
+	}
+	if source.faceIndex != nil {
+		source.faceIndex.save()
+	}
 	source.pool.Close()
 	source.pool = nil
 	close(source.pending)
@@ -281,6 +342,20 @@ func (db *Database) HandleDirUpdates(fn DirsFunc) {
 	db.dirUpdateFuncs = append(db.dirUpdateFuncs, fn)
 }
 
+// HandleRelinks registers a function called with the ids of files that were
+// relinked to a new path once the change is committed.
+func (db *Database) HandleRelinks(fn IdsFunc) {
+	db.relinkFuncs = append(db.relinkFuncs, fn)
+}
+
+// HandleTagUpdates registers a function called with the new version of a
+// tag, its name and the time it was updated at, once files were added to or
+// removed from the tag. It is called on the database writer, so it should
+// not query the database.
+func (db *Database) HandleTagUpdates(fn TagFunc) {
+	db.tagUpdateFuncs = append(db.tagUpdateFuncs, fn)
+}
+
 func (source *Database) writePendingInfosSqlite() {
 	conn := source.open()
 	defer conn.Close()
@@ -291,7 +366,7 @@ func (source *Database) writePendingInfosSqlite() {
 	defer upsertPrefix.Finalize()
 
 	updateMeta := conn.Prep(`
-		INSERT INTO infos(path_prefix_id, filename, width, height, orientation, created_at_unix, created_at_tz_offset, latitude, longitude)
+		INSERT INTO infos(path_prefix_id, filename, width, height, orientation, created_at_unix, created_at_tz_offset, latitude, longitude, iso, aperture, exposure_time, focal_length_35, lens)
 		SELECT
 			id as path_prefix_id,
 			? as filename,
@@ -301,7 +376,12 @@ func (source *Database) writePendingInfosSqlite() {
 			? as created_at_unix,
 			? as created_at_tz_offset,
 			? as latitude,
-			? as longitude
+			? as longitude,
+			? as iso,
+			? as aperture,
+			? as exposure_time,
+			? as focal_length_35,
+			? as lens
 		FROM prefix
 		WHERE str == ?
 		ON CONFLICT(path_prefix_id, filename) DO UPDATE SET
@@ -311,7 +391,12 @@ func (source *Database) writePendingInfosSqlite() {
 			latitude=excluded.latitude,
 			longitude=excluded.longitude,
 			created_at_unix=excluded.created_at_unix,
-			created_at_tz_offset=excluded.created_at_tz_offset;`)
+			created_at_tz_offset=excluded.created_at_tz_offset,
+			iso=excluded.iso,
+			aperture=excluded.aperture,
+			exposure_time=excluded.exposure_time,
+			focal_length_35=excluded.focal_length_35,
+			lens=excluded.lens;`)
 	defer updateMeta.Finalize()
 
 	updateColor := conn.Prep(`
@@ -332,15 +417,47 @@ func (source *Database) writePendingInfosSqlite() {
 	defer updateAI.Finalize()
 
 	insertFace := conn.Prep(`
-		INSERT INTO face(file_id, x, y, w, h, confidence, embedding)
-		VALUES (?, ?, ?, ?, ?, ?, ?);`)
+		INSERT INTO face(file_id, x, y, w, h, confidence, embedding, person_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
 	defer insertFace.Finalize()
 
+	listAssignedFaces := conn.Prep(`
+		SELECT x, y, w, h, person_id
+		FROM face
+		WHERE file_id = ? AND person_id IS NOT NULL;`)
+	defer listAssignedFaces.Finalize()
+
 	deleteFaces := conn.Prep(`
 		DELETE FROM face
 		WHERE file_id = ?;`)
 	defer deleteFaces.Finalize()
 
+	listFaceIds := conn.Prep(`
+		SELECT id
+		FROM face
+		WHERE file_id = ?;`)
+	defer listFaceIds.Finalize()
+
+	removeIndexedFaces := func(fileId int64) {
+		if source.faceIndex == nil {
+			return
+		}
+		listFaceIds.BindInt64(1, fileId)
+		for {
+			if exists, err := listFaceIds.Step(); err != nil {
+				log.Printf("Unable to list faces for %d: %s\n", fileId, err.Error())
+				break
+			} else if !exists {
+				break
+			}
+			source.faceIndex.remove(listFaceIds.ColumnInt64(0))
+		}
+		err := listFaceIds.Reset()
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	updateFaceCount := conn.Prep(`
 		UPDATE infos SET face_count = ?
 		WHERE id = ?;`)
@@ -361,25 +478,78 @@ func (source *Database) writePendingInfosSqlite() {
 		WHERE id == ?;`)
 	defer delete.Finalize()
 
+	deleteDuplicate := conn.Prep(`
+		DELETE
+		FROM duplicate
+		WHERE file_id == ?;`)
+	defer deleteDuplicate.Finalize()
+
+	deleteTagSuggestions := conn.Prep(`
+		DELETE
+		FROM tag_suggestion
+		WHERE file_id == ?;`)
+	defer deleteTagSuggestions.Finalize()
+
+	updateHash := conn.Prep(`
+		UPDATE infos
+		SET hash_partial = ?, hash_full = ?
+		WHERE id == ?;`)
+	defer updateHash.Finalize()
+
+	updateRating := conn.Prep(`
+		UPDATE infos
+		SET rating = ?
+		WHERE id == ?;`)
+	defer updateRating.Finalize()
+
+	relink := conn.Prep(`
+		UPDATE infos
+		SET
+			path_prefix_id = (SELECT id FROM prefix WHERE str == ?),
+			filename = ?
+		WHERE id == ?;`)
+	defer relink.Finalize()
+
+	insertTaskError := conn.Prep(`
+		INSERT INTO task_error(task_run_id, file_id, path, error, created_at_ms)
+		VALUES (?, ?, ?, ?, ?);`)
+	defer insertTaskError.Finalize()
+
 	upsertIndex := conn.Prep(`
 		INSERT OR REPLACE INTO dirs(path, indexed_at)
 		VALUES (?, ?);`)
 	defer upsertIndex.Finalize()
 
 	insertTag := conn.Prep(`
-		INSERT INTO tag(name, updated_at_ms)
-		VALUES (?, ?)
+		INSERT INTO tag(name, updated_at_ms, parent_id)
+		VALUES (?, ?, ?)
 		RETURNING id;`)
 	defer insertTag.Finalize()
 
 	addTagVersion := conn.Prep(`
-		INSERT INTO tag(name, updated_at_ms)
-		SELECT name, ? as updated_at_ms
+		INSERT INTO tag(name, updated_at_ms, auto, parent_id)
+		SELECT name, ? as updated_at_ms, auto, parent_id
 		FROM tag
 		WHERE id == ?
-		RETURNING id;`)
+		RETURNING id, name;`)
 	defer addTagVersion.Finalize()
 
+	// Children refer to the active version of their parent
+	relinkTagChildren := conn.Prep(`
+		UPDATE tag
+		SET parent_id = :id
+		WHERE parent_id IN (
+			SELECT id
+			FROM tag
+			WHERE name IN (
+				SELECT name
+				FROM tag
+				WHERE id == :id
+			)
+			AND id != :id
+		);`)
+	defer relinkTagChildren.Finalize()
+
 	deactivateTags := conn.Prep(`
 		UPDATE tag
 		SET active = false
@@ -507,6 +677,7 @@ func (source *Database) writePendingInfosSqlite() {
 
 	pendingCompactionTags := tagSet{}
 	pendingUpdatedDirs := make(stringSet)
+	pendingRelinkedIds := make([]ImageId, 0)
 	commitBarriers := make([]chan any, 0)
 
 	defer func() {
@@ -569,6 +740,14 @@ func (source *Database) writePendingInfosSqlite() {
 			}
 			clear(pendingUpdatedDirs)
 
+			// Flush relinked ids
+			if len(pendingRelinkedIds) > 0 {
+				for _, fn := range source.relinkFuncs {
+					fn(pendingRelinkedIds)
+				}
+				pendingRelinkedIds = pendingRelinkedIds[:0]
+			}
+
 			// Optimize if needed
 			if time.Since(lastOptimize).Hours() >= 1 {
 				lastOptimize = time.Now()
@@ -650,7 +829,8 @@ func (source *Database) writePendingInfosSqlite() {
 					updateMeta.BindFloat(7, imageInfo.LatLng.Lat.Degrees())
 					updateMeta.BindFloat(8, imageInfo.LatLng.Lng.Degrees())
 				}
-				updateMeta.BindText(9, dir)
+				bindCamera(updateMeta, 9, imageInfo.Camera)
+				updateMeta.BindText(14, dir)
 
 				_, err := updateMeta.Step()
 				if err != nil {
@@ -695,11 +875,38 @@ func (source *Database) writePendingInfosSqlite() {
 				if err != nil {
 					panic(err)
 				}
+				source.clipIndex.add(imageInfo.Id, imageInfo.Embedding)
 
 			case UpdateFaces:
+				// Keep the persons of the existing faces to assign them to
+				// the new ones at the same place
+				var assigned []assignedFace
+				listAssignedFaces.BindInt64(1, int64(imageInfo.Id))
+				for {
+					if exists, err := listAssignedFaces.Step(); err != nil {
+						log.Printf("Unable to list assigned faces for %d: %s\n", imageInfo.Id, err.Error())
+						break
+					} else if !exists {
+						break
+					}
+					assigned = append(assigned, assignedFace{
+						X:        listAssignedFaces.ColumnInt(0),
+						Y:        listAssignedFaces.ColumnInt(1),
+						W:        listAssignedFaces.ColumnInt(2),
+						H:        listAssignedFaces.ColumnInt(3),
+						PersonId: listAssignedFaces.ColumnInt64(4),
+					})
+				}
+				err := listAssignedFaces.Reset()
+				if err != nil {
+					panic(err)
+				}
+				personIds := matchFacePersons(assigned, imageInfo.Faces)
+
 				// Delete existing faces for this image
+				removeIndexedFaces(imageInfo.Id)
 				deleteFaces.BindInt64(1, int64(imageInfo.Id))
-				_, err := deleteFaces.Step()
+				_, err = deleteFaces.Step()
 				if err != nil {
 					log.Printf("Unable to delete faces for %d: %s\n", imageInfo.Id, err.Error())
 					continue
@@ -710,7 +917,7 @@ func (source *Database) writePendingInfosSqlite() {
 				}
 
 				// Insert new faces
-				for _, face := range imageInfo.Faces {
+				for i, face := range imageInfo.Faces {
 					insertFace.BindInt64(1, int64(imageInfo.Id))
 					insertFace.BindInt64(2, int64(face.X))
 					insertFace.BindInt64(3, int64(face.Y))
@@ -718,6 +925,11 @@ func (source *Database) writePendingInfosSqlite() {
 					insertFace.BindInt64(5, int64(face.H))
 					insertFace.BindInt64(6, int64(face.Confidence))
 					insertFace.BindBytes(7, face.Embedding)
+					if personIds[i] != 0 {
+						insertFace.BindInt64(8, personIds[i])
+					} else {
+						insertFace.BindNull(8)
+					}
 
 					_, err := insertFace.Step()
 					if err != nil {
@@ -728,6 +940,7 @@ func (source *Database) writePendingInfosSqlite() {
 					if err != nil {
 						panic(err)
 					}
+					source.faceIndex.add(conn.LastInsertRowID(), ai.FromRaw(face.Embedding, ai.FaceEmbeddingInvNorm))
 				}
 
 				// Mark face count for this image (even if no faces were found)
@@ -784,9 +997,35 @@ func (source *Database) writePendingInfosSqlite() {
 					}
 				}
 
+				// Delete embeddings from the indexes
+				source.clipIndex.remove(int64(id))
+				removeIndexedFaces(int64(id))
+
+				// Delete duplicate group membership
+				deleteDuplicate.BindInt64(1, int64(id))
+				_, err := deleteDuplicate.Step()
+				if err != nil {
+					log.Printf("Unable to delete duplicate %d: %s\n", id, err.Error())
+				}
+				err = deleteDuplicate.Reset()
+				if err != nil {
+					panic(err)
+				}
+
+				// Delete tag suggestions
+				deleteTagSuggestions.BindInt64(1, int64(id))
+				_, err = deleteTagSuggestions.Step()
+				if err != nil {
+					log.Printf("Unable to delete tag suggestions %d: %s\n", id, err.Error())
+				}
+				err = deleteTagSuggestions.Reset()
+				if err != nil {
+					panic(err)
+				}
+
 				// Delete image info
 				delete.BindInt64(1, int64(id))
-				_, err := delete.Step()
+				_, err = delete.Step()
 				if err != nil {
 					log.Printf("Unable to delete path %s: %s\n", imageInfo.Path, err.Error())
 					continue
@@ -796,6 +1035,69 @@ func (source *Database) writePendingInfosSqlite() {
 					panic(err)
 				}
 
+			case UpdateRating:
+				if imageInfo.Rating == 0 {
+					updateRating.BindNull(1)
+				} else {
+					updateRating.BindInt64(1, int64(imageInfo.Rating))
+				}
+				updateRating.BindInt64(2, imageInfo.Id)
+				_, err := updateRating.Step()
+				if err != nil {
+					log.Printf("Unable to update rating %d: %s\n", imageInfo.Id, err.Error())
+					continue
+				}
+				err = updateRating.Reset()
+				if err != nil {
+					panic(err)
+				}
+
+			case UpdateHash:
+				updateHash.BindInt64(1, imageInfo.Hash.Partial)
+				if imageInfo.Hash.Full == nil {
+					updateHash.BindNull(2)
+				} else {
+					updateHash.BindBytes(2, imageInfo.Hash.Full)
+				}
+				updateHash.BindInt64(3, imageInfo.Id)
+				_, err := updateHash.Step()
+				if err != nil {
+					log.Printf("Unable to update hash %d: %s\n", imageInfo.Id, err.Error())
+					continue
+				}
+				err = updateHash.Reset()
+				if err != nil {
+					panic(err)
+				}
+
+			case Relink:
+				dir, file := filepath.Split(imageInfo.Path)
+
+				upsertPrefix.BindText(1, dir)
+				_, err := upsertPrefix.Step()
+				if err != nil {
+					log.Printf("Unable to insert path prefix %s: %s\n", dir, err.Error())
+					continue
+				}
+				err = upsertPrefix.Reset()
+				if err != nil {
+					panic(err)
+				}
+
+				relink.BindText(1, dir)
+				relink.BindText(2, file)
+				relink.BindInt64(3, imageInfo.Id)
+				_, err = relink.Step()
+				if err != nil {
+					log.Printf("Unable to relink %d to %s: %s\n", imageInfo.Id, imageInfo.Path, err.Error())
+					continue
+				}
+				err = relink.Reset()
+				if err != nil {
+					panic(err)
+				}
+				pendingRelinkedIds = append(pendingRelinkedIds, ImageId(imageInfo.Id))
+
 			case Index:
 				upsertIndex.BindText(1, imageInfo.Path)
 				upsertIndex.BindText(2, imageInfo.DateTime.Format(dateFormat))
@@ -825,9 +1127,15 @@ func (source *Database) writePendingInfosSqlite() {
 				}
 
 				if !ok {
+					parentId, err := addTagParents(conn, tagName)
+					if err != nil {
+						log.Printf("Unable to add parents of tag %s: %s\n", tagName, err.Error())
+						continue
+					}
 					insertTag.BindText(1, tagName)
 					insertTag.BindInt64(2, toUnixMs(time.Now()))
-					_, err := insertTag.Step()
+					bindTagParentId(insertTag, 3, parentId)
+					_, err = insertTag.Step()
 					if err != nil {
 						log.Printf("Unable to insert tag %s: %s\n", tagName, err.Error())
 						continue
@@ -860,9 +1168,15 @@ func (source *Database) writePendingInfosSqlite() {
 				}
 
 				if !ok {
+					parentId, err := addTagParents(conn, tagName)
+					if err != nil {
+						log.Printf("Unable to add parents of tag %s: %s\n", tagName, err.Error())
+						continue
+					}
 					insertTag.BindText(1, tagName)
 					insertTag.BindInt64(2, toUnixMs(time.Now()))
-					_, err := insertTag.Step()
+					bindTagParentId(insertTag, 3, parentId)
+					_, err = insertTag.Step()
 					if err != nil {
 						log.Printf("Unable to insert tag %s: %s\n", tagName, err.Error())
 						continue
@@ -926,11 +1240,24 @@ func (source *Database) writePendingInfosSqlite() {
 					continue
 				}
 				tagId = tag.Id(addTagVersion.ColumnInt64(0))
+				tagName := addTagVersion.ColumnText(1)
 				err = addTagVersion.Reset()
 				if err != nil {
 					panic(err)
 				}
 
+				// Relink children before the old versions are deleted
+				relinkTagChildren.BindInt64(1, int64(tagId))
+				_, err = relinkTagChildren.Step()
+				if err != nil {
+					log.Printf("Unable to relink tag children %d: %s\n", tagId, err.Error())
+					continue
+				}
+				err = relinkTagChildren.Reset()
+				if err != nil {
+					panic(err)
+				}
+
 				// Delete old tags
 				deleteOldTags.BindInt64(1, int64(tagId))
 				_, err = deleteOldTags.Step()
@@ -989,6 +1316,33 @@ func (source *Database) writePendingInfosSqlite() {
 				imageInfo.Done <- updatedAt
 				close(imageInfo.Done)
 
+				if imageInfo.Type != CompactTagIds {
+					for _, fn := range source.tagUpdateFuncs {
+						fn(tagId, tagName, updatedAt)
+					}
+				}
+
+			case AddTaskError:
+				e := imageInfo.TaskError
+				insertTaskError.BindInt64(1, e.RunId)
+				if e.FileId == 0 {
+					insertTaskError.BindNull(2)
+				} else {
+					insertTaskError.BindInt64(2, int64(e.FileId))
+				}
+				insertTaskError.BindText(3, e.Path)
+				insertTaskError.BindText(4, e.Error)
+				insertTaskError.BindInt64(5, toUnixMs(e.CreatedAt))
+				_, err := insertTaskError.Step()
+				if err != nil {
+					log.Printf("Unable to add task error %d: %s\n", e.RunId, err.Error())
+					continue
+				}
+				err = insertTaskError.Reset()
+				if err != nil {
+					panic(err)
+				}
+
 			case CommitBarrier:
 				commitBarriers = append(commitBarriers, imageInfo.Done)
 			}
@@ -997,6 +1351,18 @@ func (source *Database) writePendingInfosSqlite() {
 	}
 }
 
+// writeDirect runs a write outside of the pending writes transaction, so that
+// the caller gets the result back directly.
+func (source *Database) writeDirect(fn func(conn *sqlite.Conn) error) error {
+	source.transactionMutex.Lock()
+	defer source.transactionMutex.Unlock()
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	return fn(conn)
+}
+
 func (source *Database) GetPathFromId(id ImageId) (string, bool) {
 	conn := source.pool.Get(context.TODO())
 	defer source.pool.Put(conn)
@@ -1018,6 +1384,29 @@ func (source *Database) GetPathFromId(id ImageId) (string, bool) {
 	return stmt.ColumnText(0), true
 }
 
+func (source *Database) GetIdFromPath(path string) (ImageId, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT infos.id
+		FROM infos
+		JOIN prefix ON path_prefix_id == prefix.id
+		WHERE str == ? AND filename == ?;`)
+	defer stmt.Reset()
+
+	dir, file := filepath.Split(path)
+	stmt.BindText(1, dir)
+	stmt.BindText(2, file)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return 0, false
+	}
+
+	return ImageId(stmt.ColumnInt64(0)), true
+}
+
 func (source *Database) Get(id ImageId) (InfoResult, bool) {
 
 	conn := source.pool.Get(context.TODO())
@@ -1307,6 +1696,33 @@ func (source *Database) WriteTags(id ImageId, tags []tag.Tag) error {
 	return nil
 }
 
+// WriteRating sets the rating of the file from 1 to 5, or 0 to unrate it.
+func (source *Database) WriteRating(id ImageId, rating int) {
+	source.pending <- &InfoWrite{
+		Id:     int64(id),
+		Type:   UpdateRating,
+		Rating: rating,
+	}
+}
+
+func (source *Database) WriteHash(id ImageId, hash ContentHash) {
+	source.pending <- &InfoWrite{
+		Id:   int64(id),
+		Type: UpdateHash,
+		Hash: hash,
+	}
+}
+
+// Relink moves the file with the given id to a new path, keeping its id and
+// everything associated with it. The new path must not be in use.
+func (source *Database) Relink(id ImageId, path string) {
+	source.pending <- &InfoWrite{
+		Id:   int64(id),
+		Type: Relink,
+		Path: path,
+	}
+}
+
 func (source *Database) Delete(id ImageId) error {
 	source.pending <- &InfoWrite{
 		Id:   int64(id),
@@ -1479,7 +1895,7 @@ func (source *Database) GetTag(id tag.Id) (tag.Tag, bool) {
 	defer source.pool.Put(conn)
 
 	stmt := conn.Prep(`
-	SELECT name, updated_at_ms
+	SELECT name, updated_at_ms, auto, ` + tagParentNameSQL + `
 	FROM tag
 	WHERE id = ?
 	AND active = true;`)
@@ -1496,6 +1912,8 @@ func (source *Database) GetTag(id tag.Id) (tag.Tag, bool) {
 		Id:        id,
 		Name:      stmt.ColumnText(0),
 		UpdatedAt: fromUnixMs(stmt.ColumnInt64(1)),
+		Auto:      stmt.ColumnBool(2),
+		Parent:    stmt.ColumnText(3),
 	}, true
 }
 
@@ -1504,7 +1922,7 @@ func (source *Database) GetTagByName(name string) (tag.Tag, bool) {
 	defer source.pool.Put(conn)
 
 	stmt := conn.Prep(`
-	SELECT id, updated_at_ms
+	SELECT id, updated_at_ms, auto, ` + tagParentNameSQL + `
 	FROM tag
 	WHERE name = ?
 	AND active = true;`)
@@ -1521,6 +1939,8 @@ func (source *Database) GetTagByName(name string) (tag.Tag, bool) {
 		Id:        tag.Id(stmt.ColumnInt(0)),
 		Name:      name,
 		UpdatedAt: fromUnixMs(stmt.ColumnInt64(1)),
+		Auto:      stmt.ColumnBool(2),
+		Parent:    stmt.ColumnText(3),
 	}, true
 }
 
@@ -1637,7 +2057,7 @@ func (source *Database) ListTags(q string, limit int) <-chan tag.Tag {
 		defer source.pool.Put(conn)
 
 		sql := `
-		SELECT id, name, updated_at_ms
+		SELECT id, name, updated_at_ms, ` + tagParentNameSQL + `
 		FROM tag
 		WHERE name LIKE ?
 		` + defaultTagConditions + `
@@ -1660,6 +2080,7 @@ func (source *Database) ListTags(q string, limit int) <-chan tag.Tag {
 				Id:        tag.Id(stmt.ColumnInt(0)),
 				Name:      stmt.ColumnText(1),
 				UpdatedAt: fromUnixMs(stmt.ColumnInt64(2)),
+				Parent:    stmt.ColumnText(3),
 			}
 		}
 		close(out)
@@ -1758,6 +2179,7 @@ func (source *Database) SetIndexed(dir string) {
 type Dependency struct {
 	db        *Database
 	tagNames  []string
+	metadata  bool
 	updatedAt time.Time
 }
 type Dependencies []Dependency
@@ -1779,8 +2201,16 @@ func (source *Database) UpdateStaleness(dep *Dependency) {
 		}
 		dep.updatedAt = updatedAt
 	}
+	if dep.metadata {
+		updatedAt := fromUnixMs(source.metadataUpdatedAt.Load())
+		if updatedAt.After(dep.updatedAt) {
+			dep.updatedAt = updatedAt
+		}
+	}
 }
 
+// GetLatestTagUpdateTime returns the last time any of the tags or their
+// descendants were updated.
 func (source *Database) GetLatestTagUpdateTime(tagNames []string) (time.Time, bool) {
 	conn := source.pool.Get(context.TODO())
 	defer source.pool.Put(conn)
@@ -1788,12 +2218,14 @@ func (source *Database) GetLatestTagUpdateTime(tagNames []string) (time.Time, bo
 	sql := `
 	SELECT MAX(updated_at_ms)
 	FROM tag
-	WHERE name IN (`
-	length := len(tagNames)
-	if length > 1 {
-		sql += strings.Repeat("?, ", length-1)
+	WHERE (`
+	for i := range tagNames {
+		if i > 0 {
+			sql += " OR "
+		}
+		sql += tagSubtreeSQL(fmt.Sprintf("?%d", i+1))
 	}
-	sql += `?)
+	sql += `)
 	AND active = true;`
 
 	stmt := conn.Prep(sql)
@@ -1964,11 +2396,11 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 	out := make(chan SourcedInfo, 1000)
 
 	tags := options.Expression.Tags.Values()
-	filenames := options.Expression.Filenames.Values()
 	deps := Dependencies{
 		Dependency{
 			db:       source,
 			tagNames: tags,
+			metadata: hasMetadataCondition(options.Expression.Condition),
 		},
 	}
 
@@ -1977,6 +2409,13 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 		return out, deps
 	}
 
+	cond, err := newConditionSQL(options.Expression.Condition)
+	if err != nil {
+		log.Printf("Unable to list files: %s\n", err.Error())
+		close(out)
+		return out, deps
+	}
+
 	go func() {
 		if options.Batch == 0 {
 			defer metrics.Elapsed("list infos sqlite")()
@@ -1984,31 +2423,7 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 
 		sql := ""
 
-		if len(tags) > 0 {
-			sql += `
-			WITH
-			`
-			for i := range tags {
-				if i > 0 {
-					sql += ","
-				}
-				sql += `
-				tag` + strconv.Itoa(i) + ` AS (
-					SELECT file_id, len
-					FROM infos_tag
-					WHERE tag_id IN (
-						SELECT id
-						FROM tag
-						WHERE active = true
-						AND name = ?
-						LIMIT 1
-					)
-				)
-				`
-			}
-		}
-
-		similarityOrder := isSimilarityOrder(options.OrderBy)
+		memoryOrder := isMemoryOrder(options.OrderBy)
 
 		joinEmbeddings := false
 		var emb []float32
@@ -2030,6 +2445,18 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			joinFaces = true
 		}
 
+		filterFiles := options.prefixDirs != nil && options.Filter.filtersFiles()
+		// The path filter is applied in Go, so the files are only counted
+		// towards the limit once they pass it
+		sqlLimit := options.Limit > 0 && !memoryOrder && !filterFiles
+		filterCol := 9
+		if joinEmbeddings {
+			filterCol += 2
+		}
+		if joinFaces {
+			filterCol++
+		}
+
 		sql += `
 			SELECT * FROM (
 		`
@@ -2044,18 +2471,13 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			if joinFaces {
 				sql += `, face.embedding`
 			}
+			if filterFiles {
+				sql += `, path_prefix_id, filename`
+			}
 			sql += `
 				FROM infos
 			`
 
-			if len(tags) > 0 {
-				for i := range tags {
-					sql += fmt.Sprintf(`
-						JOIN tag%[1]d ON id BETWEEN tag%[1]d.file_id AND tag%[1]d.file_id+tag%[1]d.len
-					`, i)
-				}
-			}
-
 			if joinEmbeddings {
 				sql += `
 					LEFT JOIN clip_emb ON clip_emb.file_id = infos.id
@@ -2087,34 +2509,21 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 				sql += `
 					)`
 			}
-			if !options.Expression.Created.From.IsZero() {
-				sql += `
-					AND created_at_unix >= :created_from
-				`
-			}
-			if !options.Expression.Created.To.IsZero() {
-				sql += `
-					AND created_at_unix < :created_to
-				`
-			}
-			if len(filenames) > 0 {
-				sql += `
-					AND (
-				`
-				for i := range filenames {
-					sql += fmt.Sprintf(
-						`filename LIKE :filename%[1]d ESCAPE '\' `,
-						i,
-					)
-					if i < len(filenames)-1 {
-						sql += "OR "
-					}
+
+			if options.similarIds != "" {
+				if joinFaces {
+					sql += `
+					AND face.id IN (SELECT value FROM json_each(:similar))`
+				} else {
+					sql += `
+					AND infos.id IN (SELECT value FROM json_each(:similar))`
 				}
-				sql += `
-					)
-				`
 			}
 
+			sql += `
+					AND ` + cond.String() + `
+			`
+
 			sql += `
 				AND path_prefix_id = ?
 			`
@@ -2160,11 +2569,11 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			sql += `
 			ORDER BY (id * 2654435761 + ? * 1664525) % 4294967296
 			`
-		case SimilarityDesc, SimilarityAsc:
-			// No SQL ordering — similarity sort happens in Go after all results are collected.
+		case SimilarityDesc, SimilarityAsc, HueAsc, HueDesc, LightnessAsc, LightnessDesc:
+			// No SQL ordering — similarity and color sort happens in Go after all results are collected.
 		}
 
-		if options.Limit > 0 && !similarityOrder {
+		if sqlLimit {
 			sql += `
 				LIMIT ?
 			`
@@ -2182,29 +2591,17 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 
 		bindIndex := 1
 
-		for _, t := range tags {
-			stmt.BindText(bindIndex, t)
-			bindIndex++
-		}
-
 		for _, ext := range options.Extensions {
 			stmt.BindText(bindIndex, "%"+ext)
 			bindIndex++
 		}
 
-		if !options.Expression.Created.From.IsZero() {
-			stmt.BindInt64(bindIndex, options.Expression.Created.From.Unix())
-			bindIndex++
-		}
-		if !options.Expression.Created.To.IsZero() {
-			stmt.BindInt64(bindIndex, options.Expression.Created.To.Unix())
+		if options.similarIds != "" {
+			stmt.BindText(bindIndex, options.similarIds)
 			bindIndex++
 		}
 
-		for _, filename := range filenames {
-			stmt.BindText(bindIndex, filenameToLikePattern(filename))
-			bindIndex++
-		}
+		bindIndex = cond.bind(stmt, bindIndex)
 
 		for _, prefixId := range prefixIds {
 			stmt.BindInt64(bindIndex, (int64)(prefixId))
@@ -2218,14 +2615,21 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			bindIndex++
 		}
 
-		if options.Limit > 0 && !similarityOrder {
+		if sqlLimit {
 			stmt.BindInt64(bindIndex, (int64)(options.Limit))
 		}
 
 		var lastInfo SourcedInfo
 		var lastEmb []float32
 		var lastEmbInvNorm float32
-		var similarityBuffer []SourcedInfo
+		var sortBuffer []SourcedInfo
+		emitted := 0
+
+		searchColor := options.Expression.Color
+		var searchLab Lab
+		if searchColor.Present {
+			searchLab = LabFromRGB(color.RGBA{R: searchColor.R, G: searchColor.G, B: searchColor.B, A: 0xFF})
+		}
 
 		for {
 			if exists, err := stmt.Step(); err != nil {
@@ -2234,18 +2638,29 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 				break
 			}
 
+			if filterFiles {
+				dir := options.prefixDirs[stmt.ColumnInt64(filterCol)]
+				if !options.Filter.matchFile(dir, stmt.ColumnText(filterCol+1)) {
+					continue
+				}
+			}
+
 			var info SourcedInfo
 			info.Id = (ImageId)(stmt.ColumnInt64(0))
 			if lastInfo.Id != 0 && lastInfo.Id != info.Id {
 				// If we're joining faces, there are multiple rows per image (one per face).
 				// We want to only return the one with the highest face similarity (after filtering).
 				// If we are not joining faces, this is just a slightly weird order of emission.
-				if similarityOrder {
-					similarityBuffer = append(similarityBuffer, lastInfo)
+				if memoryOrder {
+					sortBuffer = append(sortBuffer, lastInfo)
 				} else {
 					out <- lastInfo
+					emitted++
 				}
 				lastInfo = SourcedInfo{}
+				if !memoryOrder && !sqlLimit && options.Limit > 0 && emitted >= options.Limit {
+					break
+				}
 			}
 
 			info.Width = stmt.ColumnInt(1)
@@ -2253,15 +2668,18 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			info.Orientation = Orientation(stmt.ColumnInt(3))
 			info.Color = (uint32)(stmt.ColumnInt64(4))
 
+			if searchColor.Present && isSimilarityOrder(options.OrderBy) {
+				// Closest colors first, unless overridden by embedding
+				// similarity, the distance is filtered by the condition
+				if c := info.GetColor(); c.A != 0 {
+					info.Similarity = float32(1 - searchLab.DeltaE(LabFromRGB(c))/100)
+				}
+			}
+
 			unix := stmt.ColumnInt64(5)
 			timezoneOffset := stmt.ColumnInt(6)
 			info.DateTime = time.Unix(unix, 0).In(time.FixedZone("", timezoneOffset*60))
 
-			// Search post-query expression filtering
-			if !options.Expression.Created.Match(info.DateTime) {
-				continue
-			}
-
 			latlngNull := stmt.ColumnType(7) == sqlite.TypeNull || stmt.ColumnType(8) == sqlite.TypeNull
 			if latlngNull {
 				info.LatLng = NaNLatLng()
@@ -2340,25 +2758,17 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 			lastInfo = info
 		}
 
-		if similarityOrder {
+		if memoryOrder {
 			if lastInfo.Id != 0 {
 				// Add the last info if any
-				similarityBuffer = append(similarityBuffer, lastInfo)
-			}
-			if options.OrderBy == SimilarityAsc {
-				sort.Slice(similarityBuffer, func(i, j int) bool {
-					return similarityBuffer[i].Similarity < similarityBuffer[j].Similarity
-				})
-			} else {
-				sort.Slice(similarityBuffer, func(i, j int) bool {
-					return similarityBuffer[i].Similarity > similarityBuffer[j].Similarity
-				})
+				sortBuffer = append(sortBuffer, lastInfo)
 			}
-			limit := len(similarityBuffer)
+			sortInfos(sortBuffer, options.OrderBy)
+			limit := len(sortBuffer)
 			if options.Limit > 0 && options.Limit < limit {
 				limit = options.Limit
 			}
-			for _, info := range similarityBuffer[:limit] {
+			for _, info := range sortBuffer[:limit] {
 				out <- info
 			}
 		} else if lastInfo.Id != 0 {
@@ -2371,6 +2781,39 @@ func (source *Database) listWithPrefixIds(prefixIds []int64, options ListOptions
 	return out, deps
 }
 
+// bindCamera binds the camera settings to the 5 parameters starting at the
+// given index, unknown settings are bound as NULL.
+func bindCamera(stmt *sqlite.Stmt, bindIndex int, camera *Camera) {
+	if camera == nil {
+		camera = &Camera{}
+	}
+	bindNonZeroInt64(stmt, bindIndex, int64(camera.ISO))
+	bindNonZeroFloat(stmt, bindIndex+1, camera.Aperture)
+	bindNonZeroFloat(stmt, bindIndex+2, camera.ExposureTime)
+	bindNonZeroFloat(stmt, bindIndex+3, camera.FocalLength35)
+	if camera.Lens == "" {
+		stmt.BindNull(bindIndex + 4)
+	} else {
+		stmt.BindText(bindIndex+4, camera.Lens)
+	}
+}
+
+func bindNonZeroInt64(stmt *sqlite.Stmt, bindIndex int, v int64) {
+	if v == 0 {
+		stmt.BindNull(bindIndex)
+		return
+	}
+	stmt.BindInt64(bindIndex, v)
+}
+
+func bindNonZeroFloat(stmt *sqlite.Stmt, bindIndex int, v float64) {
+	if v == 0 {
+		stmt.BindNull(bindIndex)
+		return
+	}
+	stmt.BindFloat(bindIndex, v)
+}
+
 func filenameToLikePattern(filename string) string {
 	var b strings.Builder
 	hasWildcard := false
@@ -2396,11 +2839,57 @@ func filenameToLikePattern(filename string) string {
 }
 
 func (source *Database) List(dirs []string, options ListOptions) (<-chan SourcedInfo, Dependencies) {
+	// Unresolved places fail the listing below
+	if err := source.resolvePlaces(context.TODO(), options.Expression.Condition); err != nil {
+		log.Printf("Unable to resolve places: %s\n", err.Error())
+	}
 
 	dirsDone := metrics.Elapsed("list infos get dirs")
-	prefixIds := source.GetPrefixIds(dirs)
+	var prefixIds []int64
+	if options.Filter.IsZero() {
+		prefixIds = source.GetPrefixIds(dirs)
+	} else {
+		prefixIds, options.prefixDirs = source.getFilteredPrefixIds(dirs, options.Filter)
+	}
 	dirsDone()
 
+	similar := source.newSimilarSearch(dirs, options)
+	if similar != nil {
+		options.similarIds = similar.ids()
+	}
+	if options.similarIds != "" {
+		return source.listSimilar(prefixIds, options, similar)
+	}
+	return source.listPrefixIds(prefixIds, options)
+}
+
+// listSimilar lists the files most similar to the embedding of the options.
+// The search does not know about the rest of the options, so it searches
+// further until enough of the similar files match them.
+func (source *Database) listSimilar(prefixIds []int64, options ListOptions, similar *similarSearch) (<-chan SourcedInfo, Dependencies) {
+	in, deps := source.listPrefixIds(prefixIds, options)
+	out := make(chan SourcedInfo, 1000)
+	go func() {
+		defer close(out)
+		for {
+			var infos []SourcedInfo
+			for info := range in {
+				infos = append(infos, info)
+			}
+			if options.similarIds == "" || len(infos) >= similar.results || !similar.widen() {
+				for _, info := range infos {
+					out <- info
+				}
+				return
+			}
+			options.similarIds = similar.ids()
+			in, _ = source.listPrefixIds(prefixIds, options)
+		}
+	}()
+	return out, deps
+}
+
+func (source *Database) listPrefixIds(prefixIds []int64, options ListOptions) (<-chan SourcedInfo, Dependencies) {
 	// SQLite max compound select limit is 500
 	batchSize := 500
 	concurrent := (len(prefixIds) + batchSize - 1) / batchSize
@@ -2417,6 +2906,7 @@ func (source *Database) List(dirs []string, options ListOptions) (<-chan Sourced
 		Dependency{
 			db:       source,
 			tagNames: tags,
+			metadata: hasMetadataCondition(options.Expression.Condition),
 		},
 	}
 	if concurrent > source.poolSize {
@@ -2436,25 +2926,20 @@ func (source *Database) List(dirs []string, options ListOptions) (<-chan Sourced
 			}
 			opts := options
 			opts.Batch = 1 + i
-			if isSimilarityOrder(options.OrderBy) {
+			if isMemoryOrder(options.OrderBy) {
 				opts.Limit = 0
 			}
 			ch, _ := source.listWithPrefixIds(prefixIds[start:end], opts)
 			channels = append(channels, ch)
 		}
-		if isSimilarityOrder(options.OrderBy) {
+		if isMemoryOrder(options.OrderBy) {
 			var all []SourcedInfo
 			for _, ch := range channels {
 				for info := range ch {
 					all = append(all, info)
 				}
 			}
-			sort.Slice(all, func(i, j int) bool {
-				if options.OrderBy == SimilarityAsc {
-					return all[i].Similarity < all[j].Similarity
-				}
-				return all[i].Similarity > all[j].Similarity
-			})
+			sortInfos(all, options.OrderBy)
 			limit := len(all)
 			if options.Limit > 0 && options.Limit < limit {
 				limit = options.Limit
@@ -2521,8 +3006,8 @@ func (source *Database) ListWithEmbeddings(dirs []string, options ListOptions) <
 			sql += `
 			ORDER BY (id * 2654435761 + ? * 1664525) % 4294967296
 			`
-		case SimilarityDesc, SimilarityAsc:
-			// No SQL ordering needed; caller is responsible for sorting by similarity.
+		case SimilarityDesc, SimilarityAsc, HueAsc, HueDesc, LightnessAsc, LightnessDesc:
+			// No SQL ordering needed; caller is responsible for sorting by similarity or color.
 		default:
 			panic("Unsupported listing order")
 		}
@@ -2655,6 +3140,13 @@ func (source *Database) ListEmbeddings(dirs []string, options ListOptions) <-cha
 			)
 		`
 
+		switch options.OrderBy {
+		case DateAsc:
+			sql += `ORDER BY created_at_unix ASC, id ASC `
+		case DateDesc:
+			sql += `ORDER BY created_at_unix DESC, id DESC `
+		}
+
 		if options.Limit > 0 {
 			sql += `LIMIT ? `
 		}
@@ -2762,16 +3254,39 @@ func (source *Database) ListPaths(dirs []string, limit int) <-chan string {
 }
 
 func (source *Database) GetPrefixIds(dirs []string) []int64 {
-
 	out := make([]int64, 0)
+	source.listPrefixes(dirs, func(id int64, _ string) {
+		out = append(out, id)
+	})
+	return out
+}
+
+// getFilteredPrefixIds returns the ids of the prefixes of the dirs that are
+// not excluded by the filter, and their dirs relative to the dirs.
+func (source *Database) getFilteredPrefixIds(dirs []string, filter PathFilter) ([]int64, map[int64]string) {
+	ids := make([]int64, 0)
+	rels := make(map[int64]string)
+	source.listPrefixes(dirs, func(id int64, str string) {
+		rel, ok := relPath(dirs, str)
+		if !ok || !filter.Match(rel, true) {
+			return
+		}
+		ids = append(ids, id)
+		rels[id] = rel
+	})
+	return ids, rels
+}
 
+// listPrefixes calls fn with the id and path of each prefix in the dirs or
+// their subdirs.
+func (source *Database) listPrefixes(dirs []string, fn func(id int64, str string)) {
 	defer metrics.Elapsed("get prefix ids")()
 
 	conn := source.pool.Get(context.TODO())
 	defer source.pool.Put(conn)
 
 	sql := `
-			SELECT id
+			SELECT id, str
 			FROM prefix
 			WHERE
 		`
@@ -2800,9 +3315,8 @@ func (source *Database) GetPrefixIds(dirs []string) []int64 {
 		} else if !exists {
 			break
 		}
-		out = append(out, stmt.ColumnInt64(0))
+		fn(stmt.ColumnInt64(0), stmt.ColumnText(1))
 	}
-	return out
 }
 
 func (source *Database) ListIdPaths(dirs []string, limit int) <-chan IdPath {
@@ -2871,6 +3385,108 @@ func (source *Database) ListIdPaths(dirs []string, limit int) <-chan IdPath {
 	return out
 }
 
+// ListMissingHashes lists the files in the dirs without a content hash.
+func (source *Database) ListMissingHashes(dirs []string) <-chan IdPath {
+	return source.listIdPathsWhere("list missing hashes", dirs, `
+		hash_partial IS NULL
+	`)
+}
+
+// ListHashCollisions lists the files in the dirs without a full content hash
+// that share their partial hash with any other file.
+func (source *Database) ListHashCollisions(dirs []string) <-chan IdPath {
+	return source.listIdPathsWhere("list hash collisions", dirs, `
+		hash_full IS NULL AND
+		hash_partial IN (
+			SELECT hash_partial
+			FROM infos
+			WHERE hash_partial IS NOT NULL
+			GROUP BY hash_partial
+			HAVING COUNT(*) > 1
+		)
+	`)
+}
+
+func (source *Database) listIdPathsWhere(name string, dirs []string, condition string) <-chan IdPath {
+	out := make(chan IdPath, 1000)
+	go func() {
+		defer metrics.Elapsed(name)()
+
+		conn := source.pool.Get(context.TODO())
+		defer source.pool.Put(conn)
+
+		sql := `
+			SELECT infos.id, str || filename as path
+			FROM infos
+			JOIN prefix ON path_prefix_id == prefix.id
+			WHERE (` + condition + `) AND path_prefix_id IN (
+				SELECT id
+				FROM prefix
+				WHERE
+		`
+
+		for i := range dirs {
+			sql += `str LIKE ? `
+			if i < len(dirs)-1 {
+				sql += "OR "
+			}
+		}
+
+		sql += `
+			);`
+
+		stmt := conn.Prep(sql)
+		defer stmt.Reset()
+
+		for i, dir := range dirs {
+			stmt.BindText(i+1, dir+"%")
+		}
+
+		for {
+			if exists, err := stmt.Step(); err != nil {
+				log.Printf("Error listing files: %s\n", err.Error())
+				break
+			} else if !exists {
+				break
+			}
+			out <- IdPath{
+				Id:   ImageId(stmt.ColumnInt64(0)),
+				Path: stmt.ColumnText(1),
+			}
+		}
+
+		close(out)
+	}()
+	return out
+}
+
+func (source *Database) GetHash(id ImageId) (ContentHash, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT hash_partial, hash_full
+	FROM infos
+	WHERE id = ? AND hash_partial IS NOT NULL;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, int64(id))
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return ContentHash{}, false
+	}
+
+	hash := ContentHash{
+		Partial: stmt.ColumnInt64(0),
+	}
+	if stmt.ColumnType(1) != sqlite.TypeNull {
+		hash.Full = make([]byte, stmt.ColumnLen(1))
+		stmt.ColumnBytes(1, hash.Full)
+	}
+	return hash, true
+}
+
 func (source *Database) ListIds(dirs []string, limit int, missingEmbedding bool) <-chan ImageId {
 	out := make(chan ImageId, 10000)
 	go func() {
@@ -3117,13 +3733,13 @@ func (source *Database) ListMissing(dirs []string, limit int, opts Missing) <-ch
 }
 
 type FaceInfo struct {
-	Id         int
-	FileId     ImageId
-	X          int
-	Y          int
-	W          int
-	H          int
-	Confidence int
+	Id         int     `json:"id"`
+	FileId     ImageId `json:"file_id"`
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	W          int     `json:"w"`
+	H          int     `json:"h"`
+	Confidence int     `json:"confidence"`
 }
 
 // readFaceEmbedding reads the face embedding from the statement at the given column index.
@@ -0,0 +1,198 @@
+package image
+
+import (
+	"context"
+	"log"
+
+	"photofield/internal/metrics"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Duplicate is the membership of a file in a group of duplicates. The group
+// id is the id of the first file of the group, which is the one to keep.
+// Exact duplicates have the same contents as another file in the group,
+// the others are only similar.
+type Duplicate struct {
+	Id      ImageId `json:"id"`
+	GroupId ImageId `json:"group_id"`
+	Exact   bool    `json:"exact"`
+}
+
+// ListExactDuplicates lists the groups of files in the dirs with the same
+// full content hash, ordered by id within each group.
+func (source *Database) ListExactDuplicates(dirs []string) [][]ImageId {
+	defer metrics.Elapsed("list exact duplicates")()
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := `
+		SELECT id, hash_full
+		FROM infos
+		WHERE hash_full IS NOT NULL AND path_prefix_id IN (
+			SELECT id
+			FROM prefix
+			WHERE
+	`
+
+	for i := range dirs {
+		sql += `str LIKE ? `
+		if i < len(dirs)-1 {
+			sql += "OR "
+		}
+	}
+
+	sql += `
+		)
+		ORDER BY hash_full, id;`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	for i, dir := range dirs {
+		stmt.BindText(i+1, dir+"%")
+	}
+
+	groups := make([][]ImageId, 0)
+	var group []ImageId
+	var last []byte
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing exact duplicates: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		id := ImageId(stmt.ColumnInt64(0))
+		hash := make([]byte, stmt.ColumnLen(1))
+		stmt.ColumnBytes(1, hash)
+		if string(hash) != string(last) {
+			if len(group) > 1 {
+				groups = append(groups, group)
+			}
+			group = nil
+			last = hash
+		}
+		group = append(group, id)
+	}
+	if len(group) > 1 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// WriteDuplicates replaces the duplicates of the files in the dirs.
+func (source *Database) WriteDuplicates(dirs []string, duplicates []Duplicate) error {
+	return source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT write_duplicates;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO write_duplicates;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE write_duplicates;", nil)
+		}()
+
+		sql := `
+			DELETE FROM duplicate
+			WHERE file_id IN (
+				SELECT id
+				FROM infos
+				WHERE path_prefix_id IN (
+					SELECT id
+					FROM prefix
+					WHERE
+		`
+		for i := range dirs {
+			sql += `str LIKE ? `
+			if i < len(dirs)-1 {
+				sql += "OR "
+			}
+		}
+		sql += `
+				)
+			);`
+
+		remove := conn.Prep(sql)
+		defer remove.Reset()
+		for i, dir := range dirs {
+			remove.BindText(i+1, dir+"%")
+		}
+		if _, err := remove.Step(); err != nil {
+			return err
+		}
+
+		insert := conn.Prep(`
+		INSERT OR REPLACE INTO duplicate(file_id, group_id, exact)
+		VALUES (?, ?, ?);`)
+		defer insert.Reset()
+
+		for _, d := range duplicates {
+			insert.BindInt64(1, int64(d.Id))
+			insert.BindInt64(2, int64(d.GroupId))
+			insert.BindBool(3, d.Exact)
+			if _, err := insert.Step(); err != nil {
+				return err
+			}
+			if err := insert.Reset(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListDuplicates lists the duplicates of the files in the dirs by file id.
+func (source *Database) ListDuplicates(dirs []string) map[ImageId]Duplicate {
+	defer metrics.Elapsed("list duplicates")()
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := `
+		SELECT file_id, group_id, exact
+		FROM duplicate
+		JOIN infos ON infos.id == file_id
+		WHERE path_prefix_id IN (
+			SELECT id
+			FROM prefix
+			WHERE
+	`
+
+	for i := range dirs {
+		sql += `str LIKE ? `
+		if i < len(dirs)-1 {
+			sql += "OR "
+		}
+	}
+
+	sql += `
+		);`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	for i, dir := range dirs {
+		stmt.BindText(i+1, dir+"%")
+	}
+
+	duplicates := make(map[ImageId]Duplicate)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing duplicates: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		d := Duplicate{
+			Id:      ImageId(stmt.ColumnInt64(0)),
+			GroupId: ImageId(stmt.ColumnInt64(1)),
+			Exact:   stmt.ColumnBool(2),
+		}
+		duplicates[d.Id] = d
+	}
+	return duplicates
+}
@@ -0,0 +1,341 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"photofield/internal/ai"
+	"photofield/internal/ann"
+	"photofield/internal/metrics"
+	"photofield/internal/search"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// EmbeddingIndexConfig configures the approximate nearest neighbour indexes
+// of the AI embeddings used to search large collections by similarity.
+type EmbeddingIndexConfig struct {
+	Enable bool `json:"enable"`
+	// Collections with fewer files are searched by comparing all embeddings
+	MinFiles int `json:"min_files"`
+	// Number of most similar files listed when sorting by similarity
+	Results int `json:"results"`
+}
+
+// embeddingIndex is an approximate nearest neighbour index of either the
+// image or the face embeddings, kept in memory and persisted to a file next
+// to the database.
+type embeddingIndex struct {
+	name    string
+	path    string
+	count   string
+	list    string
+	read    func(stmt *sqlite.Stmt) (ai.Embedding, error)
+	index   atomic.Pointer[ann.Index]
+	ready   atomic.Bool
+	changed atomic.Bool
+}
+
+// Index parameters, a good balance of accuracy, memory and indexing time for
+// CLIP and face embeddings
+const (
+	embeddingIndexLinks      = 16
+	embeddingIndexCandidates = 100
+)
+
+func newClipIndex(dbPath string) *embeddingIndex {
+	return &embeddingIndex{
+		name:  "clip",
+		path:  strings.TrimSuffix(dbPath, ".db") + ".clip.hnsw",
+		count: `SELECT COUNT(*) FROM clip_emb;`,
+		list:  `SELECT file_id, inv_norm, embedding FROM clip_emb;`,
+		read: func(stmt *sqlite.Stmt) (ai.Embedding, error) {
+			return readEmbedding(stmt, 1, 2)
+		},
+	}
+}
+
+func newFaceIndex(dbPath string) *embeddingIndex {
+	return &embeddingIndex{
+		name:  "face",
+		path:  strings.TrimSuffix(dbPath, ".db") + ".face.hnsw",
+		count: `SELECT COUNT(*) FROM face;`,
+		list:  `SELECT id, embedding FROM face;`,
+		read: func(stmt *sqlite.Stmt) (ai.Embedding, error) {
+			return readFaceEmbedding(stmt, 1)
+		},
+	}
+}
+
+// get returns the index once it is loaded or built, nil otherwise.
+func (e *embeddingIndex) get() *ann.Index {
+	if e == nil || !e.ready.Load() {
+		return nil
+	}
+	return e.index.Load()
+}
+
+func (e *embeddingIndex) add(id int64, emb ai.Embedding) {
+	if e == nil {
+		return
+	}
+	index := e.index.Load()
+	if index == nil {
+		return
+	}
+	if err := index.Add(id, emb.Float32()); err != nil {
+		log.Printf("embedding index %s unable to add %d: %s\n", e.name, id, err.Error())
+		return
+	}
+	e.changed.Store(true)
+}
+
+func (e *embeddingIndex) remove(id int64) {
+	if e == nil {
+		return
+	}
+	index := e.index.Load()
+	if index == nil {
+		return
+	}
+	index.Remove(id)
+	e.changed.Store(true)
+}
+
+func (e *embeddingIndex) save() {
+	index := e.index.Load()
+	if !e.ready.Load() || index == nil || !e.changed.Swap(false) {
+		return
+	}
+	defer metrics.Elapsed("embedding index save " + e.name)()
+	if err := index.Save(e.path); err != nil {
+		log.Printf("embedding index %s unable to save: %s\n", e.name, err.Error())
+	}
+}
+
+// load loads the saved index, or builds it from the database if it is
+// missing or out of date, e.g. after a crash.
+func (e *embeddingIndex) load(source *Database) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	count := 0
+	stmt := conn.Prep(e.count)
+	if exists, err := stmt.Step(); err != nil || !exists {
+		log.Printf("embedding index %s unable to count embeddings: %v\n", e.name, err)
+		stmt.Reset()
+		return
+	}
+	count = stmt.ColumnInt(0)
+	stmt.Reset()
+
+	index, err := ann.Load(e.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Printf("embedding index %s not found, building\n", e.name)
+	case err != nil:
+		log.Printf("embedding index %s unable to load, rebuilding: %s\n", e.name, err.Error())
+	case index.Len() != count:
+		log.Printf("embedding index %s has %d of %d embeddings, rebuilding\n", e.name, index.Len(), count)
+	case index.Deleted() > index.Len()/4:
+		log.Printf("embedding index %s has %d removed embeddings, rebuilding\n", e.name, index.Deleted())
+	default:
+		e.index.Store(index)
+		e.ready.Store(true)
+		log.Printf("embedding index %s loaded %d embeddings\n", e.name, index.Len())
+		return
+	}
+
+	// Embeddings written while building are added as they are written
+	index = ann.New(embeddingIndexLinks, embeddingIndexCandidates)
+	e.index.Store(index)
+
+	done := metrics.Elapsed("embedding index build " + e.name)
+	stmt = conn.Prep(e.list)
+	defer stmt.Reset()
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("embedding index %s unable to list embeddings: %s\n", e.name, err.Error())
+			return
+		} else if !exists {
+			break
+		}
+		id := stmt.ColumnInt64(0)
+		if index.Has(id) {
+			continue
+		}
+		emb, err := e.read(stmt)
+		if err != nil {
+			continue
+		}
+		if err := index.Add(id, emb.Float32()); err != nil {
+			log.Printf("embedding index %s unable to add %d: %s\n", e.name, id, err.Error())
+		}
+	}
+	done()
+
+	e.ready.Store(true)
+	e.changed.Store(true)
+	e.save()
+}
+
+// EnableEmbeddingIndexes enables the approximate nearest neighbour indexes
+// of the image and face embeddings. Until they are loaded, similarity search
+// compares all embeddings.
+func (source *Database) EnableEmbeddingIndexes(config EmbeddingIndexConfig) {
+	if !config.Enable {
+		return
+	}
+	source.indexConfig = config
+	source.clipIndex = newClipIndex(source.path)
+	source.faceIndex = newFaceIndex(source.path)
+}
+
+// LoadEmbeddingIndexes loads the enabled embedding indexes, building them
+// from the database if needed, which can take a while for large databases.
+func (source *Database) LoadEmbeddingIndexes() {
+	if source.clipIndex == nil || source.faceIndex == nil {
+		return
+	}
+	source.clipIndex.load(source)
+	source.faceIndex.load(source)
+}
+
+// similarSearch looks up the files or faces most similar to an embedding in
+// an embedding index, looking further each time more are needed.
+type similarSearch struct {
+	name      string
+	index     *ann.Index
+	vec       []float32
+	threshold search.Float32
+	total     int
+	k         int
+	// Number of most similar files listed
+	results int
+}
+
+// newSimilarSearch returns the search of the files or faces most similar to
+// the embedding of the options, or nil if the embeddings should be compared
+// exactly, e.g. for small collections or while the indexes are built.
+func (source *Database) newSimilarSearch(dirs []string, options ListOptions) *similarSearch {
+	var e *embeddingIndex
+	var emb ai.Embedding
+	switch {
+	case options.ImageEmbedding != nil:
+		e = source.clipIndex
+		emb = options.ImageEmbedding
+	case options.FaceEmbedding != nil:
+		e = source.faceIndex
+		emb = options.FaceEmbedding
+	default:
+		return nil
+	}
+	index := e.get()
+	if index == nil {
+		return nil
+	}
+	threshold := options.Expression.Threshold
+	if !isSimilarityOrder(options.OrderBy) && !threshold.Present {
+		return nil
+	}
+
+	count, ok := source.GetDirsCount(dirs)
+	if !ok || count == 0 || count < source.indexConfig.MinFiles {
+		return nil
+	}
+	total := index.Len()
+
+	results := source.indexConfig.Results
+	if options.Limit > 0 {
+		results = options.Limit
+	}
+	if results <= 0 {
+		results = 1000
+	}
+	// The index covers all dirs, so look further for smaller collections
+	k := results
+	if total > count {
+		k = int(int64(results) * int64(total) / int64(count))
+	}
+	return &similarSearch{
+		name:      e.name,
+		index:     index,
+		vec:       emb.Float32(),
+		threshold: threshold,
+		total:     total,
+		k:         k,
+		results:   results,
+	}
+}
+
+// ids returns the ids of the most similar files or faces as a JSON array,
+// or an empty string if comparing all of them is about as fast.
+func (s *similarSearch) ids() string {
+	defer metrics.Elapsed("embedding index search " + s.name)()
+	for {
+		if s.k > s.total/2 {
+			// Comparing all is about as fast
+			return ""
+		}
+		found, err := s.index.Search(s.vec, s.k, s.k)
+		if err != nil {
+			log.Printf("embedding index %s search failed: %s\n", s.name, err.Error())
+			return ""
+		}
+		// Similarities are approximate, so look a bit below the threshold
+		if s.threshold.Present && len(found) == s.k && found[len(found)-1].Similarity >= s.threshold.Value-0.02 {
+			s.k *= 2
+			continue
+		}
+		ids := make([]int64, len(found))
+		for i, r := range found {
+			ids[i] = r.Id
+		}
+		b, err := json.Marshal(ids)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// widen looks further on the next search, returning false if all above the
+// threshold were already found.
+func (s *similarSearch) widen() bool {
+	if s.threshold.Present {
+		return false
+	}
+	s.k *= 2
+	return true
+}
+
+// SimilarFile is a file found by the similarity of its image embedding.
+type SimilarFile struct {
+	Id         ImageId
+	Similarity float32
+}
+
+// ListSimilarFiles returns up to k files of all dirs most similar to the
+// embedding with their approximate similarity, or false if the image
+// embedding index is not enabled or loaded yet.
+func (source *Database) ListSimilarFiles(emb ai.Embedding, k int) ([]SimilarFile, bool) {
+	index := source.clipIndex.get()
+	if index == nil {
+		return nil, false
+	}
+	found, err := index.Search(emb.Float32(), k, max(k, embeddingIndexCandidates))
+	if err != nil {
+		log.Printf("embedding index %s search failed: %s\n", source.clipIndex.name, err.Error())
+		return nil, false
+	}
+	files := make([]SimilarFile, len(found))
+	for i, r := range found {
+		files[i] = SimilarFile{Id: ImageId(r.Id), Similarity: r.Similarity}
+	}
+	return files, true
+}
@@ -45,6 +45,14 @@ func NewExifToolMostlyGeekLoader(exifToolCount int, exifToolPath string) (*ExifT
 		"-Rotation#",
 		"-ImageWidth#",
 		"-ImageHeight#",
+		// Camera
+		"-ISO#",
+		"-FNumber#",
+		"-ExposureTime#",
+		"-FocalLength35efl#",
+		"-LensModel",
+		"-LensID",
+		"-Rating#",
 	)
 	decoder.flags = append(decoder.flags, tag.ExifFlags...)
 	decoder.flags = append(decoder.flags,
@@ -85,6 +93,8 @@ func (decoder *ExifToolMostlyGeekLoader) DecodeInfo(path string, info *Info) ([]
 	imageHeight := ""
 	latitude := ""
 	longitude := ""
+	camera := Camera{}
+	lensID := ""
 
 	// var gpsTime time.Time
 
@@ -112,6 +122,21 @@ func (decoder *ExifToolMostlyGeekLoader) DecodeInfo(path string, info *Info) ([]
 			latitude = value
 		case "GPSLongitude":
 			longitude = value
+		case "ISO":
+			camera.ISO, _ = strconv.Atoi(value)
+		case "FNumber":
+			camera.Aperture, _ = strconv.ParseFloat(value, 64)
+		case "ExposureTime":
+			camera.ExposureTime, _ = strconv.ParseFloat(value, 64)
+		case "FocalLength35efl":
+			camera.FocalLength35, _ = strconv.ParseFloat(value, 64)
+		case "LensModel":
+			camera.Lens = value
+		case "LensID":
+			lensID = value
+		case "Rating":
+			rating, _ := strconv.ParseFloat(value, 64)
+			info.Rating = ClampRating(int(rating))
 		default:
 			if name, ok := tag.ExifTagToName[name]; ok {
 				tags = append(tags, tag.NewExif(name, value))
@@ -198,6 +223,13 @@ func (decoder *ExifToolMostlyGeekLoader) DecodeInfo(path string, info *Info) ([]
 		info.Width, info.Height = info.Height, info.Width
 	}
 
+	if camera.Lens == "" {
+		camera.Lens = lensID
+	}
+	if camera != (Camera{}) {
+		info.Camera = &camera
+	}
+
 	// println(path, info.Width, info.Height, info.DateTime.String())
 
 	return tags, nil
@@ -0,0 +1,165 @@
+package image
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PathFilter limits the files of the dirs of a collection by their paths
+// relative to the dirs.
+type PathFilter struct {
+	// Include are glob patterns of which at least one needs to match the
+	// file, all files are included if empty
+	Include []string
+	// Exclude are glob patterns of the files and dirs to leave out
+	Exclude []string
+	// SkipHidden leaves out files and dirs starting with a dot
+	SkipHidden bool
+	// MaxDepth is the max number of path elements of the relative paths of
+	// the files, e.g. 1 for only the files directly in the dirs, 0 for any
+	MaxDepth int
+}
+
+// DefaultExclude are the patterns of the files and dirs left out of the
+// collections without exclude patterns of their own, e.g. the thumbnails
+// Synology NAS keep next to the photos.
+var DefaultExclude = []string{"@eaDir"}
+
+// IsZero returns true if the filter includes all files.
+func (f PathFilter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && !f.SkipHidden && f.MaxDepth == 0
+}
+
+// Equal returns true if both filters include the same files.
+func (f PathFilter) Equal(other PathFilter) bool {
+	return slices.Equal(f.Include, other.Include) &&
+		slices.Equal(f.Exclude, other.Exclude) &&
+		f.SkipHidden == other.SkipHidden &&
+		f.MaxDepth == other.MaxDepth
+}
+
+// Match returns true if the file, or the dir if dir is true, at the
+// slash-separated path relative to the collection dir passes the filter.
+// Dirs are only checked for exclusion, as their files may still be included.
+//
+// Patterns without a slash match any element of the path, e.g. "@eaDir" or
+// "*.lrprev". Patterns with a slash match the path from the start, with "**"
+// matching any number of elements, e.g. "exports/**/*.jpg". A pattern
+// matching a dir also matches everything in it.
+func (f PathFilter) Match(rel string, dir bool) bool {
+	if rel == "" {
+		return true
+	}
+	parts := strings.Split(rel, "/")
+	if f.MaxDepth > 0 {
+		if dir && len(parts) >= f.MaxDepth {
+			return false
+		}
+		if len(parts) > f.MaxDepth {
+			return false
+		}
+	}
+	if f.SkipHidden {
+		for _, p := range parts {
+			if strings.HasPrefix(p, ".") {
+				return false
+			}
+		}
+	}
+	for _, pattern := range f.Exclude {
+		if matchGlob(pattern, parts) {
+			return false
+		}
+	}
+	if dir || len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if matchGlob(pattern, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// filtersFiles returns true if the filter can exclude files of dirs that
+// are not excluded themselves.
+func (f PathFilter) filtersFiles() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0 || f.SkipHidden
+}
+
+// matchFile returns true if the file with the name in the relative dir
+// passes the filter.
+func (f PathFilter) matchFile(dir string, name string) bool {
+	return f.Match(path.Join(dir, name), false)
+}
+
+// MatchPath returns true if the file or dir at the absolute path passes the
+// filter relative to the most specific of the dirs containing it. Paths
+// outside of the dirs do not pass.
+func (f PathFilter) MatchPath(dirs []string, p string, dir bool) bool {
+	rel, ok := relPath(dirs, p)
+	if !ok {
+		return false
+	}
+	return f.Match(rel, dir)
+}
+
+// relPath returns the slash-separated path relative to the most specific of
+// the dirs containing it.
+func relPath(dirs []string, p string) (string, bool) {
+	// Dirs end with a separator, unlike the paths of the dirs themselves
+	p += string(filepath.Separator)
+	root := ""
+	found := false
+	for _, dir := range dirs {
+		if strings.HasPrefix(p, dir) && len(dir) >= len(root) {
+			root = dir
+			found = true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	rel := strings.TrimPrefix(p, root)
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	return rel, true
+}
+
+func matchGlob(pattern string, parts []string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		for _, p := range parts {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(pattern, "/"), parts)
+}
+
+// matchSegments returns true if the pattern segments match the leading
+// parts of the path.
+func matchSegments(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
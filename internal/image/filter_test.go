@@ -0,0 +1,43 @@
+package image
+
+import "testing"
+
+func TestPathFilterMatch(t *testing.T) {
+	testCases := []struct {
+		name   string
+		filter PathFilter
+		rel    string
+		dir    bool
+		want   bool
+	}{
+		{"empty includes all", PathFilter{}, "a/b/c.jpg", false, true},
+		{"root dir always matches", PathFilter{MaxDepth: 1}, "", true, true},
+		{"name pattern excludes file", PathFilter{Exclude: []string{"*.lrprev"}}, "a/b.lrprev", false, false},
+		{"name pattern excludes nested dir", PathFilter{Exclude: []string{"@eaDir"}}, "a/@eaDir", true, false},
+		{"name pattern excludes files in dir", PathFilter{Exclude: []string{".trash"}}, "a/.trash/b.jpg", false, false},
+		{"path pattern excludes dir from start", PathFilter{Exclude: []string{"exports/**"}}, "exports/x/a.jpg", false, false},
+		{"path pattern does not match elsewhere", PathFilter{Exclude: []string{"exports/**"}}, "a/exports/b.jpg", false, true},
+		{"path prefix excludes dir contents", PathFilter{Exclude: []string{"2020/raw"}}, "2020/raw/a.jpg", false, false},
+		{"double star matches nested", PathFilter{Exclude: []string{"**/edits/*.jpg"}}, "a/b/edits/c.jpg", false, false},
+		{"double star matches zero dirs", PathFilter{Exclude: []string{"**/edits/*.jpg"}}, "edits/c.jpg", false, false},
+		{"include matches", PathFilter{Include: []string{"*.jpg"}}, "a/b.jpg", false, true},
+		{"include does not match", PathFilter{Include: []string{"*.jpg"}}, "a/b.png", false, false},
+		{"include does not prune dirs", PathFilter{Include: []string{"*.jpg"}}, "a", true, true},
+		{"exclude wins over include", PathFilter{Include: []string{"*.jpg"}, Exclude: []string{"b.jpg"}}, "a/b.jpg", false, false},
+		{"hidden file", PathFilter{SkipHidden: true}, "a/.b.jpg", false, false},
+		{"hidden dir", PathFilter{SkipHidden: true}, ".cache", true, false},
+		{"visible file", PathFilter{SkipHidden: true}, "a/b.jpg", false, true},
+		{"max depth file in dir", PathFilter{MaxDepth: 1}, "a.jpg", false, true},
+		{"max depth file in subdir", PathFilter{MaxDepth: 1}, "a/b.jpg", false, false},
+		{"max depth prunes dir", PathFilter{MaxDepth: 1}, "a", true, false},
+		{"max depth keeps dir", PathFilter{MaxDepth: 2}, "a", true, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.filter.Match(tc.rel, tc.dir)
+			if got != tc.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tc.rel, tc.dir, got, tc.want)
+			}
+		})
+	}
+}
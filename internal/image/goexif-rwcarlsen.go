@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"photofield/internal/tag"
+	"strings"
 
 	"github.com/rwcarlsen/goexif/exif"
 )
@@ -29,6 +30,39 @@ func getOrientationFromExif(x *exif.Exif) string {
 	return "1"
 }
 
+func getCameraFromExif(x *exif.Exif) *Camera {
+	camera := Camera{}
+	if tag, err := x.Get(exif.ISOSpeedRatings); err == nil {
+		camera.ISO, _ = tag.Int(0)
+	}
+	camera.Aperture = getExifRat(x, exif.FNumber)
+	camera.ExposureTime = getExifRat(x, exif.ExposureTime)
+	if tag, err := x.Get(exif.FocalLengthIn35mmFilm); err == nil {
+		focal, _ := tag.Int(0)
+		camera.FocalLength35 = float64(focal)
+	}
+	if tag, err := x.Get(exif.LensModel); err == nil {
+		camera.Lens, _ = tag.StringVal()
+		camera.Lens = strings.TrimSpace(strings.TrimRight(camera.Lens, "\x00"))
+	}
+	if camera == (Camera{}) {
+		return nil
+	}
+	return &camera
+}
+
+func getExifRat(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
 func (decoder *GoExifRwcarlsenLoader) DecodeInfo(path string, info *Info) ([]tag.Tag, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -42,6 +76,7 @@ func (decoder *GoExifRwcarlsenLoader) DecodeInfoReader(r io.ReadSeeker, info *In
 	x, err := exif.Decode(r)
 	if err == nil {
 		info.DateTime, _ = x.DateTime()
+		info.Camera = getCameraFromExif(x)
 	}
 
 	orientation := parseOrientation(getOrientationFromExif(x))
@@ -19,6 +19,18 @@ type Info struct {
 	Color         uint32
 	Orientation   Orientation
 	LatLng        s2.LatLng
+	Camera        *Camera // Only set when decoding, nil when listed
+	Rating        int     // Only set when decoding, 0 if unrated
+}
+
+// Camera contains the camera and exposure settings a photo was taken with,
+// zero values are unknown.
+type Camera struct {
+	ISO           int
+	Aperture      float64 // f-number
+	ExposureTime  float64 // seconds
+	FocalLength35 float64 // 35mm equivalent in millimeters
+	Lens          string
 }
 
 const earthRadiusKm = 6371.01
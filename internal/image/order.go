@@ -0,0 +1,76 @@
+package image
+
+import "sort"
+
+// grayChroma is the chroma below which colors are considered gray for
+// sorting by hue, as their hue is not perceptible.
+const grayChroma = 10
+
+func isColorOrder(order ListOrder) bool {
+	switch order {
+	case HueAsc, HueDesc, LightnessAsc, LightnessDesc:
+		return true
+	default:
+		return false
+	}
+}
+
+// isMemoryOrder returns true if the order is not supported by SQL, so all
+// the listed infos need to be sorted in memory.
+func isMemoryOrder(order ListOrder) bool {
+	return isSimilarityOrder(order) || isColorOrder(order)
+}
+
+// colorOrderKey returns the ascending sort key of the info color. Gray colors
+// are sorted by lightness after all the hues.
+func colorOrderKey(info *Info, order ListOrder) float64 {
+	lab := LabFromRGB(info.GetColor())
+	switch order {
+	case HueAsc, HueDesc:
+		if lab.Chroma() < grayChroma {
+			return 360 + lab.L
+		}
+		return lab.Hue()
+	default:
+		return lab.L
+	}
+}
+
+// sortInfos sorts the infos in place by an in-memory order.
+func sortInfos(infos []SourcedInfo, order ListOrder) {
+	switch order {
+	case SimilarityAsc:
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Similarity < infos[j].Similarity
+		})
+	case SimilarityDesc:
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Similarity > infos[j].Similarity
+		})
+	case HueAsc, HueDesc, LightnessAsc, LightnessDesc:
+		keys := make([]float64, len(infos))
+		for i := range infos {
+			keys[i] = colorOrderKey(&infos[i].Info, order)
+		}
+		desc := order == HueDesc || order == LightnessDesc
+		sort.Stable(colorSorter{infos: infos, keys: keys, desc: desc})
+	}
+}
+
+type colorSorter struct {
+	infos []SourcedInfo
+	keys  []float64
+	desc  bool
+}
+
+func (s colorSorter) Len() int { return len(s.infos) }
+func (s colorSorter) Less(i, j int) bool {
+	if s.desc {
+		return s.keys[i] > s.keys[j]
+	}
+	return s.keys[i] < s.keys[j]
+}
+func (s colorSorter) Swap(i, j int) {
+	s.infos[i], s.infos[j] = s.infos[j], s.infos[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
@@ -0,0 +1,522 @@
+package image
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"photofield/internal/ai"
+	"photofield/internal/metrics"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Person is a group of faces of the same person, found by clustering the
+// face embeddings. Persons are unnamed until named by the user, hidden ones
+// are not listed by default.
+type Person struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Hidden      bool      `json:"hidden"`
+	FaceCount   int       `json:"face_count"`
+	CoverFaceId int64     `json:"cover_face_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FaceEmbedding is the embedding of a face with the person it is assigned
+// to, 0 if it is not assigned to any.
+type FaceEmbedding struct {
+	Id        int64
+	PersonId  int64
+	Embedding []float32
+}
+
+// assignedFace is the box of a face assigned to a person.
+type assignedFace struct {
+	X, Y, W, H int
+	PersonId   int64
+}
+
+// faceMatchOverlap is the minimum intersection over union of the boxes of
+// an existing and a redetected face for them to be considered the same.
+const faceMatchOverlap = 0.5
+
+// faceOverlap returns the intersection over union of the boxes.
+func faceOverlap(x1, y1, w1, h1, x2, y2, w2, h2 int) float64 {
+	w := min(x1+w1, x2+w2) - max(x1, x2)
+	h := min(y1+h1, y2+h2) - max(y1, y2)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	intersection := float64(w * h)
+	union := float64(w1*h1+w2*h2) - intersection
+	if union <= 0 {
+		return 0
+	}
+	return intersection / union
+}
+
+// matchFacePersons returns the persons of the redetected faces, matching
+// each assigned face to the redetected face overlapping it the most, so that
+// detecting the faces again keeps them assigned. Unmatched faces get 0.
+func matchFacePersons(assigned []assignedFace, faces []ai.Face) []int64 {
+	personIds := make([]int64, len(faces))
+	taken := make([]bool, len(faces))
+	for _, a := range assigned {
+		best := -1
+		bestOverlap := 0.
+		for i, f := range faces {
+			if taken[i] {
+				continue
+			}
+			overlap := faceOverlap(a.X, a.Y, a.W, a.H, f.X, f.Y, f.W, f.H)
+			if overlap >= faceMatchOverlap && overlap > bestOverlap {
+				best = i
+				bestOverlap = overlap
+			}
+		}
+		if best >= 0 {
+			personIds[best] = a.PersonId
+			taken[best] = true
+		}
+	}
+	return personIds
+}
+
+const personSelectSQL = `
+	SELECT
+		person.id,
+		coalesce(person.name, ''),
+		person.hidden,
+		(SELECT COUNT(*) FROM face WHERE face.person_id = person.id),
+		(SELECT id FROM face WHERE face.person_id = person.id ORDER BY confidence DESC, id ASC LIMIT 1),
+		person.created_at_ms,
+		person.updated_at_ms
+	FROM person
+`
+
+func readPerson(stmt *sqlite.Stmt) Person {
+	return Person{
+		Id:          stmt.ColumnInt64(0),
+		Name:        stmt.ColumnText(1),
+		Hidden:      stmt.ColumnBool(2),
+		FaceCount:   stmt.ColumnInt(3),
+		CoverFaceId: stmt.ColumnInt64(4),
+		CreatedAt:   fromUnixMs(stmt.ColumnInt64(5)),
+		UpdatedAt:   fromUnixMs(stmt.ColumnInt64(6)),
+	}
+}
+
+func bindPersonName(stmt *sqlite.Stmt, param int, name string) {
+	if name == "" {
+		stmt.BindNull(param)
+	} else {
+		stmt.BindText(param, name)
+	}
+}
+
+// ListPersons lists the persons with named ones first, followed by the
+// ones with the most faces.
+func (source *Database) ListPersons(hidden bool) []Person {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := personSelectSQL
+	if !hidden {
+		sql += `WHERE NOT hidden `
+	}
+	sql += `ORDER BY person.name IS NULL, person.name COLLATE NOCASE, 4 DESC, person.id;`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	persons := make([]Person, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing persons: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		persons = append(persons, readPerson(stmt))
+	}
+	return persons
+}
+
+func (source *Database) GetPerson(id int64) (Person, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(personSelectSQL + `WHERE person.id = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return Person{}, false
+	}
+	return readPerson(stmt), true
+}
+
+// UpdatePerson updates the name and visibility of the person.
+func (source *Database) UpdatePerson(p Person) (Person, error) {
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE person
+		SET name = ?, hidden = ?, updated_at_ms = ?
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		bindPersonName(stmt, 1, p.Name)
+		stmt.BindBool(2, p.Hidden)
+		stmt.BindInt64(3, toUnixMs(time.Now()))
+		stmt.BindInt64(4, p.Id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return p, err
+	}
+	p, _ = source.GetPerson(p.Id)
+	return p, nil
+}
+
+// MergePersons moves the faces of the other persons to the person with the
+// id and removes the others. The person takes the name of the first named
+// other person if it has none.
+func (source *Database) MergePersons(id int64, others []int64) (Person, error) {
+	err := source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT merge_persons;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO merge_persons;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE merge_persons;", nil)
+		}()
+
+		rename := conn.Prep(`
+		UPDATE person
+		SET name = coalesce(name, (SELECT name FROM person WHERE id = ?)), updated_at_ms = ?
+		WHERE id = ?;`)
+		defer rename.Reset()
+		move := conn.Prep(`UPDATE face SET person_id = ? WHERE person_id = ?;`)
+		defer move.Reset()
+		remove := conn.Prep(`DELETE FROM person WHERE id = ?;`)
+		defer remove.Reset()
+
+		now := toUnixMs(time.Now())
+		for _, other := range others {
+			if other == id {
+				continue
+			}
+			rename.BindInt64(1, other)
+			rename.BindInt64(2, now)
+			rename.BindInt64(3, id)
+			if _, err := rename.Step(); err != nil {
+				return err
+			}
+			if conn.Changes() == 0 {
+				return ErrNotFound
+			}
+			if err := rename.Reset(); err != nil {
+				return err
+			}
+
+			move.BindInt64(1, id)
+			move.BindInt64(2, other)
+			if _, err := move.Step(); err != nil {
+				return err
+			}
+			if err := move.Reset(); err != nil {
+				return err
+			}
+
+			remove.BindInt64(1, other)
+			if _, err := remove.Step(); err != nil {
+				return err
+			}
+			if conn.Changes() == 0 {
+				return ErrNotFound
+			}
+			if err := remove.Reset(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Person{}, err
+	}
+	p, ok := source.GetPerson(id)
+	if !ok {
+		return p, ErrNotFound
+	}
+	return p, nil
+}
+
+// SplitPerson moves the faces of the person with the id to a new unnamed
+// person and returns it. Faces of other persons are ignored.
+func (source *Database) SplitPerson(id int64, faceIds []int64) (Person, error) {
+	var newId int64
+	err := source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT split_person;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO split_person;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE split_person;", nil)
+		}()
+
+		if newId, err = insertPerson(conn); err != nil {
+			return err
+		}
+
+		move := conn.Prep(`UPDATE face SET person_id = ? WHERE id = ? AND person_id = ?;`)
+		defer move.Reset()
+
+		moved := 0
+		for _, faceId := range faceIds {
+			move.BindInt64(1, newId)
+			move.BindInt64(2, faceId)
+			move.BindInt64(3, id)
+			if _, err := move.Step(); err != nil {
+				return err
+			}
+			moved += conn.Changes()
+			if err := move.Reset(); err != nil {
+				return err
+			}
+		}
+		if moved == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return Person{}, err
+	}
+	p, _ := source.GetPerson(newId)
+	return p, nil
+}
+
+func insertPerson(conn *sqlite.Conn) (int64, error) {
+	stmt := conn.Prep(`
+	INSERT INTO person(created_at_ms, updated_at_ms)
+	VALUES (?, ?)
+	RETURNING id;`)
+	defer stmt.Reset()
+
+	now := toUnixMs(time.Now())
+	stmt.BindInt64(1, now)
+	stmt.BindInt64(2, now)
+	if _, err := stmt.Step(); err != nil {
+		return 0, err
+	}
+	return stmt.ColumnInt64(0), nil
+}
+
+// ListPersonFaces lists the faces of the person, most confident first.
+func (source *Database) ListPersonFaces(id int64) []FaceInfo {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT id, file_id, x, y, w, h, confidence
+		FROM face
+		WHERE person_id = ?
+		ORDER BY confidence DESC, id ASC;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+
+	faces := make([]FaceInfo, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing faces of person %d: %s\n", id, err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		faces = append(faces, readFaceInfo(stmt))
+	}
+	return faces
+}
+
+// ListFaceEmbeddings lists the embeddings of the faces of the files in the
+// dirs ordered by face id.
+func (source *Database) ListFaceEmbeddings(dirs []string) []FaceEmbedding {
+	defer metrics.Elapsed("list face embeddings")()
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := `
+		SELECT face.id, coalesce(face.person_id, 0), face.embedding
+		FROM face
+		INNER JOIN infos ON infos.id = face.file_id
+		WHERE path_prefix_id IN (
+			SELECT id
+			FROM prefix
+			WHERE
+	`
+	for i := range dirs {
+		sql += `str LIKE ? `
+		if i < len(dirs)-1 {
+			sql += "OR "
+		}
+	}
+	sql += `
+		)
+		ORDER BY face.id;`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	for i, dir := range dirs {
+		stmt.BindText(i+1, dir+"%")
+	}
+
+	faces := make([]FaceEmbedding, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing face embeddings: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		emb, err := readFaceEmbedding(stmt, 2)
+		if err != nil {
+			continue
+		}
+		faces = append(faces, FaceEmbedding{
+			Id:        stmt.ColumnInt64(0),
+			PersonId:  stmt.ColumnInt64(1),
+			Embedding: emb.Float32(),
+		})
+	}
+	return faces
+}
+
+// ListPersonCentroids returns the normalized mean embedding of the faces of
+// each person by person id, including hidden persons, so that their faces
+// stay with them.
+func (source *Database) ListPersonCentroids() map[int64][]float32 {
+	defer metrics.Elapsed("list person centroids")()
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT person_id, embedding
+		FROM face
+		WHERE person_id IS NOT NULL;`)
+	defer stmt.Reset()
+
+	centroids := make(map[int64][]float32)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing person faces: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		emb, err := readFaceEmbedding(stmt, 1)
+		if err != nil {
+			continue
+		}
+		id := stmt.ColumnInt64(0)
+		vec := emb.Float32()
+		sum, ok := centroids[id]
+		if !ok {
+			sum = make([]float32, len(vec))
+			centroids[id] = sum
+		}
+		if len(sum) != len(vec) {
+			continue
+		}
+		for i, v := range vec {
+			sum[i] += v
+		}
+	}
+	for _, c := range centroids {
+		norm := float32(0)
+		for _, v := range c {
+			norm += v * v
+		}
+		if norm == 0 {
+			continue
+		}
+		inv := float32(1 / math.Sqrt(float64(norm)))
+		for i := range c {
+			c[i] *= inv
+		}
+	}
+	return centroids
+}
+
+// WriteFacePersons assigns the faces to persons by face id and creates a
+// new person for each of the clusters of face ids. Unnamed persons left
+// without faces are removed.
+func (source *Database) WriteFacePersons(assign map[int64]int64, clusters [][]int64) error {
+	return source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT write_face_persons;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO write_face_persons;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE write_face_persons;", nil)
+		}()
+
+		update := conn.Prep(`UPDATE face SET person_id = ? WHERE id = ?;`)
+		defer update.Reset()
+
+		set := func(faceId, personId int64) error {
+			update.BindInt64(1, personId)
+			update.BindInt64(2, faceId)
+			if _, err := update.Step(); err != nil {
+				return err
+			}
+			return update.Reset()
+		}
+
+		for faceId, personId := range assign {
+			if err := set(faceId, personId); err != nil {
+				return err
+			}
+		}
+		for _, cluster := range clusters {
+			personId, err := insertPerson(conn)
+			if err != nil {
+				return err
+			}
+			for _, faceId := range cluster {
+				if err := set(faceId, personId); err != nil {
+					return err
+				}
+			}
+		}
+
+		return sqlitex.Execute(conn, `
+			DELETE FROM person
+			WHERE name IS NULL AND id NOT IN (
+				SELECT person_id
+				FROM face
+				WHERE person_id IS NOT NULL
+			);`, nil)
+	})
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	img "photofield/internal/image"
+	"photofield/internal/tag"
+	"photofield/internal/task"
+)
+
+// defaultAutotagNeighbors is the number of most similar tagged files each
+// file is classified by if not configured.
+const defaultAutotagNeighbors = 5
+
+// RunAutotag suggests the auto tags for the files in the collection that
+// do not have them yet. Each file is classified by the auto tags of the
+// most similar tagged files, as compared by their AI embeddings, and the
+// tag is suggested if the classification is confident enough.
+//
+// Suggestions are written to the suggest: tag of each auto tag, e.g.
+// suggest:beach, until they are accepted or rejected. Rejected suggestions
+// are not suggested again.
+func RunAutotag(ctx context.Context, cfg Config, t *task.Task) error {
+	if cfg.DB == nil {
+		return nil
+	}
+	dirs := t.Dirs
+
+	counter := t.Counter()
+	defer close(counter)
+
+	tags := cfg.DB.ListAutoTags()
+	if len(tags) == 0 {
+		log.Println("index autotag skipped: no auto tags")
+		return nil
+	}
+
+	k := cfg.AutotagNeighbors
+	if k <= 0 {
+		k = defaultAutotagNeighbors
+	}
+	ids := make([]tag.Id, len(tags))
+	names := make([]string, len(tags))
+	byId := make(map[tag.Id]string, len(tags))
+	skip := make(map[tag.Id]img.Ids, len(tags))
+	for i, at := range tags {
+		ids[i] = at.Id
+		names[i] = at.Name
+		byId[at.Id] = at.Name
+		// Files that already have the tag or rejected it
+		s := cfg.DB.GetTagImageIds(at.Id)
+		s.AddTree(cfg.DB.GetTagSuggestionIds(at.Name, 0, true))
+		skip[at.Id] = s
+	}
+	classifier := cfg.DB.NewTagClassifier(ids, k)
+	if classifier.Len() == 0 {
+		log.Println("index autotag skipped: no tagged files with embeddings")
+		return nil
+	}
+
+	if count, ok := cfg.DB.GetDirsCount(dirs); ok {
+		t.SetTotal(count)
+		log.Printf("index autotag %d files by %d tagged files\n", count, classifier.Len())
+	}
+
+	suggestions := make([]img.TagSuggestion, 0)
+	embeddings := cfg.DB.ListEmbeddings(dirs, img.ListOptions{})
+	for e := range embeddings {
+		if ctx.Err() != nil {
+			// Drain the listing so that it releases its connection
+			continue
+		}
+		counter <- 1
+		id, confidence := classifier.Classify(e)
+		if id == 0 || confidence < cfg.AutotagMinConfidence {
+			continue
+		}
+		if skip[id].Contains(int(e.Id)) {
+			continue
+		}
+		suggestions = append(suggestions, img.TagSuggestion{
+			Tag:        byId[id],
+			FileId:     e.Id,
+			Confidence: confidence,
+		})
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := cfg.DB.WriteTagSuggestions(dirs, names, suggestions); err != nil {
+		return err
+	}
+	for _, at := range tags {
+		if err := cfg.DB.SyncSuggestTag(at); err != nil {
+			return err
+		}
+	}
+	log.Printf("index autotag suggested %d tags\n", len(suggestions))
+	return nil
+}
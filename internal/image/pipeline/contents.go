@@ -5,7 +5,6 @@ import (
 	"image"
 	"image/color"
 	"io"
-	"log"
 
 	"photofield/internal/ai"
 	img "photofield/internal/image"
@@ -33,16 +32,18 @@ type contentsProcessor struct {
 	aiService AIService
 	decoder   ImageDecoder
 	force     bool
+	fail      failFunc
 	progress  *multiProgress
 }
 
 // NewContentsProcessor creates a ContentsProcessor ready to process thumbnails.
-func newContentsProcessor(db *img.Database, aiService AIService, decoder ImageDecoder, force bool) *contentsProcessor {
+func newContentsProcessor(db *img.Database, aiService AIService, decoder ImageDecoder, force bool, fail failFunc) *contentsProcessor {
 	return &contentsProcessor{
 		db:        db,
 		aiService: aiService,
 		decoder:   decoder,
 		force:     force,
+		fail:      fail,
 		progress:  newMultiProgress("contents", 0, "color", "embedding"),
 	}
 }
@@ -57,11 +58,11 @@ func (p *contentsProcessor) Process(ctx context.Context, thumb fileWithThumb) {
 	if needsColor {
 		result := p.decoder.Decode(ctx, thumb.Thumb)
 		if result.Error != nil {
-			log.Printf("index error: decode for color %s: %v\n", thumb.Path, result.Error)
+			p.fail(thumb.fileRef, "decode for color", result.Error)
 		} else if result.Image != nil {
 			color, err := extractProminentColor(result.Image)
 			if err != nil {
-				log.Printf("index error: color extract %s: %v\n", thumb.Path, err)
+				p.fail(thumb.fileRef, "color extract", err)
 			} else {
 				info := img.Info{}
 				info.SetColorRGBA(color)
@@ -75,7 +76,7 @@ func (p *contentsProcessor) Process(ctx context.Context, thumb fileWithThumb) {
 	if needsEmbedding && p.aiService.Available() {
 		emb, err := p.aiService.EmbedImageReader(thumb.Thumb)
 		if err != nil && err != ai.ErrNotAvailable {
-			log.Printf("index error: embedding %s: %v\n", thumb.Path, err)
+			p.fail(thumb.fileRef, "embedding", err)
 		} else if err == nil {
 			p.db.WriteAI(thumb.ID, emb)
 			p.progress.IncCounter("embedding", 1)
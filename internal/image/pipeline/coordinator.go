@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sort"
 	"sync"
@@ -19,6 +20,7 @@ type Config struct {
 	// Metadata extraction
 	MetadataExtractor MetadataExtractor
 	EnableTags        bool
+	SyncXmp           func(path string) bool // Returns true if the file is synced with XMP, nil disables
 
 	// Thumbnail operations
 	ThumbnailSources    []ThumbnailSource
@@ -34,8 +36,24 @@ type Config struct {
 	MaxFaceFileSize int64 // Max file size for face detection (bytes)
 
 	// File scanning
-	Extensions     []string
+	Extensions      []string
 	VideoExtensions []string
+	ContentHash     bool                             // Relink moved files by their content hash
+	Included        func(path string, dir bool) bool // Returns false if the file or dir is excluded from its collections, nil includes all
+
+	// Duplicate detection
+	DuplicateSimilarity float32 // Min embedding similarity of near duplicates, 0 disables
+
+	// Face clustering
+	PersonSimilarity float32 // Min embedding similarity of faces of the same person, 0 disables
+	PersonMinFaces   int     // Min number of similar faces forming a new person
+
+	// Tag suggestions
+	AutotagNeighbors     int     // Number of most similar tagged files each file is classified by
+	AutotagMinConfidence float32 // Min confidence of suggested tags
+
+	// Scheduled indexing
+	Schedule func(collectionId string) // Indexes the scheduled stages of the collection if it changed, nil disables
 
 	// Worker counts
 	MetadataWorkers  int
@@ -52,6 +70,8 @@ type Config struct {
 // Higher value = higher priority = closer to tail = dequeued first.
 func stagePriority(taskType string) int {
 	switch taskType {
+	case task.TypeIndexSchedule:
+		return 4
 	case task.TypeIndexFiles:
 		return 3
 	case task.TypeIndexMetadata:
@@ -60,6 +80,8 @@ func stagePriority(taskType string) int {
 		return 1
 	case task.TypeIndexFaces:
 		return 0
+	case task.TypeIndexDuplicates, task.TypeIndexPersons, task.TypeIndexAutotag:
+		return -1
 	default:
 		return -2
 	}
@@ -100,7 +122,7 @@ func RunMetadata(ctx context.Context, cfg Config, t *task.Task) error {
 	files := fileSource(ctx, cfg.DB, dirs, maxPhotos, force, img.Missing{Metadata: true})
 
 	metaOut := processMetadata(ctx, cfg.DB, cfg.MetadataExtractor,
-		files, cfg.MetadataWorkers, cfg.EnableTags, counter)
+		files, cfg.MetadataWorkers, cfg.EnableTags, cfg.SyncXmp, counter, taskFailures(cfg.DB, t))
 
 	for range metaOut {
 	}
@@ -145,9 +167,10 @@ func RunContents(ctx context.Context, cfg Config, t *task.Task) error {
 
 	metaOut := fileSourceWithMetadata(ctx, cfg.DB, dirs, maxPhotos, force, includeEmbedding)
 
-	contents := newContentsProcessor(cfg.DB, cfg.AIService, cfg.ImageDecoder, force)
+	fail := taskFailures(cfg.DB, t)
+	contents := newContentsProcessor(cfg.DB, cfg.AIService, cfg.ImageDecoder, force, fail)
 	processThumbnails(ctx, cfg.ThumbnailSources, cfg.ThumbnailGenerators,
-		cfg.ThumbnailSink, metaOut, cfg.ThumbnailWorkers, counter, contents.Process)
+		cfg.ThumbnailSink, metaOut, cfg.ThumbnailWorkers, counter, fail, contents.Process)
 	contents.Done()
 
 	return nil
@@ -204,9 +227,13 @@ func RunFaces(ctx context.Context, cfg Config, t *task.Task) error {
 	}()
 
 	processFaces(ctx, cfg.DB, cfg.FaceDetector,
-		contentsFiles, cfg.FaceWorkers, cfg.MaxFaceFileSize, cfg.VideoExtensions, counter)
+		contentsFiles, cfg.FaceWorkers, cfg.MaxFaceFileSize, cfg.VideoExtensions, counter, taskFailures(cfg.DB, t))
 	log.Println("index faces completed")
 
+	if err := assignPersons(ctx, cfg, dirs); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -219,6 +246,7 @@ type Coordinator struct {
 	signal   chan struct{}
 	ctx      context.Context
 	cancel   context.CancelFunc
+	done     chan struct{}
 }
 
 // NewCoordinator creates a new pipeline coordinator
@@ -231,6 +259,14 @@ func NewCoordinator(ctx context.Context, cfg Config) *Coordinator {
 		signal:   make(chan struct{}, 1),
 		ctx:      ctx,
 		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	// Runs still in progress were interrupted by a restart
+	if cfg.DB != nil {
+		if err := cfg.DB.InterruptTaskRuns(); err != nil {
+			log.Printf("index task runs interrupt error: %v\n", err)
+		}
 	}
 
 	// Start background worker
@@ -259,6 +295,7 @@ func (c *Coordinator) insertSorted(t *task.Task) {
 
 // worker processes tasks from the queue sequentially
 func (c *Coordinator) worker() {
+	defer close(c.done)
 	for {
 		select {
 		case <-c.ctx.Done():
@@ -291,6 +328,7 @@ func (c *Coordinator) processTask(t *task.Task) {
 	defer c.registry.Delete(t.Id)
 
 	log.Printf("index task start %s\n", t.Id)
+	c.startRun(t)
 
 	var err error
 	if c.cfg.TaskRunner != nil {
@@ -311,9 +349,18 @@ func (c *Coordinator) processTask(t *task.Task) {
 			err = RunContents(t.Context(), c.cfg, t)
 		case task.TypeIndexFaces:
 			err = RunFaces(t.Context(), c.cfg, t)
+		case task.TypeIndexDuplicates:
+			err = RunDuplicates(t.Context(), c.cfg, t)
+		case task.TypeIndexPersons:
+			err = RunPersons(t.Context(), c.cfg, t)
+		case task.TypeIndexAutotag:
+			err = RunAutotag(t.Context(), c.cfg, t)
+		case task.TypeIndexSchedule:
+			if c.cfg.Schedule != nil {
+				c.cfg.Schedule(t.CollectionId)
+			}
 		default:
-			log.Printf("index task error: unknown type: %q: %s\n", t.Id, t.Type)
-			return
+			err = fmt.Errorf("unknown type: %s", t.Type)
 		}
 	}
 
@@ -322,6 +369,43 @@ func (c *Coordinator) processTask(t *task.Task) {
 	} else {
 		log.Printf("index task done %s\n", t.Id)
 	}
+	c.finishRun(t, err)
+}
+
+// startRun persists the start of a task run, so that the run and the files it
+// fails to process are kept after it is done.
+func (c *Coordinator) startRun(t *task.Task) {
+	if c.cfg.DB == nil {
+		return
+	}
+	run, err := c.cfg.DB.AddTaskRun(img.TaskRun{
+		TaskId:       t.Id,
+		Type:         t.Type,
+		Name:         t.Name,
+		CollectionId: t.CollectionId,
+	})
+	if err != nil {
+		log.Printf("index task run start error: %s: %v\n", t.Id, err)
+		return
+	}
+	t.SetRunId(run.Id)
+}
+
+// finishRun persists the final counts and error of a task run.
+func (c *Coordinator) finishRun(t *task.Task, runErr error) {
+	runId := t.RunId()
+	if c.cfg.DB == nil || runId == 0 {
+		return
+	}
+	if runErr == nil && t.Context().Err() != nil {
+		runErr = t.Context().Err()
+	}
+	done, total := t.Progress()
+	// Wait for the task errors to be written
+	<-c.cfg.DB.CommitBarrier()
+	if err := c.cfg.DB.FinishTaskRun(runId, done, total, runErr); err != nil {
+		log.Printf("index task run finish error: %s: %v\n", t.Id, err)
+	}
 }
 
 // StopTask cancels and removes a task by ID. Returns true if the task was found
@@ -349,7 +433,8 @@ func (c *Coordinator) StopTask(id string) bool {
 	return true
 }
 
-// Close stops the coordinator and cancels all pending tasks
+// Close stops the coordinator, cancels all pending tasks and waits for the
+// running task to stop
 func (c *Coordinator) Close() {
 	c.cancel()
 	// Do NOT close c.signal – the worker exits via ctx.Done().
@@ -362,6 +447,14 @@ func (c *Coordinator) Close() {
 	}
 	c.queue = nil
 	c.queueMu.Unlock()
+
+	// Stop the running task and wait for it to return, so that the config
+	// and database it uses can be closed
+	c.registry.Range(func(id string, t *task.Task) bool {
+		t.Close()
+		return true
+	})
+	<-c.done
 }
 
 // addTask registers and enqueues a task. Returns the task and true if newly added,
@@ -408,6 +501,26 @@ func (c *Coordinator) AddFaces(collectionId, collectionName string, dirs []strin
 	return c.addTask(task.NewFacesTask(collectionId, collectionName, dirs, maxPhotos, force))
 }
 
+// AddDuplicates queues a duplicate detection task for the given collection.
+func (c *Coordinator) AddDuplicates(collectionId, collectionName string, dirs []string) (*task.Task, bool) {
+	return c.addTask(task.NewDuplicatesTask(collectionId, collectionName, dirs))
+}
+
+// AddPersons queues a face clustering task for the given collection.
+func (c *Coordinator) AddPersons(collectionId, collectionName string, dirs []string) (*task.Task, bool) {
+	return c.addTask(task.NewPersonsTask(collectionId, collectionName, dirs))
+}
+
+// AddAutotag queues a tag suggestion task for the given collection.
+func (c *Coordinator) AddAutotag(collectionId, collectionName string, dirs []string) (*task.Task, bool) {
+	return c.addTask(task.NewAutotagTask(collectionId, collectionName, dirs))
+}
+
+// AddSchedule queues the scheduled indexing of the given collection.
+func (c *Coordinator) AddSchedule(collectionId, collectionName string) (*task.Task, bool) {
+	return c.addTask(task.NewScheduleTask(collectionId, collectionName))
+}
+
 // AddAll queues all three stages (metadata, contents, faces) for the given collection.
 // Returns one entry per stage with a bool indicating whether it was newly added.
 func (c *Coordinator) AddAll(collectionId, collectionName string, dirs []string, maxPhotos int, force bool) ([3]*task.Task, [3]bool) {
@@ -190,3 +190,30 @@ func TestCoordinatorPriorityOrder(t *testing.T) {
 		}
 	}
 }
+
+// TestCoordinatorSchedule verifies that scheduled indexing runs as a task
+// with the id of the collection.
+func TestCoordinatorSchedule(t *testing.T) {
+	ids := make(chan string, 1)
+	cfg := Config{
+		Schedule: func(collectionId string) {
+			ids <- collectionId
+		},
+	}
+	coordinator := NewCoordinator(context.Background(), cfg)
+	defer coordinator.Close()
+
+	tsk, isNew := coordinator.AddSchedule("vacation", "Vacation")
+	if !isNew {
+		t.Fatal("Schedule task should be new")
+	}
+	<-tsk.Completed()
+	select {
+	case id := <-ids:
+		if id != "vacation" {
+			t.Errorf("Scheduled collection %q, want vacation", id)
+		}
+	default:
+		t.Error("Schedule not run")
+	}
+}
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"photofield/internal/ai"
+	img "photofield/internal/image"
+	"photofield/internal/task"
+)
+
+// duplicateWindow is the number of preceding files in date order that each
+// file is compared to when looking for near duplicates without the embedding
+// index.
+const duplicateWindow = 10
+
+// duplicateNeighbors is the number of most similar files that each file is
+// compared to when looking for near duplicates with the embedding index.
+const duplicateNeighbors = 10
+
+// duplicateGroups is a disjoint set of files, where the root of each set is
+// its lowest id.
+type duplicateGroups map[img.ImageId]img.ImageId
+
+func (g duplicateGroups) find(id img.ImageId) img.ImageId {
+	for {
+		parent, ok := g[id]
+		if !ok || parent == id {
+			return id
+		}
+		g[id] = g[parent]
+		id = parent
+	}
+}
+
+func (g duplicateGroups) union(a, b img.ImageId) {
+	ra, rb := g.find(a), g.find(b)
+	if rb < ra {
+		ra, rb = rb, ra
+	}
+	g[ra] = ra
+	g[rb] = ra
+}
+
+// RunDuplicates groups files with the same contents by their content hash
+// and near duplicates by the similarity of their AI embeddings.
+func RunDuplicates(ctx context.Context, cfg Config, t *task.Task) error {
+	if cfg.DB == nil {
+		return nil
+	}
+	dirs := t.Dirs
+
+	counter := t.Counter()
+	defer close(counter)
+
+	if count, ok := cfg.DB.GetDirsCount(dirs); ok {
+		t.SetTotal(count)
+		log.Printf("index duplicates %d files\n", count)
+	}
+
+	if _, err := hashFiles(ctx, cfg, dirs); err != nil {
+		return err
+	}
+
+	groups := make(duplicateGroups)
+	exact := make(map[img.ImageId]bool)
+	for _, ids := range cfg.DB.ListExactDuplicates(dirs) {
+		for _, id := range ids {
+			groups.union(ids[0], id)
+			exact[id] = true
+		}
+	}
+
+	if cfg.DuplicateSimilarity > 0 {
+		type embedding struct {
+			id      img.ImageId
+			emb     []float32
+			invnorm float32
+		}
+		// Files similar to a file in the index may be outside of the dirs, so
+		// they are only grouped once all files in the dirs are listed
+		type pair struct {
+			a, b img.ImageId
+		}
+		listed := make(map[img.ImageId]bool)
+		similar := make([]pair, 0)
+		window := make([]embedding, 0, duplicateWindow)
+		embeddings := cfg.DB.ListEmbeddings(dirs, img.ListOptions{OrderBy: img.DateAsc})
+		for e := range embeddings {
+			if ctx.Err() != nil {
+				// Drain the listing so that it releases its connection
+				continue
+			}
+			listed[e.Id] = true
+			if files, ok := cfg.DB.ListSimilarFiles(e.Embedding, duplicateNeighbors+1); ok {
+				for _, f := range files {
+					if f.Id != e.Id && f.Similarity >= cfg.DuplicateSimilarity {
+						similar = append(similar, pair{e.Id, f.Id})
+					}
+				}
+				counter <- 1
+				continue
+			}
+			cur := embedding{
+				id:      e.Id,
+				emb:     e.Float32(),
+				invnorm: e.InvNormFloat32(),
+			}
+			for _, prev := range window {
+				sim, err := ai.CosineSimilarityFloat32Float32(prev.emb, prev.invnorm, cur.emb, cur.invnorm)
+				if err != nil {
+					continue
+				}
+				if sim >= cfg.DuplicateSimilarity {
+					groups.union(prev.id, cur.id)
+				}
+			}
+			if len(window) == duplicateWindow {
+				window = window[1:]
+			}
+			window = append(window, cur)
+			counter <- 1
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, p := range similar {
+			if listed[p.b] {
+				groups.union(p.a, p.b)
+			}
+		}
+	}
+
+	duplicates := make([]img.Duplicate, 0, len(groups))
+	for id := range groups {
+		duplicates = append(duplicates, img.Duplicate{
+			Id:      id,
+			GroupId: groups.find(id),
+			Exact:   exact[id],
+		})
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].Id < duplicates[j].Id
+	})
+
+	if err := cfg.DB.WriteDuplicates(dirs, duplicates); err != nil {
+		return err
+	}
+	log.Printf("index duplicates found %d files\n", len(duplicates))
+	return nil
+}
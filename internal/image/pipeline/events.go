@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"photofield/internal/fs"
+
+	"github.com/rjeczalik/notify"
+)
+
+// eventWatcher watches dirs and their subdirs like fs.NewRecursiveWatcher,
+// but pairs the old and new paths of renames the way each platform reports
+// them, so that renamed files can keep their ids. Renames without a new path,
+// e.g. moves out of the watched dirs, are reported as removes.
+type eventWatcher struct {
+	Events    chan fs.Event
+	c         chan notify.EventInfo
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+}
+
+var ignoreEventPatterns = []string{
+	"*.db", "*.db-journal", "*.db-shm", "*.db-wal", "*.db-wal2", "*.tmp",
+}
+
+func newEventWatcher(dirs []string) (*eventWatcher, error) {
+	w := &eventWatcher{
+		Events: make(chan fs.Event, 100),
+		c:      make(chan notify.EventInfo, 100),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		err := notify.Watch(
+			dir+"/...",
+			w.c,
+			notify.Remove,
+			notify.Rename,
+			notify.Create,
+			notify.Write,
+		)
+		if err != nil {
+			notify.Stop(w.c)
+			return nil, err
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+func removeEventPath(events []fs.Event, path string) (found bool) {
+	for i := range events {
+		e := &events[i]
+		if e.Path == path {
+			e.Path = ""
+			found = true
+		}
+	}
+	return
+}
+
+func hasEventPath(events []fs.Event, path string) bool {
+	for _, e := range events {
+		if e.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func ignoresEventPath(path string) bool {
+	for _, pattern := range ignoreEventPatterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends the event, returning false if the watcher was closed instead.
+func (w *eventWatcher) send(e fs.Event) bool {
+	select {
+	case w.Events <- e:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *eventWatcher) run() {
+	defer close(w.exited)
+	// Update events are delayed by 1x - 2x this interval to avoid multiple
+	// updates for the same file and out of order remove and update events.
+	interval := 200 * time.Millisecond
+	ticker := &time.Ticker{
+		C: make(chan time.Time),
+	}
+	tickerRunning := false
+	next := make([]fs.Event, 0, 100)
+	pending := make([]fs.Event, 0, 100)
+	pendingRenamePath := ""
+	pendingRenameTicked := false
+	lastCreatePath := ""
+	for {
+		select {
+		case <-w.done:
+			if tickerRunning {
+				ticker.Stop()
+			}
+			return
+		case <-ticker.C:
+			for i, e := range next {
+				if e.Path == "" {
+					continue
+				}
+				if i > 0 && next[i-1].Path == e.Path {
+					continue
+				}
+				if !w.send(e) {
+					return
+				}
+			}
+			next = next[:0]
+			next, pending = pending, next
+			if pendingRenamePath != "" {
+				// Renamed without a new path, e.g. moved out of the watched dirs
+				if pendingRenameTicked {
+					if !w.send(fs.Event{
+						Op:   fs.Remove,
+						Path: pendingRenamePath,
+					}) {
+						return
+					}
+					pendingRenamePath = ""
+				}
+				pendingRenameTicked = true
+			}
+			if len(next) == 0 && pendingRenamePath == "" {
+				ticker.Stop()
+				tickerRunning = false
+			}
+		case e := <-w.c:
+			if ignoresEventPath(e.Path()) {
+				continue
+			}
+			createdPath := lastCreatePath
+			lastCreatePath = ""
+			switch e.Event() {
+			case notify.Rename:
+				if e.Path() == pendingRenamePath {
+					// Renamed dirs can be reported twice
+					continue
+				}
+				if pendingRenamePath == "" && createdPath != "" {
+					// Linux reports the new path of a rename as created, in
+					// either order
+					removeEventPath(next, createdPath)
+					removeEventPath(pending, createdPath)
+					if !w.send(fs.Event{
+						Op:      fs.Rename,
+						Path:    createdPath,
+						OldPath: e.Path(),
+					}) {
+						return
+					}
+				} else if pendingRenamePath != "" {
+					// Windows reports the old path first and the new path second
+					if !w.send(fs.Event{
+						Op:      fs.Rename,
+						Path:    e.Path(),
+						OldPath: pendingRenamePath,
+					}) {
+						return
+					}
+					pendingRenamePath = ""
+				} else {
+					pendingRenamePath = e.Path()
+					pendingRenameTicked = false
+					if !tickerRunning {
+						ticker = time.NewTicker(interval)
+						tickerRunning = true
+					}
+				}
+
+			case notify.Create,
+				notify.Write:
+				if pendingRenamePath != "" && e.Event() == notify.Create {
+					if !w.send(fs.Event{
+						Op:      fs.Rename,
+						Path:    e.Path(),
+						OldPath: pendingRenamePath,
+					}) {
+						return
+					}
+					pendingRenamePath = ""
+					continue
+				}
+				if e.Event() == notify.Create {
+					lastCreatePath = e.Path()
+				}
+				ev := fs.Event{
+					Op:   fs.Update,
+					Path: e.Path(),
+				}
+				if hasEventPath(pending, ev.Path) {
+					continue
+				}
+				pending = append(pending, ev)
+				if !tickerRunning {
+					ticker = time.NewTicker(interval)
+					tickerRunning = true
+				}
+			case notify.Remove:
+				ev := fs.Event{
+					Op:   fs.Remove,
+					Path: e.Path(),
+				}
+				if removeEventPath(next, ev.Path) {
+					continue
+				}
+				if removeEventPath(pending, ev.Path) {
+					continue
+				}
+				if !w.send(ev) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close stops watching and closes Events after run exits, dropping any
+// delayed events.
+func (w *eventWatcher) Close() {
+	if w == nil {
+		return
+	}
+	w.closeOnce.Do(func() {
+		notify.Stop(w.c)
+		close(w.done)
+		<-w.exited
+		close(w.Events)
+	})
+}
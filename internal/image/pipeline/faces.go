@@ -3,7 +3,6 @@ package pipeline
 import (
 	"context"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -36,7 +35,8 @@ func processFaces(ctx context.Context,
 	workers int,
 	maxFileSize int64,
 	videoExtensions []string,
-	counter chan<- int) {
+	counter chan<- int,
+	fail failFunc) {
 
 	var wg sync.WaitGroup
 	progress := newMultiProgress("faces", 0, "detected", "skipped")
@@ -53,7 +53,7 @@ func processFaces(ctx context.Context,
 				// Open original file
 				f, err := os.Open(file.Path)
 				if err != nil {
-					log.Printf("index error: open for faces %s: %v\n", file.Path, err)
+					fail(file.fileRef, "open for faces", err)
 					continue
 				}
 
@@ -61,7 +61,7 @@ func processFaces(ctx context.Context,
 				stat, err := f.Stat()
 				if err != nil {
 					f.Close()
-					log.Printf("index error: stat for faces %s: %v\n", file.Path, err)
+					fail(file.fileRef, "stat for faces", err)
 					continue
 				}
 
@@ -85,7 +85,7 @@ func processFaces(ctx context.Context,
 				f.Close()
 
 				if err != nil && err != ai.ErrNotAvailable {
-					log.Printf("index error: detect faces %s: %v\n", file.Path, err)
+					fail(file.fileRef, "detect faces", err)
 					continue
 				}
 
@@ -0,0 +1,25 @@
+package pipeline
+
+import (
+	"fmt"
+	"log"
+
+	img "photofield/internal/image"
+	"photofield/internal/task"
+)
+
+// failFunc logs a file that a stage failed to process, where what describes
+// the step that failed.
+type failFunc func(file fileRef, what string, err error)
+
+// taskFailures returns a failFunc that also records the failures as errors of
+// the current run of the task, so that they can be listed later.
+func taskFailures(db *img.Database, t *task.Task) failFunc {
+	runId := t.RunId()
+	return func(file fileRef, what string, err error) {
+		log.Printf("index error: %s %s: %v\n", what, file.Path, err)
+		if db != nil && runId != 0 {
+			db.WriteTaskError(runId, file.ID, file.Path, fmt.Errorf("%s: %w", what, err))
+		}
+	}
+}
@@ -17,7 +17,9 @@ import (
 
 var errSkip = errors.New("skipping the rest")
 
-func walkFiles(ctx context.Context, dir string, extensions []string, maxFiles int) <-chan string {
+// walkFiles lists the files with the extensions in the dir and its subdirs,
+// leaving out the files and dirs not included, if included is not nil.
+func walkFiles(ctx context.Context, dir string, extensions []string, included func(path string, dir bool) bool, maxFiles int) <-chan string {
 	out := make(chan string)
 	go func() {
 		finished := metrics.Elapsed(fmt.Sprintf("index %s", dir))
@@ -29,9 +31,12 @@ func walkFiles(ctx context.Context, dir string, extensions []string, maxFiles in
 		files := 0
 		err := godirwalk.Walk(dir, &godirwalk.Options{
 			Unsorted: true,
-			Callback: func(path string, _ *godirwalk.Dirent) error {
-				if strings.Contains(path, "@eaDir") {
-					return filepath.SkipDir
+			Callback: func(path string, de *godirwalk.Dirent) error {
+				if de.IsDir() {
+					if included != nil && !included(path, true) {
+						return filepath.SkipDir
+					}
+					return nil
 				}
 
 				matched := false
@@ -44,6 +49,9 @@ func walkFiles(ctx context.Context, dir string, extensions []string, maxFiles in
 				if !matched {
 					return nil
 				}
+				if included != nil && !included(path, false) {
+					return nil
+				}
 
 				files++
 				progress.Inc(1)
@@ -69,7 +77,8 @@ func walkFiles(ctx context.Context, dir string, extensions []string, maxFiles in
 
 // RunFiles scans directories for media files and updates the database.
 // New files are added, missing files are removed, and the directory is
-// marked as indexed.
+// marked as indexed. With content hashing enabled, missing files are relinked
+// to new files with the same contents instead of being removed.
 func RunFiles(ctx context.Context, cfg Config, t *task.Task) error {
 	if cfg.DB == nil {
 		return nil
@@ -78,11 +87,11 @@ func RunFiles(ctx context.Context, cfg Config, t *task.Task) error {
 	counter := t.Counter()
 	defer close(counter)
 
+	indexed := make(map[string]struct{})
 	for _, dir := range t.Dirs {
 		log.Printf("index files %s\n", dir)
-		indexed := make(map[string]struct{})
 
-		for path := range walkFiles(ctx, dir, cfg.Extensions, t.MaxPhotos) {
+		for path := range walkFiles(ctx, dir, cfg.Extensions, cfg.Included, t.MaxPhotos) {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -92,19 +101,36 @@ func RunFiles(ctx context.Context, cfg Config, t *task.Task) error {
 			indexed[path] = struct{}{}
 			counter <- 1
 		}
+	}
 
-		<-cfg.DB.CommitBarrier()
+	<-cfg.DB.CommitBarrier()
 
+	var missing []img.IdPath
+	for _, dir := range t.Dirs {
 		for ip := range cfg.DB.ListNonexistent(dir, indexed) {
-			cfg.DB.Delete(ip.Id)
-			if cfg.ThumbnailSink != nil {
-				cfg.ThumbnailSink.Delete(uint32(ip.Id))
-			}
+			missing = append(missing, ip)
 		}
+	}
 
+	if cfg.ContentHash {
+		hashed, err := hashFiles(ctx, cfg, t.Dirs)
+		if err != nil {
+			return err
+		}
+		missing = relinkMoved(ctx, cfg, missing, hashed)
+	}
+
+	for _, ip := range missing {
+		cfg.DB.Delete(ip.Id)
+		if cfg.ThumbnailSink != nil {
+			cfg.ThumbnailSink.Delete(uint32(ip.Id))
+		}
+	}
+
+	for _, dir := range t.Dirs {
 		cfg.DB.SetIndexed(dir)
-		<-cfg.DB.CommitBarrier()
 	}
+	<-cfg.DB.CommitBarrier()
 
 	log.Println("index files done")
 	return nil
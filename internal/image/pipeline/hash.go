@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"log"
+	"os"
+
+	img "photofield/internal/image"
+)
+
+// partialHashSize is the number of bytes hashed from both the start and the
+// end of a file for the partial hash.
+const partialHashSize = 64 * 1024
+
+// partialHash hashes the size, start and end of the file, which is fast and
+// tells files apart in most cases.
+func partialHash(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := stat.Size()
+
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, size)
+	if _, err := io.CopyN(h, f, min(size, partialHashSize)); err != nil {
+		return 0, err
+	}
+	if size > partialHashSize {
+		offset := max(size-partialHashSize, partialHashSize)
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return 0, err
+		}
+	}
+	sum := h.Sum(nil)
+	return int64(binary.LittleEndian.Uint64(sum)), nil
+}
+
+// fullHash hashes the entire file.
+func fullHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// hashFiles computes the partial hash of files without one and the full hash
+// of files whose partial hashes collide. It returns the ids of the files
+// hashed for the first time.
+func hashFiles(ctx context.Context, cfg Config, dirs []string) (map[img.ImageId]string, error) {
+	hashed := make(map[img.ImageId]string)
+	for ip := range cfg.DB.ListMissingHashes(dirs) {
+		if err := ctx.Err(); err != nil {
+			return hashed, err
+		}
+		partial, err := partialHash(ip.Path)
+		if os.IsNotExist(err) {
+			// Missing files are relinked or removed later
+			continue
+		}
+		if err != nil {
+			log.Printf("hash %s failed: %s\n", ip.Path, err.Error())
+			continue
+		}
+		cfg.DB.WriteHash(ip.Id, img.ContentHash{Partial: partial})
+		hashed[ip.Id] = ip.Path
+	}
+	<-cfg.DB.CommitBarrier()
+
+	for ip := range cfg.DB.ListHashCollisions(dirs) {
+		if err := ctx.Err(); err != nil {
+			return hashed, err
+		}
+		hash, ok := cfg.DB.GetHash(ip.Id)
+		if !ok {
+			continue
+		}
+		full, err := fullHash(ip.Path)
+		if os.IsNotExist(err) {
+			// Files that went missing cannot be hashed anymore
+			continue
+		}
+		if err != nil {
+			log.Printf("hash %s failed: %s\n", ip.Path, err.Error())
+			continue
+		}
+		hash.Full = full
+		cfg.DB.WriteHash(ip.Id, hash)
+	}
+	<-cfg.DB.CommitBarrier()
+	return hashed, nil
+}
+
+// relinkMoved relinks missing files to the hashed files with the same
+// contents, so that they keep their id and everything associated with it.
+// It returns the missing files that were not relinked.
+func relinkMoved(ctx context.Context, cfg Config, missing []img.IdPath, hashed map[img.ImageId]string) []img.IdPath {
+	type hashedFile struct {
+		img.IdPath
+		img.ContentHash
+	}
+
+	missingByHash := make(map[int64][]hashedFile)
+	for _, m := range missing {
+		hash, ok := cfg.DB.GetHash(m.Id)
+		if !ok {
+			continue
+		}
+		missingByHash[hash.Partial] = append(missingByHash[hash.Partial], hashedFile{m, hash})
+	}
+	if len(missingByHash) == 0 {
+		return missing
+	}
+
+	addedByHash := make(map[int64][]hashedFile)
+	for id, path := range hashed {
+		hash, ok := cfg.DB.GetHash(id)
+		if !ok {
+			continue
+		}
+		if _, ok := missingByHash[hash.Partial]; !ok {
+			continue
+		}
+		addedByHash[hash.Partial] = append(addedByHash[hash.Partial], hashedFile{img.IdPath{Id: id, Path: path}, hash})
+	}
+
+	relinked := make(map[img.ImageId]bool)
+	relink := func(from hashedFile, to hashedFile) {
+		log.Printf("index files relink %s to %s\n", from.Path, to.Path)
+		cfg.DB.Delete(to.Id)
+		if cfg.ThumbnailSink != nil {
+			cfg.ThumbnailSink.Delete(uint32(to.Id))
+		}
+		cfg.DB.Relink(from.Id, to.Path)
+		relinked[from.Id] = true
+	}
+
+	for partial, added := range addedByHash {
+		if ctx.Err() != nil {
+			break
+		}
+		candidates := missingByHash[partial]
+
+		// A single file with a matching partial hash is the same file
+		if len(candidates) == 1 && len(added) == 1 && candidates[0].Full == nil {
+			relink(candidates[0], added[0])
+			continue
+		}
+
+		// Otherwise the full hashes need to match
+		for _, a := range added {
+			full := a.Full
+			if full == nil {
+				var err error
+				full, err = fullHash(a.Path)
+				if err != nil {
+					log.Printf("hash %s failed: %s\n", a.Path, err.Error())
+					continue
+				}
+			}
+			for _, c := range candidates {
+				if relinked[c.Id] || c.Full == nil || !bytes.Equal(c.Full, full) {
+					continue
+				}
+				relink(c, a)
+				break
+			}
+		}
+	}
+	<-cfg.DB.CommitBarrier()
+
+	remaining := make([]img.IdPath, 0, len(missing))
+	for _, m := range missing {
+		if !relinked[m.Id] {
+			remaining = append(remaining, m)
+		}
+	}
+	return remaining
+}
@@ -2,11 +2,12 @@ package pipeline
 
 import (
 	"context"
-	"log"
+	"slices"
 	"sync"
 
 	img "photofield/internal/image"
 	"photofield/internal/tag"
+	"photofield/internal/xmp"
 )
 
 // MetadataExtractor extracts metadata from files
@@ -14,9 +15,11 @@ type MetadataExtractor interface {
 	DecodeInfo(path string, info *img.Info) ([]tag.Tag, error)
 }
 
-// processMetadata extracts metadata from files and writes to DB
+// processMetadata extracts metadata from files and writes to DB. The XMP
+// keywords and ratings of files synced with XMP are read as well, with XMP
+// ratings taking precedence over the embedded ones.
 func processMetadata(ctx context.Context, db *img.Database, decoder MetadataExtractor,
-	in <-chan fileRef, workers int, enableTags bool, counter chan<- int) <-chan fileWithMeta {
+	in <-chan fileRef, workers int, enableTags bool, syncXmp func(path string) bool, counter chan<- int, fail failFunc) <-chan fileWithMeta {
 	out := make(chan fileWithMeta, 100)
 
 	var wg sync.WaitGroup
@@ -32,10 +35,32 @@ func processMetadata(ctx context.Context, db *img.Database, decoder MetadataExtr
 				tags, err := decoder.DecodeInfo(file.Path, &info)
 
 				if err != nil {
-					log.Printf("index error: metadata extract %s: %v\n", file.Path, err)
+					fail(file, "metadata extract", err)
 					continue
 				}
 
+				rating, hasRating := info.Rating, info.Rating > 0
+				if syncXmp != nil && syncXmp(file.Path) {
+					m, ok, err := xmp.ReadFile(file.Path)
+					if err != nil {
+						fail(file, "xmp read", err)
+					} else if ok {
+						keywords := xmpKeywords(m)
+						for _, name := range keywords {
+							tags = append(tags, tag.Tag{Name: name})
+						}
+						if enableTags {
+							removeStaleXmpTags(db, file.ID, keywords)
+						}
+						if m.HasRating {
+							rating, hasRating = img.ClampRating(m.Rating), true
+						}
+					}
+				}
+				if hasRating {
+					db.WriteRating(file.ID, rating)
+				}
+
 				// Write to database immediately
 				db.Write(file.Path, info, img.UpdateMeta)
 				if enableTags && len(tags) > 0 {
@@ -87,3 +112,56 @@ func metadataToFileRef(in <-chan fileWithMeta) <-chan fileRef {
 	}()
 	return out
 }
+
+// SyncXmpFile reads the XMP keywords and rating of the indexed file again,
+// e.g. after its sidecar was changed by another photo manager, adding and
+// removing its tags to match.
+func SyncXmpFile(db *img.Database, id img.ImageId, path string) error {
+	m, ok, err := xmp.ReadFile(path)
+	if err != nil || !ok {
+		return err
+	}
+	keywords := xmpKeywords(m)
+	tags := make([]tag.Tag, 0, len(keywords))
+	for _, name := range keywords {
+		tags = append(tags, tag.Tag{Name: name})
+	}
+	removeStaleXmpTags(db, id, keywords)
+	db.WriteTags(id, tags)
+	if m.HasRating {
+		db.WriteRating(id, img.ClampRating(m.Rating))
+	}
+	return nil
+}
+
+// xmpKeywords returns the XMP keywords synced as tags, leaving out the ones
+// of internal tags, e.g. written by earlier versions.
+func xmpKeywords(m xmp.Meta) []string {
+	keywords := make([]string, 0)
+	for _, name := range m.Keywords() {
+		if img.SyncsXmpTag(name) {
+			keywords = append(keywords, name)
+		}
+	}
+	return keywords
+}
+
+// removeStaleXmpTags removes the tags of the file that are not among its
+// XMP keywords, as XMP has all the tags of the files synced with it.
+// Internal tags are not written to XMP, so they are kept.
+func removeStaleXmpTags(db *img.Database, id img.ImageId, keywords []string) {
+	stale := make([]tag.Id, 0)
+	for t := range db.ListImageTags(id) {
+		if !img.SyncsXmpTag(t.Name) {
+			continue
+		}
+		if !slices.Contains(keywords, t.Name) {
+			stale = append(stale, t.Id)
+		}
+	}
+	ids := img.NewIds()
+	ids.AddInt(int(id))
+	for _, t := range stale {
+		db.RemoveTagIds(t, ids)
+	}
+}
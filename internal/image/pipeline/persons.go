@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	"photofield/internal/ai"
+	"photofield/internal/ann"
+	"photofield/internal/task"
+)
+
+// personNeighbors is the max number of most similar faces considered to be
+// the neighbours of each face while clustering.
+const personNeighbors = 32
+
+// defaultPersonMinFaces is the min number of similar faces forming a new
+// person if not configured.
+const defaultPersonMinFaces = 3
+
+// RunPersons clusters the faces of the files in the collection into persons
+// with DBSCAN. Faces with at least PersonMinFaces similar faces, including
+// themselves, form the core of a person, the similar faces of which belong
+// to it as well.
+//
+// Faces that are already assigned keep their person, so that merged and
+// split persons stay as they are. The unassigned faces of a cluster join
+// the person most of its assigned faces belong to, or a new one if there
+// are none.
+func RunPersons(ctx context.Context, cfg Config, t *task.Task) error {
+	if cfg.DB == nil {
+		return nil
+	}
+	if cfg.PersonSimilarity <= 0 {
+		log.Println("index persons skipped: person similarity not configured")
+		return nil
+	}
+	dirs := t.Dirs
+	minFaces := cfg.PersonMinFaces
+	if minFaces <= 0 {
+		minFaces = defaultPersonMinFaces
+	}
+
+	counter := t.Counter()
+	defer close(counter)
+
+	faces := cfg.DB.ListFaceEmbeddings(dirs)
+	t.SetTotal(len(faces))
+	log.Printf("index persons %d faces\n", len(faces))
+
+	index := ann.New(16, 100)
+	for i, f := range faces {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := index.Add(int64(i), f.Embedding); err != nil {
+			log.Printf("index persons unable to add face %d: %s\n", f.Id, err.Error())
+		}
+	}
+
+	neighbors := func(i int) []int {
+		counter <- 1
+		results, err := index.Search(faces[i].Embedding, personNeighbors, 2*personNeighbors)
+		if err != nil {
+			return nil
+		}
+		n := make([]int, 0, len(results))
+		for _, r := range results {
+			j := int(r.Id)
+			// Index similarities are approximate
+			sim, err := ai.DotProductFloat32Float32(faces[i].Embedding, faces[j].Embedding)
+			if err != nil || sim < cfg.PersonSimilarity {
+				continue
+			}
+			n = append(n, j)
+		}
+		return n
+	}
+
+	const noise = -1
+	labels := make([]int, len(faces))
+	clusterCount := 0
+	for i := range faces {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if labels[i] != 0 {
+			continue
+		}
+		n := neighbors(i)
+		if len(n) < minFaces {
+			labels[i] = noise
+			continue
+		}
+		clusterCount++
+		labels[i] = clusterCount
+		queue := n
+		for len(queue) > 0 {
+			j := queue[0]
+			queue = queue[1:]
+			if labels[j] == noise {
+				labels[j] = clusterCount
+				continue
+			}
+			if labels[j] != 0 {
+				continue
+			}
+			labels[j] = clusterCount
+			if nj := neighbors(j); len(nj) >= minFaces {
+				queue = append(queue, nj...)
+			}
+		}
+	}
+
+	members := make([][]int, clusterCount+1)
+	for i, label := range labels {
+		if label > 0 {
+			members[label] = append(members[label], i)
+		}
+	}
+
+	assign := make(map[int64]int64)
+	clusters := make([][]int64, 0)
+	for _, m := range members {
+		votes := make(map[int64]int)
+		var personId int64
+		unassigned := make([]int64, 0, len(m))
+		for _, i := range m {
+			f := faces[i]
+			if f.PersonId == 0 {
+				unassigned = append(unassigned, f.Id)
+				continue
+			}
+			votes[f.PersonId]++
+			v := votes[f.PersonId]
+			if v > votes[personId] || v == votes[personId] && f.PersonId < personId {
+				personId = f.PersonId
+			}
+		}
+		if len(unassigned) == 0 {
+			continue
+		}
+		if personId == 0 {
+			clusters = append(clusters, unassigned)
+			continue
+		}
+		for _, id := range unassigned {
+			assign[id] = personId
+		}
+	}
+
+	if err := cfg.DB.WriteFacePersons(assign, clusters); err != nil {
+		return err
+	}
+	log.Printf("index persons found %d new persons, assigned %d faces to existing ones\n", len(clusters), len(assign))
+
+	// Faces similar to a person, but without enough similar faces
+	return assignPersons(ctx, cfg, dirs)
+}
+
+// assignPersons assigns the unassigned faces of the files in the dirs to
+// the existing person with the most similar mean face embedding, so that
+// newly indexed faces join known persons without clustering all of them
+// again.
+func assignPersons(ctx context.Context, cfg Config, dirs []string) error {
+	if cfg.DB == nil || cfg.PersonSimilarity <= 0 {
+		return nil
+	}
+
+	// Wait for the detected faces to be written
+	<-cfg.DB.CommitBarrier()
+
+	centroids := cfg.DB.ListPersonCentroids()
+	if len(centroids) == 0 {
+		return nil
+	}
+
+	assign := make(map[int64]int64)
+	for _, f := range cfg.DB.ListFaceEmbeddings(dirs) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if f.PersonId != 0 {
+			continue
+		}
+		var best int64
+		bestSim := cfg.PersonSimilarity
+		for id, c := range centroids {
+			sim, err := ai.DotProductFloat32Float32(f.Embedding, c)
+			if err != nil {
+				continue
+			}
+			if sim > bestSim || sim == bestSim && (best == 0 || id < best) {
+				best = id
+				bestSim = sim
+			}
+		}
+		if best != 0 {
+			assign[f.Id] = best
+		}
+	}
+	if len(assign) == 0 {
+		return nil
+	}
+
+	log.Printf("index persons assigned %d faces\n", len(assign))
+	return cfg.DB.WriteFacePersons(assign, nil)
+}
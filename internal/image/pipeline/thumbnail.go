@@ -3,8 +3,8 @@ package pipeline
 import (
 	"bytes"
 	"context"
+	"errors"
 	goio "io"
-	"log"
 	"sync"
 
 	pio "photofield/internal/io"
@@ -41,6 +41,7 @@ func processThumbnails(ctx context.Context,
 	in <-chan fileWithMeta,
 	workers int,
 	counter chan<- int,
+	fail failFunc,
 	process func(context.Context, fileWithThumb),
 ) {
 	var wg sync.WaitGroup
@@ -94,7 +95,7 @@ func processThumbnails(ctx context.Context,
 					}
 
 					if r.Error != nil {
-						log.Printf("index error: thumbnail generate %s: %v\n", file.Path, r.Error)
+						fail(file.fileRef, "thumbnail generate", r.Error)
 						continue
 					}
 
@@ -106,7 +107,7 @@ func processThumbnails(ctx context.Context,
 
 					var buf bytes.Buffer
 					if !sink.SetWithBuffer(ctx, id, file.Path, &buf, r) {
-						log.Printf("index error: thumbnail save %s: failed\n", file.Path)
+						fail(file.fileRef, "thumbnail save", errors.New("failed"))
 						continue
 					}
 
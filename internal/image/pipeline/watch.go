@@ -0,0 +1,297 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"photofield/internal/fs"
+	img "photofield/internal/image"
+)
+
+// WatchFunc is called with the paths changed by a batch of file events once
+// the changes are committed. Added is true if any new files were indexed and
+// still need their metadata and contents extracted.
+type WatchFunc func(paths []string, added bool)
+
+// Watcher indexes the files added, removed and renamed in the watched dirs
+// as the changes happen, without walking the dirs again.
+type Watcher struct {
+	cfg      Config
+	fsw      *eventWatcher
+	debounce time.Duration
+	onChange WatchFunc
+	done     chan struct{}
+}
+
+// NewWatcher watches the dirs and their subdirs for changes. Events are
+// collected until there are none for the debounce interval, so that e.g.
+// files still being copied are indexed once, but for at most
+// watchMaxWait intervals.
+func NewWatcher(cfg Config, dirs []string, debounce time.Duration, onChange WatchFunc) (*Watcher, error) {
+	fsw, err := newEventWatcher(dirs)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		cfg:      cfg,
+		fsw:      fsw,
+		debounce: debounce,
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// watchMaxWait caps how many debounce intervals a batch of events can be
+// delayed by, so that a steady trickle of changes is still indexed.
+const watchMaxWait = 10
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	debounceEvents(w.fsw.Events, w.debounce, watchMaxWait*w.debounce, w.apply)
+}
+
+// debounceEvents collects the events into batches until there are none for
+// the debounce interval or the first event of the batch is older than
+// maxWait, calling apply with each batch until the events are closed.
+func debounceEvents(events <-chan fs.Event, debounce, maxWait time.Duration, apply func([]fs.Event)) {
+	batch := make([]fs.Event, 0)
+	var first time.Time
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			if len(batch) == 0 {
+				first = time.Now()
+			}
+			batch = append(batch, e)
+			wait := debounce
+			if left := maxWait - time.Since(first); left < wait {
+				wait = max(left, 0)
+			}
+			timer.Reset(wait)
+		case <-timer.C:
+			apply(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// indexes returns true if the file has one of the extensions and is
+// included by its collections.
+func (w *Watcher) indexes(path string) bool {
+	if w.cfg.Included != nil && !w.cfg.Included(path, false) {
+		return false
+	}
+	lower := strings.ToLower(path)
+	for _, ext := range w.cfg.Extensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) remove(id img.ImageId) {
+	w.cfg.DB.Delete(id)
+	if w.cfg.ThumbnailSink != nil {
+		w.cfg.ThumbnailSink.Delete(uint32(id))
+	}
+}
+
+// removeAll removes the file at the path or all the files under it, if it
+// was a dir.
+func (w *Watcher) removeAll(path string) {
+	if id, ok := w.cfg.DB.GetIdFromPath(path); ok {
+		w.remove(id)
+		return
+	}
+	for ip := range w.cfg.DB.ListIdPaths([]string{path + string(filepath.Separator)}, 0) {
+		w.remove(ip.Id)
+	}
+}
+
+// add indexes the file at the path or all the files under it, if it is a dir,
+// returning true if any files were indexed.
+func (w *Watcher) add(path string, info os.FileInfo) bool {
+	if !info.IsDir() {
+		if !w.indexes(path) {
+			return false
+		}
+		return w.addFile(path)
+	}
+	added := false
+	for p := range walkFiles(context.Background(), path, w.cfg.Extensions, w.cfg.Included, 0) {
+		if w.addFile(p) {
+			added = true
+		}
+	}
+	return added
+}
+
+func (w *Watcher) addFile(path string) bool {
+	if _, ok := w.cfg.DB.GetIdFromPath(path); ok {
+		// Modified, but already indexed
+		return false
+	}
+	w.cfg.DB.Write(path, img.Info{}, img.AppendPath)
+	return true
+}
+
+// rename moves the file or all the files under the dir from the old path to
+// the new one, keeping their ids along with their tags, faces and embeddings.
+func (w *Watcher) rename(oldPath, path string, info os.FileInfo) bool {
+	if !info.IsDir() {
+		if id, ok := w.cfg.DB.GetIdFromPath(oldPath); ok {
+			if w.indexes(path) {
+				w.cfg.DB.Relink(id, path)
+				return false
+			}
+			w.remove(id)
+			return false
+		}
+		return w.add(path, info)
+	}
+	if id, ok := w.cfg.DB.GetIdFromPath(oldPath); ok {
+		// Mismatched events, a file can't become a dir
+		w.remove(id)
+		return w.add(path, info)
+	}
+	oldDir := oldPath + string(filepath.Separator)
+	for ip := range w.cfg.DB.ListIdPaths([]string{oldDir}, 0) {
+		w.cfg.DB.Relink(ip.Id, filepath.Join(path, strings.TrimPrefix(ip.Path, oldDir)))
+	}
+	<-w.cfg.DB.CommitBarrier()
+	// Pick up any files that were not indexed before
+	return w.add(path, info)
+}
+
+func (w *Watcher) apply(events []fs.Event) {
+	paths := make([]string, 0, len(events))
+	added := false
+
+	// Move and remove the indexed files first so that updates of the same
+	// paths only add the files that are new
+	for _, e := range events {
+		switch e.Op {
+		case fs.Remove:
+			w.removeAll(e.Path)
+		case fs.Rename:
+			paths = append(paths, e.OldPath)
+			info, err := os.Stat(e.Path)
+			if err != nil {
+				// Renamed again or removed since
+				w.removeAll(e.OldPath)
+				continue
+			}
+			if w.rename(e.OldPath, e.Path, info) {
+				added = true
+			}
+		default:
+			continue
+		}
+		paths = append(paths, e.Path)
+	}
+	<-w.cfg.DB.CommitBarrier()
+
+	for _, e := range events {
+		if e.Op != fs.Update && e.Op != fs.Rename || !isSidecar(e.Path) {
+			continue
+		}
+		if w.syncXmp(e.Path) {
+			paths = append(paths, e.Path)
+		}
+	}
+
+	for _, e := range events {
+		if e.Op != fs.Update {
+			continue
+		}
+		info, err := os.Stat(e.Path)
+		if err != nil {
+			continue
+		}
+		if w.add(e.Path, info) {
+			added = true
+		}
+		paths = append(paths, e.Path)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	<-w.cfg.DB.CommitBarrier()
+
+	log.Printf("watch indexed %d changes\n", len(paths))
+	if w.onChange != nil {
+		w.onChange(paths, added)
+	}
+}
+
+func isSidecar(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".xmp")
+}
+
+// syncXmp reads the XMP sidecar at the path again for the indexed files it
+// belongs to, if they are synced with XMP, returning true if there were any.
+func (w *Watcher) syncXmp(sidecar string) bool {
+	if w.cfg.SyncXmp == nil || !w.cfg.EnableTags {
+		return false
+	}
+	synced := false
+	for _, path := range sidecarFiles(sidecar) {
+		id, ok := w.cfg.DB.GetIdFromPath(path)
+		if !ok || !w.cfg.SyncXmp(path) {
+			continue
+		}
+		if err := SyncXmpFile(w.cfg.DB, id, path); err != nil {
+			log.Printf("watch unable to sync xmp of %s: %s\n", path, err.Error())
+			continue
+		}
+		synced = true
+	}
+	return synced
+}
+
+// sidecarFiles returns the paths of the files that can have the sidecar,
+// e.g. photo.jpg for photo.jpg.xmp, or photo.jpg and photo.cr2 for
+// photo.xmp, see xmp.SidecarPaths.
+func sidecarFiles(sidecar string) []string {
+	base := strings.TrimSuffix(sidecar, filepath.Ext(sidecar))
+	paths := make([]string, 0)
+	if filepath.Ext(base) != "" {
+		paths = append(paths, base)
+	}
+	dir := filepath.Dir(sidecar)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return paths
+	}
+	stem := filepath.Base(base)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || isSidecar(name) || strings.TrimSuffix(name, filepath.Ext(name)) != stem {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths
+}
+
+// Close stops watching, waiting for the changes in progress to be indexed.
+func (w *Watcher) Close() {
+	if w == nil {
+		return
+	}
+	w.fsw.Close()
+	<-w.done
+}
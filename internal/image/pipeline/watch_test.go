@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photofield/internal/fs"
+)
+
+func TestDebounceEvents(t *testing.T) {
+	events := make(chan fs.Event)
+	batches := make(chan int, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		debounceEvents(events, 50*time.Millisecond, time.Second, func(batch []fs.Event) {
+			batches <- len(batch)
+		})
+	}()
+
+	for i := 0; i < 3; i++ {
+		events <- fs.Event{Op: fs.Update, Path: "a.jpg"}
+	}
+	select {
+	case n := <-batches:
+		if n != 3 {
+			t.Errorf("expected a batch of 3 events, got %d", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the batch to be applied after the debounce interval")
+	}
+	close(events)
+	<-done
+}
+
+func TestDebounceEventsMaxWait(t *testing.T) {
+	events := make(chan fs.Event)
+	batches := make(chan int, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		debounceEvents(events, 100*time.Millisecond, 300*time.Millisecond, func(batch []fs.Event) {
+			batches <- len(batch)
+		})
+	}()
+
+	// A steady trickle of events that never pauses for the debounce interval
+	start := time.Now()
+	for time.Since(start) < time.Second {
+		events <- fs.Event{Op: fs.Update, Path: "a.jpg"}
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(events)
+	<-done
+
+	if len(batches) == 0 {
+		t.Fatal("expected batches to be applied within the max wait")
+	}
+}
+
+func TestEventWatcherClose(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newEventWatcher([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.jpg", i))
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close while the events are still delayed and nobody is reading them
+	time.Sleep(250 * time.Millisecond)
+	w.Close()
+	w.Close()
+	for range w.Events {
+	}
+}
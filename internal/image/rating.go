@@ -0,0 +1,148 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"photofield/internal/search"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// MaxRating is the highest star rating of a file.
+const MaxRating = 5
+
+// metadataKeys are the search qualifiers of the ratings, labels and
+// favorites of files.
+var metadataKeys = []string{"rating", "label", "is"}
+
+// FileMetadata is the rating, color label and favorite flag of a file.
+type FileMetadata struct {
+	Rating   int
+	Label    string
+	Favorite bool
+}
+
+// ClampRating returns the rating limited to the range from 0 to MaxRating,
+// e.g. rejected files rated -1 by some photo managers become unrated.
+func ClampRating(rating int) int {
+	return max(0, min(rating, MaxRating))
+}
+
+// hasMetadataCondition returns true if the condition filters files by their
+// ratings, labels or favorites.
+func hasMetadataCondition(cond *search.Condition) bool {
+	for _, key := range metadataKeys {
+		if len(cond.Leaves(key)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFileMetadata returns the rating, color label and favorite flag of the
+// file.
+func (source *Database) GetFileMetadata(id ImageId) (FileMetadata, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT coalesce(rating, 0), coalesce(label, ''), favorite
+		FROM infos
+		WHERE id = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, int64(id))
+	exists, err := stmt.Step()
+	if err != nil {
+		log.Printf("Error getting file metadata %d: %s\n", id, err.Error())
+		return FileMetadata{}, false
+	}
+	if !exists {
+		return FileMetadata{}, false
+	}
+	return FileMetadata{
+		Rating:   stmt.ColumnInt(0),
+		Label:    stmt.ColumnText(1),
+		Favorite: stmt.ColumnBool(2),
+	}, true
+}
+
+// SetRating sets the rating of the files from 1 to MaxRating, or 0 to unrate
+// them.
+func (source *Database) SetRating(ids Ids, rating int) error {
+	if rating < 0 || rating > MaxRating {
+		return fmt.Errorf("invalid rating %d, expected 0 to %d", rating, MaxRating)
+	}
+	return source.updateInfoRanges("set_rating", "rating", ids, func(stmt *sqlite.Stmt) {
+		if rating == 0 {
+			stmt.BindNull(1)
+		} else {
+			stmt.BindInt64(1, int64(rating))
+		}
+	})
+}
+
+// SetLabel sets the color label of the files, one of search.LabelValues, or
+// an empty label to remove it.
+func (source *Database) SetLabel(ids Ids, label string) error {
+	return source.updateInfoRanges("set_label", "label", ids, func(stmt *sqlite.Stmt) {
+		if label == "" {
+			stmt.BindNull(1)
+		} else {
+			stmt.BindText(1, label)
+		}
+	})
+}
+
+// SetFavorite marks or unmarks the files as favorites.
+func (source *Database) SetFavorite(ids Ids, favorite bool) error {
+	return source.updateInfoRanges("set_favorite", "favorite", ids, func(stmt *sqlite.Stmt) {
+		stmt.BindBool(1, favorite)
+	})
+}
+
+// updateInfoRanges sets the column of the files to the value bound by bind,
+// updating each contiguous range of ids with a single statement.
+func (source *Database) updateInfoRanges(savepoint string, column string, ids Ids, bind func(stmt *sqlite.Stmt)) error {
+	err := source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		if err := sqlitex.Execute(conn, "SAVEPOINT "+savepoint+";", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO "+savepoint+";", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE "+savepoint+";", nil)
+		}()
+
+		stmt := conn.Prep(`
+			UPDATE infos
+			SET ` + column + ` = ?
+			WHERE id BETWEEN ? AND ?;`)
+		defer stmt.Reset()
+
+		// Drain all ranges, so that the channel is not left blocked on errors
+		for r := range ids.RangeChan() {
+			if err != nil {
+				continue
+			}
+			bind(stmt)
+			stmt.BindInt64(2, int64(r.Low))
+			stmt.BindInt64(3, int64(r.High))
+			if _, err = stmt.Step(); err != nil {
+				continue
+			}
+			err = stmt.Reset()
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	source.metadataUpdatedAt.Store(toUnixMs(time.Now()))
+	return nil
+}
@@ -0,0 +1,147 @@
+package image
+
+import (
+	"context"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// SavedSearch is a named search query, optionally scoped to the dirs of a
+// single collection.
+type SavedSearch struct {
+	Id           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Search       string    `json:"search"`
+	CollectionId string    `json:"collection_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func readSavedSearch(stmt *sqlite.Stmt) SavedSearch {
+	return SavedSearch{
+		Id:           stmt.ColumnInt64(0),
+		Name:         stmt.ColumnText(1),
+		Search:       stmt.ColumnText(2),
+		CollectionId: stmt.ColumnText(3),
+		CreatedAt:    fromUnixMs(stmt.ColumnInt64(4)),
+		UpdatedAt:    fromUnixMs(stmt.ColumnInt64(5)),
+	}
+}
+
+func bindSavedSearch(stmt *sqlite.Stmt, s SavedSearch) {
+	stmt.BindText(1, s.Name)
+	stmt.BindText(2, s.Search)
+	if s.CollectionId == "" {
+		stmt.BindNull(3)
+	} else {
+		stmt.BindText(3, s.CollectionId)
+	}
+	stmt.BindInt64(4, toUnixMs(s.UpdatedAt))
+}
+
+func (source *Database) AddSavedSearch(s SavedSearch) (SavedSearch, error) {
+	now := fromUnixMs(toUnixMs(time.Now()))
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		INSERT INTO saved_search(name, search, collection_id, updated_at_ms, created_at_ms)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id;`)
+		defer stmt.Reset()
+
+		bindSavedSearch(stmt, s)
+		stmt.BindInt64(5, toUnixMs(s.CreatedAt))
+
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		s.Id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return s, err
+}
+
+func (source *Database) UpdateSavedSearch(s SavedSearch) (SavedSearch, error) {
+	s.UpdatedAt = fromUnixMs(toUnixMs(time.Now()))
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE saved_search
+		SET name = ?, search = ?, collection_id = ?, updated_at_ms = ?
+		WHERE id = ?
+		RETURNING created_at_ms;`)
+		defer stmt.Reset()
+
+		bindSavedSearch(stmt, s)
+		stmt.BindInt64(5, s.Id)
+
+		exists, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		s.CreatedAt = fromUnixMs(stmt.ColumnInt64(0))
+		return nil
+	})
+	return s, err
+}
+
+func (source *Database) DeleteSavedSearch(id int64) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		DELETE FROM saved_search
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (source *Database) GetSavedSearch(id int64) (SavedSearch, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, search, coalesce(collection_id, ''), created_at_ms, updated_at_ms
+	FROM saved_search
+	WHERE id = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return SavedSearch{}, false
+	}
+	return readSavedSearch(stmt), true
+}
+
+func (source *Database) ListSavedSearches() []SavedSearch {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, search, coalesce(collection_id, ''), created_at_ms, updated_at_ms
+	FROM saved_search
+	ORDER BY name ASC, id ASC;`)
+	defer stmt.Reset()
+
+	searches := make([]SavedSearch, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil || !exists {
+			break
+		}
+		searches = append(searches, readSavedSearch(stmt))
+	}
+	return searches
+}
@@ -44,7 +44,12 @@ func (h *knnImageHeap) Pop() interface{} {
 	return x
 }
 
-func (source *Source) classifyEmbedding(timgs []taggedImage, emb ai.Embedding, k int) (tag.Id, float32, error) {
+// classifyEmbedding returns the tag most of the k most similar tagged images
+// have, along with its confidence, the sum of the similarities of the images
+// with the tag divided by k. Ties are broken by the tag of the most similar
+// image, then by the smallest tag id, so that the result does not depend on
+// the order of the images.
+func classifyEmbedding(timgs []taggedImage, emb ai.Embedding, k int) (tag.Id, float32, error) {
 	topk := make(knnImageHeap, 0, k)
 	for _, timg := range timgs {
 		dot, err := ai.DotProductFloat32Float(timg.Emb, emb.Float())
@@ -68,24 +73,35 @@ func (source *Source) classifyEmbedding(timgs []taggedImage, emb ai.Embedding, k
 			})
 		}
 	}
-	topTagId := tag.Id(0)
-	topTagCount := 0
-	meanDist := float32(0)
+	if len(topk) == 0 {
+		return 0, 0, nil
+	}
+	counts := make(map[tag.Id]int, len(topk))
+	sims := make(map[tag.Id]float32, len(topk))
+	minDists := make(map[tag.Id]float32, len(topk))
 	for _, t := range topk {
-		meanDist += t.dist
-		if t.id == topTagId {
-			topTagCount++
-		} else {
-			topTagId = t.id
-			topTagCount = 1
+		counts[t.id]++
+		sims[t.id] += 1 - t.dist
+		if d, ok := minDists[t.id]; !ok || t.dist < d {
+			minDists[t.id] = t.dist
 		}
 	}
-	if len(topk) > 0 {
-		meanDist /= float32(len(topk))
+	topTagId := tag.Id(0)
+	for id, count := range counts {
+		top := counts[topTagId]
+		switch {
+		case topTagId == 0,
+			count > top,
+			count == top && minDists[id] < minDists[topTagId],
+			count == top && minDists[id] == minDists[topTagId] && id < topTagId:
+			topTagId = id
+		}
 	}
-	return topTagId, meanDist, nil
+	return topTagId, sims[topTagId] / float32(k), nil
 }
 
+// ListKnn lists the files whose k most similar tagged images mostly have one
+// of the searched tags, as classified by classifyEmbedding.
 func (source *Source) ListKnn(dirs []string, options ListOptions) <-chan SourcedInfo {
 	out := make(chan SourcedInfo, 1000)
 	go func() {
@@ -146,7 +162,7 @@ func (source *Source) ListKnn(dirs []string, options ListOptions) <-chan Sourced
 		done := metrics.Elapsed("list knn embeddings")
 		embeddings := source.database.ListEmbeddings(dirs, options)
 		for emb := range embeddings {
-			topTagId, _, err := source.classifyEmbedding(timgs, emb, k)
+			topTagId, _, err := classifyEmbedding(timgs, emb, k)
 			if err != nil {
 				log.Printf("Unable to classify embedding for %d: %s", emb.Id, err.Error())
 				continue
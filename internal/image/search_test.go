@@ -0,0 +1,80 @@
+package image
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"photofield/internal/ai"
+	"photofield/internal/tag"
+
+	"github.com/x448/float16"
+)
+
+func testEmbedding(v ...float32) ai.Embedding {
+	norm := float32(0)
+	for _, f := range v {
+		norm += f * f
+	}
+	invnorm := float32(1 / math.Sqrt(float64(norm)))
+	bytes := make([]byte, 2*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint16(bytes[2*i:], float16.Fromfloat32(f).Bits())
+	}
+	return ai.FromRaw(bytes, float16.Fromfloat32(invnorm).Bits())
+}
+
+func TestClassifyEmbedding(t *testing.T) {
+	timg := func(id tag.Id, v ...float32) taggedImage {
+		emb := testEmbedding(v...)
+		return taggedImage{
+			TagId:      id,
+			Emb:        emb.Float32(),
+			EmbInvNorm: emb.InvNormFloat32(),
+			Weight:     1,
+		}
+	}
+	testCases := []struct {
+		name  string
+		timgs []taggedImage
+		emb   []float32
+		k     int
+		want  tag.Id
+	}{
+		{
+			name:  "most images",
+			timgs: []taggedImage{timg(1, 1, 0), timg(2, 0.9, 0.1), timg(2, 0.8, 0.2)},
+			emb:   []float32{1, 0},
+			k:     3,
+			want:  2,
+		},
+		{
+			name:  "tie by most similar image",
+			timgs: []taggedImage{timg(2, 0, 1), timg(1, 1, 0.2), timg(2, 1, 0), timg(1, 0.2, 1)},
+			emb:   []float32{1, 0},
+			k:     4,
+			want:  2,
+		},
+		{
+			name:  "tie by tag id",
+			timgs: []taggedImage{timg(3, 1, 0), timg(2, 0, 1)},
+			emb:   []float32{1, 1},
+			k:     2,
+			want:  2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Map iteration order is random, so repeat to catch flaky ties
+			for i := 0; i < 20; i++ {
+				got, _, err := classifyEmbedding(tc.timgs, testEmbedding(tc.emb...), tc.k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tc.want {
+					t.Fatalf("tag %d, want %d", got, tc.want)
+				}
+			}
+		})
+	}
+}
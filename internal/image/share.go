@@ -0,0 +1,151 @@
+package image
+
+import (
+	"context"
+	"time"
+
+	"photofield/internal/auth"
+
+	"zombiezen.com/go/sqlite"
+)
+
+func readShare(stmt *sqlite.Stmt) auth.Share {
+	share := auth.Share{
+		Id:           stmt.ColumnInt64(0),
+		CollectionId: stmt.ColumnText(1),
+		Search:       stmt.ColumnText(2),
+		TagId:        stmt.ColumnText(3),
+		PasswordHash: stmt.ColumnText(4),
+		Download:     stmt.ColumnBool(5),
+		CreatedAt:    fromUnixMs(stmt.ColumnInt64(6)),
+		ExpiresAt:    fromUnixMs(stmt.ColumnInt64(7)),
+	}
+	share.Password = share.PasswordHash != ""
+	return share
+}
+
+// AddShare adds the share with the hash of its token, removing the expired
+// ones.
+func (source *Database) AddShare(share auth.Share, tokenHash []byte) (auth.Share, error) {
+	now := time.Now()
+	share.CreatedAt = fromUnixMs(toUnixMs(now))
+	share.ExpiresAt = fromUnixMs(toUnixMs(share.ExpiresAt))
+	share.Password = share.PasswordHash != ""
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		expired := conn.Prep(`
+		DELETE FROM share
+		WHERE expires_at_ms <= ?;`)
+		defer expired.Reset()
+
+		expired.BindInt64(1, toUnixMs(now))
+		if _, err := expired.Step(); err != nil {
+			return err
+		}
+
+		stmt := conn.Prep(`
+		INSERT INTO share(token_hash, collection_id, search, tag_id, password_hash, download, created_at_ms, expires_at_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;`)
+		defer stmt.Reset()
+
+		stmt.BindBytes(1, tokenHash)
+		stmt.BindText(2, share.CollectionId)
+		stmt.BindText(3, share.Search)
+		stmt.BindText(4, share.TagId)
+		stmt.BindText(5, share.PasswordHash)
+		stmt.BindBool(6, share.Download)
+		stmt.BindInt64(7, toUnixMs(share.CreatedAt))
+		stmt.BindInt64(8, toUnixMs(share.ExpiresAt))
+
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		share.Id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return share, err
+}
+
+func (source *Database) DeleteShare(id int64) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		DELETE FROM share
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ListShares returns the unexpired shares.
+func (source *Database) ListShares() []auth.Share {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, collection_id, search, tag_id, password_hash, download, created_at_ms, expires_at_ms
+	FROM share
+	WHERE expires_at_ms > ?
+	ORDER BY id ASC;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, toUnixMs(time.Now()))
+
+	shares := make([]auth.Share, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil || !exists {
+			break
+		}
+		shares = append(shares, readShare(stmt))
+	}
+	return shares
+}
+
+// GetShare returns the unexpired share with the hash of its token.
+func (source *Database) GetShare(tokenHash []byte) (auth.Share, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, collection_id, search, tag_id, password_hash, download, created_at_ms, expires_at_ms
+	FROM share
+	WHERE token_hash = ? AND expires_at_ms > ?;`)
+	defer stmt.Reset()
+
+	stmt.BindBytes(1, tokenHash)
+	stmt.BindInt64(2, toUnixMs(time.Now()))
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.Share{}, false
+	}
+	return readShare(stmt), true
+}
+
+// GetShareById returns the unexpired share with the id.
+func (source *Database) GetShareById(id int64) (auth.Share, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, collection_id, search, tag_id, password_hash, download, created_at_ms, expires_at_ms
+	FROM share
+	WHERE id = ? AND expires_at_ms > ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+	stmt.BindInt64(2, toUnixMs(time.Now()))
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.Share{}, false
+	}
+	return readShare(stmt), true
+}
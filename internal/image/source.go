@@ -14,6 +14,7 @@ import (
 	goio "io"
 
 	"photofield/internal/ai"
+	"photofield/internal/auth"
 	"photofield/internal/geo"
 	"photofield/internal/io"
 	"photofield/internal/io/djpeg"
@@ -31,6 +32,7 @@ import (
 var ErrNotFound = errors.New("not found")
 var ErrNotAnImage = errors.New("not a supported image extension, might be video")
 var ErrUnavailable = errors.New("unavailable")
+var ErrExists = errors.New("already exists")
 
 type ImageId uint32
 
@@ -88,6 +90,7 @@ func (config *CacheConfig) MaxSizeBytes() int64 {
 
 type Caches struct {
 	Image CacheConfig `json:"image"`
+	Tiles CacheConfig `json:"tiles"`
 }
 
 func (config *Config) MaxFaceFileSizeBytes() int64 {
@@ -111,13 +114,19 @@ type Config struct {
 	DjpegPath    string `json:"djpeg_path"`
 	ExifToolPath string `json:"exif_tool_path"`
 
-	ExifToolCount        int    `json:"exif_tool_count"`
-	SkipLoadInfo         bool   `json:"skip_load_info"`
-	SkipCollectionCounts bool   `json:"skip_collection_counts"`
-	ConcurrentMetaLoads  int    `json:"concurrent_meta_loads"`
-	ConcurrentColorLoads int    `json:"concurrent_color_loads"`
-	ConcurrentAILoads    int    `json:"concurrent_ai_loads"`
-	MaxFaceFileSize      string `json:"max_face_file_size"`
+	ExifToolCount        int     `json:"exif_tool_count"`
+	SkipLoadInfo         bool    `json:"skip_load_info"`
+	SkipCollectionCounts bool    `json:"skip_collection_counts"`
+	ConcurrentMetaLoads  int     `json:"concurrent_meta_loads"`
+	ConcurrentColorLoads int     `json:"concurrent_color_loads"`
+	ConcurrentAILoads    int     `json:"concurrent_ai_loads"`
+	MaxFaceFileSize      string  `json:"max_face_file_size"`
+	ContentHash          bool    `json:"content_hash"`
+	DuplicateSimilarity  float32 `json:"duplicate_similarity"`
+	PersonSimilarity     float32 `json:"person_similarity"`
+	PersonMinFaces       int     `json:"person_min_faces"`
+
+	EmbeddingIndex EmbeddingIndexConfig `json:"embedding_index"`
 
 	ListExtensions []string        `json:"extensions"`
 	DateFormats    []string        `json:"date_formats"`
@@ -162,9 +171,17 @@ func NewSource(config Config, migrations embed.FS, geo *geo.Geo) *Source {
 	source := Source{}
 	source.Config = config
 	source.database = NewDatabase(filepath.Join(config.DataDir, "photofield.cache.db"), migrations)
+	source.database.EnableEmbeddingIndexes(config.EmbeddingIndex)
+	go source.database.LoadEmbeddingIndexes()
 	source.imageInfoCache = newInfoCache()
 	source.pathCache = newPathCache()
+	source.database.HandleRelinks(func(ids []ImageId) {
+		for _, id := range ids {
+			source.pathCache.Delete(id)
+		}
+	})
 	source.Geo = geo
+	source.database.EnablePlaces(geo)
 
 	source.SourceLatencyHistogram = metrics.AddHistogram(
 		"source_latency",
@@ -290,10 +307,28 @@ func (source *Source) HandleDirUpdates(fn DirsFunc) {
 	source.database.HandleDirUpdates(fn)
 }
 
+func (source *Source) HandleTagUpdates(fn TagFunc) {
+	source.database.HandleTagUpdates(fn)
+}
+
 func (source *Source) Vacuum() error {
 	return source.database.vacuum()
 }
 
+// Backup writes a consistent copy of the database to the path while it is
+// in use.
+func (source *Source) Backup(path string) error {
+	return source.database.Backup(path)
+}
+
+func (source *Source) ExportUserData(w goio.Writer) (UserDataStats, error) {
+	return source.database.ExportUserData(w)
+}
+
+func (source *Source) ImportUserData(r goio.Reader) (UserDataStats, error) {
+	return source.database.ImportUserData(r)
+}
+
 func (source *Source) Close() {
 	source.decoder.Close()
 	source.database.Close()
@@ -384,6 +419,10 @@ func (source *Source) ListFaces(dirs []string, options ListOptions) <-chan FaceI
 	return source.database.ListFaces(dirs, options)
 }
 
+func (source *Source) ListDuplicates(dirs []string) map[ImageId]Duplicate {
+	return source.database.ListDuplicates(dirs)
+}
+
 func (source *Source) GetDir(dir string) Info {
 	if source == nil {
 		return Info{}
@@ -398,6 +437,17 @@ func (source *Source) GetDirsCount(dirs []string) int {
 	return count
 }
 
+// HasReader returns true if the source with the name can be read by
+// GetImageReader.
+func (source *Source) HasReader(sourceName string) bool {
+	for _, s := range source.Sources {
+		if _, ok := s.(io.Reader); ok && s.Name() == sourceName {
+			return true
+		}
+	}
+	return false
+}
+
 func (source *Source) GetImageReader(id ImageId, sourceName string, fn func(r goio.ReadSeeker, err error)) {
 	ctx := context.TODO()
 	path, err := source.GetImagePath(id)
@@ -463,6 +513,34 @@ func (source *Source) ListTagsOfTag(id tag.Id, limit int) <-chan tag.Tag {
 	return source.database.ListTagsOfTag(id, limit)
 }
 
+func (source *Source) ListTagChildren(id tag.Id, limit int) []tag.Tag {
+	return source.database.ListTagChildren(id, limit)
+}
+
+func (source *Source) ListTagTree(id tag.Id) []tag.Tag {
+	return source.database.ListTagTree(id)
+}
+
+func (source *Source) MoveTag(t tag.Tag, name string) (tag.Tag, error) {
+	return source.database.MoveTag(t, name)
+}
+
+func (source *Source) GetFileMetadata(id ImageId) (FileMetadata, bool) {
+	return source.database.GetFileMetadata(id)
+}
+
+func (source *Source) SetRating(ids Ids, rating int) error {
+	return source.database.SetRating(ids, rating)
+}
+
+func (source *Source) SetLabel(ids Ids, label string) error {
+	return source.database.SetLabel(ids, label)
+}
+
+func (source *Source) SetFavorite(ids Ids, favorite bool) error {
+	return source.database.SetFavorite(ids, favorite)
+}
+
 func (source *Source) AddTagIds(id tag.Id, ids Ids) time.Time {
 	return source.database.AddTagIds(id, ids)
 }
@@ -475,6 +553,138 @@ func (source *Source) InvertTagIds(id tag.Id, ids Ids) time.Time {
 	return source.database.InvertTagIds(id, ids)
 }
 
+func (source *Source) AddSavedSearch(s SavedSearch) (SavedSearch, error) {
+	return source.database.AddSavedSearch(s)
+}
+
+func (source *Source) UpdateSavedSearch(s SavedSearch) (SavedSearch, error) {
+	return source.database.UpdateSavedSearch(s)
+}
+
+func (source *Source) DeleteSavedSearch(id int64) error {
+	return source.database.DeleteSavedSearch(id)
+}
+
+func (source *Source) GetSavedSearch(id int64) (SavedSearch, bool) {
+	return source.database.GetSavedSearch(id)
+}
+
+func (source *Source) ListSavedSearches() []SavedSearch {
+	return source.database.ListSavedSearches()
+}
+
+func (source *Source) AddUser(name string, passwordHash string, role auth.Role) (auth.User, error) {
+	return source.database.AddUser(name, passwordHash, role)
+}
+
+func (source *Source) SetUserRole(id int64, role auth.Role) error {
+	return source.database.SetUserRole(id, role)
+}
+
+func (source *Source) SetUserPassword(id int64, passwordHash string) error {
+	return source.database.SetUserPassword(id, passwordHash)
+}
+
+func (source *Source) DeleteUser(id int64) error {
+	return source.database.DeleteUser(id)
+}
+
+func (source *Source) GetUser(id int64) (auth.User, bool) {
+	return source.database.GetUser(id)
+}
+
+func (source *Source) GetUserByName(name string) (auth.User, string, bool) {
+	return source.database.GetUserByName(name)
+}
+
+func (source *Source) ListUsers() []auth.User {
+	return source.database.ListUsers()
+}
+
+func (source *Source) AddUserSession(userId int64, tokenHash []byte, expiresAt time.Time) error {
+	return source.database.AddUserSession(userId, tokenHash, expiresAt)
+}
+
+func (source *Source) DeleteUserSession(tokenHash []byte) error {
+	return source.database.DeleteUserSession(tokenHash)
+}
+
+func (source *Source) GetSessionUser(tokenHash []byte) (auth.User, bool) {
+	return source.database.GetSessionUser(tokenHash)
+}
+
+func (source *Source) AddUserToken(userId int64, name string, tokenHash []byte) (auth.Token, error) {
+	return source.database.AddUserToken(userId, name, tokenHash)
+}
+
+func (source *Source) DeleteUserToken(userId int64, id int64) error {
+	return source.database.DeleteUserToken(userId, id)
+}
+
+func (source *Source) ListUserTokens(userId int64) []auth.Token {
+	return source.database.ListUserTokens(userId)
+}
+
+func (source *Source) GetTokenUser(tokenHash []byte) (auth.User, bool) {
+	return source.database.GetTokenUser(tokenHash)
+}
+
+func (source *Source) AddShare(share auth.Share, tokenHash []byte) (auth.Share, error) {
+	return source.database.AddShare(share, tokenHash)
+}
+
+func (source *Source) DeleteShare(id int64) error {
+	return source.database.DeleteShare(id)
+}
+
+func (source *Source) ListShares() []auth.Share {
+	return source.database.ListShares()
+}
+
+func (source *Source) GetShare(tokenHash []byte) (auth.Share, bool) {
+	return source.database.GetShare(tokenHash)
+}
+
+func (source *Source) GetShareById(id int64) (auth.Share, bool) {
+	return source.database.GetShareById(id)
+}
+
+func (source *Source) ListPersons(hidden bool) []Person {
+	return source.database.ListPersons(hidden)
+}
+
+func (source *Source) GetPerson(id int64) (Person, bool) {
+	return source.database.GetPerson(id)
+}
+
+func (source *Source) UpdatePerson(p Person) (Person, error) {
+	return source.database.UpdatePerson(p)
+}
+
+func (source *Source) MergePersons(id int64, others []int64) (Person, error) {
+	return source.database.MergePersons(id, others)
+}
+
+func (source *Source) SplitPerson(id int64, faceIds []int64) (Person, error) {
+	return source.database.SplitPerson(id, faceIds)
+}
+
+func (source *Source) ListPersonFaces(id int64) []FaceInfo {
+	return source.database.ListPersonFaces(id)
+}
+
+func (source *Source) ListTaskRuns(options TaskRunListOptions) []TaskRun {
+	return source.database.ListTaskRuns(options)
+}
+
+func (source *Source) GetTaskRun(id int64) (TaskRun, bool) {
+	return source.database.GetTaskRun(id)
+}
+
+func (source *Source) ListTaskErrors(runId int64) []TaskError {
+	return source.database.ListTaskErrors(runId)
+}
+
 func (source *Source) IdChanToIds(ch <-chan ImageId) Ids {
 	ids := NewIds()
 	for id := range ch {
@@ -507,6 +717,28 @@ func (source *Source) GetTagImageIds(id tag.Id) Ids {
 	return source.database.GetTagImageIds(id)
 }
 
+func (source *Source) SetTagAuto(t tag.Tag, auto bool) error {
+	return source.database.SetTagAuto(t, auto)
+}
+
+func (source *Source) ListTagSuggestions(name string, limit int) []TagSuggestion {
+	return source.database.ListTagSuggestions(name, limit)
+}
+
+// GetTagSuggestionIds returns the files the tag is suggested for with at
+// least the confidence.
+func (source *Source) GetTagSuggestionIds(name string, minConfidence float32) Ids {
+	return source.database.GetTagSuggestionIds(name, minConfidence, false)
+}
+
+func (source *Source) AcceptTagSuggestions(t tag.Tag, ids Ids) error {
+	return source.database.AcceptTagSuggestions(t, ids)
+}
+
+func (source *Source) RejectTagSuggestions(t tag.Tag, ids Ids) error {
+	return source.database.RejectTagSuggestions(t, ids)
+}
+
 // WriteDummyFiles creates dummy database entries for testing purposes
 func (source *Source) WriteDummyFiles(count int, seed int64) error {
 	rng := rand.New(rand.NewSource(seed))
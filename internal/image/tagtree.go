@@ -0,0 +1,362 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"photofield/internal/tag"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// tagParentNameSQL selects the name of the parent of the tag.
+const tagParentNameSQL = `(
+		SELECT parent.name
+		FROM tag AS parent
+		WHERE parent.id = tag.parent_id
+	)`
+
+// tagSubtreeSQL returns the condition matching the tag with the name in the
+// param and all of its descendants, e.g. places/europe/slovenia for
+// places/europe. The descendants are matched by the range of names between
+// the separator and the character after it, so that the index on the name
+// can be used.
+func tagSubtreeSQL(param string) string {
+	after := string(rune(tag.Separator[0] + 1))
+	return `(name = ` + param + ` OR (name >= ` + param + ` || '` + tag.Separator + `' AND name < ` + param + ` || '` + after + `'))`
+}
+
+func bindTagParentId(stmt *sqlite.Stmt, param int, id tag.Id) {
+	if id == 0 {
+		stmt.BindNull(param)
+		return
+	}
+	stmt.BindInt64(param, int64(id))
+}
+
+// addTagParents returns the id of the parent of the tag with the name,
+// adding the parent and its ancestors if they do not exist yet. Returns 0
+// if the tag has no parent.
+func addTagParents(conn *sqlite.Conn, name string) (tag.Id, error) {
+	parent, ok := tag.ParentName(name)
+	if !ok {
+		return 0, nil
+	}
+
+	get := conn.Prep(`
+		SELECT id
+		FROM tag
+		WHERE name = ? AND active = true;`)
+	get.BindText(1, parent)
+	exists, err := get.Step()
+	if err != nil {
+		get.Reset()
+		return 0, err
+	}
+	id := tag.Id(get.ColumnInt64(0))
+	if err := get.Reset(); err != nil {
+		return 0, err
+	}
+	if exists {
+		return id, nil
+	}
+
+	grandparentId, err := addTagParents(conn, parent)
+	if err != nil {
+		return 0, err
+	}
+
+	insert := conn.Prep(`
+		INSERT INTO tag(name, updated_at_ms, parent_id)
+		VALUES (?, ?, ?)
+		RETURNING id;`)
+	insert.BindText(1, parent)
+	insert.BindInt64(2, toUnixMs(time.Now()))
+	bindTagParentId(insert, 3, grandparentId)
+	if _, err := insert.Step(); err != nil {
+		insert.Reset()
+		return 0, err
+	}
+	id = tag.Id(insert.ColumnInt64(0))
+	if err := insert.Reset(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// linkTagParents links the hierarchical tags added before tags had parents
+// to their parents.
+func (source *Database) linkTagParents() error {
+	return source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		names := make([]string, 0)
+		err = sqlitex.Execute(conn, `
+			SELECT name
+			FROM tag
+			WHERE active = true AND parent_id IS NULL AND instr(name, ?) > 1;`,
+			&sqlitex.ExecOptions{
+				Args: []any{tag.Separator},
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					names = append(names, stmt.ColumnText(0))
+					return nil
+				},
+			})
+		if err != nil || len(names) == 0 {
+			return err
+		}
+
+		if err := sqlitex.Execute(conn, "SAVEPOINT link_tag_parents;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO link_tag_parents;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE link_tag_parents;", nil)
+		}()
+
+		for _, name := range names {
+			if err := setTagParent(conn, name); err != nil {
+				return err
+			}
+		}
+		log.Printf("linked %d hierarchical tags to their parents\n", len(names))
+		return nil
+	})
+}
+
+// setTagParent links all versions of the tag with the name to its parent,
+// adding the parent if it does not exist yet.
+func setTagParent(conn *sqlite.Conn, name string) error {
+	parentId, err := addTagParents(conn, name)
+	if err != nil {
+		return err
+	}
+	stmt := conn.Prep(`
+		UPDATE tag
+		SET parent_id = ?
+		WHERE name = ?;`)
+	defer stmt.Reset()
+
+	bindTagParentId(stmt, 1, parentId)
+	stmt.BindText(2, name)
+	_, err = stmt.Step()
+	return err
+}
+
+// MoveTag renames the tag and all of its descendants, moving them under the
+// parent of the new name, e.g. moving places/slovenia/ljubljana to
+// places/europe/slovenia/ljubljana when renaming places/slovenia to
+// places/europe/slovenia. The suggestions of the tags move along with them.
+func (source *Database) MoveTag(t tag.Tag, name string) (tag.Tag, error) {
+	if name == t.Name {
+		return t, nil
+	}
+	if strings.HasPrefix(name, t.Name+tag.Separator) {
+		return tag.Tag{}, fmt.Errorf("unable to move tag %s under itself", t.Name)
+	}
+	err := source.writeDirect(func(conn *sqlite.Conn) (err error) {
+		exists := false
+		err = sqlitex.Execute(conn, `
+			SELECT 1
+			FROM tag
+			WHERE `+tagSubtreeSQL("?1")+`
+			LIMIT 1;`,
+			&sqlitex.ExecOptions{
+				Args: []any{name},
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					exists = true
+					return nil
+				},
+			})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrExists
+		}
+
+		if err := sqlitex.Execute(conn, "SAVEPOINT move_tag;", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				sqlitex.Execute(conn, "ROLLBACK TO move_tag;", nil)
+			}
+			sqlitex.Execute(conn, "RELEASE move_tag;", nil)
+		}()
+
+		// Rename all versions, so that they stay versions of the same tag
+		rename := conn.Prep(`
+			UPDATE tag
+			SET
+				name = :new || substr(name, length(:old) + 1),
+				updated_at_ms = CASE WHEN active THEN :now ELSE updated_at_ms END
+			WHERE ` + tagSubtreeSQL(":old") + `;`)
+		defer rename.Reset()
+
+		renameSuggestions := conn.Prep(`
+			UPDATE OR REPLACE tag_suggestion
+			SET tag_name = :new || substr(tag_name, length(:old) + 1)
+			WHERE tag_name = :old OR substr(tag_name, 1, length(:old) + 1) = :old || '` + tag.Separator + `';`)
+		defer renameSuggestions.Reset()
+
+		now := toUnixMs(time.Now())
+		moved := []string{name}
+		for _, prefix := range []string{"", SuggestPrefix} {
+			rename.SetText(":new", prefix+name)
+			rename.SetText(":old", prefix+t.Name)
+			rename.SetInt64(":now", now)
+			if _, err := rename.Step(); err != nil {
+				return err
+			}
+			if prefix != "" && conn.Changes() > 0 {
+				moved = append(moved, prefix+name)
+			}
+			if err := rename.Reset(); err != nil {
+				return err
+			}
+		}
+
+		renameSuggestions.SetText(":new", name)
+		renameSuggestions.SetText(":old", t.Name)
+		if _, err := renameSuggestions.Step(); err != nil {
+			return err
+		}
+
+		for _, n := range moved {
+			if err := setTagParent(conn, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return tag.Tag{}, err
+	}
+	moved, ok := source.GetTagByName(name)
+	if !ok {
+		return tag.Tag{}, ErrNotFound
+	}
+	return moved, nil
+}
+
+// ListTagTree lists the descendants of the tag as a tree, or all tags if
+// the id is 0.
+func (source *Database) ListTagTree(id tag.Id) []tag.Tag {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, updated_at_ms, auto, parent_id
+	FROM tag
+	WHERE true
+	` + defaultTagConditions + `
+	ORDER BY name ASC;`)
+	defer stmt.Reset()
+
+	tags := make(map[tag.Id]*tag.Tag)
+	parents := make(map[tag.Id]tag.Id)
+	order := make([]tag.Id, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing tag tree: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		t := &tag.Tag{
+			Id:        tag.Id(stmt.ColumnInt64(0)),
+			Name:      stmt.ColumnText(1),
+			UpdatedAt: fromUnixMs(stmt.ColumnInt64(2)),
+			Auto:      stmt.ColumnBool(3),
+		}
+		tags[t.Id] = t
+		parents[t.Id] = tag.Id(stmt.ColumnInt64(4))
+		order = append(order, t.Id)
+	}
+
+	// Descendants are listed after their ancestors, as they are sorted by
+	// name, so attaching them in reverse builds the tree bottom up
+	roots := make([]tag.Tag, 0)
+	for i := len(order) - 1; i >= 0; i-- {
+		t := tags[order[i]]
+		parent, ok := tags[parents[t.Id]]
+		if ok {
+			t.Parent = parent.Name
+		}
+		switch {
+		case ok && parent.Id == id, !ok && id == 0:
+			roots = append([]tag.Tag{*t}, roots...)
+		case ok:
+			parent.Children = append([]tag.Tag{*t}, parent.Children...)
+		}
+	}
+	return roots
+}
+
+// ListTagChildren lists the children of the tag, or the tags without a
+// parent if the id is 0.
+func (source *Database) ListTagChildren(id tag.Id, limit int) []tag.Tag {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := `
+	SELECT id, name, updated_at_ms, auto, ` + tagParentNameSQL + `
+	FROM tag
+	WHERE `
+	if id == 0 {
+		sql += `parent_id IS NULL`
+	} else {
+		sql += `parent_id = ?`
+	}
+	sql += defaultTagConditions + `
+	ORDER BY name ASC
+	LIMIT ?;`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	param := 1
+	if id != 0 {
+		stmt.BindInt64(param, int64(id))
+		param++
+	}
+	stmt.BindInt64(param, int64(limit))
+
+	tags := make([]tag.Tag, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing tag children: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		tags = append(tags, tag.Tag{
+			Id:        tag.Id(stmt.ColumnInt64(0)),
+			Name:      stmt.ColumnText(1),
+			UpdatedAt: fromUnixMs(stmt.ColumnInt64(2)),
+			Auto:      stmt.ColumnBool(3),
+			Parent:    stmt.ColumnText(4),
+		})
+	}
+	return tags
+}
+
+// internalTagPrefixes are the namespaces of the tags managed by Photofield
+// rather than users, e.g. selections, EXIF and suggested tags.
+var internalTagPrefixes = []string{"sys:", "exif:", SuggestPrefix}
+
+// SyncsXmpTag returns true if the tag is synced with the XMP keywords of
+// files, so that other photo managers only see the tags of users.
+func SyncsXmpTag(name string) bool {
+	for _, prefix := range internalTagPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,278 @@
+package image
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// TaskRun is a single run of a background task, kept after the task is done.
+type TaskRun struct {
+	Id           int64     `json:"id"`
+	TaskId       string    `json:"task_id"`
+	Type         string    `json:"type"`
+	Name         string    `json:"name"`
+	CollectionId string    `json:"collection_id"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Done         int       `json:"done"`
+	Total        int       `json:"total"`
+	Error        string    `json:"error,omitempty"`
+	ErrorCount   int       `json:"error_count"`
+}
+
+// Finished returns true if the run has ended, successfully or not.
+func (r TaskRun) Finished() bool {
+	return !r.FinishedAt.IsZero()
+}
+
+// TaskError is a file that a task run failed to process.
+type TaskError struct {
+	RunId     int64     `json:"-"`
+	FileId    ImageId   `json:"file_id,omitempty"`
+	Path      string    `json:"path"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskRunListOptions filters the listed task runs, zero values match all.
+type TaskRunListOptions struct {
+	Finished     bool
+	TaskId       string
+	Type         string
+	CollectionId string
+	Limit        int
+}
+
+const taskRunColumns = `
+	id, task_id, type, name, collection_id, started_at_ms, finished_at_ms,
+	done, total, coalesce(error, ''),
+	(SELECT COUNT(*) FROM task_error WHERE task_run_id = task_run.id)`
+
+func readTaskRun(stmt *sqlite.Stmt) TaskRun {
+	r := TaskRun{
+		Id:           stmt.ColumnInt64(0),
+		TaskId:       stmt.ColumnText(1),
+		Type:         stmt.ColumnText(2),
+		Name:         stmt.ColumnText(3),
+		CollectionId: stmt.ColumnText(4),
+		StartedAt:    fromUnixMs(stmt.ColumnInt64(5)),
+		Done:         stmt.ColumnInt(7),
+		Total:        stmt.ColumnInt(8),
+		Error:        stmt.ColumnText(9),
+		ErrorCount:   stmt.ColumnInt(10),
+	}
+	if stmt.ColumnType(6) != sqlite.TypeNull {
+		r.FinishedAt = fromUnixMs(stmt.ColumnInt64(6))
+	}
+	return r
+}
+
+// taskRunsKept is the number of finished runs kept for each task.
+const taskRunsKept = 10
+
+// AddTaskRun records the start of a task run and returns it with its id,
+// removing the oldest finished runs of the task and their errors beyond
+// taskRunsKept.
+func (source *Database) AddTaskRun(r TaskRun) (TaskRun, error) {
+	r.StartedAt = fromUnixMs(toUnixMs(time.Now()))
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		old := `
+		SELECT id
+		FROM task_run
+		WHERE task_id = ? AND finished_at_ms IS NOT NULL
+		ORDER BY started_at_ms DESC, id DESC
+		LIMIT -1 OFFSET ?`
+		for _, sql := range []string{
+			`DELETE FROM task_error WHERE task_run_id IN (` + old + `);`,
+			`DELETE FROM task_run WHERE id IN (` + old + `);`,
+		} {
+			expired := conn.Prep(sql)
+			expired.BindText(1, r.TaskId)
+			expired.BindInt64(2, taskRunsKept-1)
+			_, err := expired.Step()
+			expired.Reset()
+			if err != nil {
+				return err
+			}
+		}
+
+		stmt := conn.Prep(`
+		INSERT INTO task_run(task_id, type, name, collection_id, started_at_ms)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id;`)
+		defer stmt.Reset()
+
+		stmt.BindText(1, r.TaskId)
+		stmt.BindText(2, r.Type)
+		stmt.BindText(3, r.Name)
+		stmt.BindText(4, r.CollectionId)
+		stmt.BindInt64(5, toUnixMs(r.StartedAt))
+
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		r.Id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return r, err
+}
+
+// FinishTaskRun records the end of a task run with its final counts and
+// error, if any.
+func (source *Database) FinishTaskRun(id int64, done int, total int, runErr error) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE task_run
+		SET finished_at_ms = ?, done = ?, total = ?, error = ?
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, toUnixMs(time.Now()))
+		stmt.BindInt64(2, int64(done))
+		stmt.BindInt64(3, int64(total))
+		if runErr == nil {
+			stmt.BindNull(4)
+		} else {
+			stmt.BindText(4, runErr.Error())
+		}
+		stmt.BindInt64(5, id)
+
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// InterruptTaskRuns finishes the runs left unfinished by a previous process,
+// e.g. one that was stopped or crashed while indexing.
+func (source *Database) InterruptTaskRuns() error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE task_run
+		SET finished_at_ms = ?, error = 'interrupted'
+		WHERE finished_at_ms IS NULL;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, toUnixMs(time.Now()))
+		_, err := stmt.Step()
+		return err
+	})
+}
+
+// WriteTaskError records a file that a task run failed to process.
+func (source *Database) WriteTaskError(runId int64, fileId ImageId, path string, err error) {
+	source.pending <- &InfoWrite{
+		Type: AddTaskError,
+		TaskError: TaskError{
+			RunId:     runId,
+			FileId:    fileId,
+			Path:      path,
+			Error:     err.Error(),
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+func (source *Database) GetTaskRun(id int64) (TaskRun, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT ` + taskRunColumns + `
+	FROM task_run
+	WHERE id = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return TaskRun{}, false
+	}
+	return readTaskRun(stmt), true
+}
+
+// ListTaskRuns lists the task runs, most recently started first.
+func (source *Database) ListTaskRuns(options TaskRunListOptions) []TaskRun {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	sql := `
+	SELECT ` + taskRunColumns + `
+	FROM task_run
+	WHERE (? = 0 OR finished_at_ms IS NOT NULL)
+	AND (? = '' OR task_id = ?)
+	AND (? = '' OR type = ?)
+	AND (? = '' OR collection_id = ?)
+	ORDER BY started_at_ms DESC, id DESC
+	LIMIT ?;`
+
+	stmt := conn.Prep(sql)
+	defer stmt.Reset()
+
+	stmt.BindBool(1, options.Finished)
+	stmt.BindText(2, options.TaskId)
+	stmt.BindText(3, options.TaskId)
+	stmt.BindText(4, options.Type)
+	stmt.BindText(5, options.Type)
+	stmt.BindText(6, options.CollectionId)
+	stmt.BindText(7, options.CollectionId)
+	limit := int64(options.Limit)
+	if limit <= 0 {
+		limit = -1
+	}
+	stmt.BindInt64(8, limit)
+
+	runs := make([]TaskRun, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing task runs: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		runs = append(runs, readTaskRun(stmt))
+	}
+	return runs
+}
+
+// ListTaskErrors lists the files that a task run failed to process in the
+// order they failed.
+func (source *Database) ListTaskErrors(runId int64) []TaskError {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT coalesce(file_id, 0), path, error, created_at_ms
+	FROM task_error
+	WHERE task_run_id = ?
+	ORDER BY id ASC;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, runId)
+
+	errs := make([]TaskError, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error listing task errors: %s\n", err.Error())
+			break
+		} else if !exists {
+			break
+		}
+		errs = append(errs, TaskError{
+			RunId:     runId,
+			FileId:    ImageId(stmt.ColumnInt64(0)),
+			Path:      stmt.ColumnText(1),
+			Error:     stmt.ColumnText(2),
+			CreatedAt: fromUnixMs(stmt.ColumnInt64(3)),
+		})
+	}
+	return errs
+}
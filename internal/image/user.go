@@ -0,0 +1,355 @@
+package image
+
+import (
+	"context"
+	"time"
+
+	"photofield/internal/auth"
+
+	"zombiezen.com/go/sqlite"
+)
+
+func readUser(stmt *sqlite.Stmt) auth.User {
+	return auth.User{
+		Id:        stmt.ColumnInt64(0),
+		Name:      stmt.ColumnText(1),
+		Role:      auth.Role(stmt.ColumnText(2)),
+		CreatedAt: fromUnixMs(stmt.ColumnInt64(3)),
+	}
+}
+
+// AddUser adds a user with the password hashed by auth.HashPassword,
+// returning ErrExists if a user with the name already exists.
+func (source *Database) AddUser(name string, passwordHash string, role auth.Role) (auth.User, error) {
+	user := auth.User{
+		Name:      name,
+		Role:      role,
+		CreatedAt: fromUnixMs(toUnixMs(time.Now())),
+	}
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		INSERT INTO user(name, password_hash, role, created_at_ms)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO NOTHING
+		RETURNING id;`)
+		defer stmt.Reset()
+
+		stmt.BindText(1, user.Name)
+		stmt.BindText(2, passwordHash)
+		stmt.BindText(3, string(user.Role))
+		stmt.BindInt64(4, toUnixMs(user.CreatedAt))
+
+		exists, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrExists
+		}
+		user.Id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return user, err
+}
+
+// SetUserRole changes the role of the user.
+func (source *Database) SetUserRole(id int64, role auth.Role) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE user
+		SET role = ?
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindText(1, string(role))
+		stmt.BindInt64(2, id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// SetUserPassword changes the password of the user and ends all of their
+// login sessions.
+func (source *Database) SetUserPassword(id int64, passwordHash string) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		UPDATE user
+		SET password_hash = ?
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindText(1, passwordHash)
+		stmt.BindInt64(2, id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+
+		sessions := conn.Prep(`
+		DELETE FROM user_session
+		WHERE user_id = ?;`)
+		defer sessions.Reset()
+
+		sessions.BindInt64(1, id)
+		_, err := sessions.Step()
+		return err
+	})
+}
+
+// DeleteUser deletes the user along with their sessions and API tokens.
+func (source *Database) DeleteUser(id int64) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		DELETE FROM user
+		WHERE id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, id)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+
+		for _, sql := range []string{
+			`DELETE FROM user_session WHERE user_id = ?;`,
+			`DELETE FROM user_token WHERE user_id = ?;`,
+		} {
+			stmt := conn.Prep(sql)
+			stmt.BindInt64(1, id)
+			_, err := stmt.Step()
+			stmt.Reset()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (source *Database) GetUser(id int64) (auth.User, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, role, created_at_ms
+	FROM user
+	WHERE id = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, id)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.User{}, false
+	}
+	return readUser(stmt), true
+}
+
+// GetUserByName returns the user with the name along with their password
+// hash.
+func (source *Database) GetUserByName(name string) (auth.User, string, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, role, created_at_ms, password_hash
+	FROM user
+	WHERE name = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindText(1, name)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.User{}, "", false
+	}
+	return readUser(stmt), stmt.ColumnText(4), true
+}
+
+func (source *Database) ListUsers() []auth.User {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, role, created_at_ms
+	FROM user
+	ORDER BY name ASC;`)
+	defer stmt.Reset()
+
+	users := make([]auth.User, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil || !exists {
+			break
+		}
+		users = append(users, readUser(stmt))
+	}
+	return users
+}
+
+// AddUserSession adds a login session with the hash of the session token,
+// removing the expired ones.
+func (source *Database) AddUserSession(userId int64, tokenHash []byte, expiresAt time.Time) error {
+	now := time.Now()
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		expired := conn.Prep(`
+		DELETE FROM user_session
+		WHERE expires_at_ms <= ?;`)
+		defer expired.Reset()
+
+		expired.BindInt64(1, toUnixMs(now))
+		if _, err := expired.Step(); err != nil {
+			return err
+		}
+
+		stmt := conn.Prep(`
+		INSERT INTO user_session(token_hash, user_id, created_at_ms, expires_at_ms)
+		VALUES (?, ?, ?, ?);`)
+		defer stmt.Reset()
+
+		stmt.BindBytes(1, tokenHash)
+		stmt.BindInt64(2, userId)
+		stmt.BindInt64(3, toUnixMs(now))
+		stmt.BindInt64(4, toUnixMs(expiresAt))
+		_, err := stmt.Step()
+		return err
+	})
+}
+
+func (source *Database) DeleteUserSession(tokenHash []byte) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		DELETE FROM user_session
+		WHERE token_hash = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindBytes(1, tokenHash)
+		_, err := stmt.Step()
+		return err
+	})
+}
+
+// GetSessionUser returns the user of the unexpired session with the hash
+// of the session token.
+func (source *Database) GetSessionUser(tokenHash []byte) (auth.User, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT user.id, name, role, user.created_at_ms
+	FROM user_session
+	JOIN user ON user.id = user_id
+	WHERE token_hash = ? AND expires_at_ms > ?;`)
+	defer stmt.Reset()
+
+	stmt.BindBytes(1, tokenHash)
+	stmt.BindInt64(2, toUnixMs(time.Now()))
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.User{}, false
+	}
+	return readUser(stmt), true
+}
+
+// AddUserToken adds an API token of the user with the hash of the token.
+func (source *Database) AddUserToken(userId int64, name string, tokenHash []byte) (auth.Token, error) {
+	token := auth.Token{
+		Name:      name,
+		CreatedAt: fromUnixMs(toUnixMs(time.Now())),
+	}
+	err := source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		INSERT INTO user_token(user_id, name, token_hash, created_at_ms)
+		VALUES (?, ?, ?, ?)
+		RETURNING id;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, userId)
+		stmt.BindText(2, token.Name)
+		stmt.BindBytes(3, tokenHash)
+		stmt.BindInt64(4, toUnixMs(token.CreatedAt))
+
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		token.Id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return token, err
+}
+
+func (source *Database) DeleteUserToken(userId int64, id int64) error {
+	return source.writeDirect(func(conn *sqlite.Conn) error {
+		stmt := conn.Prep(`
+		DELETE FROM user_token
+		WHERE id = ? AND user_id = ?;`)
+		defer stmt.Reset()
+
+		stmt.BindInt64(1, id)
+		stmt.BindInt64(2, userId)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		if conn.Changes() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (source *Database) ListUserTokens(userId int64) []auth.Token {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT id, name, created_at_ms
+	FROM user_token
+	WHERE user_id = ?
+	ORDER BY id ASC;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, userId)
+
+	tokens := make([]auth.Token, 0)
+	for {
+		if exists, err := stmt.Step(); err != nil || !exists {
+			break
+		}
+		tokens = append(tokens, auth.Token{
+			Id:        stmt.ColumnInt64(0),
+			Name:      stmt.ColumnText(1),
+			CreatedAt: fromUnixMs(stmt.ColumnInt64(2)),
+		})
+	}
+	return tokens
+}
+
+// GetTokenUser returns the user of the API token with the hash.
+func (source *Database) GetTokenUser(tokenHash []byte) (auth.User, bool) {
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+	SELECT user.id, user.name, role, user.created_at_ms
+	FROM user_token
+	JOIN user ON user.id = user_id
+	WHERE token_hash = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindBytes(1, tokenHash)
+
+	exists, _ := stmt.Step()
+	if !exists {
+		return auth.User{}, false
+	}
+	return readUser(stmt), true
+}
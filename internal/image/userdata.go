@@ -0,0 +1,347 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"photofield/internal/metrics"
+	"photofield/internal/search"
+	"photofield/internal/tag"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// UserData is a record of the data curated by users that cannot be
+// recreated by indexing, exported as one JSON object per line. Tag records
+// have a Tag name, file records have a Path instead.
+type UserData struct {
+	Tag  string `json:"tag,omitempty"`
+	Auto bool   `json:"auto,omitempty"`
+
+	Path string `json:"path,omitempty"`
+	// Hash is the partial content hash of the file in hex, used to find the
+	// file again if it was moved, with HashFull telling apart collisions
+	Hash     string   `json:"hash,omitempty"`
+	HashFull string   `json:"hash_full,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Rating   int      `json:"rating,omitempty"`
+	Label    string   `json:"label,omitempty"`
+	Favorite bool     `json:"favorite,omitempty"`
+}
+
+// UserDataStats counts the records exported or imported.
+type UserDataStats struct {
+	Tags  int `json:"tags"`
+	Files int `json:"files"`
+	// Moved files were matched by their hash, as their path was not found
+	Moved int `json:"moved"`
+	// Missing files were found by neither their path nor their hash
+	Missing int `json:"missing"`
+}
+
+func formatPartialHash(partial int64) string {
+	return fmt.Sprintf("%016x", uint64(partial))
+}
+
+func parsePartialHash(s string) (int64, error) {
+	v, err := strconv.ParseUint(s, 16, 64)
+	return int64(v), err
+}
+
+// ExportUserData writes the tags, the tags of the files including
+// selections, and the ratings, labels and favorites of the files as
+// newline-delimited JSON keyed by file path.
+func (source *Database) ExportUserData(w io.Writer) (UserDataStats, error) {
+	defer metrics.Elapsed("export user data")()
+
+	stats := UserDataStats{}
+	enc := json.NewEncoder(w)
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	files := make(map[ImageId]*UserData)
+	file := func(id ImageId) *UserData {
+		d, ok := files[id]
+		if !ok {
+			d = &UserData{}
+			files[id] = d
+		}
+		return d
+	}
+
+	tags := conn.Prep(`
+		SELECT id, name, auto
+		FROM tag
+		WHERE active
+		ORDER BY name;`)
+	defer tags.Reset()
+
+	for {
+		if exists, err := tags.Step(); err != nil {
+			return stats, err
+		} else if !exists {
+			break
+		}
+		id := tag.Id(tags.ColumnInt64(0))
+		name := tags.ColumnText(1)
+		if strings.HasPrefix(name, SuggestPrefix) {
+			// Suggestions are recreated by INDEX_AUTOTAG
+			continue
+		}
+		if err := enc.Encode(UserData{Tag: name, Auto: tags.ColumnBool(2)}); err != nil {
+			return stats, err
+		}
+		stats.Tags++
+		for _, fid := range source.getTagImageIdsWithConn(conn, id).IntSlice() {
+			d := file(ImageId(fid))
+			d.Tags = append(d.Tags, name)
+		}
+	}
+
+	meta := conn.Prep(`
+		SELECT id, coalesce(rating, 0), coalesce(label, ''), favorite
+		FROM infos
+		WHERE rating IS NOT NULL OR label IS NOT NULL OR favorite;`)
+	defer meta.Reset()
+
+	for {
+		if exists, err := meta.Step(); err != nil {
+			return stats, err
+		} else if !exists {
+			break
+		}
+		d := file(ImageId(meta.ColumnInt64(0)))
+		d.Rating = meta.ColumnInt(1)
+		d.Label = meta.ColumnText(2)
+		d.Favorite = meta.ColumnBool(3)
+	}
+
+	ids := make([]ImageId, 0, len(files))
+	for id := range files {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	path := conn.Prep(`
+		SELECT str || filename, hash_partial, hash_full
+		FROM infos
+		JOIN prefix ON path_prefix_id == prefix.id
+		WHERE infos.id == ?;`)
+	defer path.Reset()
+
+	for _, id := range ids {
+		path.BindInt64(1, int64(id))
+		exists, err := path.Step()
+		if err != nil {
+			return stats, err
+		}
+		if !exists {
+			// Tag ranges can span ids of deleted files
+			path.Reset()
+			continue
+		}
+		d := files[id]
+		d.Path = path.ColumnText(0)
+		if path.ColumnType(1) != sqlite.TypeNull {
+			d.Hash = formatPartialHash(path.ColumnInt64(1))
+		}
+		if path.ColumnType(2) != sqlite.TypeNull {
+			full := make([]byte, path.ColumnLen(2))
+			path.ColumnBytes(2, full)
+			d.HashFull = hex.EncodeToString(full)
+		}
+		if err := path.Reset(); err != nil {
+			return stats, err
+		}
+		if err := enc.Encode(d); err != nil {
+			return stats, err
+		}
+		stats.Files++
+	}
+
+	return stats, nil
+}
+
+// ImportUserData reads the records written by ExportUserData and adds the
+// tags and the files to them, and sets the ratings, labels and favorites of
+// the files. Files not found by their path are matched by their hash.
+func (source *Database) ImportUserData(r io.Reader) (UserDataStats, error) {
+	defer metrics.Elapsed("import user data")()
+
+	stats := UserDataStats{}
+	tagIds := make(map[string]Ids)
+	var tagNames []string
+	addTagId := func(name string, id ImageId) {
+		ids, ok := tagIds[name]
+		if !ok {
+			ids = NewIds()
+			tagIds[name] = ids
+			tagNames = append(tagNames, name)
+		}
+		ids.AddInt(int(id))
+	}
+	ratings := make(map[int]Ids)
+	labels := make(map[string]Ids)
+	favorites := NewIds()
+
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var d UserData
+		err := dec.Decode(&d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		if d.Tag != "" {
+			t, err := source.getOrAddTag(d.Tag)
+			if err != nil {
+				return stats, fmt.Errorf("record %d: %w", n, err)
+			}
+			if d.Auto && !t.Auto {
+				if err := source.SetTagAuto(t, true); err != nil {
+					return stats, fmt.Errorf("record %d: %w", n, err)
+				}
+			}
+			stats.Tags++
+			continue
+		}
+
+		id, ok := source.GetIdFromPath(d.Path)
+		if !ok && d.Hash != "" {
+			id, ok = source.getIdFromHash(d.Hash, d.HashFull)
+			if ok {
+				stats.Moved++
+			}
+		}
+		if !ok {
+			stats.Missing++
+			continue
+		}
+		stats.Files++
+
+		for _, name := range d.Tags {
+			addTagId(name, id)
+		}
+		if rating := ClampRating(d.Rating); rating > 0 {
+			if ratings[rating] == nil {
+				ratings[rating] = NewIds()
+			}
+			ratings[rating].AddInt(int(id))
+		}
+		if slices.Contains(search.LabelValues, d.Label) {
+			if labels[d.Label] == nil {
+				labels[d.Label] = NewIds()
+			}
+			labels[d.Label].AddInt(int(id))
+		}
+		if d.Favorite {
+			favorites.AddInt(int(id))
+		}
+	}
+
+	for _, name := range tagNames {
+		t, err := source.getOrAddTag(name)
+		if err != nil {
+			return stats, err
+		}
+		source.AddTagIds(t.Id, tagIds[name])
+	}
+	for rating, ids := range ratings {
+		if err := source.SetRating(ids, rating); err != nil {
+			return stats, err
+		}
+	}
+	for label, ids := range labels {
+		if err := source.SetLabel(ids, label); err != nil {
+			return stats, err
+		}
+	}
+	if favorites.Len() > 0 {
+		if err := source.SetFavorite(favorites, true); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// getOrAddTag returns the active version of the tag with the name, adding
+// it first if it does not exist yet.
+func (source *Database) getOrAddTag(name string) (tag.Tag, error) {
+	if t, ok := source.GetTagByName(name); ok {
+		return t, nil
+	}
+	if !tag.ValidName(name) {
+		return tag.Tag{}, fmt.Errorf("invalid tag name %q", name)
+	}
+	done, err := source.AddTag(name)
+	if err != nil {
+		return tag.Tag{}, err
+	}
+	<-done
+	t, ok := source.GetTagByName(name)
+	if !ok {
+		return tag.Tag{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// getIdFromHash returns the id of the file with the hex encoded hashes. If
+// the full hashes cannot be compared, the partial hash needs to match a
+// single file.
+func (source *Database) getIdFromHash(partial string, full string) (ImageId, bool) {
+	p, err := parsePartialHash(partial)
+	if err != nil {
+		return 0, false
+	}
+	f, err := hex.DecodeString(full)
+	if err != nil {
+		return 0, false
+	}
+
+	conn := source.pool.Get(context.TODO())
+	defer source.pool.Put(conn)
+
+	stmt := conn.Prep(`
+		SELECT id, hash_full
+		FROM infos
+		WHERE hash_partial = ?;`)
+	defer stmt.Reset()
+
+	stmt.BindInt64(1, p)
+
+	var ids []ImageId
+	for {
+		if exists, err := stmt.Step(); err != nil {
+			log.Printf("Error getting file by hash %s: %s\n", partial, err.Error())
+			return 0, false
+		} else if !exists {
+			break
+		}
+		id := ImageId(stmt.ColumnInt64(0))
+		if len(f) == 0 || stmt.ColumnType(1) == sqlite.TypeNull {
+			ids = append(ids, id)
+			continue
+		}
+		rowFull := make([]byte, stmt.ColumnLen(1))
+		stmt.ColumnBytes(1, rowFull)
+		if bytes.Equal(rowFull, f) {
+			return id, true
+		}
+	}
+	if len(ids) != 1 {
+		return 0, false
+	}
+	return ids[0], true
+}
@@ -24,7 +24,7 @@ func TestRoundtrip(t *testing.T) {
 			{Width: 256, Height: 171},
 		},
 	}
-	images, err := test.GenerateTestDataset("../../testdata", dataset)
+	images, err := test.GenerateTestDataset(t.TempDir(), dataset)
 	if err != nil {
 		t.Fatalf("failed to generate test dataset: %v", err)
 	}
@@ -234,7 +234,7 @@ func TestEncodeOrientation(t *testing.T) {
 			{Width: 170, Height: 256, ExifTags: map[string]string{"Orientation#": "8"}},
 		},
 	}
-	images, err := test.GenerateTestDataset("../../testdata", dataset)
+	images, err := test.GenerateTestDataset(t.TempDir(), dataset)
 	if err != nil {
 		t.Fatalf("failed to generate test dataset: %v", err)
 	}
@@ -27,8 +27,12 @@ const (
 	Highlights Type = "HIGHLIGHTS"
 	Flex       Type = "FLEX"
 	Faces      Type = "FACES"
+	Duplicates Type = "DUPLICATES"
 )
 
+// Types are the valid layout types.
+var Types = []Type{Album, Timeline, Square, Wall, Map, Similarity, Strip, Highlights, Flex, Faces, Duplicates}
+
 type Order int
 
 const (
@@ -41,6 +45,10 @@ const (
 	ShuffleMonthly
 	SimilarityDesc
 	SimilarityAsc
+	HueAsc
+	HueDesc
+	LightnessAsc
+	LightnessDesc
 )
 
 func IsSimilarityOrder(order Order) bool {
@@ -64,6 +72,14 @@ func OrderFromSort(s string) Order {
 		return SimilarityDesc
 	case "+similarity":
 		return SimilarityAsc
+	case "+hue":
+		return HueAsc
+	case "-hue":
+		return HueDesc
+	case "+lightness":
+		return LightnessAsc
+	case "-lightness":
+		return LightnessDesc
 	default:
 		return None
 	}
@@ -142,6 +158,9 @@ type PhotoRegionData struct {
 	CreatedAt  string             `json:"created_at"`
 	Thumbnails []RegionThumbnail  `json:"thumbnails"`
 	Tags       []tag.Tag          `json:"tags"`
+	Rating     int                `json:"rating,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	Favorite   bool               `json:"favorite,omitempty"`
 	Faces      []RegionFace       `json:"faces,omitempty"`
 	Location   string             `json:"location,omitempty"` // reverse geocoded location
 	LatLng     *PhotoRegionLatLng `json:"latlng,omitempty"`
@@ -240,6 +259,8 @@ func (regionSource PhotoRegionSource) getRegionFromPhoto(id int, photo *render.P
 		tags = append(tags, tag)
 	}
 
+	metadata, _ := source.GetFileMetadata(photo.Id)
+
 	faceInfos := source.GetFacesByFileId(photo.Id)
 	faces := make([]RegionFace, 0, len(faceInfos))
 	for _, f := range faceInfos {
@@ -267,6 +288,9 @@ func (regionSource PhotoRegionSource) getRegionFromPhoto(id int, photo *render.P
 			CreatedAt:  info.DateTime.Format(time.RFC3339),
 			Thumbnails: thumbnails,
 			Tags:       tags,
+			Rating:     metadata.Rating,
+			Label:      metadata.Label,
+			Favorite:   metadata.Favorite,
 			Faces:      faces,
 			Location:   location,
 			LatLng:     latlng,
@@ -0,0 +1,139 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"photofield/internal/image"
+	"photofield/internal/metrics"
+	"photofield/internal/render"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/tdewolff/canvas"
+)
+
+type duplicateGroup struct {
+	exact bool
+	infos []image.SourcedInfo
+}
+
+func duplicateCaption(info image.SourcedInfo, source *image.Source) string {
+	caption := fmt.Sprintf("%d × %d", info.Width, info.Height)
+	path, err := source.GetImagePath(info.Id)
+	if err != nil {
+		return caption
+	}
+	if stat, err := os.Stat(path); err == nil {
+		caption += " · " + units.HumanSize(float64(stat.Size()))
+	}
+	return caption + "\n" + path
+}
+
+// LayoutDuplicates lays out each group of duplicates as a row, with the
+// dimensions, file size and path below each file. Files that are not
+// duplicates are skipped.
+func LayoutDuplicates(infos <-chan image.SourcedInfo, duplicates map[image.ImageId]image.Duplicate, layout Layout, scene *render.Scene, source *image.Source) {
+	sceneMargin := 10.
+	topMargin := 64.
+	headerHeight := 40.
+	captionHeight := 50.
+	groupSpacing := 32.
+
+	if strings.Contains(layout.Tweaks, "nomargin") {
+		sceneMargin = 0
+		topMargin = 0
+	} else if strings.Contains(layout.Tweaks, "notopmargin") {
+		topMargin = 0
+	}
+
+	layout.ImageSpacing = 0.02 * layout.ImageHeight
+
+	scene.Bounds.W = layout.ViewportWidth
+	scene.Solids = make([]render.Solid, 0)
+	scene.Texts = make([]render.Text, 0)
+	scene.Photos = scene.Photos[:0]
+
+	layoutPlaced := metrics.Elapsed("layout placing")
+
+	// Group in listing order of the first file of each group
+	groupIndex := make(map[image.ImageId]int)
+	groups := make([]duplicateGroup, 0)
+	for info := range infos {
+		d, ok := duplicates[info.Id]
+		if !ok {
+			continue
+		}
+		i, ok := groupIndex[d.GroupId]
+		if !ok {
+			i = len(groups)
+			groupIndex[d.GroupId] = i
+			groups = append(groups, duplicateGroup{})
+		}
+		groups[i].exact = groups[i].exact || d.Exact
+		groups[i].infos = append(groups[i].infos, info)
+	}
+
+	headerFont := scene.Fonts.Main.Face(50, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	captionFont := scene.Fonts.Main.Face(30, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	rect := render.Rect{
+		X: sceneMargin,
+		Y: sceneMargin + topMargin,
+		W: scene.Bounds.W - sceneMargin*2,
+	}
+
+	for _, group := range groups {
+		kind := "similar"
+		if group.exact {
+			kind = "identical"
+		}
+		header := render.NewTextFromRect(
+			render.Rect{X: rect.X, Y: rect.Y, W: rect.W, H: headerHeight},
+			&headerFont,
+			fmt.Sprintf("%d %s files", len(group.infos), kind),
+		)
+		header.VAlign = canvas.Bottom
+		scene.Texts = append(scene.Texts, header)
+		rect.Y += headerHeight
+
+		// Fit the whole group into a single row
+		width := 0.
+		for _, info := range group.infos {
+			width += layout.ImageHeight * info.AspectRatio()
+		}
+		width += float64(len(group.infos)-1) * layout.ImageSpacing
+		scale := 1.
+		if width > rect.W {
+			scale = rect.W / width
+		}
+		height := layout.ImageHeight * scale
+
+		x := rect.X
+		for _, info := range group.infos {
+			w := height * info.AspectRatio()
+			scene.Photos = append(scene.Photos, render.Photo{
+				Id: info.Id,
+				Sprite: render.Sprite{
+					Rect: render.Rect{X: x, Y: rect.Y, W: w, H: height},
+				},
+			})
+			caption := render.NewTextFromRect(
+				render.Rect{X: x, Y: rect.Y + height, W: w, H: captionHeight},
+				&captionFont,
+				duplicateCaption(info, source),
+			)
+			scene.Texts = append(scene.Texts, caption)
+			x += w + layout.ImageSpacing*scale
+		}
+		rect.Y += height + captionHeight + groupSpacing
+	}
+	layoutPlaced()
+
+	log.Printf("layout duplicates %d groups\n", len(groups))
+
+	scene.Bounds.H = rect.Y + sceneMargin
+	scene.RegionSource = PhotoRegionSource{
+		Source: source,
+	}
+}
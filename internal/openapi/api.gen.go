@@ -12,10 +12,47 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// Defines values for CollectionPostExpandSort.
+const (
+	CollectionPostExpandSortAsc CollectionPostExpandSort = "asc"
+
+	CollectionPostExpandSortDesc CollectionPostExpandSort = "desc"
+)
+
+// Defines values for CollectionScheduleStages.
+const (
+	CollectionScheduleStagesContents CollectionScheduleStages = "contents"
+
+	CollectionScheduleStagesDuplicates CollectionScheduleStages = "duplicates"
+
+	CollectionScheduleStagesFaces CollectionScheduleStages = "faces"
+
+	CollectionScheduleStagesFiles CollectionScheduleStages = "files"
+
+	CollectionScheduleStagesMetadata CollectionScheduleStages = "metadata"
+)
+
+// Defines values for Label.
+const (
+	LabelBlue Label = "blue"
+
+	LabelEmpty Label = ""
+
+	LabelGreen Label = "green"
+
+	LabelPurple Label = "purple"
+
+	LabelRed Label = "red"
+
+	LabelYellow Label = "yellow"
+)
+
 // Defines values for LayoutType.
 const (
 	LayoutTypeALBUM LayoutType = "ALBUM"
 
+	LayoutTypeDUPLICATES LayoutType = "DUPLICATES"
+
 	LayoutTypeFACES LayoutType = "FACES"
 
 	LayoutTypeFLEX LayoutType = "FLEX"
@@ -40,23 +77,89 @@ const (
 	OperationSUBTRACT Operation = "SUBTRACT"
 )
 
+// Defines values for TagSuggestionsPostOp.
+const (
+	TagSuggestionsPostOpACCEPT TagSuggestionsPostOp = "ACCEPT"
+
+	TagSuggestionsPostOpREJECT TagSuggestionsPostOp = "REJECT"
+)
+
+// Defines values for TaskState.
+const (
+	TaskStateFinished TaskState = "finished"
+
+	TaskStateRunning TaskState = "running"
+)
+
 // Defines values for TaskType.
 const (
 	TaskTypeINDEXALL TaskType = "INDEX_ALL"
 
+	TaskTypeINDEXAUTOTAG TaskType = "INDEX_AUTOTAG"
+
 	TaskTypeINDEXCONTENTS TaskType = "INDEX_CONTENTS"
 
 	TaskTypeINDEXCONTENTSAI TaskType = "INDEX_CONTENTS_AI"
 
 	TaskTypeINDEXCONTENTSCOLOR TaskType = "INDEX_CONTENTS_COLOR"
 
+	TaskTypeINDEXDUPLICATES TaskType = "INDEX_DUPLICATES"
+
 	TaskTypeINDEXFACES TaskType = "INDEX_FACES"
 
 	TaskTypeINDEXFILES TaskType = "INDEX_FILES"
 
 	TaskTypeINDEXMETADATA TaskType = "INDEX_METADATA"
+
+	TaskTypeINDEXPERSONS TaskType = "INDEX_PERSONS"
+
+	TaskTypeINDEXSCHEDULE TaskType = "INDEX_SCHEDULE"
 )
 
+// Defines values for UserRole.
+const (
+	UserRoleAdmin UserRole = "admin"
+
+	UserRoleEditor UserRole = "editor"
+
+	UserRoleViewer UserRole = "viewer"
+)
+
+// ApiToken defines model for ApiToken.
+type ApiToken struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Id        ApiTokenId `json:"id"`
+	Name      string     `json:"name"`
+}
+
+// ApiTokenCreated defines model for ApiTokenCreated.
+type ApiTokenCreated struct {
+	// Embedded struct due to allOf(#/components/schemas/ApiToken)
+	ApiToken `yaml:",inline"`
+	// Embedded fields due to inline allOf schema
+	// Secret to use in an `Authorization` header with the `Bearer` scheme, only returned once.
+	Token string `json:"token"`
+}
+
+// ApiTokenId defines model for ApiTokenId.
+type ApiTokenId int
+
+// ApiTokenPost defines model for ApiTokenPost.
+type ApiTokenPost struct {
+	Name string `json:"name"`
+}
+
+// Backup defines model for Backup.
+type Backup struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Path of the backup on the server.
+	Path string `json:"path"`
+
+	// Size of the backup in bytes.
+	Size int `json:"size"`
+}
+
 // Bounds defines model for Bounds.
 type Bounds struct {
 	H float32 `json:"h"`
@@ -67,6 +170,7 @@ type Bounds struct {
 
 // Capabilities defines model for Capabilities.
 type Capabilities struct {
+	Auth   Capability     `json:"auth"`
 	Docs   DocsCapability `json:"docs"`
 	Search Capability     `json:"search"`
 	Tags   Capability     `json:"tags"`
@@ -79,18 +183,139 @@ type Capability struct {
 
 // Collection defines model for Collection.
 type Collection struct {
-	Id CollectionId `json:"id"`
+	// Names of the users allowed to access the collection if authentication is enabled, all users if empty. Admins can access all collections.
+	Allow *CollectionAllow `json:"allow,omitempty"`
+
+	// Directories of the files of the collection
+	Dirs *CollectionDirs `json:"dirs,omitempty"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Exclude *CollectionPatterns `json:"exclude,omitempty"`
+	Id      CollectionId        `json:"id"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Include *CollectionPatterns `json:"include,omitempty"`
 
 	// Time of latest performed full index
-	IndexedAt *time.Time `json:"indexed_at,omitempty"`
+	IndexedAt *time.Time  `json:"indexed_at,omitempty"`
+	Layout    *LayoutType `json:"layout,omitempty"`
+
+	// Max number of path elements below the dirs, 0 for any
+	MaxDepth *CollectionMaxDepth `json:"max_depth,omitempty"`
 
 	// User-friendly name
-	Name *string `json:"name,omitempty"`
+	Name          *string             `json:"name,omitempty"`
+	SavedSearchId *SavedSearchId      `json:"saved_search_id,omitempty"`
+	Schedule      *CollectionSchedule `json:"schedule,omitempty"`
+
+	// Search the files of the dirs are narrowed down by, only set for virtual collections of saved searches.
+	Search *string `json:"search,omitempty"`
+
+	// Files and dirs starting with a dot are left out
+	SkipHidden *bool `json:"skip_hidden,omitempty"`
+
+	// Order of the files, e.g. +date, -date or +shuffle-daily
+	Sort *CollectionSort `json:"sort,omitempty"`
+
+	// Files added, removed and renamed in the dirs are indexed as they change
+	Watch *bool `json:"watch,omitempty"`
+
+	// Tags and ratings are read from the XMP metadata of the files and tags are written back to their XMP sidecars
+	Xmp *bool `json:"xmp,omitempty"`
 }
 
+// Names of the users allowed to access the collection if authentication is enabled, all users if empty. Admins can access all collections.
+type CollectionAllow []string
+
+// Directories of the files of the collection
+type CollectionDirs []string
+
 // CollectionId defines model for CollectionId.
 type CollectionId string
 
+// Max number of path elements below the dirs, 0 for any
+type CollectionMaxDepth int
+
+// CollectionPatch defines model for CollectionPatch.
+type CollectionPatch struct {
+	// Names of the users allowed to access the collection if authentication is enabled, all users if empty. Admins can access all collections.
+	Allow *CollectionAllow `json:"allow,omitempty"`
+
+	// Directories of the files of the collection
+	Dirs *CollectionDirs `json:"dirs,omitempty"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Exclude *CollectionPatterns `json:"exclude,omitempty"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Include *CollectionPatterns `json:"include,omitempty"`
+	Layout  *LayoutType         `json:"layout,omitempty"`
+	Limit   *int                `json:"limit,omitempty"`
+
+	// Max number of path elements below the dirs, 0 for any
+	MaxDepth   *CollectionMaxDepth `json:"max_depth,omitempty"`
+	SkipHidden *bool               `json:"skip_hidden,omitempty"`
+
+	// Order of the files, e.g. +date, -date or +shuffle-daily
+	Sort  *CollectionSort `json:"sort,omitempty"`
+	Watch *bool           `json:"watch,omitempty"`
+	Xmp   *bool           `json:"xmp,omitempty"`
+}
+
+// Glob patterns of the paths of the files relative to the dirs
+type CollectionPatterns []string
+
+// CollectionPost defines model for CollectionPost.
+type CollectionPost struct {
+	// Names of the users allowed to access the collection if authentication is enabled, all users if empty. Admins can access all collections.
+	Allow *CollectionAllow `json:"allow,omitempty"`
+
+	// Directories of the files of the collection
+	Dirs CollectionDirs `json:"dirs"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Exclude    *CollectionPatterns       `json:"exclude,omitempty"`
+	ExpandSort *CollectionPostExpandSort `json:"expand_sort,omitempty"`
+
+	// Create a collection for each subdir of the dirs instead
+	ExpandSubdirs *bool `json:"expand_subdirs,omitempty"`
+
+	// Glob patterns of the paths of the files relative to the dirs
+	Include *CollectionPatterns `json:"include,omitempty"`
+	Layout  *LayoutType         `json:"layout,omitempty"`
+	Limit   *int                `json:"limit,omitempty"`
+
+	// Max number of path elements below the dirs, 0 for any
+	MaxDepth *CollectionMaxDepth `json:"max_depth,omitempty"`
+
+	// User-friendly name, the id is derived from it
+	Name       string `json:"name"`
+	SkipHidden *bool  `json:"skip_hidden,omitempty"`
+
+	// Order of the files, e.g. +date, -date or +shuffle-daily
+	Sort  *CollectionSort `json:"sort,omitempty"`
+	Watch *bool           `json:"watch,omitempty"`
+	Xmp   *bool           `json:"xmp,omitempty"`
+}
+
+// CollectionPostExpandSort defines model for CollectionPost.ExpandSort.
+type CollectionPostExpandSort string
+
+// CollectionSchedule defines model for CollectionSchedule.
+type CollectionSchedule struct {
+	// Cron expression of when to index the collection
+	Index string `json:"index"`
+
+	// Stages to index, files, metadata, contents and faces if empty
+	Stages *[]CollectionScheduleStages `json:"stages,omitempty"`
+}
+
+// CollectionScheduleStages defines model for CollectionSchedule.Stages.
+type CollectionScheduleStages string
+
+// Order of the files, e.g. +date, -date or +shuffle-daily
+type CollectionSort string
+
 // Color defines model for Color.
 type Color string
 
@@ -106,12 +331,43 @@ type DocsCapability struct {
 // A validated and typed search query expression, types omitted as this is subject to many changes.
 type Expression map[string]interface{}
 
+// Face defines model for Face.
+type Face struct {
+	// Detection confidence from 0 to 100
+	Confidence int    `json:"confidence"`
+	FileId     FileId `json:"file_id"`
+	H          int    `json:"h"`
+	Id         FaceId `json:"id"`
+	W          int    `json:"w"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+}
+
+// FaceId defines model for FaceId.
+type FaceId int
+
 // FileBinary defines model for FileBinary.
 type FileBinary string
 
 // FileId defines model for FileId.
 type FileId int
 
+// Set the metadata of the specified files.
+// You need to provide either a `scene_id` & `bounds`, `file_id` or `tag_id`.
+type FilesMetadataPost struct {
+	Bounds   *Bounds `json:"bounds,omitempty"`
+	Favorite *bool   `json:"favorite,omitempty"`
+	FileId   *FileId `json:"file_id,omitempty"`
+
+	// Color label, empty if unlabeled.
+	Label *Label `json:"label,omitempty"`
+
+	// Star rating from 1 to 5, 0 if unrated.
+	Rating  *Rating  `json:"rating,omitempty"`
+	SceneId *SceneId `json:"scene_id,omitempty"`
+	TagId   *TagId   `json:"tag_id,omitempty"`
+}
+
 // GeoJSON FeatureCollection
 type GeoJSON struct {
 	// Array of GeoJSON features representing photos in the scene
@@ -165,15 +421,44 @@ type GeoJSONProperties struct {
 // ImageHeight defines model for ImageHeight.
 type ImageHeight float32
 
+// Color label, empty if unlabeled.
+type Label string
+
 // LayoutType defines model for LayoutType.
 type LayoutType string
 
 // Limit defines model for Limit.
 type Limit int
 
+// Login defines model for Login.
+type Login struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
 // Operation defines model for Operation.
 type Operation string
 
+// Person defines model for Person.
+type Person struct {
+	CoverFaceId *FaceId    `json:"cover_face_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	FaceCount   int        `json:"face_count"`
+	Hidden      bool       `json:"hidden"`
+	Id          PersonId   `json:"id"`
+	Name        *string    `json:"name,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// PersonId defines model for PersonId.
+type PersonId int
+
+// PersonPatch defines model for PersonPatch.
+type PersonPatch struct {
+	Hidden *bool   `json:"hidden,omitempty"`
+	Name   *string `json:"name,omitempty"`
+}
+
 // Problem defines model for Problem.
 type Problem struct {
 	// The HTTP status code generated by the origin server for this occurrence of the problem.
@@ -183,6 +468,9 @@ type Problem struct {
 	Title *string `json:"title,omitempty"`
 }
 
+// Star rating from 1 to 5, 0 if unrated.
+type Rating int
+
 // Region defines model for Region.
 type Region struct {
 	Bounds Bounds      `json:"bounds"`
@@ -203,6 +491,27 @@ type RegionMinimal struct {
 	Id     RegionId `json:"id"`
 }
 
+// SavedSearch defines model for SavedSearch.
+type SavedSearch struct {
+	CollectionId *CollectionId `json:"collection_id,omitempty"`
+	CreatedAt    *time.Time    `json:"created_at,omitempty"`
+	Id           SavedSearchId `json:"id"`
+	Name         string        `json:"name"`
+	Search       Search        `json:"search"`
+	UpdatedAt    *time.Time    `json:"updated_at,omitempty"`
+}
+
+// SavedSearchId defines model for SavedSearchId.
+type SavedSearchId int
+
+// SavedSearchPost defines model for SavedSearchPost.
+type SavedSearchPost struct {
+	// Collection to search in, all collections if omitted.
+	CollectionId *string `json:"collection_id,omitempty"`
+	Name         string  `json:"name"`
+	Search       Search  `json:"search"`
+}
+
 // Scene defines model for Scene.
 type Scene struct {
 	Bounds *Bounds `json:"bounds,omitempty"`
@@ -246,21 +555,86 @@ type SearchQuery struct {
 
 // SearchToken defines model for SearchToken.
 type SearchToken struct {
-	End   int    `json:"end"`
-	Start int    `json:"start"`
+	// Nesting level of the parenthesized group the token is in.
+	Depth *int `json:"depth,omitempty"`
+	End   int  `json:"end"`
+
+	// Whether the term or group opened by the parenthesis is negated.
+	Not   *bool `json:"not,omitempty"`
+	Start int   `json:"start"`
+
+	// One of `word`, `string`, `qualifier`, `operator` (OR, AND) or `paren`.
 	Type  string `json:"type"`
 	Value string `json:"value"`
 }
 
+// Share defines model for Share.
+type Share struct {
+	CollectionId CollectionId `json:"collection_id"`
+	CreatedAt    *time.Time   `json:"created_at,omitempty"`
+
+	// Whether the original files can be downloaded.
+	Download  bool      `json:"download"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Id        ShareId   `json:"id"`
+
+	// Whether the share link needs a password.
+	Password bool    `json:"password"`
+	Search   *Search `json:"search,omitempty"`
+	TagId    *TagId  `json:"tag_id,omitempty"`
+}
+
+// ShareCreated defines model for ShareCreated.
+type ShareCreated struct {
+	// Embedded struct due to allOf(#/components/schemas/Share)
+	Share `yaml:",inline"`
+	// Embedded fields due to inline allOf schema
+	// Secret to pass in the `share` query parameter or the `X-Share-Token` header, only returned once.
+	Token string `json:"token"`
+}
+
+// ShareId defines model for ShareId.
+type ShareId int
+
+// SharePost defines model for SharePost.
+type SharePost struct {
+	CollectionId CollectionId `json:"collection_id"`
+
+	// Whether the original files can be downloaded.
+	Download *bool `json:"download,omitempty"`
+
+	// When the share link expires, in 30 days by default.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Password needed to open the share link, if any.
+	Password *string `json:"password,omitempty"`
+	Search   *Search `json:"search,omitempty"`
+	TagId    *TagId  `json:"tag_id,omitempty"`
+}
+
+// ShareUnlock defines model for ShareUnlock.
+type ShareUnlock struct {
+	Password string `json:"password"`
+}
+
 // Sort defines model for Sort.
 type Sort string
 
 // Tag defines model for Tag.
 type Tag struct {
+	// Suggested for similar files by the INDEX_AUTOTAG task.
+	Auto *bool `json:"auto,omitempty"`
+
+	// Child tags, only listed for tag trees.
+	Children *[]Tag `json:"children,omitempty"`
+
 	// ETag for optimistic concurrency control
-	Etag      *string    `json:"etag,omitempty"`
-	Id        *string    `json:"id,omitempty"`
-	Name      *string    `json:"name,omitempty"`
+	Etag *string `json:"etag,omitempty"`
+	Id   *string `json:"id,omitempty"`
+	Name *string `json:"name,omitempty"`
+
+	// Id of the parent tag of hierarchical tags.
+	Parent    *string    `json:"parent,omitempty"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
@@ -277,6 +651,37 @@ type TagFilesPost struct {
 // TagId defines model for TagId.
 type TagId string
 
+// TagPatch defines model for TagPatch.
+type TagPatch struct {
+	// Suggest the tag for files similar to the tagged ones.
+	Auto *bool `json:"auto,omitempty"`
+
+	// Rename the tag to the full new name, moving it to the parent of the new name, e.g. `places/europe/slovenia`.
+	Name *string `json:"name,omitempty"`
+
+	// Move the tag under the parent tag, keeping the last part of its name, or to the top level if empty.
+	Parent *string `json:"parent,omitempty"`
+}
+
+// TagSuggestion defines model for TagSuggestion.
+type TagSuggestion struct {
+	// Confidence of the suggestion from 0 to 1.
+	Confidence float32 `json:"confidence"`
+	FileId     FileId  `json:"file_id"`
+}
+
+// Accept or reject the suggestions of the tag for the files, or for all
+// files the tag is suggested for if `file_ids` is omitted, optionally
+// only the ones with at least `min_confidence`.
+type TagSuggestionsPost struct {
+	FileIds       *[]FileId            `json:"file_ids,omitempty"`
+	MinConfidence *float32             `json:"min_confidence,omitempty"`
+	Op            TagSuggestionsPostOp `json:"op"`
+}
+
+// TagSuggestionsPostOp defines model for TagSuggestionsPost.Op.
+type TagSuggestionsPostOp string
+
 // Tags defines model for Tags.
 type Tags []Tag
 
@@ -291,32 +696,86 @@ type Task struct {
 	CollectionId *CollectionId `json:"collection_id,omitempty"`
 
 	// Number of items already processed.
-	Done *int   `json:"done,omitempty"`
-	Id   TaskId `json:"id"`
-	Name string `json:"name"`
+	Done *int `json:"done,omitempty"`
+
+	// Error the task failed or was stopped with.
+	Error *string `json:"error,omitempty"`
+
+	// Number of files the task failed to process.
+	ErrorCount *int `json:"error_count,omitempty"`
+
+	// Time the task finished, only set for finished tasks.
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Id         TaskId     `json:"id"`
+	Name       string     `json:"name"`
 
 	// Number of items pending as part of the task.
-	Pending *int `json:"pending,omitempty"`
+	Pending   *int       `json:"pending,omitempty"`
+	RunId     *TaskRunId `json:"run_id,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// Number of items to process, if known.
+	Total *int `json:"total,omitempty"`
 
 	// Task type for background operations.
 	//
 	// Use `force: true` to reprocess all files, `force: false` (default) to only
 	// process files with missing data.
 	//
+	// INDEX_DUPLICATES groups files with identical contents and near
+	// duplicates with similar AI embeddings, see the `dup:` search qualifier.
+	//
+	// INDEX_PERSONS clusters the detected faces into persons, see the
+	// `person:` search qualifier.
+	//
+	// INDEX_AUTOTAG suggests the tags marked as `auto` for similar files,
+	// see the `suggest:` tags.
+	//
+	// INDEX_SCHEDULE checks a collection for changes at the time of its
+	// schedule and queues its scheduled stages if it changed. It is only
+	// queued by the schedule.
+	//
 	// Deprecated (old queue-based path, use the pipeline types instead):
 	// - INDEX_CONTENTS_COLOR
 	// - INDEX_CONTENTS_AI
 	Type *TaskType `json:"type,omitempty"`
 }
 
+// TaskError defines model for TaskError.
+type TaskError struct {
+	CreatedAt time.Time `json:"created_at"`
+	Error     string    `json:"error"`
+	FileId    *FileId   `json:"file_id,omitempty"`
+	Path      string    `json:"path"`
+}
+
 // TaskId defines model for TaskId.
 type TaskId string
 
+// TaskRunId defines model for TaskRunId.
+type TaskRunId int64
+
+// TaskState defines model for TaskState.
+type TaskState string
+
 // Task type for background operations.
 //
 // Use `force: true` to reprocess all files, `force: false` (default) to only
 // process files with missing data.
 //
+// INDEX_DUPLICATES groups files with identical contents and near
+// duplicates with similar AI embeddings, see the `dup:` search qualifier.
+//
+// INDEX_PERSONS clusters the detected faces into persons, see the
+// `person:` search qualifier.
+//
+// INDEX_AUTOTAG suggests the tags marked as `auto` for similar files,
+// see the `suggest:` tags.
+//
+// INDEX_SCHEDULE checks a collection for changes at the time of its
+// schedule and queues its scheduled stages if it changed. It is only
+// queued by the schedule.
+//
 // Deprecated (old queue-based path, use the pipeline types instead):
 // - INDEX_CONTENTS_COLOR
 // - INDEX_CONTENTS_AI
@@ -328,21 +787,66 @@ type TileCoord int
 // Tweaks defines model for Tweaks.
 type Tweaks string
 
+// User defines model for User.
+type User struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Id        UserId     `json:"id"`
+	Name      string     `json:"name"`
+
+	// Viewers can browse the collections they are allowed to, editors can also tag, rate and label files, and admins can also manage collections, users and tasks, and access all collections.
+	Role UserRole `json:"role"`
+}
+
+// UserId defines model for UserId.
+type UserId int
+
+// UserPatch defines model for UserPatch.
+type UserPatch struct {
+	Password *string `json:"password,omitempty"`
+
+	// Viewers can browse the collections they are allowed to, editors can also tag, rate and label files, and admins can also manage collections, users and tasks, and access all collections.
+	Role *UserRole `json:"role,omitempty"`
+}
+
+// UserPost defines model for UserPost.
+type UserPost struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+
+	// Viewers can browse the collections they are allowed to, editors can also tag, rate and label files, and admins can also manage collections, users and tasks, and access all collections.
+	Role UserRole `json:"role"`
+}
+
+// Viewers can browse the collections they are allowed to, editors can also tag, rate and label files, and admins can also manage collections, users and tasks, and access all collections.
+type UserRole string
+
 // ViewportHeight defines model for ViewportHeight.
 type ViewportHeight float32
 
 // ViewportWidth defines model for ViewportWidth.
 type ViewportWidth float32
 
+// ApiTokenIdPathParam defines model for ApiTokenIdPathParam.
+type ApiTokenIdPathParam ApiTokenId
+
 // FileIdPathParam defines model for FileIdPathParam.
 type FileIdPathParam FileId
 
 // FilenamePathParam defines model for FilenamePathParam.
 type FilenamePathParam string
 
+// PersonIdPathParam defines model for PersonIdPathParam.
+type PersonIdPathParam PersonId
+
+// SavedSearchIdPathParam defines model for SavedSearchIdPathParam.
+type SavedSearchIdPathParam SavedSearchId
+
 // SearchParam defines model for SearchParam.
 type SearchParam Search
 
+// ShareIdPathParam defines model for ShareIdPathParam.
+type ShareIdPathParam ShareId
+
 // SizePathParam defines model for SizePathParam.
 type SizePathParam string
 
@@ -352,6 +856,29 @@ type TagIdPathParam TagId
 // TaskIdPathParam defines model for TaskIdPathParam.
 type TaskIdPathParam TaskId
 
+// UserIdPathParam defines model for UserIdPathParam.
+type UserIdPathParam UserId
+
+// PostAuthLoginJSONBody defines parameters for PostAuthLogin.
+type PostAuthLoginJSONBody Login
+
+// PostCollectionsJSONBody defines parameters for PostCollections.
+type PostCollectionsJSONBody CollectionPost
+
+// PatchCollectionsIdJSONBody defines parameters for PatchCollectionsId.
+type PatchCollectionsIdJSONBody CollectionPatch
+
+// GetCollectionsIdDownloadZipParams defines parameters for GetCollectionsIdDownloadZip.
+type GetCollectionsIdDownloadZipParams struct {
+	Search *Search `json:"search,omitempty"`
+
+	// Name of a resized variant to download instead of the original files, see the `sources` of the `media` config, e.g. `M`. Files without the variant are downloaded as originals.
+	Size *string `json:"size,omitempty"`
+}
+
+// PostFilesMetadataJSONBody defines parameters for PostFilesMetadata.
+type PostFilesMetadataJSONBody FilesMetadataPost
+
 // GetFilesIdPreviewsFilenameParams defines parameters for GetFilesIdPreviewsFilename.
 type GetFilesIdPreviewsFilenameParams struct {
 	// Target width in pixels. If omitted, uses original width or scales proportionally with height.
@@ -379,6 +906,31 @@ type GetFilesIdPreviewsFilenameParams struct {
 	CropH *int `json:"crop_h,omitempty"`
 }
 
+// GetPersonsParams defines parameters for GetPersons.
+type GetPersonsParams struct {
+	// Include hidden persons.
+	Hidden *bool `json:"hidden,omitempty"`
+}
+
+// PatchPersonsIdJSONBody defines parameters for PatchPersonsId.
+type PatchPersonsIdJSONBody PersonPatch
+
+// PostPersonsIdMergeJSONBody defines parameters for PostPersonsIdMerge.
+type PostPersonsIdMergeJSONBody struct {
+	Ids []PersonId `json:"ids"`
+}
+
+// PostPersonsIdSplitJSONBody defines parameters for PostPersonsIdSplit.
+type PostPersonsIdSplitJSONBody struct {
+	FaceIds []FaceId `json:"face_ids"`
+}
+
+// PostSavedSearchesJSONBody defines parameters for PostSavedSearches.
+type PostSavedSearchesJSONBody SavedSearchPost
+
+// PutSavedSearchesIdJSONBody defines parameters for PutSavedSearchesId.
+type PutSavedSearchesIdJSONBody SavedSearchPost
+
 // GetScenesParams defines parameters for GetScenes.
 type GetScenesParams struct {
 	// Collection ID
@@ -459,18 +1011,48 @@ type GetScenesSceneIdTilesParams struct {
 	QualityPreset   *string `json:"quality_preset,omitempty"`
 }
 
+// PostShareUnlockJSONBody defines parameters for PostShareUnlock.
+type PostShareUnlockJSONBody ShareUnlock
+
+// PostSharesJSONBody defines parameters for PostShares.
+type PostSharesJSONBody SharePost
+
 // GetTagsParams defines parameters for GetTags.
 type GetTagsParams struct {
 	// Search custom text query
 	Q *SearchParam `json:"q,omitempty"`
+
+	// List the children of the tag with this id, or the tags without a parent if empty. Ignores the search query.
+	Parent *TagId `json:"parent,omitempty"`
+
+	// List the tags as a tree with their `children`, below the `parent` tag if set. Ignores the search query.
+	Tree *bool `json:"tree,omitempty"`
 }
 
 // PostTagsJSONBody defines parameters for PostTags.
 type PostTagsJSONBody TagsPost
 
+// PatchTagsIdJSONBody defines parameters for PatchTagsId.
+type PatchTagsIdJSONBody TagPatch
+
+// GetTagsIdDownloadZipParams defines parameters for GetTagsIdDownloadZip.
+type GetTagsIdDownloadZipParams struct {
+	// Name of a resized variant to download instead of the original files, see the `sources` of the `media` config, e.g. `M`. Files without the variant are downloaded as originals.
+	Size *string `json:"size,omitempty"`
+}
+
 // PostTagsIdFilesJSONBody defines parameters for PostTagsIdFiles.
 type PostTagsIdFilesJSONBody TagFilesPost
 
+// GetTagsIdSuggestionsParams defines parameters for GetTagsIdSuggestions.
+type GetTagsIdSuggestionsParams struct {
+	// Maximum number of suggestions, 100 by default.
+	Limit *Limit `json:"limit,omitempty"`
+}
+
+// PostTagsIdSuggestionsJSONBody defines parameters for PostTagsIdSuggestions.
+type PostTagsIdSuggestionsJSONBody TagSuggestionsPost
+
 // GetTasksParams defines parameters for GetTasks.
 type GetTasksParams struct {
 	// Task type to filter on.
@@ -478,6 +1060,12 @@ type GetTasksParams struct {
 
 	// Collection ID for the tasks
 	CollectionId *CollectionId `json:"collection_id,omitempty"`
+
+	// Running or finished tasks, running by default.
+	State *TaskState `json:"state,omitempty"`
+
+	// Maximum number of finished tasks, 100 by default.
+	Limit *Limit `json:"limit,omitempty"`
 }
 
 // PostTasksJSONBody defines parameters for PostTasks.
@@ -492,36 +1080,142 @@ type PostTasksJSONBody struct {
 	// Use `force: true` to reprocess all files, `force: false` (default) to only
 	// process files with missing data.
 	//
+	// INDEX_DUPLICATES groups files with identical contents and near
+	// duplicates with similar AI embeddings, see the `dup:` search qualifier.
+	//
+	// INDEX_PERSONS clusters the detected faces into persons, see the
+	// `person:` search qualifier.
+	//
+	// INDEX_AUTOTAG suggests the tags marked as `auto` for similar files,
+	// see the `suggest:` tags.
+	//
+	// INDEX_SCHEDULE checks a collection for changes at the time of its
+	// schedule and queues its scheduled stages if it changed. It is only
+	// queued by the schedule.
+	//
 	// Deprecated (old queue-based path, use the pipeline types instead):
 	// - INDEX_CONTENTS_COLOR
 	// - INDEX_CONTENTS_AI
 	Type TaskType `json:"type"`
 }
 
+// GetTasksIdErrorsParams defines parameters for GetTasksIdErrors.
+type GetTasksIdErrorsParams struct {
+	// Run of the task to get the errors of.
+	RunId *TaskRunId `json:"run_id,omitempty"`
+}
+
+// PostTokensJSONBody defines parameters for PostTokens.
+type PostTokensJSONBody ApiTokenPost
+
+// PostUsersJSONBody defines parameters for PostUsers.
+type PostUsersJSONBody UserPost
+
+// PatchUsersIdJSONBody defines parameters for PatchUsersId.
+type PatchUsersIdJSONBody UserPatch
+
+// PostAuthLoginJSONRequestBody defines body for PostAuthLogin for application/json ContentType.
+type PostAuthLoginJSONRequestBody PostAuthLoginJSONBody
+
+// PostCollectionsJSONRequestBody defines body for PostCollections for application/json ContentType.
+type PostCollectionsJSONRequestBody PostCollectionsJSONBody
+
+// PatchCollectionsIdJSONRequestBody defines body for PatchCollectionsId for application/json ContentType.
+type PatchCollectionsIdJSONRequestBody PatchCollectionsIdJSONBody
+
+// PostFilesMetadataJSONRequestBody defines body for PostFilesMetadata for application/json ContentType.
+type PostFilesMetadataJSONRequestBody PostFilesMetadataJSONBody
+
+// PatchPersonsIdJSONRequestBody defines body for PatchPersonsId for application/json ContentType.
+type PatchPersonsIdJSONRequestBody PatchPersonsIdJSONBody
+
+// PostPersonsIdMergeJSONRequestBody defines body for PostPersonsIdMerge for application/json ContentType.
+type PostPersonsIdMergeJSONRequestBody PostPersonsIdMergeJSONBody
+
+// PostPersonsIdSplitJSONRequestBody defines body for PostPersonsIdSplit for application/json ContentType.
+type PostPersonsIdSplitJSONRequestBody PostPersonsIdSplitJSONBody
+
+// PostSavedSearchesJSONRequestBody defines body for PostSavedSearches for application/json ContentType.
+type PostSavedSearchesJSONRequestBody PostSavedSearchesJSONBody
+
+// PutSavedSearchesIdJSONRequestBody defines body for PutSavedSearchesId for application/json ContentType.
+type PutSavedSearchesIdJSONRequestBody PutSavedSearchesIdJSONBody
+
 // PostScenesJSONRequestBody defines body for PostScenes for application/json ContentType.
 type PostScenesJSONRequestBody PostScenesJSONBody
 
+// PostShareUnlockJSONRequestBody defines body for PostShareUnlock for application/json ContentType.
+type PostShareUnlockJSONRequestBody PostShareUnlockJSONBody
+
+// PostSharesJSONRequestBody defines body for PostShares for application/json ContentType.
+type PostSharesJSONRequestBody PostSharesJSONBody
+
 // PostTagsJSONRequestBody defines body for PostTags for application/json ContentType.
 type PostTagsJSONRequestBody PostTagsJSONBody
 
+// PatchTagsIdJSONRequestBody defines body for PatchTagsId for application/json ContentType.
+type PatchTagsIdJSONRequestBody PatchTagsIdJSONBody
+
 // PostTagsIdFilesJSONRequestBody defines body for PostTagsIdFiles for application/json ContentType.
 type PostTagsIdFilesJSONRequestBody PostTagsIdFilesJSONBody
 
+// PostTagsIdSuggestionsJSONRequestBody defines body for PostTagsIdSuggestions for application/json ContentType.
+type PostTagsIdSuggestionsJSONRequestBody PostTagsIdSuggestionsJSONBody
+
 // PostTasksJSONRequestBody defines body for PostTasks for application/json ContentType.
 type PostTasksJSONRequestBody PostTasksJSONBody
 
+// PostTokensJSONRequestBody defines body for PostTokens for application/json ContentType.
+type PostTokensJSONRequestBody PostTokensJSONBody
+
+// PostUsersJSONRequestBody defines body for PostUsers for application/json ContentType.
+type PostUsersJSONRequestBody PostUsersJSONBody
+
+// PatchUsersIdJSONRequestBody defines body for PatchUsersId for application/json ContentType.
+type PatchUsersIdJSONRequestBody PatchUsersIdJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 
+	// (POST /admin/backup)
+	PostAdminBackup(w http.ResponseWriter, r *http.Request)
+
+	// (POST /auth/login)
+	PostAuthLogin(w http.ResponseWriter, r *http.Request)
+
+	// (POST /auth/logout)
+	PostAuthLogout(w http.ResponseWriter, r *http.Request)
+
+	// (GET /auth/me)
+	GetAuthMe(w http.ResponseWriter, r *http.Request)
+
 	// (GET /capabilities)
 	GetCapabilities(w http.ResponseWriter, r *http.Request)
 
 	// (GET /collections)
 	GetCollections(w http.ResponseWriter, r *http.Request)
 
+	// (POST /collections)
+	PostCollections(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /collections/{id})
+	DeleteCollectionsId(w http.ResponseWriter, r *http.Request, id CollectionId)
+
 	// (GET /collections/{id})
 	GetCollectionsId(w http.ResponseWriter, r *http.Request, id CollectionId)
 
+	// (PATCH /collections/{id})
+	PatchCollectionsId(w http.ResponseWriter, r *http.Request, id CollectionId)
+
+	// (GET /collections/{id}/download.zip)
+	GetCollectionsIdDownloadZip(w http.ResponseWriter, r *http.Request, id CollectionId, params GetCollectionsIdDownloadZipParams)
+
+	// (GET /events)
+	GetEvents(w http.ResponseWriter, r *http.Request)
+
+	// (POST /files/metadata)
+	PostFilesMetadata(w http.ResponseWriter, r *http.Request)
+
 	// (GET /files/{id})
 	GetFilesId(w http.ResponseWriter, r *http.Request, id FileIdPathParam)
 
@@ -534,6 +1228,39 @@ type ServerInterface interface {
 	// (GET /files/{id}/variants/{size}/{filename})
 	GetFilesIdVariantsSizeFilename(w http.ResponseWriter, r *http.Request, id FileIdPathParam, size SizePathParam, filename FilenamePathParam)
 
+	// (GET /persons)
+	GetPersons(w http.ResponseWriter, r *http.Request, params GetPersonsParams)
+
+	// (GET /persons/{id})
+	GetPersonsId(w http.ResponseWriter, r *http.Request, id PersonIdPathParam)
+
+	// (PATCH /persons/{id})
+	PatchPersonsId(w http.ResponseWriter, r *http.Request, id PersonIdPathParam)
+
+	// (GET /persons/{id}/faces)
+	GetPersonsIdFaces(w http.ResponseWriter, r *http.Request, id PersonIdPathParam)
+
+	// (POST /persons/{id}/merge)
+	PostPersonsIdMerge(w http.ResponseWriter, r *http.Request, id PersonIdPathParam)
+
+	// (POST /persons/{id}/split)
+	PostPersonsIdSplit(w http.ResponseWriter, r *http.Request, id PersonIdPathParam)
+
+	// (GET /saved-searches)
+	GetSavedSearches(w http.ResponseWriter, r *http.Request)
+
+	// (POST /saved-searches)
+	PostSavedSearches(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /saved-searches/{id})
+	DeleteSavedSearchesId(w http.ResponseWriter, r *http.Request, id SavedSearchIdPathParam)
+
+	// (GET /saved-searches/{id})
+	GetSavedSearchesId(w http.ResponseWriter, r *http.Request, id SavedSearchIdPathParam)
+
+	// (PUT /saved-searches/{id})
+	PutSavedSearchesId(w http.ResponseWriter, r *http.Request, id SavedSearchIdPathParam)
+
 	// (GET /scenes)
 	GetScenes(w http.ResponseWriter, r *http.Request, params GetScenesParams)
 
@@ -561,6 +1288,21 @@ type ServerInterface interface {
 	// (GET /scenes/{scene_id}/tiles)
 	GetScenesSceneIdTiles(w http.ResponseWriter, r *http.Request, sceneId SceneId, params GetScenesSceneIdTilesParams)
 
+	// (GET /share)
+	GetShare(w http.ResponseWriter, r *http.Request)
+
+	// (POST /share/unlock)
+	PostShareUnlock(w http.ResponseWriter, r *http.Request)
+
+	// (GET /shares)
+	GetShares(w http.ResponseWriter, r *http.Request)
+
+	// (POST /shares)
+	PostShares(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /shares/{id})
+	DeleteSharesId(w http.ResponseWriter, r *http.Request, id ShareIdPathParam)
+
 	// (GET /tags)
 	GetTags(w http.ResponseWriter, r *http.Request, params GetTagsParams)
 
@@ -570,13 +1312,25 @@ type ServerInterface interface {
 	// (GET /tags/{id})
 	GetTagsId(w http.ResponseWriter, r *http.Request, id TagIdPathParam)
 
+	// (PATCH /tags/{id})
+	PatchTagsId(w http.ResponseWriter, r *http.Request, id TagIdPathParam)
+
+	// (GET /tags/{id}/download.zip)
+	GetTagsIdDownloadZip(w http.ResponseWriter, r *http.Request, id TagIdPathParam, params GetTagsIdDownloadZipParams)
+
 	// (POST /tags/{id}/files)
 	PostTagsIdFiles(w http.ResponseWriter, r *http.Request, id TagIdPathParam)
 
 	// (GET /tags/{id}/files-tags)
 	GetTagsIdFilesTags(w http.ResponseWriter, r *http.Request, id TagIdPathParam)
 
-	// (GET /tasks)
+	// (GET /tags/{id}/suggestions)
+	GetTagsIdSuggestions(w http.ResponseWriter, r *http.Request, id TagIdPathParam, params GetTagsIdSuggestionsParams)
+
+	// (POST /tags/{id}/suggestions)
+	PostTagsIdSuggestions(w http.ResponseWriter, r *http.Request, id TagIdPathParam)
+
+	// (GET /tasks)
 	GetTasks(w http.ResponseWriter, r *http.Request, params GetTasksParams)
 
 	// (POST /tasks)
@@ -584,6 +1338,30 @@ type ServerInterface interface {
 
 	// (DELETE /tasks/{id})
 	DeleteTasksId(w http.ResponseWriter, r *http.Request, id TaskIdPathParam)
+
+	// (GET /tasks/{id}/errors)
+	GetTasksIdErrors(w http.ResponseWriter, r *http.Request, id TaskIdPathParam, params GetTasksIdErrorsParams)
+
+	// (GET /tokens)
+	GetTokens(w http.ResponseWriter, r *http.Request)
+
+	// (POST /tokens)
+	PostTokens(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /tokens/{id})
+	DeleteTokensId(w http.ResponseWriter, r *http.Request, id ApiTokenIdPathParam)
+
+	// (GET /users)
+	GetUsers(w http.ResponseWriter, r *http.Request)
+
+	// (POST /users)
+	PostUsers(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /users/{id})
+	DeleteUsersId(w http.ResponseWriter, r *http.Request, id UserIdPathParam)
+
+	// (PATCH /users/{id})
+	PatchUsersId(w http.ResponseWriter, r *http.Request, id UserIdPathParam)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -594,6 +1372,66 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
+// PostAdminBackup operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminBackup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAdminBackup(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostAuthLogin operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthLogin(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostAuthLogout operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthLogout(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetAuthMe operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthMe(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetCapabilities operation middleware
 func (siw *ServerInterfaceWrapper) GetCapabilities(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -624,6 +1462,47 @@ func (siw *ServerInterfaceWrapper) GetCollections(w http.ResponseWriter, r *http
 	handler(w, r.WithContext(ctx))
 }
 
+// PostCollections operation middleware
+func (siw *ServerInterfaceWrapper) PostCollections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostCollections(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteCollectionsId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteCollectionsId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id CollectionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteCollectionsId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetCollectionsId operation middleware
 func (siw *ServerInterfaceWrapper) GetCollectionsId(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -650,6 +1529,113 @@ func (siw *ServerInterfaceWrapper) GetCollectionsId(w http.ResponseWriter, r *ht
 	handler(w, r.WithContext(ctx))
 }
 
+// PatchCollectionsId operation middleware
+func (siw *ServerInterfaceWrapper) PatchCollectionsId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id CollectionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchCollectionsId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetCollectionsIdDownloadZip operation middleware
+func (siw *ServerInterfaceWrapper) GetCollectionsIdDownloadZip(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id CollectionId
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetCollectionsIdDownloadZipParams
+
+	// ------------- Optional query parameter "search" -------------
+	if paramValue := r.URL.Query().Get("search"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "search", r.URL.Query(), &params.Search)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter search: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "size" -------------
+	if paramValue := r.URL.Query().Get("size"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "size", r.URL.Query(), &params.Size)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter size: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetCollectionsIdDownloadZip(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetEvents operation middleware
+func (siw *ServerInterfaceWrapper) GetEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetEvents(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostFilesMetadata operation middleware
+func (siw *ServerInterfaceWrapper) PostFilesMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostFilesMetadata(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetFilesId operation middleware
 func (siw *ServerInterfaceWrapper) GetFilesId(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -881,86 +1867,355 @@ func (siw *ServerInterfaceWrapper) GetFilesIdVariantsSizeFilename(w http.Respons
 	handler(w, r.WithContext(ctx))
 }
 
-// GetScenes operation middleware
-func (siw *ServerInterfaceWrapper) GetScenes(w http.ResponseWriter, r *http.Request) {
+// GetPersons operation middleware
+func (siw *ServerInterfaceWrapper) GetPersons(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetScenesParams
+	var params GetPersonsParams
 
-	// ------------- Required query parameter "collection_id" -------------
-	if paramValue := r.URL.Query().Get("collection_id"); paramValue != "" {
+	// ------------- Optional query parameter "hidden" -------------
+	if paramValue := r.URL.Query().Get("hidden"); paramValue != "" {
 
-	} else {
-		http.Error(w, "Query argument collection_id is required, but not found", http.StatusBadRequest)
-		return
 	}
 
-	err = runtime.BindQueryParameter("form", true, true, "collection_id", r.URL.Query(), &params.CollectionId)
+	err = runtime.BindQueryParameter("form", true, false, "hidden", r.URL.Query(), &params.Hidden)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter collection_id: %s", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Invalid format for parameter hidden: %s", err), http.StatusBadRequest)
 		return
 	}
 
-	// ------------- Optional query parameter "viewport_width" -------------
-	if paramValue := r.URL.Query().Get("viewport_width"); paramValue != "" {
-
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetPersons(w, r, params)
 	}
 
-	err = runtime.BindQueryParameter("form", true, false, "viewport_width", r.URL.Query(), &params.ViewportWidth)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter viewport_width: %s", err), http.StatusBadRequest)
-		return
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	// ------------- Optional query parameter "viewport_height" -------------
-	if paramValue := r.URL.Query().Get("viewport_height"); paramValue != "" {
-
-	}
+	handler(w, r.WithContext(ctx))
+}
 
-	err = runtime.BindQueryParameter("form", true, false, "viewport_height", r.URL.Query(), &params.ViewportHeight)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter viewport_height: %s", err), http.StatusBadRequest)
-		return
-	}
+// GetPersonsId operation middleware
+func (siw *ServerInterfaceWrapper) GetPersonsId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	// ------------- Optional query parameter "image_height" -------------
-	if paramValue := r.URL.Query().Get("image_height"); paramValue != "" {
+	var err error
 
-	}
+	// ------------- Path parameter "id" -------------
+	var id PersonIdPathParam
 
-	err = runtime.BindQueryParameter("form", true, false, "image_height", r.URL.Query(), &params.ImageHeight)
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter image_height: %s", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
 		return
 	}
 
-	// ------------- Optional query parameter "layout" -------------
-	if paramValue := r.URL.Query().Get("layout"); paramValue != "" {
-
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetPersonsId(w, r, id)
 	}
 
-	err = runtime.BindQueryParameter("form", true, false, "layout", r.URL.Query(), &params.Layout)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter layout: %s", err), http.StatusBadRequest)
-		return
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	// ------------- Optional query parameter "sort" -------------
-	if paramValue := r.URL.Query().Get("sort"); paramValue != "" {
+	handler(w, r.WithContext(ctx))
+}
 
-	}
+// PatchPersonsId operation middleware
+func (siw *ServerInterfaceWrapper) PatchPersonsId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id PersonIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid format for parameter sort: %s", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
 		return
 	}
 
-	// ------------- Optional query parameter "search" -------------
-	if paramValue := r.URL.Query().Get("search"); paramValue != "" {
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchPersonsId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetPersonsIdFaces operation middleware
+func (siw *ServerInterfaceWrapper) GetPersonsIdFaces(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id PersonIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetPersonsIdFaces(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostPersonsIdMerge operation middleware
+func (siw *ServerInterfaceWrapper) PostPersonsIdMerge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id PersonIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostPersonsIdMerge(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostPersonsIdSplit operation middleware
+func (siw *ServerInterfaceWrapper) PostPersonsIdSplit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id PersonIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostPersonsIdSplit(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetSavedSearches operation middleware
+func (siw *ServerInterfaceWrapper) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetSavedSearches(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostSavedSearches operation middleware
+func (siw *ServerInterfaceWrapper) PostSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostSavedSearches(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteSavedSearchesId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteSavedSearchesId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id SavedSearchIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteSavedSearchesId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetSavedSearchesId operation middleware
+func (siw *ServerInterfaceWrapper) GetSavedSearchesId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id SavedSearchIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetSavedSearchesId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PutSavedSearchesId operation middleware
+func (siw *ServerInterfaceWrapper) PutSavedSearchesId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id SavedSearchIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutSavedSearchesId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetScenes operation middleware
+func (siw *ServerInterfaceWrapper) GetScenes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetScenesParams
+
+	// ------------- Required query parameter "collection_id" -------------
+	if paramValue := r.URL.Query().Get("collection_id"); paramValue != "" {
+
+	} else {
+		http.Error(w, "Query argument collection_id is required, but not found", http.StatusBadRequest)
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "collection_id", r.URL.Query(), &params.CollectionId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter collection_id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "viewport_width" -------------
+	if paramValue := r.URL.Query().Get("viewport_width"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "viewport_width", r.URL.Query(), &params.ViewportWidth)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter viewport_width: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "viewport_height" -------------
+	if paramValue := r.URL.Query().Get("viewport_height"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "viewport_height", r.URL.Query(), &params.ViewportHeight)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter viewport_height: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "image_height" -------------
+	if paramValue := r.URL.Query().Get("image_height"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "image_height", r.URL.Query(), &params.ImageHeight)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter image_height: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "layout" -------------
+	if paramValue := r.URL.Query().Get("layout"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "layout", r.URL.Query(), &params.Layout)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter layout: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+	if paramValue := r.URL.Query().Get("sort"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter sort: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "search" -------------
+	if paramValue := r.URL.Query().Get("search"); paramValue != "" {
 
 	}
 
@@ -1537,26 +2792,134 @@ func (siw *ServerInterfaceWrapper) GetScenesSceneIdTiles(w http.ResponseWriter,
 	handler(w, r.WithContext(ctx))
 }
 
-// GetTags operation middleware
-func (siw *ServerInterfaceWrapper) GetTags(w http.ResponseWriter, r *http.Request) {
+// GetShare operation middleware
+func (siw *ServerInterfaceWrapper) GetShare(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var err error
-
-	// Parameter object where we will unmarshal all parameters from the context
-	var params GetTagsParams
-
-	// ------------- Optional query parameter "q" -------------
-	if paramValue := r.URL.Query().Get("q"); paramValue != "" {
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShare(w, r)
+	}
 
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	err = runtime.BindQueryParameter("form", true, false, "q", r.URL.Query(), &params.Q)
+	handler(w, r.WithContext(ctx))
+}
+
+// PostShareUnlock operation middleware
+func (siw *ServerInterfaceWrapper) PostShareUnlock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostShareUnlock(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetShares operation middleware
+func (siw *ServerInterfaceWrapper) GetShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShares(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostShares operation middleware
+func (siw *ServerInterfaceWrapper) PostShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostShares(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteSharesId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteSharesId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ShareIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteSharesId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetTags operation middleware
+func (siw *ServerInterfaceWrapper) GetTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTagsParams
+
+	// ------------- Optional query parameter "q" -------------
+	if paramValue := r.URL.Query().Get("q"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "q", r.URL.Query(), &params.Q)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid format for parameter q: %s", err), http.StatusBadRequest)
 		return
 	}
 
+	// ------------- Optional query parameter "parent" -------------
+	if paramValue := r.URL.Query().Get("parent"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "parent", r.URL.Query(), &params.Parent)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter parent: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "tree" -------------
+	if paramValue := r.URL.Query().Get("tree"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "tree", r.URL.Query(), &params.Tree)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter tree: %s", err), http.StatusBadRequest)
+		return
+	}
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetTags(w, r, params)
 	}
@@ -1609,6 +2972,72 @@ func (siw *ServerInterfaceWrapper) GetTagsId(w http.ResponseWriter, r *http.Requ
 	handler(w, r.WithContext(ctx))
 }
 
+// PatchTagsId operation middleware
+func (siw *ServerInterfaceWrapper) PatchTagsId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TagIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchTagsId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetTagsIdDownloadZip operation middleware
+func (siw *ServerInterfaceWrapper) GetTagsIdDownloadZip(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TagIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTagsIdDownloadZipParams
+
+	// ------------- Optional query parameter "size" -------------
+	if paramValue := r.URL.Query().Get("size"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "size", r.URL.Query(), &params.Size)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter size: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTagsIdDownloadZip(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // PostTagsIdFiles operation middleware
 func (siw *ServerInterfaceWrapper) PostTagsIdFiles(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -1661,6 +3090,72 @@ func (siw *ServerInterfaceWrapper) GetTagsIdFilesTags(w http.ResponseWriter, r *
 	handler(w, r.WithContext(ctx))
 }
 
+// GetTagsIdSuggestions operation middleware
+func (siw *ServerInterfaceWrapper) GetTagsIdSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TagIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTagsIdSuggestionsParams
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTagsIdSuggestions(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostTagsIdSuggestions operation middleware
+func (siw *ServerInterfaceWrapper) PostTagsIdSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TagIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostTagsIdSuggestions(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetTasks operation middleware
 func (siw *ServerInterfaceWrapper) GetTasks(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -1692,6 +3187,28 @@ func (siw *ServerInterfaceWrapper) GetTasks(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// ------------- Optional query parameter "state" -------------
+	if paramValue := r.URL.Query().Get("state"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "state", r.URL.Query(), &params.State)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter state: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetTasks(w, r, params)
 	}
@@ -1744,6 +3261,184 @@ func (siw *ServerInterfaceWrapper) DeleteTasksId(w http.ResponseWriter, r *http.
 	handler(w, r.WithContext(ctx))
 }
 
+// GetTasksIdErrors operation middleware
+func (siw *ServerInterfaceWrapper) GetTasksIdErrors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id TaskIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTasksIdErrorsParams
+
+	// ------------- Optional query parameter "run_id" -------------
+	if paramValue := r.URL.Query().Get("run_id"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "run_id", r.URL.Query(), &params.RunId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter run_id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTasksIdErrors(w, r, id, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetTokens operation middleware
+func (siw *ServerInterfaceWrapper) GetTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetTokens(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostTokens operation middleware
+func (siw *ServerInterfaceWrapper) PostTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostTokens(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteTokensId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteTokensId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ApiTokenIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteTokensId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetUsers operation middleware
+func (siw *ServerInterfaceWrapper) GetUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetUsers(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostUsers operation middleware
+func (siw *ServerInterfaceWrapper) PostUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostUsers(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteUsersId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteUsersId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id UserIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteUsersId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PatchUsersId operation middleware
+func (siw *ServerInterfaceWrapper) PatchUsersId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id UserIdPathParam
+
+	err = runtime.BindStyledParameter("simple", false, "id", chi.URLParam(r, "id"), &id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PatchUsersId(w, r, id)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // Handler creates http.Handler with routing matching OpenAPI spec.
 func Handler(si ServerInterface) http.Handler {
 	return HandlerWithOptions(si, ChiServerOptions{})
@@ -1781,15 +3476,45 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		HandlerMiddlewares: options.Middlewares,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/backup", wrapper.PostAdminBackup)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/login", wrapper.PostAuthLogin)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/logout", wrapper.PostAuthLogout)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/auth/me", wrapper.GetAuthMe)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/capabilities", wrapper.GetCapabilities)
 	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/collections", wrapper.GetCollections)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/collections", wrapper.PostCollections)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/collections/{id}", wrapper.DeleteCollectionsId)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/collections/{id}", wrapper.GetCollectionsId)
 	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/collections/{id}", wrapper.PatchCollectionsId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/collections/{id}/download.zip", wrapper.GetCollectionsIdDownloadZip)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/events", wrapper.GetEvents)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/files/metadata", wrapper.PostFilesMetadata)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/files/{id}", wrapper.GetFilesId)
 	})
@@ -1802,6 +3527,39 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/files/{id}/variants/{size}/{filename}", wrapper.GetFilesIdVariantsSizeFilename)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/persons", wrapper.GetPersons)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/persons/{id}", wrapper.GetPersonsId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/persons/{id}", wrapper.PatchPersonsId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/persons/{id}/faces", wrapper.GetPersonsIdFaces)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/persons/{id}/merge", wrapper.PostPersonsIdMerge)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/persons/{id}/split", wrapper.PostPersonsIdSplit)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/saved-searches", wrapper.GetSavedSearches)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/saved-searches", wrapper.PostSavedSearches)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/saved-searches/{id}", wrapper.DeleteSavedSearchesId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/saved-searches/{id}", wrapper.GetSavedSearchesId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/saved-searches/{id}", wrapper.PutSavedSearchesId)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/scenes", wrapper.GetScenes)
 	})
@@ -1829,6 +3587,21 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/scenes/{scene_id}/tiles", wrapper.GetScenesSceneIdTiles)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/share", wrapper.GetShare)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/share/unlock", wrapper.PostShareUnlock)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/shares", wrapper.GetShares)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/shares", wrapper.PostShares)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/shares/{id}", wrapper.DeleteSharesId)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/tags", wrapper.GetTags)
 	})
@@ -1838,12 +3611,24 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/tags/{id}", wrapper.GetTagsId)
 	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/tags/{id}", wrapper.PatchTagsId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/tags/{id}/download.zip", wrapper.GetTagsIdDownloadZip)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/tags/{id}/files", wrapper.PostTagsIdFiles)
 	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/tags/{id}/files-tags", wrapper.GetTagsIdFilesTags)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/tags/{id}/suggestions", wrapper.GetTagsIdSuggestions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/tags/{id}/suggestions", wrapper.PostTagsIdSuggestions)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/tasks", wrapper.GetTasks)
 	})
@@ -1853,6 +3638,30 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Delete(options.BaseURL+"/tasks/{id}", wrapper.DeleteTasksId)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/tasks/{id}/errors", wrapper.GetTasksIdErrors)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/tokens", wrapper.GetTokens)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/tokens", wrapper.PostTokens)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/tokens/{id}", wrapper.DeleteTokensId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/users", wrapper.GetUsers)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/users", wrapper.PostUsers)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/users/{id}", wrapper.DeleteUsersId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/users/{id}", wrapper.PatchUsersId)
+	})
 
 	return r
 }
@@ -44,21 +44,23 @@ func (photo *Photo) Place(x float64, y float64, width float64, height float64, s
 	photo.Sprite.PlaceFit(x, y, width, height, imageWidth, imageHeight)
 }
 
-func (photo *Photo) Draw(ctx context.Context, config *Render, scene *Scene, c *canvas.Context, scales Scales, source *image.Source, selected bool, crop Rect) {
+// Draw draws the photo, returning false if it was drawn as a placeholder
+// instead, e.g. as its thumbnail or color is not available yet.
+func (photo *Photo) Draw(ctx context.Context, config *Render, scene *Scene, c *canvas.Context, scales Scales, source *image.Source, selected bool, crop Rect) bool {
 	defer trace.StartRegion(ctx, "photo.Draw").End()
 
 	pixelArea := photo.Sprite.Rect.GetPixelArea(c, image.Size{X: 1, Y: 1})
 
 	// Avoid drawing almost-invisible photos or squares
 	if pixelArea < 0.1 {
-		return
+		return true
 	}
 
 	if config.TransparencyMask {
 		style := c.Style
 		style.FillColor = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
 		photo.Sprite.DrawWithStyle(c, style)
-		return
+		return true
 	}
 
 	if pixelArea < config.MaxSolidPixelArea {
@@ -78,7 +80,7 @@ func (photo *Photo) Draw(ctx context.Context, config *Render, scene *Scene, c *c
 		style.FillColor = info.GetColor()
 
 		photo.Sprite.DrawInsetWithStyle(c, style, (1-scale)*photo.Sprite.Rect.W)
-		return
+		return info.Color != 0
 	}
 
 	drawn := false
@@ -95,7 +97,7 @@ func (photo *Photo) Draw(ctx context.Context, config *Render, scene *Scene, c *c
 			A: 0xFF,
 		}
 		photo.Sprite.DrawWithStyle(c, style)
-		return
+		return true
 	}
 
 	size := info.Size()
@@ -240,5 +242,5 @@ func (photo *Photo) Draw(ctx context.Context, config *Render, scene *Scene, c *c
 		style.FillColor = canvas.Red
 		photo.Sprite.DrawWithStyle(c, style)
 	}
-
+	return drawn
 }
@@ -2,11 +2,14 @@ package render
 
 import (
 	"context"
+	"encoding/binary"
+	"hash/fnv"
 	"image/color"
 	"math"
 	"runtime"
 	"runtime/trace"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/peterstace/simplefeatures/rtree"
@@ -134,6 +137,12 @@ type Scene struct {
 	RegionSource  RegionSource   `json:"-"`
 	Stale         bool           `json:"stale"`
 	Dependencies  []Dependency   `json:"-"`
+	// FileIds are the ids of the files of the photos, set once loaded
+	FileIds image.Ids `json:"-"`
+	// LayoutHash identifies what is drawn where in the scene, set once loaded
+	LayoutHash uint64 `json:"-"`
+	// Loaded is closed once the scene is loaded
+	Loaded chan struct{} `json:"-"`
 }
 
 func (scene *Scene) BuildIndex() {
@@ -152,6 +161,79 @@ func (scene *Scene) BuildIndex() {
 	scene.PhotoIndex = rtree.BulkLoad(bulkItems)
 }
 
+// BuildFileIds sets the ids of the files of the photos, so that they can
+// be looked up without going through all of them.
+func (scene *Scene) BuildFileIds() {
+	ids := image.NewIds()
+	for _, photo := range scene.Photos {
+		ids.AddInt(int(photo.Id))
+	}
+	scene.FileIds = ids
+}
+
+// BuildLayoutHash sets the hash of the photos, texts and solids of the scene
+// and their rects, so that scenes laid out the same way can share tiles.
+func (scene *Scene) BuildLayoutHash() {
+	h := fnv.New64a()
+	b := make([]byte, 0, 64)
+	rect := func(b []byte, r Rect) []byte {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(r.X))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(r.Y))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(r.W))
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(r.H))
+	}
+	b = rect(b, scene.Bounds)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(scene.Photos)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(scene.Texts)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(scene.Solids)))
+	h.Write(b)
+	for i, photo := range scene.Photos {
+		b = binary.LittleEndian.AppendUint32(b[:0], uint32(photo.Id))
+		b = rect(b, photo.Sprite.Rect)
+		if len(scene.PhotoCrops) != 0 {
+			b = rect(b, scene.PhotoCrops[i])
+		}
+		h.Write(b)
+	}
+	for _, text := range scene.Texts {
+		b = rect(b[:0], text.Sprite.Rect)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(text.Text)))
+		b = append(b, text.Text...)
+		h.Write(b)
+	}
+	for _, solid := range scene.Solids {
+		b = rect(b[:0], solid.Sprite.Rect)
+		if solid.Color != nil {
+			r, g, bl, a := solid.Color.RGBA()
+			b = binary.LittleEndian.AppendUint32(b, r)
+			b = binary.LittleEndian.AppendUint32(b, g)
+			b = binary.LittleEndian.AppendUint32(b, bl)
+			b = binary.LittleEndian.AppendUint32(b, a)
+		}
+		h.Write(b)
+	}
+	scene.LayoutHash = h.Sum64()
+}
+
+// ContainsFile returns true if the loaded scene has a photo of the file.
+func (scene *Scene) ContainsFile(id image.ImageId) bool {
+	return scene.FileIds != nil && scene.FileIds.Contains(int(id))
+}
+
+// WaitLoaded waits for the scene to load, returning false if the context
+// is done first.
+func (scene *Scene) WaitLoaded(ctx context.Context) bool {
+	if scene.Loaded == nil {
+		return !scene.Loading
+	}
+	select {
+	case <-scene.Loaded:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func (scene *Scene) UpdateStaleness() {
 	for _, dep := range scene.Dependencies {
 		if dep.UpdatedAt().After(scene.CreatedAt) {
@@ -162,6 +244,23 @@ func (scene *Scene) UpdateStaleness() {
 	scene.Stale = false
 }
 
+// DependenciesUpdatedAt returns the last time any of the dependencies of
+// the scene were updated, and false if the scene is stale as that was after
+// it was created.
+func (scene *Scene) DependenciesUpdatedAt() (time.Time, bool) {
+	updatedAt := time.Time{}
+	for _, dep := range scene.Dependencies {
+		depUpdatedAt := dep.UpdatedAt()
+		if depUpdatedAt.After(scene.CreatedAt) {
+			return depUpdatedAt, false
+		}
+		if depUpdatedAt.After(updatedAt) {
+			updatedAt = depUpdatedAt
+		}
+	}
+	return updatedAt, true
+}
+
 type Scales struct {
 	Tile float64
 }
@@ -171,7 +270,7 @@ type PhotoRef struct {
 	Photo *Photo
 }
 
-func drawPhotoRefs(ctx context.Context, id int, photoRefs <-chan PhotoRef, config *Render, scene *Scene, c *canvas.Context, scales Scales, wg *sync.WaitGroup, source *image.Source) {
+func drawPhotoRefs(ctx context.Context, id int, photoRefs <-chan PhotoRef, config *Render, scene *Scene, c *canvas.Context, scales Scales, wg *sync.WaitGroup, source *image.Source, incomplete *atomic.Bool) {
 	trace.WithRegion(ctx, "drawPhotoRefs", func() {
 		for photoRef := range photoRefs {
 			selected := config.Selected.Contains(int(photoRef.Photo.Id))
@@ -179,7 +278,9 @@ func drawPhotoRefs(ctx context.Context, id int, photoRefs <-chan PhotoRef, confi
 			if len(scene.PhotoCrops) != 0 {
 				crop = scene.PhotoCrops[photoRef.Index]
 			}
-			photoRef.Photo.Draw(ctx, config, scene, c, scales, source, selected, crop)
+			if !photoRef.Photo.Draw(ctx, config, scene, c, scales, source, selected, crop) {
+				incomplete.Store(true)
+			}
 		}
 		wg.Done()
 	})
@@ -231,7 +332,9 @@ func (scene *Scene) TileView(zoom int, x int, y int, tileSize int) (canvasToTile
 	return
 }
 
-func (scene *Scene) Draw(ctx context.Context, config *Render, c *canvas.Context, scales Scales, source *image.Source) {
+// Draw draws the visible part of the scene, returning false if any photos
+// were drawn as placeholders.
+func (scene *Scene) Draw(ctx context.Context, config *Render, c *canvas.Context, scales Scales, source *image.Source) bool {
 	trace.WithRegion(ctx, "solid.Draw", func() {
 		for i := range scene.Solids {
 			solid := &scene.Solids[i]
@@ -265,16 +368,18 @@ func (scene *Scene) Draw(ctx context.Context, config *Render, c *canvas.Context,
 
 	visiblePhotos := scene.GetVisiblePhotoRefs(ctx, tileCanvasRect, 0)
 
+	incomplete := &atomic.Bool{}
 	wg := &sync.WaitGroup{}
 	wg.Add(concurrent)
 	for i := 0; i < concurrent; i++ {
-		go drawPhotoRefs(ctx, i, visiblePhotos, config, scene, c, scales, wg, source)
+		go drawPhotoRefs(ctx, i, visiblePhotos, config, scene, c, scales, wg, source, incomplete)
 	}
 	wg.Wait()
 
 	// micros := time.Since(startTime).Microseconds()
 	// log.Printf("scene draw %5d / %5d photos, %6d μs all, %.2f μs / photo\n", visiblePhotoCount, photoCount, micros, float64(micros)/float64(visiblePhotoCount))
 
+	return !incomplete.Load()
 }
 
 // GetTimestamps generates a slice of Unix-like timestamps for each row in the
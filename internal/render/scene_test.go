@@ -1,6 +1,7 @@
 package render
 
 import (
+	"context"
 	"photofield/internal/layout/shuffle"
 	"testing"
 	"time"
@@ -200,3 +201,58 @@ func TestShuffleDependency_UpdatedAt_WeekBoundaries(t *testing.T) {
 			hour, min, sec)
 	}
 }
+
+type fixedDependency time.Time
+
+func (d fixedDependency) UpdatedAt() time.Time {
+	return time.Time(d)
+}
+
+func TestScene_DependenciesUpdatedAt(t *testing.T) {
+	createdAt := time.Date(2024, 6, 15, 14, 0, 0, 0, time.UTC)
+	scene := Scene{
+		CreatedAt: createdAt,
+		Dependencies: []Dependency{
+			fixedDependency(createdAt.Add(-2 * time.Hour)),
+			fixedDependency(createdAt.Add(-time.Hour)),
+		},
+	}
+	updatedAt, ok := scene.DependenciesUpdatedAt()
+	if !ok || !updatedAt.Equal(createdAt.Add(-time.Hour)) {
+		t.Errorf("got %v %v, want %v true", updatedAt, ok, createdAt.Add(-time.Hour))
+	}
+
+	scene.Dependencies = append(scene.Dependencies, fixedDependency(createdAt.Add(time.Minute)))
+	if _, ok := scene.DependenciesUpdatedAt(); ok {
+		t.Error("stale scene not detected")
+	}
+}
+
+func TestScene_ContainsFile(t *testing.T) {
+	scene := Scene{
+		Loading: true,
+		Loaded:  make(chan struct{}),
+		Photos:  []Photo{{Id: 3}, {Id: 5}},
+	}
+	if scene.ContainsFile(3) {
+		t.Error("loading scene contains file")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if scene.WaitLoaded(ctx) {
+		t.Error("waited for loading scene past the context")
+	}
+
+	go func() {
+		scene.BuildFileIds()
+		scene.Loading = false
+		close(scene.Loaded)
+	}()
+	if !scene.WaitLoaded(context.Background()) {
+		t.Fatal("loaded scene not waited for")
+	}
+	if !scene.ContainsFile(3) || !scene.ContainsFile(5) || scene.ContainsFile(4) {
+		t.Errorf("file ids %v, want 3 and 5", scene.FileIds.IntSlice())
+	}
+}
@@ -1,6 +1,7 @@
 package scene
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -27,8 +28,11 @@ type SceneSource struct {
 	maxSize    int64
 	sceneCache *ristretto.Cache[string, *render.Scene]
 	scenes     sync.Map
+	loadFuncs  []SceneFunc
 }
 
+type SceneFunc func(scene *render.Scene, config SceneConfig)
+
 type loadingScene struct {
 	scene  *render.Scene
 	loaded chan struct{}
@@ -72,13 +76,21 @@ func getSceneCost(scene *render.Scene) int64 {
 	return structCost + photosCost + solidsCost + textsCost
 }
 
-func (source *SceneSource) loadScene(config SceneConfig, imageSource *image.Source) *render.Scene {
+// HandleLoads registers a function called with the scenes as they start
+// loading and once they are loaded, see render.Scene.Loading.
+func (source *SceneSource) HandleLoads(fn SceneFunc) {
+	source.loadFuncs = append(source.loadFuncs, fn)
+}
+
+func (source *SceneSource) loadScene(id string, config SceneConfig, imageSource *image.Source) *render.Scene {
 
 	log.Printf("scene loading %v", config.Collection.Id)
 
 	scene := source.DefaultScene
+	scene.Id = id
 	scene.CreatedAt = time.Now()
 	scene.Loading = true
+	scene.Loaded = make(chan struct{})
 	scene.Search = config.Scene.Search
 
 	// Compute shuffle seed for SQL ordering (UnixMilli is important for LCG random shuffling)
@@ -95,23 +107,40 @@ func (source *SceneSource) loadScene(config SceneConfig, imageSource *image.Sour
 	var imageEmbedding ai.Embedding
 	var faceEmbedding ai.Embedding
 
+	// Called before the scene is loaded in the background and changes
+	for _, fn := range source.loadFuncs {
+		fn(&scene, config)
+	}
+
 	go func() {
 		finished := metrics.Elapsed("scene load " + config.Collection.Id)
 
 		var expression search.Expression
-		if scene.Search != "" {
+		query := config.Collection.SearchWith(scene.Search)
+		if query != "" {
 			searchDone := metrics.Elapsed("search")
-			q, err := search.Parse(scene.Search)
+			q, err := search.Parse(query)
 			if err != nil && scene.Error == "" {
 				scene.Error = fmt.Sprintf("parse failed: %s", err.Error())
 			}
 
-			scene.SearchTokens = q.Tokens()
+			// Tokens are positioned in the scene search, not the combined one
+			if query == scene.Search {
+				scene.SearchTokens = q.Tokens()
+			} else if sq, err := search.Parse(scene.Search); err == nil {
+				scene.SearchTokens = sq.Tokens()
+			}
 			expression, err = q.Expression()
 			if err != nil && scene.Error == "" {
 				scene.Error = err.Error()
 			}
 
+			// Resolve named places to their bounds
+			err = imageSource.ResolvePlaces(context.TODO(), expression.Condition)
+			if err != nil && scene.Error == "" {
+				scene.Error = err.Error()
+			}
+
 			// If an image is specified, get its embedding
 			if expression.Image.Present {
 				embedding, err := imageSource.GetImageEmbedding(image.ImageId(expression.Image.Value))
@@ -209,6 +238,9 @@ func (source *SceneSource) loadScene(config SceneConfig, imageSource *image.Sour
 				layout.LayoutStrip(infos, config.Layout, &scene, imageSource)
 			case layout.Flex:
 				layout.LayoutFlex(infos, config.Layout, &scene, imageSource)
+			case layout.Duplicates:
+				duplicates := imageSource.ListDuplicates(config.Collection.Dirs)
+				layout.LayoutDuplicates(infos, duplicates, config.Layout, &scene, imageSource)
 			case layout.Faces:
 				faceInfos := imageSource.ListFaces(config.Collection.Dirs, image.ListOptions{
 					OrderBy:        order,
@@ -249,12 +281,18 @@ func (source *SceneSource) loadScene(config SceneConfig, imageSource *image.Sour
 		}
 		finishedIndex := metrics.Elapsed("scene load " + config.Collection.Id)
 		scene.BuildIndex()
+		scene.BuildFileIds()
+		scene.BuildLayoutHash()
 		finishedIndex()
 		scene.Dependencies = append(scene.Dependencies, config.Collection)
 		scene.FileCount = len(scene.Photos)
 		scene.Loading = false
+		close(scene.Loaded)
 		finished()
 		log.Printf("photos %d, scene %.0f x %.0f\n", len(scene.Photos), scene.Bounds.W, scene.Bounds.H)
+		for _, fn := range source.loadFuncs {
+			fn(&scene, config)
+		}
 	}()
 
 	return &scene
@@ -307,6 +345,15 @@ func (source *SceneSource) GetSceneById(id string, imageSource *image.Source) *r
 	return nil
 }
 
+// GetSceneConfig returns the config the scene with the id was created with.
+func (source *SceneSource) GetSceneConfig(id string) (SceneConfig, bool) {
+	stored, ok := source.scenes.Load(id)
+	if !ok {
+		return SceneConfig{}, false
+	}
+	return stored.(storedScene).config, true
+}
+
 func sceneConfigEqual(a SceneConfig, b SceneConfig) bool {
 	if a.Collection.Limit != b.Collection.Limit {
 		return false
@@ -314,6 +361,9 @@ func sceneConfigEqual(a SceneConfig, b SceneConfig) bool {
 	if a.Collection.IndexLimit != b.Collection.IndexLimit {
 		return false
 	}
+	if !a.Collection.PathFilter().Equal(b.Collection.PathFilter()) {
+		return false
+	}
 	for _, dirA := range a.Collection.Dirs {
 		found := false
 		for _, dirB := range b.Collection.Dirs {
@@ -353,6 +403,10 @@ func sceneConfigEqual(a SceneConfig, b SceneConfig) bool {
 		return false
 	}
 
+	if a.Collection.Search != b.Collection.Search {
+		return false
+	}
+
 	if a.Layout.Type != "" &&
 		b.Layout.Type != "" &&
 		a.Layout.Type != b.Layout.Type {
@@ -379,6 +433,19 @@ func (source *SceneSource) GetScenesWithConfig(config SceneConfig) []*render.Sce
 	return scenes
 }
 
+// GetScenesMatching returns the scenes with configs matching the function.
+func (source *SceneSource) GetScenesMatching(match func(config SceneConfig) bool) []*render.Scene {
+	scenes := make([]*render.Scene, 0)
+	source.scenes.Range(func(_, value interface{}) bool {
+		stored := value.(storedScene)
+		if match(stored.config) {
+			scenes = append(scenes, stored.scene)
+		}
+		return true
+	})
+	return scenes
+}
+
 func (source *SceneSource) Add(config SceneConfig, imageSource *image.Source) *render.Scene {
 
 	id := config.Scene.Id
@@ -392,8 +459,7 @@ func (source *SceneSource) Add(config SceneConfig, imageSource *image.Source) *r
 
 	source.pruneScenes()
 
-	scene := source.loadScene(config, imageSource)
-	scene.Id = id
+	scene := source.loadScene(id, config, imageSource)
 
 	source.scenes.Store(scene.Id, storedScene{
 		scene:  scene,
@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the standard five fields of minute,
+// hour, day of month, month and day of week.
+type Cron struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Days of month and week are matched with OR if both are restricted,
+	// i.e. neither covers its full range, e.g. with * or ?
+	domAny bool
+	dowAny bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression, e.g. "0 3 * * *" for every day at 3am or
+// "*/15 9-17 * * 1-5" for every 15 minutes during working hours. Fields can be
+// lists of values, ranges and steps. The @hourly, @daily, @weekly, @monthly
+// and @yearly shorthands are also supported.
+func Parse(spec string) (Cron, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return Cron{}, fmt.Errorf("expected %d fields, got %d in %q", len(cronFields), len(fields), spec)
+	}
+
+	var bits [5]uint64
+	var full [5]bool
+	for i, f := range cronFields {
+		b, all, err := parseCronField(fields[i], f)
+		if err != nil {
+			return Cron{}, fmt.Errorf("%s: %w", f.name, err)
+		}
+		bits[i] = b
+		full[i] = all
+	}
+
+	c := Cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: full[2],
+		dowAny: full[4],
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	if c.dow&0x7f == 0x7f {
+		c.dowAny = true
+	}
+	return c, nil
+}
+
+// parseCronField returns the bits of the values of the field and whether
+// any of its parts covers the full range of the field before stepping,
+// e.g. * and */2 do, while 1-15 does not.
+func parseCronField(s string, f cronField) (bits uint64, full bool, err error) {
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, false, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" && rng != "?" {
+			from, to, isRange := strings.Cut(rng, "-")
+			lo, err = strconv.Atoi(from)
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				hi, err = strconv.Atoi(to)
+				if err != nil {
+					return 0, false, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, false, fmt.Errorf("%q out of range %d-%d", part, f.min, f.max)
+		}
+		if lo == f.min && hi == f.max {
+			full = true
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, full, nil
+}
+
+func (c Cron) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time matching the expression after t, or the zero
+// time if there is none, e.g. for the 30th of February.
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
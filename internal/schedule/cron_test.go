@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, 1, 31, 13, 45, 30, 0, time.UTC) // Wednesday
+
+	testCases := []struct {
+		spec   string
+		expect time.Time
+	}{
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 14, 0, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2024, 1, 31, 13, 46, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 1, 31, 14, 0, 0, 0, time.UTC)},
+		{"50,55 13 * * *", time.Date(2024, 1, 31, 13, 50, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC)},
+		{"0 3 * * 0", time.Date(2024, 2, 4, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2024, 2, 4, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 1-5", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 6", time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */2 * 1", time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 ? * 1", time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1-31 * 6", time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 5 * 0-6", time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			c, err := Parse(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := c.Next(from)
+			if !next.Equal(tc.expect) {
+				t.Errorf("next = %v, want %v", next, tc.expect)
+			}
+		})
+	}
+}
+
+func TestCronParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 3 * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("parse %q did not fail", spec)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"log"
+	"time"
+)
+
+// Job is run at the times of its cron expression.
+type Job struct {
+	Name string
+	Cron Cron
+	Run  func()
+}
+
+// Scheduler runs jobs at their scheduled times in local time until closed.
+type Scheduler struct {
+	jobs []Job
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a scheduler running the jobs in the background.
+func New(jobs []Job) *Scheduler {
+	s := &Scheduler{
+		jobs: jobs,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *Scheduler) run() {
+	defer close(s.done)
+	next := make([]time.Time, len(s.jobs))
+	now := time.Now()
+	for i, job := range s.jobs {
+		next[i] = job.Cron.Next(now)
+		log.Printf("schedule %s next at %s\n", job.Name, next[i].Format(time.RFC3339))
+	}
+
+	for {
+		var earliest time.Time
+		for _, t := range next {
+			if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case now = <-timer.C:
+		}
+
+		for i, job := range s.jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			log.Printf("schedule %s run\n", job.Name)
+			job.Run()
+			next[i] = job.Cron.Next(now)
+		}
+	}
+}
+
+// Close stops the scheduler, waiting for the jobs already running to return.
+func (s *Scheduler) Close() {
+	close(s.stop)
+	<-s.done
+}
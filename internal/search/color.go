@@ -0,0 +1,72 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultColorDistance is the maximum perceptual distance (ΔE) of the
+// prominent color of a photo from the searched color, unless specified.
+const DefaultColorDistance = 20
+
+// Color is an sRGB color parsed from values like #3366ff, 36f or
+// #3366ff,10 with a maximum distance.
+type Color struct {
+	FieldMeta   `json:"meta,omitempty"`
+	R           uint8   `json:"r"`
+	G           uint8   `json:"g"`
+	B           uint8   `json:"b"`
+	MaxDistance float64 `json:"max_distance,omitempty"`
+}
+
+func (q *Query) ExpressionColor(key string) (c Color) {
+	terms := q.QualifierTerms(key)
+	if len(terms) == 0 {
+		return
+	}
+
+	c = termColor(key, terms[0])
+	if len(terms) > 1 {
+		c.Error = fmt.Errorf("multiple qualifiers: %s", key)
+	}
+	return
+}
+
+func termColor(key string, term *Term) (c Color) {
+	c.Present = true
+	c.Name = key
+	c.Token = term.Token()
+
+	value, distance, hasDistance := strings.Cut(term.Qualifier.Value, ",")
+	c.R, c.G, c.B, c.Error = parseHexColor(value)
+	if c.Error != nil {
+		return
+	}
+
+	c.MaxDistance = DefaultColorDistance
+	if hasDistance {
+		d, err := strconv.ParseFloat(distance, 64)
+		if err != nil || d <= 0 {
+			c.Error = fmt.Errorf("invalid distance: %s", distance)
+			return
+		}
+		c.MaxDistance = d
+	}
+	return
+}
+
+func parseHexColor(s string) (r, g, b uint8, err error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid color: %s (use e.g. #3366ff)", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid color: %s (use e.g. #3366ff)", s)
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), nil
+}
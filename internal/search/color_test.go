@@ -0,0 +1,43 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		r, g, b  uint8
+		distance float64
+		wantErr  bool
+	}{
+		{input: "color:#3366ff", r: 0x33, g: 0x66, b: 0xff, distance: DefaultColorDistance},
+		{input: "color:3366FF", r: 0x33, g: 0x66, b: 0xff, distance: DefaultColorDistance},
+		{input: "color:#36f", r: 0x33, g: 0x66, b: 0xff, distance: DefaultColorDistance},
+		{input: "color:#3366ff,10", r: 0x33, g: 0x66, b: 0xff, distance: 10},
+		{input: "color:#3366f", wantErr: true},
+		{input: "color:blue", wantErr: true},
+		{input: "color:#3366ff,0", wantErr: true},
+		{input: "color:#3366ff color:#ff0000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			assert.NoError(t, err)
+			expr, err := query.Expression()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, expr.Color.Present)
+			assert.Equal(t, tt.r, expr.Color.R)
+			assert.Equal(t, tt.g, expr.Color.G)
+			assert.Equal(t, tt.b, expr.Color.B)
+			assert.Equal(t, tt.distance, expr.Color.MaxDistance)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Op is the boolean operator joining the children of a Condition.
+type Op string
+
+const (
+	And Op = "AND"
+	Or  Op = "OR"
+)
+
+// Condition is a boolean tree of the filtering qualifiers in a query.
+//
+// Inner nodes have an Op and Children, leaves have the qualifier Key and the
+// typed Value parsed from it, e.g. String for tag:, DateRange for
+// created: and NumberRange for iso:. Words, strings and qualifiers that tune the search rather than
+// filter files (t:, k:, img:, ...) are not part of the tree.
+type Condition struct {
+	Op       Op
+	Not      bool
+	Children []*Condition
+	Key      string
+	Value    any
+	Token    Token
+}
+
+// conditionQualifiers parse the value of each filtering qualifier.
+var conditionQualifiers = map[string]func(term *Term) (any, FieldMeta){
+	"tag": func(term *Term) (any, FieldMeta) {
+		s := termString("tag", term)
+		return s, s.FieldMeta
+	},
+	"filename": func(term *Term) (any, FieldMeta) {
+		s := termString("filename", term)
+		return s, s.FieldMeta
+	},
+	"created": func(term *Term) (any, FieldMeta) {
+		r := termDateRange("created", term)
+		return r, r.FieldMeta
+	},
+	"iso":     numberQualifier("iso"),
+	"f":       numberQualifier("f"),
+	"shutter": numberQualifier("shutter"),
+	"focal":   numberQualifier("focal"),
+	"lens": func(term *Term) (any, FieldMeta) {
+		s := termString("lens", term)
+		return s, s.FieldMeta
+	},
+	"color": func(term *Term) (any, FieldMeta) {
+		c := termColor("color", term)
+		return c, c.FieldMeta
+	},
+	"width":  numberQualifier("width"),
+	"height": numberQualifier("height"),
+	"mp":     numberQualifier("mp"),
+	"aspect": func(term *Term) (any, FieldMeta) {
+		// Named shapes or a width to height ratio, e.g. aspect:>16/9
+		if slices.Contains(AspectValues, term.Qualifier.Value) {
+			s := termEnum("aspect", term, AspectValues)
+			return s, s.FieldMeta
+		}
+		r := termNumberRange("aspect", term)
+		if r.Error != nil {
+			r.Error = fmt.Errorf("unsupported value: %s (use %s or a ratio like >16/9)", term.Qualifier.Value, strings.Join(AspectValues, ", "))
+		}
+		return r, r.FieldMeta
+	},
+	"orientation": func(term *Term) (any, FieldMeta) {
+		s := termEnum("orientation", term, OrientationValues)
+		return s, s.FieldMeta
+	},
+	"near": func(term *Term) (any, FieldMeta) {
+		n := termNear("near", term)
+		return n, n.FieldMeta
+	},
+	"bbox": func(term *Term) (any, FieldMeta) {
+		b := termBBox("bbox", term)
+		return b, b.FieldMeta
+	},
+	"place": func(term *Term) (any, FieldMeta) {
+		p := termPlace("place", term)
+		return p, p.FieldMeta
+	},
+	"dup": func(term *Term) (any, FieldMeta) {
+		s := termEnum("dup", term, DuplicateValues)
+		return s, s.FieldMeta
+	},
+	"person": func(term *Term) (any, FieldMeta) {
+		s := termString("person", term)
+		return s, s.FieldMeta
+	},
+	"rating": numberQualifier("rating"),
+	"label": func(term *Term) (any, FieldMeta) {
+		s := termEnum("label", term, LabelValues)
+		return s, s.FieldMeta
+	},
+	"is": func(term *Term) (any, FieldMeta) {
+		s := termEnum("is", term, IsValues)
+		return s, s.FieldMeta
+	},
+}
+
+// AspectValues are the named shapes of the aspect: qualifier.
+var AspectValues = []string{"portrait", "landscape", "square", "pano"}
+
+// OrientationValues are the values of the orientation: qualifier.
+var OrientationValues = []string{"normal", "rotated", "mirrored"}
+
+// DuplicateValues are the values of the dup: qualifier, "extra" matches all
+// but the first file of each duplicate group.
+var DuplicateValues = []string{"any", "exact", "near", "extra"}
+
+// LabelValues are the color labels of files and the values of the label:
+// qualifier.
+var LabelValues = []string{"red", "yellow", "green", "blue", "purple"}
+
+// IsValues are the flags of files matched by the is: qualifier.
+var IsValues = []string{"favorite"}
+
+func numberQualifier(key string) func(term *Term) (any, FieldMeta) {
+	return func(term *Term) (any, FieldMeta) {
+		r := termNumberRange(key, term)
+		return r, r.FieldMeta
+	}
+}
+
+// Condition returns the boolean filter tree of the query, or nil if the
+// query does not filter files.
+func (q *Query) Condition() (*Condition, []FieldMeta) {
+	if q == nil {
+		return nil, nil
+	}
+	var errs []FieldMeta
+	c := clausesCondition(q.Clauses, &errs)
+	return c, errs
+}
+
+func clausesCondition(clauses []*Clause, errs *[]FieldMeta) *Condition {
+	or := &Condition{Op: Or}
+	for _, clause := range clauses {
+		and := &Condition{Op: And}
+		for _, term := range clause.Terms {
+			c := termCondition(term, errs)
+			if c != nil {
+				and.Children = append(and.Children, c)
+			}
+		}
+		if len(and.Children) == 0 {
+			// A clause without filters matches everything, so the whole
+			// disjunction does too.
+			return nil
+		}
+		or.Children = append(or.Children, and.simplify())
+	}
+	if len(or.Children) == 0 {
+		return nil
+	}
+	return or.simplify()
+}
+
+func termCondition(term *Term, errs *[]FieldMeta) *Condition {
+	if term.Group != nil {
+		c := clausesCondition(term.Group.Clauses, errs)
+		if c == nil {
+			return nil
+		}
+		if term.Not {
+			c.Not = !c.Not
+		}
+		return c
+	}
+	if term.Qualifier == nil {
+		return nil
+	}
+	parse, ok := conditionQualifiers[term.Qualifier.Key]
+	if !ok {
+		return nil
+	}
+	value, meta := parse(term)
+	if meta.Error != nil {
+		*errs = append(*errs, meta)
+		return nil
+	}
+	return &Condition{
+		Not:   term.Not,
+		Key:   term.Qualifier.Key,
+		Value: value,
+		Token: term.Token(),
+	}
+}
+
+// simplify replaces nodes with a single child by the child itself.
+func (c *Condition) simplify() *Condition {
+	if len(c.Children) != 1 {
+		return c
+	}
+	child := c.Children[0]
+	if c.Not {
+		child.Not = !child.Not
+	}
+	return child
+}
+
+// IsLeaf returns true if the condition is a single qualifier.
+func (c *Condition) IsLeaf() bool {
+	return c.Key != ""
+}
+
+// Leaves returns all the qualifier leaves of the tree with the given key.
+func (c *Condition) Leaves(key string) []*Condition {
+	if c == nil {
+		return nil
+	}
+	if c.IsLeaf() {
+		if c.Key == key {
+			return []*Condition{c}
+		}
+		return nil
+	}
+	var leaves []*Condition
+	for _, child := range c.Children {
+		leaves = append(leaves, child.Leaves(key)...)
+	}
+	return leaves
+}
+
+// String returns the condition in the query syntax with explicit operators
+// and parentheses.
+func (c *Condition) String() string {
+	if c == nil {
+		return ""
+	}
+	var b strings.Builder
+	c.write(&b, false)
+	return b.String()
+}
+
+func (c *Condition) write(b *strings.Builder, nested bool) {
+	if c.Not {
+		b.WriteString("NOT ")
+	}
+	if c.IsLeaf() {
+		b.WriteString(c.Token.Key)
+		b.WriteString(":")
+		b.WriteString(quoteValue(c.Token.QualVal))
+		return
+	}
+	paren := nested || c.Not
+	if paren {
+		b.WriteString("(")
+	}
+	for i, child := range c.Children {
+		if i > 0 {
+			b.WriteString(" ")
+			b.WriteString(string(c.Op))
+			b.WriteString(" ")
+		}
+		child.write(b, true)
+	}
+	if paren {
+		b.WriteString(")")
+	}
+}
+
+// quoteValue quotes qualifier values that would not parse back as a word.
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"()") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestCondition(t *testing.T) {
+	testCases := []struct {
+		search string
+		want   string
+	}{
+		{search: "", want: ""},
+		{search: "hello world", want: ""},
+		{search: "tag:a", want: "tag:a"},
+		{search: "NOT tag:a", want: "NOT tag:a"},
+		{search: "tag:a tag:b", want: "tag:a AND tag:b"},
+		{search: "tag:a AND tag:b", want: "tag:a AND tag:b"},
+		{search: "tag:a OR tag:b", want: "tag:a OR tag:b"},
+		{search: "tag:a tag:b OR tag:c", want: "(tag:a AND tag:b) OR tag:c"},
+		{search: "(tag:beach OR tag:lake) created:2021", want: "(tag:beach OR tag:lake) AND created:2021"},
+		{search: "NOT (tag:a OR tag:b)", want: "NOT (tag:a OR tag:b)"},
+		{search: "NOT (tag:a)", want: "NOT tag:a"},
+		{search: "NOT (NOT tag:a)", want: "tag:a"},
+		{search: "hello tag:a k:5", want: "tag:a"},
+		{search: "hello OR tag:a", want: ""},
+		{search: "(hello) tag:a", want: "tag:a"},
+		{search: "created:2021 OR filename:*.png", want: "created:2021 OR filename:*.png"},
+		{search: "iso:>1600 f:<2.8", want: "iso:>1600 AND f:<2.8"},
+		{search: `lens:"RF 24-105" OR focal:24..70`, want: `lens:"RF 24-105" OR focal:24..70`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.search, func(t *testing.T) {
+			query, err := Parse(tc.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cond, errs := query.Condition()
+			assert.Equal(t, 0, len(errs))
+			assert.Equal(t, tc.want, cond.String())
+		})
+	}
+}
+
+func TestConditionLeaves(t *testing.T) {
+	query, err := Parse("(tag:a OR tag:b) NOT tag:c created:2021")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond, _ := query.Condition()
+	tags := cond.Leaves("tag")
+	assert.Equal(t, 3, len(tags))
+	assert.Equal(t, "a", tags[0].Value.(String).Value)
+	assert.Equal(t, true, tags[2].Not)
+	created := cond.Leaves("created")
+	assert.Equal(t, 1, len(created))
+	assert.Equal(t, 2021, created[0].Value.(DateRange).From.Year())
+}
+
+func TestConditionMultipleCreatedAlternatives(t *testing.T) {
+	query, err := Parse("created:2021 OR created:2023")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := query.Expression()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(expr.Condition.Leaves("created")))
+
+	query, err = Parse("created:2021 created:2023")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = query.Expression()
+	assert.Error(t, err)
+}
+
+func TestConditionErrors(t *testing.T) {
+	query, err := Parse("created:2021-13 OR tag:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := query.Expression()
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(expr.Errors))
+}
+
+func TestConditionAspect(t *testing.T) {
+	testCases := []struct {
+		search  string
+		isRange bool
+		wantErr bool
+	}{
+		{search: "aspect:pano"},
+		{search: "aspect:>16/9", isRange: true},
+		{search: "aspect:1..2", isRange: true},
+		{search: "aspect:tall", wantErr: true},
+		{search: "orientation:rotated"},
+		{search: "orientation:sideways", wantErr: true},
+		{search: "rating:>=4", isRange: true},
+		{search: "rating:high", wantErr: true},
+		{search: "label:red"},
+		{search: "label:orange", wantErr: true},
+		{search: "is:favorite"},
+		{search: "is:starred", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.search, func(t *testing.T) {
+			query, err := Parse(tc.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cond, errs := query.Condition()
+			if tc.wantErr {
+				assert.Equal(t, 1, len(errs))
+				return
+			}
+			assert.Equal(t, 0, len(errs))
+			_, isRange := cond.Value.(NumberRange)
+			assert.Equal(t, tc.isRange, isRange)
+		})
+	}
+}
@@ -29,6 +29,26 @@ func (w DateWildcard) All() bool {
 	return w.Year && w.Month && w.Day
 }
 
+// Layouts returns the Go time layout and the equivalent SQLite strftime
+// format of the date components that are not wildcards, e.g. "01-02" and
+// "%m-%d" if only the year is a wildcard.
+func (w DateWildcard) Layouts() (layout string, format string) {
+	var layouts, formats []string
+	if !w.Year {
+		layouts = append(layouts, "2006")
+		formats = append(formats, "%Y")
+	}
+	if !w.Month {
+		layouts = append(layouts, "01")
+		formats = append(formats, "%m")
+	}
+	if !w.Day {
+		layouts = append(layouts, "02")
+		formats = append(formats, "%d")
+	}
+	return strings.Join(layouts, "-"), strings.Join(formats, "-")
+}
+
 func (w DateWildcard) Apply(reference time.Time, date time.Time) time.Time {
 	y, m, d := reference.Date()
 	if w.Year {
@@ -118,12 +138,17 @@ func (q *Query) ExpressionDateRange(key string) (r DateRange) {
 		return
 	}
 
-	if len(terms) > 1 {
+	// Alternatives can each have their own range, e.g.
+	// created:2021 OR created:2023
+	if len(terms) > 1 && !q.HasAlternatives() {
 		r.Error = fmt.Errorf("multiple qualifiers: %s", key)
 		return
 	}
 
-	term := terms[0]
+	return termDateRange(key, terms[0])
+}
+
+func termDateRange(key string, term *Term) (r DateRange) {
 	value := term.Qualifier.Value
 	r.Present = true
 	r.Name = key
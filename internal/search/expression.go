@@ -33,6 +33,10 @@ type Expression struct {
 	Filenames   Strings   `json:"filename,omitempty"`
 	Image       Int64     `json:"img,omitempty"`
 	Face        Int64     `json:"face,omitempty"`
+	Color       Color     `json:"color,omitempty"`
+
+	// Boolean tree of the filtering qualifiers, nil if there are none
+	Condition *Condition `json:"-"`
 
 	// Aggregate errors for convenient iteration
 	Errors []FieldMeta `json:"errors,omitempty"`
@@ -49,6 +53,25 @@ var validQualifiers = []string{
 	"filename",
 	"img",
 	"face",
+	"iso",
+	"f",
+	"shutter",
+	"focal",
+	"lens",
+	"color",
+	"width",
+	"height",
+	"mp",
+	"aspect",
+	"orientation",
+	"near",
+	"bbox",
+	"place",
+	"dup",
+	"person",
+	"rating",
+	"label",
+	"is",
 }
 
 var validQualifiersMap map[string]bool
@@ -109,6 +132,15 @@ func (q *Query) Expression() (Expression, error) {
 	expr.Face = q.ExpressionInt("face")
 	expr.addFieldError(expr.Face.FieldMeta)
 
+	expr.Color = q.ExpressionColor("color")
+	expr.addFieldError(expr.Color.FieldMeta)
+
+	var condErrors []FieldMeta
+	expr.Condition, condErrors = q.Condition()
+	for _, meta := range condErrors {
+		expr.addFieldErrorOnce(meta)
+	}
+
 	var err error
 	if len(expr.Errors) > 0 {
 		more := ""
@@ -147,6 +179,17 @@ func (expr *Expression) addFieldError(meta FieldMeta) {
 	expr.Errors = append(expr.Errors, meta)
 }
 
+// addFieldErrorOnce adds the error unless one was already added for the same
+// token, as the same term can be parsed both for a field and the condition.
+func (expr *Expression) addFieldErrorOnce(meta FieldMeta) {
+	for _, e := range expr.Errors {
+		if e.Token.Start == meta.Token.Start && e.Token.End == meta.Token.End {
+			return
+		}
+	}
+	expr.addFieldError(meta)
+}
+
 // checkUnknownQualifiers adds errors for any qualifiers not defined in Expression
 func (expr *Expression) checkUnknownQualifiers() {
 	if expr == nil || expr.query == nil {
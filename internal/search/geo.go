@@ -0,0 +1,123 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bounds is a latitude and longitude box in degrees. MinLng is greater than
+// MaxLng if the box crosses the antimeridian.
+type Bounds struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// Near is a circular area parsed from values like 46.05,14.5,5km.
+type Near struct {
+	FieldMeta `json:"meta,omitempty"`
+	Lat       float64 `json:"lat"`
+	Lng       float64 `json:"lng"`
+	RadiusKm  float64 `json:"radius_km"`
+}
+
+// BBox is a box parsed from values like 45.4,13.4,46.9,16.6 in the
+// minLat,minLng,maxLat,maxLng order.
+type BBox struct {
+	FieldMeta `json:"meta,omitempty"`
+	Bounds
+}
+
+// Place is a named area. The Bounds are not parsed from the query, but
+// resolved by geocoding the Name before listing.
+type Place struct {
+	FieldMeta `json:"meta,omitempty"`
+	Name      string   `json:"name"`
+	Bounds    []Bounds `json:"bounds,omitempty"`
+	Resolved  bool     `json:"resolved,omitempty"`
+}
+
+const defaultNearRadiusKm = 1
+
+func termNear(key string, term *Term) (n Near) {
+	n.Present = true
+	n.Name = key
+	n.Token = term.Token()
+
+	parts := strings.Split(term.Qualifier.Value, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		n.Error = fmt.Errorf("invalid location (use lat,lng,radius e.g. 46.05,14.5,5km)")
+		return
+	}
+	n.Lat, n.Lng, n.Error = parseLatLng(parts[0], parts[1])
+	if n.Error != nil {
+		return
+	}
+	n.RadiusKm = defaultNearRadiusKm
+	if len(parts) == 3 {
+		n.RadiusKm, n.Error = parseDistanceKm(parts[2])
+	}
+	return
+}
+
+func termBBox(key string, term *Term) (b BBox) {
+	b.Present = true
+	b.Name = key
+	b.Token = term.Token()
+
+	parts := strings.Split(term.Qualifier.Value, ",")
+	if len(parts) != 4 {
+		b.Error = fmt.Errorf("invalid box (use minLat,minLng,maxLat,maxLng)")
+		return
+	}
+	b.MinLat, b.MinLng, b.Error = parseLatLng(parts[0], parts[1])
+	if b.Error != nil {
+		return
+	}
+	b.MaxLat, b.MaxLng, b.Error = parseLatLng(parts[2], parts[3])
+	if b.Error != nil {
+		return
+	}
+	if b.MinLat > b.MaxLat {
+		b.Error = fmt.Errorf("min latitude %v is greater than max latitude %v", b.MinLat, b.MaxLat)
+	}
+	return
+}
+
+func termPlace(key string, term *Term) (p Place) {
+	s := termString(key, term)
+	p.FieldMeta = s.FieldMeta
+	p.Name = s.Value
+	return
+}
+
+func parseLatLng(latStr, lngStr string) (lat, lng float64, err error) {
+	lat, err = strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude: %s", latStr)
+	}
+	lng, err = strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude: %s", lngStr)
+	}
+	return lat, lng, nil
+}
+
+// parseDistanceKm parses distances like 5km, 300m or 2 (kilometers).
+func parseDistanceKm(s string) (float64, error) {
+	scale := 1.
+	num := s
+	if v, ok := strings.CutSuffix(s, "km"); ok {
+		num = v
+	} else if v, ok := strings.CutSuffix(s, "m"); ok {
+		num = v
+		scale = 1e-3
+	}
+	d, err := strconv.ParseFloat(num, 64)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid distance: %s (use e.g. 5km or 300m)", s)
+	}
+	return d * scale, nil
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestNear(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Near
+		wantErr bool
+	}{
+		{input: "near:46.05,14.5,5km", want: Near{Lat: 46.05, Lng: 14.5, RadiusKm: 5}},
+		{input: "near:46.05,14.5,300m", want: Near{Lat: 46.05, Lng: 14.5, RadiusKm: 0.3}},
+		{input: "near:-33.9,18.4,2", want: Near{Lat: -33.9, Lng: 18.4, RadiusKm: 2}},
+		{input: "near:46.05,14.5", want: Near{Lat: 46.05, Lng: 14.5, RadiusKm: 1}},
+		{input: "near:46.05", wantErr: true},
+		{input: "near:91,14.5,5km", wantErr: true},
+		{input: "near:46.05,181,5km", wantErr: true},
+		{input: "near:46.05,14.5,5mi", wantErr: true},
+		{input: "near:46.05,14.5,-5km", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			assert.NoError(t, err)
+			n := termNear("near", query.Terms[0])
+			if tt.wantErr {
+				assert.Error(t, n.Error)
+				return
+			}
+			assert.NoError(t, n.Error)
+			assert.Equal(t, tt.want.Lat, n.Lat)
+			assert.Equal(t, tt.want.Lng, n.Lng)
+			assert.Equal(t, tt.want.RadiusKm, n.RadiusKm)
+		})
+	}
+}
+
+func TestBBox(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Bounds
+		wantErr bool
+	}{
+		{input: "bbox:45.4,13.4,46.9,16.6", want: Bounds{MinLat: 45.4, MinLng: 13.4, MaxLat: 46.9, MaxLng: 16.6}},
+		{input: "bbox:-20,170,-10,-170", want: Bounds{MinLat: -20, MinLng: 170, MaxLat: -10, MaxLng: -170}},
+		{input: "bbox:46.9,13.4,45.4,16.6", wantErr: true},
+		{input: "bbox:45.4,13.4,46.9", wantErr: true},
+		{input: "bbox:a,b,c,d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			assert.NoError(t, err)
+			b := termBBox("bbox", query.Terms[0])
+			if tt.wantErr {
+				assert.Error(t, b.Error)
+				return
+			}
+			assert.NoError(t, b.Error)
+			assert.Equal(t, tt.want, b.Bounds)
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	query, err := Parse(`place:"Novo mesto" created:2021`)
+	assert.NoError(t, err)
+	cond, errs := query.Condition()
+	assert.Equal(t, 0, len(errs))
+	places := cond.Leaves("place")
+	assert.Equal(t, 1, len(places))
+	place := places[0].Value.(Place)
+	assert.Equal(t, "Novo mesto", place.Name)
+	assert.False(t, place.Resolved)
+}
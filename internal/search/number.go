@@ -0,0 +1,106 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numberTolerance is the relative tolerance of single value matches, so that
+// e.g. shutter:1/60 matches an exposure time stored as 0.0166666666666667.
+const numberTolerance = 1e-6
+
+// NumberRange is a numeric range parsed from values like 1600, >1600,
+// <=2.8, 24..70 or 1/250.
+type NumberRange struct {
+	FieldMeta    `json:"meta,omitempty"`
+	From         float64 `json:"from,omitempty"`
+	To           float64 `json:"to,omitempty"`
+	HasFrom      bool    `json:"has_from,omitempty"`
+	HasTo        bool    `json:"has_to,omitempty"`
+	FromExcluded bool    `json:"from_excluded,omitempty"`
+	ToExcluded   bool    `json:"to_excluded,omitempty"`
+}
+
+func (r NumberRange) Match(v float64) bool {
+	if r.HasFrom && (v < r.From || r.FromExcluded && v == r.From) {
+		return false
+	}
+	if r.HasTo && (v > r.To || r.ToExcluded && v == r.To) {
+		return false
+	}
+	return true
+}
+
+func termNumberRange(key string, term *Term) (r NumberRange) {
+	value := term.Qualifier.Value
+	r.Present = true
+	r.Name = key
+	r.Token = term.Token()
+
+	switch {
+	case strings.HasPrefix(value, ">="):
+		r.From, r.Error = parseNumber(value[2:])
+		r.HasFrom = true
+	case strings.HasPrefix(value, "<="):
+		r.To, r.Error = parseNumber(value[2:])
+		r.HasTo = true
+	case strings.HasPrefix(value, ">"):
+		r.From, r.Error = parseNumber(value[1:])
+		r.HasFrom = true
+		r.FromExcluded = true
+	case strings.HasPrefix(value, "<"):
+		r.To, r.Error = parseNumber(value[1:])
+		r.HasTo = true
+		r.ToExcluded = true
+	case strings.Contains(value, ".."):
+		bounds := strings.SplitN(value, "..", 2)
+		if bounds[0] != "" {
+			r.From, r.Error = parseNumber(bounds[0])
+			if r.Error != nil {
+				r.Error = fmt.Errorf("failed to parse start: %w", r.Error)
+				return
+			}
+			r.HasFrom = true
+		}
+		if bounds[1] != "" {
+			r.To, r.Error = parseNumber(bounds[1])
+			if r.Error != nil {
+				r.Error = fmt.Errorf("failed to parse end: %w", r.Error)
+				return
+			}
+			r.HasTo = true
+		}
+		if !r.HasFrom && !r.HasTo {
+			r.Error = fmt.Errorf("invalid range (use from..to)")
+		}
+	default:
+		var v float64
+		v, r.Error = parseNumber(value)
+		d := math.Abs(v) * numberTolerance
+		r.From, r.To = v-d, v+d
+		r.HasFrom, r.HasTo = true, true
+	}
+	return
+}
+
+// parseNumber parses a decimal number or a fraction like 1/250.
+func parseNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("value cannot be empty")
+	}
+	num, den, isFraction := strings.Cut(s, "/")
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %s", s)
+	}
+	if !isFraction {
+		return v, nil
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0, fmt.Errorf("invalid fraction: %s", s)
+	}
+	return v / d, nil
+}
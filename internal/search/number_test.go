@@ -0,0 +1,58 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestNumberRanges(t *testing.T) {
+	tests := []struct {
+		input   string
+		match   []float64
+		noMatch []float64
+		wantErr bool
+	}{
+		{input: "iso:1600", match: []float64{1600}, noMatch: []float64{800, 3200}},
+		{input: "iso:>1600", match: []float64{3200}, noMatch: []float64{1600, 800}},
+		{input: "iso:>=1600", match: []float64{1600, 3200}, noMatch: []float64{800}},
+		{input: "f:<2.8", match: []float64{1.8}, noMatch: []float64{2.8, 4}},
+		{input: "f:<=2.8", match: []float64{1.8, 2.8}, noMatch: []float64{4}},
+		{input: "focal:24..70", match: []float64{24, 50, 70}, noMatch: []float64{16, 85}},
+		{input: "focal:..35", match: []float64{24, 35}, noMatch: []float64{50}},
+		{input: "focal:200..", match: []float64{200, 400}, noMatch: []float64{100}},
+		{input: "shutter:1/60", match: []float64{0.0166666666666667}, noMatch: []float64{0.02}},
+		{input: "shutter:<1/250", match: []float64{0.001}, noMatch: []float64{0.004, 0.5}},
+		{input: "iso:high", wantErr: true},
+		{input: "iso:>", wantErr: true},
+		{input: "iso:..", wantErr: true},
+		{input: "shutter:1/0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			assert.NoError(t, err)
+			r := termNumberRange(query.Terms[0].Qualifier.Key, query.Terms[0])
+			if tt.wantErr {
+				assert.Error(t, r.Error)
+				return
+			}
+			assert.NoError(t, r.Error)
+			for _, v := range tt.match {
+				assert.True(t, r.Match(v), "expected %v to match", v)
+			}
+			for _, v := range tt.noMatch {
+				assert.False(t, r.Match(v), "expected %v not to match", v)
+			}
+		})
+	}
+}
+
+func TestQuotedQualifierValue(t *testing.T) {
+	query, err := Parse(`lens:"RF 24-105" hello`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(query.Terms))
+	assert.Equal(t, "RF 24-105", query.Terms[0].Qualifier.Value)
+	assert.Equal(t, "hello", query.Words())
+}
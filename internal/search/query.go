@@ -8,13 +8,36 @@ import (
 	"github.com/alecthomas/participle/v2/lexer"
 )
 
+// Query is the parsed form of a search string. The top level is a list of
+// alternatives joined by OR, each of which is a conjunction of terms.
 type Query struct {
-	Terms []*Term `parser:"@@*" json:"terms"`
+	Clauses []*Clause `parser:"(@@ ('OR' @@)*)?" json:"clauses,omitempty"`
+
+	// Terms are all the leaf terms of the query in source order, including
+	// the ones nested in groups.
+	Terms []*Term `parser:"" json:"terms"`
+
+	// Operators are the OR and AND keywords and group parentheses, used for
+	// highlighting.
+	operators []Token
+}
+
+// Clause is a list of terms joined by an implicit or explicit AND.
+type Clause struct {
+	Terms []*Term `parser:"@@ ('AND'? @@)*" json:"terms"`
+}
+
+// Group is a parenthesized sub-query.
+type Group struct {
+	Clauses []*Clause      `parser:"'(' @@ ('OR' @@)* ')'" json:"clauses"`
+	Pos     lexer.Position `parser:"" json:"-"`
+	EndPos  lexer.Position `parser:"" json:"-"`
 }
 
 type Term struct {
 	Not       bool           `parser:"@'NOT'?" json:"not,omitempty"`
-	String    *string        `parser:"(@String" json:"string,omitempty"`
+	Group     *Group         `parser:"(@@" json:"group,omitempty"`
+	String    *string        `parser:"| @String" json:"string,omitempty"`
 	Qualifier *Qualifier     `parser:"| @@" json:"qualifier,omitempty"`
 	Word      *string        `parser:"| @Word)" json:"word,omitempty"`
 	Pos       lexer.Position `parser:"" json:"start"`
@@ -23,7 +46,7 @@ type Term struct {
 
 type Qualifier struct {
 	Key   string `parser:"@Word ':'"`
-	Value string `parser:"@Word (@':' @Word)*"`
+	Value string `parser:"(@String | @Word (@':' @Word)*)"`
 }
 
 var lex *lexer.StatefulDefinition
@@ -35,7 +58,11 @@ var ErrNotFound = fmt.Errorf("not found")
 func init() {
 	lex = lexer.MustSimple([]lexer.SimpleRule{
 		{Name: "String", Pattern: `"(\\"|[^"])*"`},
-		{Name: "Word", Pattern: `[^\s:]+`},
+		{Name: "Operator", Pattern: `(AND|OR|NOT)\b`},
+		{Name: "Paren", Pattern: `[()]`},
+		// Parentheses are only group delimiters at the boundaries of words,
+		// so that values like IMG(1).jpg keep them
+		{Name: "Word", Pattern: `[^\s:()]([^\s:()]|\([^\s:()]*\))*`},
 		{Name: "Colon", Pattern: `:`},
 		{Name: "Whitespace", Pattern: `[ \t]+`},
 	})
@@ -67,12 +94,57 @@ func PrintTokens(str string) {
 }
 
 func Parse(str string) (*Query, error) {
-	return par.ParseString("", str)
+	q, err := par.ParseString("", str)
+	if err != nil {
+		return q, err
+	}
+	q.link(str)
+	return q, nil
 }
 
 func ParseDebug(str string) (*Query, error) {
 	PrintTokens(str)
-	return par.ParseString("", str, participle.Trace(os.Stdout))
+	q, err := par.ParseString("", str, participle.Trace(os.Stdout))
+	if err != nil {
+		return q, err
+	}
+	q.link(str)
+	return q, nil
+}
+
+// link flattens the leaf terms and collects the operator tokens of the
+// parsed query.
+func (q *Query) link(str string) {
+	q.Terms = nil
+	walkClauses(q.Clauses, func(term *Term) {
+		q.Terms = append(q.Terms, term)
+	})
+	q.operators = operatorTokens(str)
+}
+
+func walkClauses(clauses []*Clause, fn func(term *Term)) {
+	for _, clause := range clauses {
+		for _, term := range clause.Terms {
+			if term.Group != nil {
+				walkClauses(term.Group.Clauses, fn)
+				continue
+			}
+			fn(term)
+		}
+	}
+}
+
+// HasAlternatives returns true if the query uses OR anywhere.
+func (q *Query) HasAlternatives() bool {
+	if q == nil {
+		return false
+	}
+	for _, op := range q.operators {
+		if op.Type == "operator" && op.Value == "OR" {
+			return true
+		}
+	}
+	return false
 }
 
 func (q *Query) QualifierTerms(key string) []*Term {
@@ -116,3 +116,73 @@ func TestKnn2(t *testing.T) {
 	assert.Equal(t, query.Terms[2].Qualifier.Key, "tag")
 	assert.Equal(t, query.Terms[2].Qualifier.Value, "k:not")
 }
+
+func TestOr(t *testing.T) {
+	query, err := Parse(`tag:beach OR tag:lake`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(query.Clauses))
+	assert.Equal(t, 2, len(query.Terms))
+	assert.Equal(t, "beach", query.Terms[0].Qualifier.Value)
+	assert.Equal(t, "lake", query.Terms[1].Qualifier.Value)
+	assert.True(t, query.HasAlternatives())
+}
+
+func TestExplicitAnd(t *testing.T) {
+	query, err := Parse(`tag:beach AND tag:lake`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(query.Clauses))
+	assert.Equal(t, 2, len(query.Clauses[0].Terms))
+	assert.False(t, query.HasAlternatives())
+}
+
+func TestGroup(t *testing.T) {
+	query, err := ParseDebug(`(tag:beach OR tag:lake) created:2021`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(query.Clauses))
+	assert.Equal(t, 2, len(query.Clauses[0].Terms))
+	group := query.Clauses[0].Terms[0].Group
+	assert.NotZero(t, group)
+	assert.Equal(t, 2, len(group.Clauses))
+	assert.Equal(t, 3, len(query.Terms))
+	assert.Equal(t, "created", query.Terms[2].Qualifier.Key)
+}
+
+func TestNestedNotGroup(t *testing.T) {
+	query, err := Parse(`NOT (tag:a (tag:b OR tag:c))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, query.Clauses[0].Terms[0].Not)
+	assert.Equal(t, 3, len(query.Terms))
+}
+
+func TestKeywordPrefixIsWord(t *testing.T) {
+	query, err := Parse(`ORANGE NOTE ANDROID`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "ORANGE NOTE ANDROID", query.Words())
+}
+
+func TestUnbalancedGroup(t *testing.T) {
+	_, err := Parse(`(tag:a OR tag:b`)
+	assert.Error(t, err)
+}
+
+func TestParenthesesInWord(t *testing.T) {
+	query, err := Parse(`filename:IMG(1).jpg (tag:a OR filename:IMG(2))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(query.Terms))
+	assert.Equal(t, "IMG(1).jpg", query.Terms[0].Qualifier.Value)
+	assert.Equal(t, "a", query.Terms[1].Qualifier.Value)
+	assert.Equal(t, "IMG(2)", query.Terms[2].Qualifier.Value)
+	assert.NotZero(t, query.Clauses[0].Terms[1].Group)
+}
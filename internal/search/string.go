@@ -18,18 +18,20 @@ func (q *Query) ExpressionEnum(key string, validValues []string) (f String) {
 		return
 	}
 
-	value := values[0]
-	f.Present = true
-	f.Name = key
+	return termEnum(key, values[0], validValues)
+}
 
-	f.Value = value.Qualifier.Value
-	f.Token = value.Token()
+func termEnum(key string, term *Term, validValues []string) (s String) {
+	s.Present = true
+	s.Name = key
+	s.Value = term.Qualifier.Value
+	s.Token = term.Token()
 	for _, vv := range validValues {
-		if f.Value == vv {
+		if s.Value == vv {
 			return
 		}
 	}
-	f.Error = fmt.Errorf("unsupported value: %s (use %s)", f.Value, strings.Join(validValues, ", "))
+	s.Error = fmt.Errorf("unsupported value: %s (use %s)", s.Value, strings.Join(validValues, ", "))
 	return
 }
 
@@ -40,15 +42,18 @@ func (q *Query) ExpressionStrings(key string) (f Strings) {
 	}
 
 	for _, value := range values {
-		s := String{}
-		s.Present = true
-		s.Name = key
-		s.Value = value.Qualifier.Value
-		s.Token = value.Token()
-		if s.Value == "" {
-			s.Error = fmt.Errorf("value cannot be empty")
-		}
-		f = append(f, s)
+		f = append(f, termString(key, value))
+	}
+	return
+}
+
+func termString(key string, term *Term) (s String) {
+	s.Present = true
+	s.Name = key
+	s.Value = term.Qualifier.Value
+	s.Token = term.Token()
+	if s.Value == "" {
+		s.Error = fmt.Errorf("value cannot be empty")
 	}
 	return
 }
@@ -1,5 +1,7 @@
 package search
 
+import "sort"
+
 type Token struct {
 	Type    string `json:"type"`
 	Value   string `json:"value"`
@@ -8,6 +10,7 @@ type Token struct {
 	End     int    `json:"end"`
 	Key     string `json:"key,omitempty"`     // For qualifiers
 	QualVal string `json:"qualVal,omitempty"` // For qualifiers
+	Depth   int    `json:"depth,omitempty"`   // Group nesting level
 }
 
 func (term *Term) Token() Token {
@@ -17,7 +20,9 @@ func (term *Term) Token() Token {
 		End:   term.EndPos.Offset,
 	}
 
-	if term.String != nil {
+	if term.Group != nil {
+		token.Type = "group"
+	} else if term.String != nil {
 		token.Type = "string"
 		token.Value = *term.String
 	} else if term.Qualifier != nil {
@@ -33,15 +38,108 @@ func (term *Term) Token() Token {
 	return token
 }
 
+// operatorTokens lexes the source string for the boolean keywords and
+// parentheses that are not part of any term.
+func operatorTokens(str string) []Token {
+	l, err := lex.LexString("", str)
+	if err != nil {
+		return nil
+	}
+	symbols := lex.Symbols()
+	var tokens []Token
+	depth := 0
+	for {
+		tok, err := l.Next()
+		if err != nil || tok.EOF() {
+			break
+		}
+		switch tok.Type {
+		case symbols["Operator"]:
+			if tok.Value == "NOT" {
+				continue
+			}
+			tokens = append(tokens, Token{
+				Type:  "operator",
+				Value: tok.Value,
+				Start: tok.Pos.Offset,
+				End:   tok.Pos.Offset + len(tok.Value),
+				Depth: depth,
+			})
+		case symbols["Paren"]:
+			if tok.Value == ")" {
+				depth--
+			}
+			tokens = append(tokens, Token{
+				Type:  "paren",
+				Value: tok.Value,
+				Start: tok.Pos.Offset,
+				End:   tok.Pos.Offset + len(tok.Value),
+				Depth: depth,
+			})
+			if tok.Value == "(" {
+				depth++
+			}
+		}
+	}
+	return tokens
+}
+
+func appendClauseTokens(tokens []Token, clauses []*Clause, depth int) []Token {
+	for _, clause := range clauses {
+		for _, term := range clause.Terms {
+			if term.Group != nil {
+				tokens = appendClauseTokens(tokens, term.Group.Clauses, depth+1)
+				continue
+			}
+			token := term.Token()
+			token.Depth = depth
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// negatedGroups maps the offsets of the opening parentheses of negated
+// groups to the offsets of their NOT keywords.
+func negatedGroups(clauses []*Clause, offsets map[int]int) {
+	for _, clause := range clauses {
+		for _, term := range clause.Terms {
+			if term.Group == nil {
+				continue
+			}
+			if term.Not {
+				offsets[term.Group.Pos.Offset] = term.Pos.Offset
+			}
+			negatedGroups(term.Group.Clauses, offsets)
+		}
+	}
+}
+
 // Tokens returns a list of Tokens representing the query.
+//
+// Besides the terms, the list includes "operator" tokens for OR and AND and
+// "paren" tokens for group parentheses. Every token carries the group
+// nesting depth. The opening parenthesis of a negated group is marked with
+// Not and starts at the NOT keyword, same as negated terms.
 func (q *Query) Tokens() []Token {
 	if q == nil {
 		return nil
 	}
-	var tokens []Token
-	for _, term := range q.Terms {
-		token := term.Token()
-		tokens = append(tokens, token)
+	tokens := appendClauseTokens(nil, q.Clauses, 0)
+	if len(q.operators) == 0 {
+		return tokens
+	}
+	negated := make(map[int]int)
+	negatedGroups(q.Clauses, negated)
+	for _, op := range q.operators {
+		if start, ok := negated[op.Start]; ok && op.Type == "paren" {
+			op.Not = true
+			op.Start = start
+		}
+		tokens = append(tokens, op)
 	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Start < tokens[j].Start
+	})
 	return tokens
 }
@@ -295,3 +295,25 @@ func TestTokenPositionsFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestTokensGrouping(t *testing.T) {
+	query, err := Parse(`(tag:beach OR tag:lake) AND NOT (created:2021) hello`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := query.Tokens()
+	expected := []Token{
+		{Type: "paren", Value: "(", Start: 0, End: 1},
+		{Type: "qualifier", Value: "tag:beach", Start: 1, End: 10, Key: "tag", QualVal: "beach", Depth: 1},
+		{Type: "operator", Value: "OR", Start: 11, End: 13, Depth: 1},
+		{Type: "qualifier", Value: "tag:lake", Start: 14, End: 22, Key: "tag", QualVal: "lake", Depth: 1},
+		{Type: "paren", Value: ")", Start: 22, End: 23},
+		{Type: "operator", Value: "AND", Start: 24, End: 27},
+		{Type: "paren", Value: "(", Not: true, Start: 28, End: 33},
+		{Type: "qualifier", Value: "created:2021", Start: 33, End: 45, Key: "created", QualVal: "2021", Depth: 1},
+		{Type: "paren", Value: ")", Start: 45, End: 46},
+		{Type: "word", Value: "hello", Start: 47, End: 52},
+	}
+	assert.Equal(t, expected, tokens)
+}
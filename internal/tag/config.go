@@ -9,4 +9,12 @@ type Config struct {
 	Exif struct {
 		Enable bool `json:"enable"`
 	} `json:"exif"`
+
+	// Suggestions of the auto tags by the INDEX_AUTOTAG task
+	Autotag struct {
+		// Number of most similar tagged files each file is classified by
+		Neighbors int `json:"neighbors"`
+		// Min confidence from 0 to 1 of the suggested tags
+		MinConfidence float32 `json:"min_confidence"`
+	} `json:"autotag"`
 }
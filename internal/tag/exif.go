@@ -11,11 +11,7 @@ import (
 var exifNames = []string{
 	"Make",
 	"Model",
-	// "ISO",
-	// "ShutterSpeed",
-	// "Aperture",
 	// "ExposureCompensation",
-	// "FocalLength35efl",
 	// "FocusMode",
 	// "WhiteBalance",
 	// "MeteringMode",
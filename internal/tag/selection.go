@@ -1,6 +1,11 @@
 package tag
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+const selectionPrefix = "sys:select:col:"
 
 func NewSelection(collectionId string) (Tag, error) {
 	var t Tag
@@ -10,6 +15,20 @@ func NewSelection(collectionId string) (Tag, error) {
 		return t, err
 	}
 
-	t.Name = fmt.Sprintf("sys:select:col:%s:%s", collectionId, rand)
+	t.Name = fmt.Sprintf("%s%s:%s", selectionPrefix, collectionId, rand)
 	return t, nil
 }
+
+// SelectionCollectionId returns the id of the collection of the selection
+// tag with the name, or false if it is not a selection tag.
+func SelectionCollectionId(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, selectionPrefix)
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 {
+		return "", false
+	}
+	return rest[:i], true
+}
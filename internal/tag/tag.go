@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"strings"
 	"time"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
@@ -11,11 +12,41 @@ import (
 
 type Id uint32
 
+// Separator separates the names of hierarchical tags, e.g. places/europe is
+// the parent of places/europe/slovenia.
+const Separator = "/"
+
 type Tag struct {
 	Id        Id        `json:"id"`
 	Name      string    `json:"name"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 	FileCount int       `json:"file_count"`
+	// Auto tags are suggested for similar files by the INDEX_AUTOTAG task
+	Auto bool `json:"auto,omitempty"`
+	// Name of the parent tag of hierarchical tags
+	Parent   string `json:"parent,omitempty"`
+	Children []Tag  `json:"children,omitempty"`
+}
+
+// ParentName returns the name of the parent of the tag with the name, or
+// false if it is not a hierarchical tag.
+func ParentName(name string) (string, bool) {
+	i := strings.LastIndex(name, Separator)
+	if i <= 0 {
+		return "", false
+	}
+	return name[:i], true
+}
+
+// ValidName returns true if the name is not empty and none of its
+// hierarchy levels are empty either.
+func ValidName(name string) bool {
+	for _, s := range strings.Split(name, Separator) {
+		if strings.TrimSpace(s) == "" {
+			return false
+		}
+	}
+	return true
 }
 
 func (t Tag) ETag() string {
@@ -29,6 +60,9 @@ type ExternalTag struct {
 	Name      string `json:"name"`
 	UpdatedAt string `json:"updated_at,omitempty"`
 	FileCount int    `json:"file_count"`
+	Auto      bool   `json:"auto,omitempty"`
+	Parent    string `json:"parent,omitempty"`
+	Children  []Tag  `json:"children,omitempty"`
 	ETag      string `json:"etag,omitempty"`
 }
 
@@ -42,6 +76,9 @@ func (t Tag) MarshalJSON() ([]byte, error) {
 		Name:      t.Name,
 		UpdatedAt: t.UpdatedAt.Format(time.RFC3339),
 		FileCount: t.FileCount,
+		Auto:      t.Auto,
+		Parent:    t.Parent,
+		Children:  t.Children,
 		ETag:      t.ETag(),
 	})
 }
@@ -9,10 +9,14 @@ import (
 
 // Pipeline task type constants
 const (
-	TypeIndexFiles    = "INDEX_FILES"
-	TypeIndexMetadata = "INDEX_METADATA"
-	TypeIndexContents = "INDEX_CONTENTS"
-	TypeIndexFaces    = "INDEX_FACES"
+	TypeIndexFiles      = "INDEX_FILES"
+	TypeIndexMetadata   = "INDEX_METADATA"
+	TypeIndexContents   = "INDEX_CONTENTS"
+	TypeIndexFaces      = "INDEX_FACES"
+	TypeIndexDuplicates = "INDEX_DUPLICATES"
+	TypeIndexPersons    = "INDEX_PERSONS"
+	TypeIndexAutotag    = "INDEX_AUTOTAG"
+	TypeIndexSchedule   = "INDEX_SCHEDULE"
 )
 
 // Task represents a long-running operation that can be tracked
@@ -34,6 +38,9 @@ type Task struct {
 	Force          bool      `json:"-"` // Force reprocessing even if data already exists
 	EnqueuedAt     time.Time `json:"-"` // Used for priority ordering within a stage
 
+	// Persisted run of the task, 0 if not started or not persisted
+	runId int64
+
 	// Context for cancellation and completion signaling
 	ctx    context.Context    `json:"-"`
 	cancel context.CancelFunc `json:"-"`
@@ -73,6 +80,20 @@ func (t *Task) Progress() (done int, total int) {
 	return
 }
 
+// SetRunId sets the id of the persisted run of the task in a thread-safe way.
+func (t *Task) SetRunId(id int64) {
+	t.mu.Lock()
+	t.runId = id
+	t.mu.Unlock()
+}
+
+// RunId returns the id of the persisted run of the task, 0 if there is none.
+func (t *Task) RunId() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.runId
+}
+
 // Completed returns a channel that closes when the task is complete
 func (t *Task) Completed() <-chan struct{} {
 	return t.ctx.Done()
@@ -164,10 +185,14 @@ func NewIndexTask(collectionId, collectionName string, dirs []string, maxPhotos
 // newStageTask is a shared constructor for per-stage tasks
 func newStageTask(taskType, collectionId, collectionName string, dirs []string, maxPhotos int, force bool) *Task {
 	typeSlug := map[string]string{
-		TypeIndexFiles:    "files",
-		TypeIndexMetadata: "metadata",
-		TypeIndexContents: "contents",
-		TypeIndexFaces:    "faces",
+		TypeIndexFiles:      "files",
+		TypeIndexMetadata:   "metadata",
+		TypeIndexContents:   "contents",
+		TypeIndexFaces:      "faces",
+		TypeIndexDuplicates: "duplicates",
+		TypeIndexPersons:    "persons",
+		TypeIndexAutotag:    "autotag",
+		TypeIndexSchedule:   "schedule",
 	}[taskType]
 	t := New(
 		taskType,
@@ -203,4 +228,23 @@ func NewFacesTask(collectionId, collectionName string, dirs []string, maxPhotos
 	return newStageTask(TypeIndexFaces, collectionId, collectionName, dirs, maxPhotos, force)
 }
 
+// NewDuplicatesTask creates a task for grouping duplicate files
+func NewDuplicatesTask(collectionId, collectionName string, dirs []string) *Task {
+	return newStageTask(TypeIndexDuplicates, collectionId, collectionName, dirs, 0, false)
+}
+
+// NewPersonsTask creates a task for clustering faces into persons
+func NewPersonsTask(collectionId, collectionName string, dirs []string) *Task {
+	return newStageTask(TypeIndexPersons, collectionId, collectionName, dirs, 0, false)
+}
 
+// NewAutotagTask creates a task for suggesting auto tags for similar files
+func NewAutotagTask(collectionId, collectionName string, dirs []string) *Task {
+	return newStageTask(TypeIndexAutotag, collectionId, collectionName, dirs, 0, false)
+}
+
+// NewScheduleTask creates a task for indexing the stages of the schedule of
+// a collection if it changed since it was last indexed
+func NewScheduleTask(collectionId, collectionName string) *Task {
+	return newStageTask(TypeIndexSchedule, collectionId, collectionName, nil, 0, false)
+}
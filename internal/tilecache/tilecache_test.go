@@ -0,0 +1,103 @@
+package tilecache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustKey(t *testing.T, v any) string {
+	t.Helper()
+	key, err := Key(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKey(t *testing.T) {
+	type params struct {
+		Zoom int
+		X, Y int
+	}
+	a := mustKey(t, params{Zoom: 3, X: 1, Y: 2})
+	if len(a) != 64 {
+		t.Errorf("key %q, want 64 hex characters", a)
+	}
+	if b := mustKey(t, params{Zoom: 3, X: 1, Y: 2}); a != b {
+		t.Errorf("keys of same params differ, %s != %s", a, b)
+	}
+	if b := mustKey(t, params{Zoom: 3, X: 2, Y: 1}); a == b {
+		t.Errorf("keys of different params are the same, %s", a)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b, d := mustKey(t, "a"), mustKey(t, "b"), mustKey(t, "d")
+	if _, ok := c.Get(a); ok {
+		t.Error("empty cache hit")
+	}
+	for _, key := range []string{a, b} {
+		if err := c.Put(key, []byte("1234")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, ok := c.Get(a)
+	if !ok || !bytes.Equal(got, []byte("1234")) {
+		t.Fatalf("got %q %v, want 1234", got, ok)
+	}
+
+	// b is the least recently used
+	if err := c.Put(d, []byte("5678")); err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 8 {
+		t.Errorf("size %d, want 8", c.Size())
+	}
+	if _, ok := c.Get(b); ok {
+		t.Error("least recently used tile not evicted")
+	}
+	if _, err := os.Stat(c.path(b)); !os.IsNotExist(err) {
+		t.Errorf("evicted tile file exists, %v", err)
+	}
+	if err := c.Put(mustKey(t, "large"), []byte("12345678901")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(a); !ok {
+		t.Error("tile evicted by tile larger than the cache")
+	}
+
+	// Reopen with a leftover temporary file
+	tmp := filepath.Join(dir, "00", "interrupted.tmp")
+	os.MkdirAll(filepath.Dir(tmp), 0755)
+	os.WriteFile(tmp, []byte("12"), 0644)
+	c, err = Open(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 8 {
+		t.Errorf("reopened size %d, want 8", c.Size())
+	}
+	if got, ok := c.Get(d); !ok || !bytes.Equal(got, []byte("5678")) {
+		t.Errorf("reopened got %q %v, want 5678", got, ok)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary file not removed, %v", err)
+	}
+
+	// Shrinking removes the least recently used tiles
+	c.SetMaxSize(4)
+	if c.Size() != 4 {
+		t.Errorf("shrunk size %d, want 4", c.Size())
+	}
+	if _, ok := c.Get(d); !ok {
+		t.Error("most recently used tile evicted by shrinking")
+	}
+}
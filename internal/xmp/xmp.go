@@ -0,0 +1,329 @@
+// Package xmp reads and writes the keywords and ratings of XMP metadata,
+// either embedded in the files or in .xmp sidecar files next to them, as
+// used by darktable, digiKam, Lightroom and other photo managers.
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"photofield/internal/tag"
+)
+
+const (
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsDC  = "http://purl.org/dc/elements/1.1/"
+	nsLR  = "http://ns.adobe.com/lightroom/1.0/"
+	nsXMP = "http://ns.adobe.com/xap/1.0/"
+)
+
+// HierarchySeparator separates the levels of the keywords of
+// lr:hierarchicalSubject, e.g. places|europe|slovenia.
+const HierarchySeparator = "|"
+
+// maxEmbeddedSize is the max number of bytes from the start of a file
+// searched for embedded XMP, as it is usually stored in the header.
+const maxEmbeddedSize = 4 << 20
+
+var ErrInvalid = errors.New("invalid xmp")
+
+// Meta is the XMP metadata photofield syncs.
+type Meta struct {
+	// Subjects are the flat keywords of dc:subject
+	Subjects []string
+	// HierarchicalSubjects are the keywords of lr:hierarchicalSubject
+	HierarchicalSubjects []string
+	// Rating of xmp:Rating from 1 to 5, -1 if rejected, 0 if unrated
+	Rating    int
+	HasRating bool
+}
+
+// Parse parses the keywords and the rating of the XMP packet.
+func Parse(r io.Reader) (Meta, error) {
+	m := Meta{}
+	d := xml.NewDecoder(r)
+	d.Strict = false
+
+	var list *[]string
+	var text strings.Builder
+	inLi := false
+	inRating := false
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return m, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name {
+			case xml.Name{Space: nsRDF, Local: "Description"}:
+				for _, attr := range t.Attr {
+					if attr.Name == (xml.Name{Space: nsXMP, Local: "Rating"}) {
+						m.setRating(attr.Value)
+					}
+				}
+			case xml.Name{Space: nsDC, Local: "subject"}:
+				list = &m.Subjects
+			case xml.Name{Space: nsLR, Local: "hierarchicalSubject"}:
+				list = &m.HierarchicalSubjects
+			case xml.Name{Space: nsRDF, Local: "li"}:
+				inLi = list != nil
+				text.Reset()
+			case xml.Name{Space: nsXMP, Local: "Rating"}:
+				inRating = true
+				text.Reset()
+			}
+		case xml.CharData:
+			if inLi || inRating {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name {
+			case xml.Name{Space: nsDC, Local: "subject"}, xml.Name{Space: nsLR, Local: "hierarchicalSubject"}:
+				list = nil
+			case xml.Name{Space: nsRDF, Local: "li"}:
+				if inLi {
+					if s := strings.TrimSpace(text.String()); s != "" {
+						*list = append(*list, s)
+					}
+				}
+				inLi = false
+			case xml.Name{Space: nsXMP, Local: "Rating"}:
+				m.setRating(text.String())
+				inRating = false
+			}
+		}
+	}
+}
+
+func (m *Meta) setRating(value string) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return
+	}
+	m.Rating = int(f)
+	m.HasRating = true
+}
+
+// Find returns the XMP packet embedded in the data of a file, or nil if
+// there is none.
+func Find(data []byte) []byte {
+	for _, tags := range [][2]string{
+		{"<x:xmpmeta", "</x:xmpmeta>"},
+		{"<rdf:RDF", "</rdf:RDF>"},
+	} {
+		start := bytes.Index(data, []byte(tags[0]))
+		if start == -1 {
+			continue
+		}
+		end := bytes.Index(data[start:], []byte(tags[1]))
+		if end == -1 {
+			continue
+		}
+		return data[start : start+end+len(tags[1])]
+	}
+	return nil
+}
+
+// SidecarPaths returns the paths the sidecar of the file can have, e.g.
+// photo.jpg.xmp as used by darktable and digiKam, or photo.xmp as used by
+// Lightroom.
+func SidecarPaths(path string) []string {
+	return []string{
+		path + ".xmp",
+		strings.TrimSuffix(path, filepath.Ext(path)) + ".xmp",
+	}
+}
+
+// SidecarPath returns the path of the existing sidecar of the file, or the
+// path of a new one if it has none.
+func SidecarPath(path string) (string, bool) {
+	paths := SidecarPaths(path)
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return paths[0], false
+}
+
+// ReadFile reads the XMP metadata of the file from its sidecar, or from the
+// file itself if it has none. Returns false if there is no XMP metadata.
+func ReadFile(path string) (Meta, bool, error) {
+	if p, ok := SidecarPath(path); ok {
+		f, err := os.Open(p)
+		if err != nil {
+			return Meta{}, false, err
+		}
+		defer f.Close()
+		m, err := Parse(f)
+		return m, err == nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Meta{}, false, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxEmbeddedSize))
+	if err != nil {
+		return Meta{}, false, err
+	}
+	packet := Find(data)
+	if packet == nil {
+		return Meta{}, false, nil
+	}
+	m, err := Parse(bytes.NewReader(packet))
+	return m, err == nil, err
+}
+
+// Keywords returns the keywords as tag names, with the levels of
+// hierarchical keywords separated by the tag separator. Flat keywords that
+// are levels of hierarchical ones are skipped, as Lightroom and others add
+// all levels of hierarchical keywords as flat keywords as well.
+func (m Meta) Keywords() []string {
+	names := make([]string, 0, len(m.Subjects)+len(m.HierarchicalSubjects))
+	seen := make(map[string]bool)
+	levels := make(map[string]bool)
+	for _, s := range m.HierarchicalSubjects {
+		parts := strings.Split(s, HierarchySeparator)
+		for _, p := range parts {
+			levels[p] = true
+		}
+		name := strings.Join(parts, tag.Separator)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, s := range m.Subjects {
+		if levels[s] || seen[s] {
+			continue
+		}
+		seen[s] = true
+		names = append(names, s)
+	}
+	return names
+}
+
+// FromKeywords returns the metadata of the tag names, adding all levels of
+// hierarchical tags as flat keywords, so that they can be found by tools
+// not supporting hierarchical keywords.
+func FromKeywords(names []string) Meta {
+	m := Meta{
+		Subjects:             make([]string, 0, len(names)),
+		HierarchicalSubjects: make([]string, 0, len(names)),
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		parts := strings.Split(name, tag.Separator)
+		m.HierarchicalSubjects = append(m.HierarchicalSubjects, strings.Join(parts, HierarchySeparator))
+		for _, p := range parts {
+			if !seen[p] {
+				seen[p] = true
+				m.Subjects = append(m.Subjects, p)
+			}
+		}
+	}
+	return m
+}
+
+const sidecarTemplate = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>
+`
+
+const keywordsDescription = `<rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:lr="http://ns.adobe.com/lightroom/1.0/">`
+
+var keywordsMatchers = []*regexp.Regexp{
+	regexp.MustCompile(`(?s)\s*<dc:subject(\s[^>]*)?(/>|>.*?</dc:subject>)`),
+	regexp.MustCompile(`(?s)\s*<lr:hierarchicalSubject(\s[^>]*)?(/>|>.*?</lr:hierarchicalSubject>)`),
+	// Descriptions left empty by the previous writes
+	regexp.MustCompile(`(?s)\s*` + regexp.QuoteMeta(keywordsDescription) + `\s*</rdf:Description>`),
+}
+
+// WriteSidecar writes the keywords to the sidecar of the file, replacing
+// the previous keywords and keeping all other metadata. The sidecar is only
+// created if there are any keywords.
+func WriteSidecar(path string, m Meta) error {
+	p, exists := SidecarPath(path)
+	data := []byte(sidecarTemplate)
+	if exists {
+		var err error
+		data, err = os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+	} else if len(m.Subjects) == 0 && len(m.HierarchicalSubjects) == 0 {
+		return nil
+	}
+
+	for _, matcher := range keywordsMatchers {
+		data = matcher.ReplaceAll(data, nil)
+	}
+
+	end := bytes.LastIndex(data, []byte("</rdf:RDF>"))
+	if end == -1 {
+		return ErrInvalid
+	}
+
+	var b bytes.Buffer
+	b.Write(data[:end])
+	if len(m.Subjects) > 0 || len(m.HierarchicalSubjects) > 0 {
+		b.WriteString(" " + keywordsDescription + "\n")
+		writeBag(&b, "dc:subject", m.Subjects)
+		writeBag(&b, "lr:hierarchicalSubject", m.HierarchicalSubjects)
+		b.WriteString("  </rdf:Description>\n ")
+	}
+	b.Write(data[end:])
+
+	// Written to a unique temporary file in the same dir and renamed, so
+	// that the sidecar is never left half written
+	f, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(b.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, p)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func writeBag(b *bytes.Buffer, name string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	b.WriteString("   <" + name + ">\n    <rdf:Bag>\n")
+	for _, item := range items {
+		b.WriteString("     <rdf:li>")
+		xml.EscapeText(b, []byte(item))
+		b.WriteString("</rdf:li>\n")
+	}
+	b.WriteString("    </rdf:Bag>\n   </" + name + ">\n")
+}
@@ -0,0 +1,157 @@
+package xmp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const darktableSidecar = `<?xml version="1.0" encoding="UTF-8"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="XMP Core 4.4.0-Exiv2">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:lr="http://ns.adobe.com/lightroom/1.0/"
+    xmlns:darktable="http://darktable.sf.net/"
+   xmp:Rating="4"
+   darktable:xmp_version="5">
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>beach</rdf:li>
+     <rdf:li>places</rdf:li>
+     <rdf:li>europe</rdf:li>
+     <rdf:li>slovenia</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <lr:hierarchicalSubject>
+    <rdf:Bag>
+     <rdf:li>places|europe|slovenia</rdf:li>
+    </rdf:Bag>
+   </lr:hierarchicalSubject>
+   <darktable:history>
+    <rdf:Seq/>
+   </darktable:history>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(darktableSidecar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.HasRating || m.Rating != 4 {
+		t.Errorf("rating = %d, want 4", m.Rating)
+	}
+	if got, want := m.Keywords(), []string{"places/europe/slovenia", "beach"}; !slices.Equal(got, want) {
+		t.Errorf("keywords = %v, want %v", got, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	data := []byte("\xff\xd8\xff\xe1http://ns.adobe.com/xap/1.0/\x00" + darktableSidecar + "\xff\xd9")
+	m, err := Parse(strings.NewReader(string(Find(data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Subjects) != 4 {
+		t.Errorf("subjects = %v, want 4", m.Subjects)
+	}
+	if Find([]byte("no xmp here")) != nil {
+		t.Errorf("found xmp in data without it")
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+
+	// No sidecar is created without keywords
+	if err := WriteSidecar(path, FromKeywords(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SidecarPath(path); ok {
+		t.Fatal("sidecar created without keywords")
+	}
+
+	keywords := []string{"places/europe/slovenia", "beach & sea"}
+	if err := WriteSidecar(path, FromKeywords(keywords)); err != nil {
+		t.Fatal(err)
+	}
+	m, ok, err := ReadFile(path)
+	if err != nil || !ok {
+		t.Fatalf("read = %v, %v", ok, err)
+	}
+	if got := m.Keywords(); !slices.Equal(got, keywords) {
+		t.Errorf("keywords = %v, want %v", got, keywords)
+	}
+
+	// Existing sidecars keep their other metadata
+	sidecar := filepath.Join(dir, "other.xmp")
+	if err := os.WriteFile(sidecar, []byte(darktableSidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "other.nef")
+	for range 2 {
+		if err := WriteSidecar(other, FromKeywords([]string{"forest"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, _, err = ReadFile(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Keywords(), []string{"forest"}; !slices.Equal(got, want) {
+		t.Errorf("keywords = %v, want %v", got, want)
+	}
+	if m.Rating != 4 {
+		t.Errorf("rating = %d, want 4", m.Rating)
+	}
+	data, _ := os.ReadFile(sidecar)
+	if !strings.Contains(string(data), "darktable:history") {
+		t.Errorf("sidecar lost other metadata:\n%s", data)
+	}
+	if n := strings.Count(string(data), "<rdf:Description"); n != 2 {
+		t.Errorf("sidecar has %d descriptions, want 2:\n%s", n, data)
+	}
+}
+
+func TestWriteSidecarConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- WriteSidecar(path, FromKeywords([]string{fmt.Sprintf("tag%d", i)}))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("write: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "photo.jpg.xmp" {
+		t.Errorf("dir has %v, want only the sidecar", entries)
+	}
+	m, ok, err := ReadFile(path)
+	if err != nil || !ok || len(m.Keywords()) != 1 {
+		t.Errorf("read %v %v %v, want a single keyword", m, ok, err)
+	}
+}
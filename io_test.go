@@ -494,7 +494,7 @@ func TestThumbnailGeneration(t *testing.T) {
 			{Width: 240, Height: 320},
 		},
 	}
-	images, err := test.GenerateTestDataset("testdata", dataset)
+	images, err := test.GenerateTestDataset(t.TempDir(), dataset)
 	if err != nil {
 		t.Fatalf("failed to generate test dataset: %v", err)
 	}
@@ -558,7 +558,7 @@ func TestThumbnailGenerationOrientation(t *testing.T) {
 			{Width: 60, Height: 30, ExifTags: map[string]string{"Orientation": "Rotate 270 CW"}},
 		},
 	}
-	images, err := test.GenerateTestDataset("testdata", dataset)
+	images, err := test.GenerateTestDataset(t.TempDir(), dataset)
 	if err != nil {
 		t.Fatalf("failed to generate test dataset: %v", err)
 	}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"embed"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	goimage "image"
@@ -18,9 +21,11 @@ import (
 	"net"
 	"os/signal"
 	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"runtime/trace"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -53,6 +58,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"photofield/internal/auth"
 	"photofield/internal/codec"
 	"photofield/internal/collection"
 	"photofield/internal/fs/rewrite"
@@ -66,10 +72,12 @@ import (
 	"photofield/internal/openapi"
 	"photofield/internal/render"
 	"photofield/internal/scene"
+	"photofield/internal/schedule"
 	"photofield/internal/search"
 	"photofield/internal/tag"
 	inttask "photofield/internal/task"
 	"photofield/internal/test"
+	"photofield/internal/xmp"
 )
 
 //go:generate go run github.com/deepmap/oapi-codegen/cmd/oapi-codegen@v1.8.2 -generate=types,chi-server -package=openapi -o internal/openapi/api.gen.go api.yaml
@@ -114,6 +122,8 @@ var globalGeo *geo.Geo
 var sceneSource *scene.SceneSource
 var collections []collection.Collection
 var pipelineCoordinator *pipeline.Coordinator
+var collectionScheduler *schedule.Scheduler
+var collectionWatcher *pipeline.Watcher
 
 var requestsOut chan struct{}
 var requests []ApiRequest
@@ -138,13 +148,19 @@ func instrumentationMiddleware(next http.Handler) http.Handler {
 }
 
 type Task struct {
-	Id           string    `json:"id"`
-	Type         string    `json:"type"`
-	Name         string    `json:"name"`
-	CollectionId string    `json:"collection_id"`
-	Done         int       `json:"done"`
-	Pending      int       `json:"pending,omitempty"`
-	enqueuedAt   time.Time `json:"-"`
+	Id           string     `json:"id"`
+	Type         string     `json:"type"`
+	Name         string     `json:"name"`
+	CollectionId string     `json:"collection_id"`
+	Done         int        `json:"done"`
+	Pending      int        `json:"pending,omitempty"`
+	Total        int        `json:"total,omitempty"`
+	RunId        int64      `json:"run_id,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	ErrorCount   int        `json:"error_count,omitempty"`
+	enqueuedAt   time.Time  `json:"-"`
 }
 
 type TileWriter func(w io.Writer) error
@@ -183,7 +199,9 @@ func respond(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
 	chirender.Respond(w, r, v)
 }
 
-func drawTile(ctx context.Context, c *canvas.Context, r *render.Render, scene *render.Scene, zoom int, x int, y int) {
+// drawTile draws the tile of the scene, returning false if any photos were
+// drawn as placeholders.
+func drawTile(ctx context.Context, c *canvas.Context, r *render.Render, scene *render.Scene, zoom int, x int, y int) bool {
 
 	scales := render.Scales{
 		Tile: 1 / float64(r.TileSize),
@@ -200,7 +218,7 @@ func drawTile(ctx context.Context, c *canvas.Context, r *render.Render, scene *r
 
 	c.SetFillColor(canvas.Black)
 
-	scene.Draw(ctx, r, c, scales, imageSource)
+	return scene.Draw(ctx, r, c, scales, imageSource)
 }
 
 func getImagePool(config *render.Render) *sync.Pool {
@@ -264,9 +282,50 @@ func getCollectionById(id string) *collection.Collection {
 			return &collections[i]
 		}
 	}
+	if savedId, ok := collection.ParseSavedSearchCollectionId(id); ok && imageSource != nil {
+		s, ok := imageSource.GetSavedSearch(savedId)
+		if !ok {
+			return nil
+		}
+		c, ok := getSavedSearchCollection(s)
+		if !ok {
+			return nil
+		}
+		return &c
+	}
 	return nil
 }
 
+// getSavedSearchCollection returns the virtual collection of a saved search
+// sourced from its collection, or all collections if it has none.
+func getSavedSearchCollection(s image.SavedSearch) (collection.Collection, bool) {
+	var bases []*collection.Collection
+	for i := range collections {
+		if s.CollectionId == "" || collections[i].Id == s.CollectionId {
+			bases = append(bases, &collections[i])
+		}
+	}
+	if len(bases) == 0 {
+		return collection.Collection{}, false
+	}
+	return collection.FromSavedSearch(s, bases), true
+}
+
+func getSavedSearchCollections() []collection.Collection {
+	var items []collection.Collection
+	if imageSource == nil {
+		return items
+	}
+	for _, s := range imageSource.ListSavedSearches() {
+		c, ok := getSavedSearchCollection(s)
+		if !ok {
+			continue
+		}
+		items = append(items, c)
+	}
+	return items
+}
+
 func pushApiRequest(request ApiRequest) {
 	requestsMutex.Lock()
 	requests = append(requests, request)
@@ -353,7 +412,7 @@ func (*Api) PostScenes(w http.ResponseWriter, r *http.Request) {
 	sceneConfig := defaultSceneConfig
 
 	collection := getCollectionById(string(data.CollectionId))
-	if collection == nil {
+	if collection == nil || !canAccessCollection(r, collection) {
 		problem(w, r, http.StatusBadRequest, "Collection not found")
 		return
 	}
@@ -392,6 +451,10 @@ func (*Api) PostScenes(w http.ResponseWriter, r *http.Request) {
 	if data.Search != nil {
 		sceneConfig.Scene.Search = string(*data.Search)
 	}
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		// Share links only show the files of the share
+		sceneConfig.Scene.Search = share.SceneSearch()
+	}
 
 	scene := sceneSource.Add(sceneConfig, imageSource)
 
@@ -416,11 +479,14 @@ func (*Api) GetScenes(w http.ResponseWriter, r *http.Request, params openapi.Get
 	if params.Search != nil {
 		sceneConfig.Scene.Search = string(*params.Search)
 	}
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		sceneConfig.Scene.Search = share.SceneSearch()
+	}
 	if params.Tweaks != nil {
 		sceneConfig.Layout.Tweaks = string(*params.Tweaks)
 	}
 	collection := getCollectionById(string(params.CollectionId))
-	if collection == nil {
+	if collection == nil || !canAccessCollection(r, collection) {
 		problem(w, r, http.StatusBadRequest, "Collection not found")
 		return
 	}
@@ -469,7 +535,7 @@ func (*Api) GetScenes(w http.ResponseWriter, r *http.Request, params openapi.Get
 
 func (*Api) GetScenesId(w http.ResponseWriter, r *http.Request, id openapi.SceneId) {
 
-	scene := sceneSource.GetSceneById(string(id), imageSource)
+	scene := getAllowedSceneById(r, id)
 	if scene == nil {
 		problem(w, r, http.StatusNotFound, "Scene not found")
 		return
@@ -479,13 +545,21 @@ func (*Api) GetScenesId(w http.ResponseWriter, r *http.Request, id openapi.Scene
 }
 
 func (*Api) GetCollections(w http.ResponseWriter, r *http.Request) {
+	items := make([]collection.Collection, 0, len(collections))
 	for i := range collections {
 		collection := &collections[i]
+		if !canAccessCollection(r, collection) {
+			continue
+		}
 		collection.UpdateIndexedAt(imageSource)
+		items = append(items, *collection)
 	}
-	items := collections
-	if items == nil {
-		items = make([]collection.Collection, 0)
+	for _, collection := range getSavedSearchCollections() {
+		if !canAccessCollection(r, &collection) {
+			continue
+		}
+		collection.UpdateIndexedAt(imageSource)
+		items = append(items, collection)
 	}
 	respond(w, r, http.StatusOK, struct {
 		Items []collection.Collection `json:"items"`
@@ -496,179 +570,787 @@ func (*Api) GetCollections(w http.ResponseWriter, r *http.Request) {
 
 func (*Api) GetCollectionsId(w http.ResponseWriter, r *http.Request, id openapi.CollectionId) {
 
-	for _, collection := range collections {
-		if collection.Id == string(id) {
-			collection.UpdateIndexedAt(imageSource)
-			collection.UpdateIndexedCount(imageSource)
-			respond(w, r, http.StatusOK, collection)
-			return
-		}
+	collection := getCollectionById(string(id))
+	if collection == nil || !canAccessCollection(r, collection) {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
 	}
 
-	problem(w, r, http.StatusNotFound, "Scene not found")
+	collection.UpdateIndexedAt(imageSource)
+	collection.UpdateIndexedCount(imageSource)
+	respond(w, r, http.StatusOK, collection)
 }
 
-func taskDisplayOrder(taskType string) int {
-	switch taskType {
-	case string(openapi.TaskTypeINDEXMETADATA):
-		return 0
-	case string(openapi.TaskTypeINDEXCONTENTS):
-		return 1
-	case string(openapi.TaskTypeINDEXFACES):
-		return 2
-	default:
-		return 3
+func (*Api) GetCollectionsIdDownloadZip(w http.ResponseWriter, r *http.Request, id openapi.CollectionId, params openapi.GetCollectionsIdDownloadZipParams) {
+	collection := getCollectionById(string(id))
+	if collection == nil || !canAccessCollection(r, collection) {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
+	}
+	size, err := downloadSize(params.Size)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
 	}
-}
 
-// pipelineTaskResponse converts a pipeline task to an API Task for HTTP responses.
-func pipelineTaskResponse(pt *inttask.Task, taskType, collectionId string) *Task {
-	return &Task{
-		Id:           pt.Id,
-		Type:         taskType,
-		Name:         pt.Name,
-		CollectionId: collectionId,
-		enqueuedAt:   pt.EnqueuedAt,
+	q := ""
+	if params.Search != nil {
+		q = string(*params.Search)
+	}
+	if share, ok := auth.ShareFromContext(r.Context()); ok {
+		q = share.SceneSearch()
+	}
+	var expression search.Expression
+	if query := collection.SearchWith(q); query != "" {
+		parsed, err := search.Parse(query)
+		if err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		expression, err = parsed.Expression()
+		if err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Similarity searches need embeddings and thresholds of scenes
+		if expression.Text != "" || expression.Image.Present || expression.Face.Present || expression.Filter.Value == "knn" {
+			problem(w, r, http.StatusBadRequest, "Similarity searches cannot be downloaded, select the files instead")
+			return
+		}
+		if err := imageSource.ResolvePlaces(r.Context(), expression.Condition); err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
-}
 
-// taskItems wraps tasks in the standard items response envelope.
-func taskItems(tasks ...*Task) struct {
-	Items []*Task `json:"items"`
-} {
-	return struct {
-		Items []*Task `json:"items"`
-	}{Items: tasks}
+	infos, _ := collection.GetInfos(imageSource, image.ListOptions{
+		Limit:      collection.Limit,
+		Expression: expression,
+	})
+	files := make([]image.ImageId, 0)
+	for info := range infos {
+		files = append(files, info.Id)
+	}
+	if len(files) == 0 {
+		problem(w, r, http.StatusNotFound, "No files to download")
+		return
+	}
+	writeZip(w, r, collection.Id+".zip", files, size)
 }
 
-func (*Api) GetTasks(w http.ResponseWriter, r *http.Request, params openapi.GetTasksParams) {
+func (*Api) PostCollections(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.CollectionPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	tasks := make([]*Task, 0)
+	c, err := collectionFromPost(data)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if pipelineCoordinator != nil {
-		for _, pt := range pipelineCoordinator.List() {
-			if params.Type != nil && pt.Type != string(*params.Type) {
-				continue
-			}
-			if params.CollectionId != nil && pt.CollectionId != string(*params.CollectionId) {
-				continue
-			}
-			done, total := pt.Progress()
-			pending := total - done
-			if pending < 0 {
-				pending = 0
-			}
-			tasks = append(tasks, &Task{
-				Id:           pt.Id,
-				Type:         pt.Type,
-				Name:         pt.Name,
-				CollectionId: pt.CollectionId,
-				Done:         done,
-				Pending:      pending,
-				enqueuedAt:   pt.EnqueuedAt,
-			})
+	var ids []string
+	if c.ExpandSubdirs {
+		for _, child := range c.Expand() {
+			ids = append(ids, child.Id)
 		}
+	} else {
+		valid := c
+		valid.Dirs = slices.Clone(c.Dirs)
+		valid.MakeValid()
+		ids = append(ids, valid.Id)
+	}
+	if len(ids) == 0 {
+		problem(w, r, http.StatusBadRequest, "No subdirs to expand")
+		return
 	}
 
-	sort.Slice(tasks, func(i, j int) bool {
-		a := tasks[i]
-		b := tasks[j]
-		ap, bp := a.Pending > 0, b.Pending > 0
-		if ap != bp {
-			return ap
-		}
-		ao, bo := taskDisplayOrder(a.Type), taskDisplayOrder(b.Type)
-		if ao != bo {
-			return ao < bo
-		}
-		if !a.enqueuedAt.Equal(b.enqueuedAt) {
-			return a.enqueuedAt.After(b.enqueuedAt)
+	managedMutex.Lock()
+	defer managedMutex.Unlock()
+
+	for _, id := range ids {
+		if getCollectionById(id) != nil {
+			problem(w, r, http.StatusConflict, fmt.Sprintf("Collection %s already exists", id))
+			return
 		}
-		return a.Id < b.Id
+	}
+
+	dataDir := imageSource.DataDir
+	managed, err := loadManagedCollections(dataDir)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	managed.Collections = append(managed.Collections, c)
+	managed.Deleted = slices.DeleteFunc(managed.Deleted, func(id string) bool {
+		return slices.Contains(ids, id)
 	})
+	if err := saveManagedCollections(dataDir, managed); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	reloadCollections()
 
-	respond(w, r, http.StatusOK, struct {
-		Items []*Task `json:"items"`
+	items := make([]collection.Collection, 0, len(ids))
+	for _, id := range ids {
+		c := getCollectionById(id)
+		if c == nil {
+			continue
+		}
+		c.UpdateIndexedAt(imageSource)
+		items = append(items, *c)
+	}
+	respond(w, r, http.StatusCreated, struct {
+		Items []collection.Collection `json:"items"`
 	}{
-		Items: tasks,
+		Items: items,
 	})
 }
 
-func (*Api) PostTasks(w http.ResponseWriter, r *http.Request) {
-	data := &openapi.PostTasksJSONBody{}
+func (*Api) PatchCollectionsId(w http.ResponseWriter, r *http.Request, id openapi.CollectionId) {
+	data := &openapi.CollectionPatch{}
 	if err := chirender.Decode(r, data); err != nil {
 		problem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	collection := getCollectionById(string(data.CollectionId))
-	if collection == nil {
-		problem(w, r, http.StatusBadRequest, "Collection not found")
+	managedMutex.Lock()
+	defer managedMutex.Unlock()
+
+	current := getCollectionById(string(id))
+	if current == nil {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if !isConfiguredCollection(string(id)) {
+		problem(w, r, http.StatusBadRequest, "Saved search collections are updated through their saved search")
 		return
 	}
 
-	invalidateWhenCompleted := func(t *inttask.Task) {
-		go func() {
-			<-t.Completed()
-			collection.Invalidate()
-		}()
+	dataDir := imageSource.DataDir
+	managed, err := loadManagedCollections(dataDir)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	switch data.Type {
+	index := managedCollectionIndex(managed, string(id))
+	var c collection.Collection
+	if index >= 0 {
+		c = managed.Collections[index]
+	} else {
+		// Override the configured or expanded collection with a managed copy
+		c = *current
+		c.Dirs = slices.Clone(current.Dirs)
+		c.ExpandSubdirs = false
+		c.ExpandSort = ""
+		c.IndexedAt = nil
+		c.IndexedCount = 0
+		c.InvalidatedAt = nil
+	}
+	if err := patchCollection(&c, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if index >= 0 {
+		managed.Collections[index] = c
+	} else {
+		managed.Collections = append(managed.Collections, c)
+	}
+	if err := saveManagedCollections(dataDir, managed); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	reloadCollections()
 
-	case openapi.TaskTypeINDEXFILES:
-		pt, isNew := pipelineCoordinator.AddFiles(
-			string(data.CollectionId), collection.Name,
-			collection.Dirs, collection.IndexLimit,
-		)
-		invalidateWhenCompleted(pt)
-		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXFILES), string(data.CollectionId))
-		if isNew {
-			respond(w, r, http.StatusAccepted, taskItems(t))
-		} else {
-			respond(w, r, http.StatusConflict, taskItems(t))
-		}
+	updated := getCollectionById(string(id))
+	if updated == nil {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
+	}
+	updated.UpdateIndexedAt(imageSource)
+	updated.UpdateIndexedCount(imageSource)
+	respond(w, r, http.StatusOK, updated)
+}
 
-	case openapi.TaskTypeINDEXMETADATA:
-		force := data.Force != nil && *data.Force
-		pt, isNew := pipelineCoordinator.AddMetadata(
-			string(data.CollectionId), collection.Name,
-			collection.Dirs, collection.IndexLimit, force,
-		)
-		invalidateWhenCompleted(pt)
-		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXMETADATA), string(data.CollectionId))
-		if isNew {
-			respond(w, r, http.StatusAccepted, taskItems(t))
-		} else {
-			respond(w, r, http.StatusConflict, taskItems(t))
-		}
+func (*Api) DeleteCollectionsId(w http.ResponseWriter, r *http.Request, id openapi.CollectionId) {
+	managedMutex.Lock()
+	defer managedMutex.Unlock()
 
-	case openapi.TaskTypeINDEXCONTENTS:
-		force := data.Force != nil && *data.Force
-		pt, isNew := pipelineCoordinator.AddContents(
-			string(data.CollectionId), collection.Name,
-			collection.Dirs, collection.IndexLimit, force,
-		)
-		invalidateWhenCompleted(pt)
-		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXCONTENTS), string(data.CollectionId))
-		if isNew {
-			respond(w, r, http.StatusAccepted, taskItems(t))
-		} else {
-			respond(w, r, http.StatusConflict, taskItems(t))
+	if getCollectionById(string(id)) == nil {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if !isConfiguredCollection(string(id)) {
+		problem(w, r, http.StatusBadRequest, "Saved search collections are deleted through their saved search")
+		return
+	}
+
+	dataDir := imageSource.DataDir
+	managed, err := loadManagedCollections(dataDir)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := managedCollectionIndex(managed, string(id)); i >= 0; i = managedCollectionIndex(managed, string(id)) {
+		managed.Collections = slices.Delete(managed.Collections, i, i+1)
+	}
+	if err := saveManagedCollections(dataDir, managed); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Configured and expanded collections are hidden instead
+	appConfig, err := loadConfig(dataDir)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if slices.ContainsFunc(appConfig.Collections, func(c collection.Collection) bool {
+		return c.Id == string(id)
+	}) {
+		managed.Deleted = append(managed.Deleted, string(id))
+		if err := saveManagedCollections(dataDir, managed); err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
 		}
+	}
+	reloadCollections()
 
-	case openapi.TaskTypeINDEXFACES:
-		force := data.Force != nil && *data.Force
-		pt, isNew := pipelineCoordinator.AddFaces(
-			string(data.CollectionId), collection.Name,
-			collection.Dirs, collection.IndexLimit, force,
-		)
-		invalidateWhenCompleted(pt)
-		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXFACES), string(data.CollectionId))
-		if isNew {
-			respond(w, r, http.StatusAccepted, taskItems(t))
-		} else {
-			respond(w, r, http.StatusConflict, taskItems(t))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// savedSearchFromPost validates the posted saved search, which can only
+// search the collections the user of the request is allowed to access.
+func savedSearchFromPost(r *http.Request, data *openapi.SavedSearchPost) (image.SavedSearch, error) {
+	s := image.SavedSearch{
+		Name:   strings.TrimSpace(data.Name),
+		Search: strings.TrimSpace(string(data.Search)),
+	}
+	if s.Name == "" {
+		return s, errors.New("name required")
+	}
+	if s.Search == "" {
+		return s, errors.New("search required")
+	}
+	q, err := search.Parse(s.Search)
+	if err != nil {
+		return s, fmt.Errorf("invalid search: %w", err)
+	}
+	if _, err := q.Expression(); err != nil {
+		return s, fmt.Errorf("invalid search: %w", err)
+	}
+	if data.CollectionId != nil && *data.CollectionId != "" {
+		s.CollectionId = *data.CollectionId
+		found := false
+		for i := range collections {
+			if collections[i].Id == s.CollectionId && canAccessCollection(r, &collections[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return s, errors.New("collection not found")
+		}
+	}
+	if !canAccessSavedSearch(r, s) {
+		return s, errors.New("collection_id required to search only allowed collections")
+	}
+	return s, nil
+}
+
+func (*Api) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	items := make([]image.SavedSearch, 0)
+	for _, s := range imageSource.ListSavedSearches() {
+		if canAccessSavedSearch(r, s) {
+			items = append(items, s)
+		}
+	}
+	respond(w, r, http.StatusOK, struct {
+		Items []image.SavedSearch `json:"items"`
+	}{
+		Items: items,
+	})
+}
+
+func (*Api) PostSavedSearches(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.SavedSearchPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s, err := savedSearchFromPost(r, data)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s, err = imageSource.AddSavedSearch(s)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusCreated, s)
+}
+
+func (*Api) GetSavedSearchesId(w http.ResponseWriter, r *http.Request, id openapi.SavedSearchIdPathParam) {
+	s, ok := imageSource.GetSavedSearch(int64(id))
+	if !ok || !canAccessSavedSearch(r, s) {
+		problem(w, r, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	respond(w, r, http.StatusOK, s)
+}
+
+func (*Api) PutSavedSearchesId(w http.ResponseWriter, r *http.Request, id openapi.SavedSearchIdPathParam) {
+	data := &openapi.SavedSearchPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if current, ok := imageSource.GetSavedSearch(int64(id)); !ok || !canAccessSavedSearch(r, current) {
+		problem(w, r, http.StatusNotFound, "Saved search not found")
+		return
+	}
+
+	s, err := savedSearchFromPost(r, data)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Id = int64(id)
+
+	s, err = imageSource.UpdateSavedSearch(s)
+	if errors.Is(err, image.ErrNotFound) {
+		problem(w, r, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, s)
+}
+
+func (*Api) DeleteSavedSearchesId(w http.ResponseWriter, r *http.Request, id openapi.SavedSearchIdPathParam) {
+	if s, ok := imageSource.GetSavedSearch(int64(id)); !ok || !canAccessSavedSearch(r, s) {
+		problem(w, r, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	err := imageSource.DeleteSavedSearch(int64(id))
+	if errors.Is(err, image.ErrNotFound) {
+		problem(w, r, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) GetPersons(w http.ResponseWriter, r *http.Request, params openapi.GetPersonsParams) {
+	hidden := params.Hidden != nil && *params.Hidden
+	persons := make([]image.Person, 0)
+	for _, p := range imageSource.ListPersons(hidden) {
+		if p, ok := allowedPerson(r, p); ok {
+			persons = append(persons, p)
+		}
+	}
+	respond(w, r, http.StatusOK, struct {
+		Items []image.Person `json:"items"`
+	}{
+		Items: persons,
+	})
+}
+
+func (*Api) GetPersonsId(w http.ResponseWriter, r *http.Request, id openapi.PersonIdPathParam) {
+	p, ok := imageSource.GetPerson(int64(id))
+	if ok {
+		p, ok = allowedPerson(r, p)
+	}
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	respond(w, r, http.StatusOK, p)
+}
+
+func (*Api) PatchPersonsId(w http.ResponseWriter, r *http.Request, id openapi.PersonIdPathParam) {
+	data := &openapi.PersonPatch{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p, ok := imageSource.GetPerson(int64(id))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	if data.Name != nil {
+		p.Name = strings.TrimSpace(*data.Name)
+	}
+	if data.Hidden != nil {
+		p.Hidden = *data.Hidden
+	}
+
+	p, err := imageSource.UpdatePerson(p)
+	if errors.Is(err, image.ErrNotFound) {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, p)
+}
+
+func (*Api) GetPersonsIdFaces(w http.ResponseWriter, r *http.Request, id openapi.PersonIdPathParam) {
+	if _, ok := imageSource.GetPerson(int64(id)); !ok {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	faces := imageSource.ListPersonFaces(int64(id))
+	allowed := allowedFaces(r, faces)
+	if len(allowed) == 0 && len(faces) > 0 {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	respond(w, r, http.StatusOK, struct {
+		Items []image.FaceInfo `json:"items"`
+	}{
+		Items: allowed,
+	})
+}
+
+func (*Api) PostPersonsIdMerge(w http.ResponseWriter, r *http.Request, id openapi.PersonIdPathParam) {
+	data := &openapi.PostPersonsIdMergeJSONBody{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(data.Ids) == 0 {
+		problem(w, r, http.StatusBadRequest, "ids required")
+		return
+	}
+
+	others := make([]int64, len(data.Ids))
+	for i, other := range data.Ids {
+		others[i] = int64(other)
+	}
+	p, err := imageSource.MergePersons(int64(id), others)
+	if errors.Is(err, image.ErrNotFound) {
+		problem(w, r, http.StatusNotFound, "Person not found")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, p)
+}
+
+func (*Api) PostPersonsIdSplit(w http.ResponseWriter, r *http.Request, id openapi.PersonIdPathParam) {
+	data := &openapi.PostPersonsIdSplitJSONBody{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(data.FaceIds) == 0 {
+		problem(w, r, http.StatusBadRequest, "face_ids required")
+		return
+	}
+
+	faceIds := make([]int64, len(data.FaceIds))
+	for i, faceId := range data.FaceIds {
+		faceIds[i] = int64(faceId)
+	}
+	p, err := imageSource.SplitPerson(int64(id), faceIds)
+	if errors.Is(err, image.ErrNotFound) {
+		problem(w, r, http.StatusNotFound, "Person or faces not found")
+		return
+	}
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusCreated, p)
+}
+
+func taskDisplayOrder(taskType string) int {
+	switch taskType {
+	case string(openapi.TaskTypeINDEXMETADATA):
+		return 0
+	case string(openapi.TaskTypeINDEXCONTENTS):
+		return 1
+	case string(openapi.TaskTypeINDEXFACES):
+		return 2
+	case string(openapi.TaskTypeINDEXDUPLICATES):
+		return 3
+	case string(openapi.TaskTypeINDEXPERSONS):
+		return 4
+	case string(openapi.TaskTypeINDEXAUTOTAG):
+		return 5
+	default:
+		return 6
+	}
+}
+
+// pipelineTaskResponse converts a pipeline task to an API Task for HTTP responses.
+func pipelineTaskResponse(pt *inttask.Task, taskType, collectionId string) *Task {
+	return &Task{
+		Id:           pt.Id,
+		Type:         taskType,
+		Name:         pt.Name,
+		CollectionId: collectionId,
+		enqueuedAt:   pt.EnqueuedAt,
+	}
+}
+
+// taskItems wraps tasks in the standard items response envelope.
+func taskItems(tasks ...*Task) struct {
+	Items []*Task `json:"items"`
+} {
+	return struct {
+		Items []*Task `json:"items"`
+	}{Items: tasks}
+}
+
+// finishedTasks lists the persisted runs of finished tasks.
+func finishedTasks(params openapi.GetTasksParams) []*Task {
+	options := image.TaskRunListOptions{
+		Finished: true,
+		Limit:    100,
+	}
+	if params.Type != nil {
+		options.Type = string(*params.Type)
+	}
+	if params.CollectionId != nil {
+		options.CollectionId = string(*params.CollectionId)
+	}
+	if params.Limit != nil {
+		options.Limit = int(*params.Limit)
+	}
+	runs := imageSource.ListTaskRuns(options)
+	tasks := make([]*Task, 0, len(runs))
+	for _, run := range runs {
+		startedAt, finishedAt := run.StartedAt, run.FinishedAt
+		tasks = append(tasks, &Task{
+			Id:           run.TaskId,
+			Type:         run.Type,
+			Name:         run.Name,
+			CollectionId: run.CollectionId,
+			Done:         run.Done,
+			Total:        run.Total,
+			RunId:        run.Id,
+			StartedAt:    &startedAt,
+			FinishedAt:   &finishedAt,
+			Error:        run.Error,
+			ErrorCount:   run.ErrorCount,
+		})
+	}
+	return tasks
+}
+
+// pipelineTaskItem returns the current progress of the pipeline task.
+func pipelineTaskItem(pt *inttask.Task) *Task {
+	done, total := pt.Progress()
+	pending := total - done
+	if pending < 0 {
+		pending = 0
+	}
+	return &Task{
+		Id:           pt.Id,
+		Type:         pt.Type,
+		Name:         pt.Name,
+		CollectionId: pt.CollectionId,
+		Done:         done,
+		Pending:      pending,
+		Total:        total,
+		RunId:        pt.RunId(),
+		enqueuedAt:   pt.EnqueuedAt,
+	}
+}
+
+func (*Api) GetTasks(w http.ResponseWriter, r *http.Request, params openapi.GetTasksParams) {
+
+	if params.State != nil && *params.State == openapi.TaskStateFinished {
+		tasks := make([]*Task, 0)
+		for _, t := range finishedTasks(params) {
+			if canAccessCollectionId(r, t.CollectionId) {
+				tasks = append(tasks, t)
+			}
+		}
+		respond(w, r, http.StatusOK, taskItems(tasks...))
+		return
+	}
+
+	tasks := make([]*Task, 0)
+
+	if pipelineCoordinator != nil {
+		for _, pt := range pipelineCoordinator.List() {
+			if params.Type != nil && pt.Type != string(*params.Type) {
+				continue
+			}
+			if params.CollectionId != nil && pt.CollectionId != string(*params.CollectionId) {
+				continue
+			}
+			if !canAccessCollectionId(r, pt.CollectionId) {
+				continue
+			}
+			tasks = append(tasks, pipelineTaskItem(pt))
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a := tasks[i]
+		b := tasks[j]
+		ap, bp := a.Pending > 0, b.Pending > 0
+		if ap != bp {
+			return ap
+		}
+		ao, bo := taskDisplayOrder(a.Type), taskDisplayOrder(b.Type)
+		if ao != bo {
+			return ao < bo
+		}
+		if !a.enqueuedAt.Equal(b.enqueuedAt) {
+			return a.enqueuedAt.After(b.enqueuedAt)
+		}
+		return a.Id < b.Id
+	})
+
+	respond(w, r, http.StatusOK, struct {
+		Items []*Task `json:"items"`
+	}{
+		Items: tasks,
+	})
+}
+
+func (*Api) PostTasks(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.PostTasksJSONBody{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection := getCollectionById(string(data.CollectionId))
+	if collection == nil {
+		problem(w, r, http.StatusBadRequest, "Collection not found")
+		return
+	}
+
+	invalidateWhenCompleted := func(t *inttask.Task) {
+		go func() {
+			<-t.Completed()
+			invalidateCollection(collection)
+		}()
+	}
+
+	switch data.Type {
+
+	case openapi.TaskTypeINDEXFILES:
+		pt, isNew := pipelineCoordinator.AddFiles(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs, collection.IndexLimit,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXFILES), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXMETADATA:
+		force := data.Force != nil && *data.Force
+		pt, isNew := pipelineCoordinator.AddMetadata(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs, collection.IndexLimit, force,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXMETADATA), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXCONTENTS:
+		force := data.Force != nil && *data.Force
+		pt, isNew := pipelineCoordinator.AddContents(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs, collection.IndexLimit, force,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXCONTENTS), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXFACES:
+		force := data.Force != nil && *data.Force
+		pt, isNew := pipelineCoordinator.AddFaces(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs, collection.IndexLimit, force,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXFACES), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXDUPLICATES:
+		pt, isNew := pipelineCoordinator.AddDuplicates(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXDUPLICATES), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXPERSONS:
+		pt, isNew := pipelineCoordinator.AddPersons(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXPERSONS), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
+		}
+
+	case openapi.TaskTypeINDEXAUTOTAG:
+		pt, isNew := pipelineCoordinator.AddAutotag(
+			string(data.CollectionId), collection.Name,
+			collection.Dirs,
+		)
+		invalidateWhenCompleted(pt)
+		t := pipelineTaskResponse(pt, string(openapi.TaskTypeINDEXAUTOTAG), string(data.CollectionId))
+		if isNew {
+			respond(w, r, http.StatusAccepted, taskItems(t))
+		} else {
+			respond(w, r, http.StatusConflict, taskItems(t))
 		}
 
 	case openapi.TaskTypeINDEXALL:
@@ -703,6 +1385,38 @@ func (*Api) PostTasks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (*Api) GetTasksIdErrors(w http.ResponseWriter, r *http.Request, id openapi.TaskIdPathParam, params openapi.GetTasksIdErrorsParams) {
+	var runId int64
+	if params.RunId != nil {
+		runId = int64(*params.RunId)
+	} else if pipelineCoordinator != nil {
+		if pt, ok := pipelineCoordinator.Get(string(id)); ok {
+			runId = pt.RunId()
+		}
+	}
+	if runId == 0 {
+		// Most recent run of the task
+		runs := imageSource.ListTaskRuns(image.TaskRunListOptions{
+			TaskId: string(id),
+			Limit:  1,
+		})
+		if len(runs) > 0 {
+			runId = runs[0].Id
+		}
+	}
+	run, ok := imageSource.GetTaskRun(runId)
+	if !ok || run.TaskId != string(id) {
+		problem(w, r, http.StatusNotFound, "Task not found")
+		return
+	}
+
+	respond(w, r, http.StatusOK, struct {
+		Items []image.TaskError `json:"items"`
+	}{
+		Items: imageSource.ListTaskErrors(runId),
+	})
+}
+
 func (*Api) DeleteTasksId(w http.ResponseWriter, r *http.Request, id openapi.TaskIdPathParam) {
 	if pipelineCoordinator == nil {
 		problem(w, r, http.StatusNotFound, "Task not found")
@@ -715,19 +1429,432 @@ func (*Api) DeleteTasksId(w http.ResponseWriter, r *http.Request, id openapi.Tas
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (*Api) GetCapabilities(w http.ResponseWriter, r *http.Request) {
-	docsurl := os.Getenv("PHOTOFIELD_DOCS_URL")
-	if docsurl == "" {
-		docsurl = "/docs/usage"
+func (*Api) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	docsurl := os.Getenv("PHOTOFIELD_DOCS_URL")
+	if docsurl == "" {
+		docsurl = "/docs/usage"
+	}
+	capabilities := openapi.Capabilities{}
+	if imageSource != nil {
+		capabilities.Search.Supported = true
+	}
+	capabilities.Tags.Supported = tagsEnabled
+	capabilities.Auth.Supported = authConfig.Enable
+	capabilities.Docs.Supported = docsurl != ""
+	capabilities.Docs.Url = docsurl
+	respond(w, r, http.StatusOK, capabilities)
+}
+
+func (*Api) PostAuthLogin(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.Login{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !allowPasswordAttempt(w, r) {
+		return
+	}
+	user, hash, ok := imageSource.GetUserByName(data.Name)
+	if !ok || !auth.CheckPassword(hash, data.Password) {
+		passwordLimiter.Fail(clientAddr(r))
+		problem(w, r, http.StatusUnauthorized, "Invalid name or password")
+		return
+	}
+	passwordLimiter.Reset(clientAddr(r))
+
+	token, err := auth.NewToken()
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	expires := time.Now().Add(authConfig.SessionDuration())
+	if err := imageSource.AddUserSession(user.Id, auth.HashToken(token), expires); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	setSessionCookie(w, r, token, expires)
+	respond(w, r, http.StatusOK, user)
+}
+
+func (*Api) PostAuthLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SESSION_COOKIE); err == nil && cookie.Value != "" {
+		if err := imageSource.DeleteUserSession(auth.HashToken(cookie.Value)); err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	setSessionCookie(w, r, "", time.Time{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) GetAuthMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusUnauthorized, "Login required")
+		return
+	}
+	respond(w, r, http.StatusOK, user)
+}
+
+func (*Api) GetUsers(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, struct {
+		Items []auth.User `json:"items"`
+	}{
+		Items: imageSource.ListUsers(),
+	})
+}
+
+func (*Api) PostUsers(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.UserPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := auth.ValidName(data.Name); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	role := auth.Role(data.Role)
+	if !role.Valid() {
+		problem(w, r, http.StatusBadRequest, "Invalid role")
+		return
+	}
+	hash, err := auth.HashPassword(data.Password)
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := imageSource.AddUser(data.Name, hash, role)
+	if err == image.ErrExists {
+		problem(w, r, http.StatusConflict, "User already exists")
+		return
+	} else if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respond(w, r, http.StatusCreated, user)
+}
+
+func (*Api) PatchUsersId(w http.ResponseWriter, r *http.Request, id openapi.UserIdPathParam) {
+	data := &openapi.UserPatch{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := imageSource.GetUser(int64(id))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	var hash string
+	if data.Password != nil {
+		var err error
+		hash, err = auth.HashPassword(*data.Password)
+		if err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if data.Role != nil {
+		role := auth.Role(*data.Role)
+		if !role.Valid() {
+			problem(w, r, http.StatusBadRequest, "Invalid role")
+			return
+		}
+		if current, _ := auth.UserFromContext(r.Context()); current.Id == user.Id && role != auth.Admin {
+			problem(w, r, http.StatusBadRequest, "Admins cannot demote themselves")
+			return
+		}
+		if err := imageSource.SetUserRole(user.Id, role); err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		user.Role = role
+	}
+	if hash != "" {
+		if err := imageSource.SetUserPassword(user.Id, hash); err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	respond(w, r, http.StatusOK, user)
+}
+
+func (*Api) DeleteUsersId(w http.ResponseWriter, r *http.Request, id openapi.UserIdPathParam) {
+	if current, _ := auth.UserFromContext(r.Context()); current.Id == int64(id) {
+		problem(w, r, http.StatusBadRequest, "Users cannot delete themselves")
+		return
+	}
+	err := imageSource.DeleteUser(int64(id))
+	if err == image.ErrNotFound {
+		problem(w, r, http.StatusNotFound, "User not found")
+		return
+	} else if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) GetTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusUnauthorized, "Login required")
+		return
+	}
+	respond(w, r, http.StatusOK, struct {
+		Items []auth.Token `json:"items"`
+	}{
+		Items: imageSource.ListUserTokens(user.Id),
+	})
+}
+
+func (*Api) PostTokens(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.ApiTokenPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusUnauthorized, "Login required")
+		return
+	}
+	name := strings.TrimSpace(data.Name)
+	if name == "" {
+		problem(w, r, http.StatusBadRequest, "name required")
+		return
+	}
+
+	secret, err := auth.NewToken()
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	token, err := imageSource.AddUserToken(user.Id, name, auth.HashToken(secret))
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respond(w, r, http.StatusCreated, openapi.ApiTokenCreated{
+		ApiToken: openapi.ApiToken{
+			Id:        openapi.ApiTokenId(token.Id),
+			Name:      token.Name,
+			CreatedAt: &token.CreatedAt,
+		},
+		Token: secret,
+	})
+}
+
+func (*Api) DeleteTokensId(w http.ResponseWriter, r *http.Request, id openapi.ApiTokenIdPathParam) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusUnauthorized, "Login required")
+		return
+	}
+	err := imageSource.DeleteUserToken(user.Id, int64(id))
+	if err == image.ErrNotFound {
+		problem(w, r, http.StatusNotFound, "API token not found")
+		return
+	} else if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) GetShares(w http.ResponseWriter, r *http.Request) {
+	shares := make([]auth.Share, 0)
+	for _, share := range imageSource.ListShares() {
+		if canAccessCollectionId(r, share.CollectionId) {
+			shares = append(shares, share)
+		}
+	}
+	respond(w, r, http.StatusOK, struct {
+		Items []auth.Share `json:"items"`
+	}{
+		Items: shares,
+	})
+}
+
+func (*Api) PostShares(w http.ResponseWriter, r *http.Request) {
+	data := &openapi.SharePost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection := getCollectionById(string(data.CollectionId))
+	if collection == nil || !canAccessCollection(r, collection) {
+		problem(w, r, http.StatusBadRequest, "Collection not found")
+		return
+	}
+
+	share := auth.Share{
+		CollectionId: collection.Id,
+		ExpiresAt:    time.Now().Add(30 * 24 * time.Hour),
+	}
+	if data.Search != nil {
+		share.Search = strings.TrimSpace(string(*data.Search))
+	}
+	if data.TagId != nil {
+		share.TagId = string(*data.TagId)
+	}
+	if share.Search != "" && share.TagId != "" {
+		problem(w, r, http.StatusBadRequest, "Either search or tag_id can be shared, not both")
+		return
+	}
+	if share.Search != "" {
+		if _, err := search.Parse(share.Search); err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if share.TagId != "" {
+		// Only selections, as other tags can change after sharing
+		if !strings.HasPrefix(share.TagId, "sys:select:col:"+collection.Id+":") {
+			problem(w, r, http.StatusBadRequest, "tag_id needs to be a selection of the collection")
+			return
+		}
+		if _, ok := imageSource.GetTagByName(share.TagId); !ok {
+			problem(w, r, http.StatusBadRequest, "Tag not found")
+			return
+		}
+	}
+	if data.ExpiresAt != nil {
+		if !data.ExpiresAt.After(time.Now()) {
+			problem(w, r, http.StatusBadRequest, "expires_at needs to be in the future")
+			return
+		}
+		share.ExpiresAt = *data.ExpiresAt
+	}
+	if data.Download != nil {
+		share.Download = *data.Download
+	}
+	if data.Password != nil && *data.Password != "" {
+		hash, err := auth.HashPassword(*data.Password)
+		if err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		share.PasswordHash = hash
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	share, err = imageSource.AddShare(share, auth.HashToken(token))
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respond(w, r, http.StatusCreated, struct {
+		auth.Share
+		Token string `json:"token"`
+	}{
+		Share: share,
+		Token: token,
+	})
+}
+
+func (*Api) DeleteSharesId(w http.ResponseWriter, r *http.Request, id openapi.ShareIdPathParam) {
+	share, ok := imageSource.GetShareById(int64(id))
+	if !ok || !canAccessCollectionId(r, share.CollectionId) {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+	err := imageSource.DeleteShare(int64(id))
+	if err == image.ErrNotFound {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	} else if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) GetShare(w http.ResponseWriter, r *http.Request) {
+	share, ok := auth.ShareFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+	respond(w, r, http.StatusOK, share)
+}
+
+func (*Api) PostShareUnlock(w http.ResponseWriter, r *http.Request) {
+	share, ok := auth.ShareFromContext(r.Context())
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+	data := &openapi.ShareUnlock{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if share.PasswordHash == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !allowPasswordAttempt(w, r) {
+		return
+	}
+	if !auth.CheckPassword(share.PasswordHash, data.Password) {
+		passwordLimiter.Fail(clientAddr(r))
+		problem(w, r, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	passwordLimiter.Reset(clientAddr(r))
+	http.SetCookie(w, &http.Cookie{
+		Name:     shareCookieName(share),
+		Value:    share.UnlockProof(shareToken(r)),
+		Path:     "/",
+		Expires:  share.ExpiresAt,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (*Api) PostAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if imageSource == nil {
+		problem(w, r, http.StatusInternalServerError, "Database not available")
+		return
 	}
-	capabilities := openapi.Capabilities{}
-	if imageSource != nil {
-		capabilities.Search.Supported = true
+
+	now := time.Now()
+	dir := filepath.Join(imageSource.DataDir, "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
 	}
-	capabilities.Tags.Supported = tagsEnabled
-	capabilities.Docs.Supported = docsurl != ""
-	capabilities.Docs.Url = docsurl
-	respond(w, r, http.StatusOK, capabilities)
+	path := filepath.Join(dir, fmt.Sprintf("photofield.cache.%s.db", now.Format("20060102-150405")))
+	if err := imageSource.Backup(path); err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		problem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusCreated, openapi.Backup{
+		Path:      path,
+		Size:      int(stat.Size()),
+		CreatedAt: now,
+	})
 }
 
 func (*Api) GetScenesSceneIdTiles(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdTilesParams) {
@@ -787,7 +1914,7 @@ func GetScenesSceneIdTilesImpl(w http.ResponseWriter, r *http.Request, sceneId o
 	ctx, task := trace.NewTask(r.Context(), "GetScenesSceneIdTilesImpl")
 	defer task.End()
 
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -818,6 +1945,8 @@ func GetScenesSceneIdTilesImpl(w http.ResponseWriter, r *http.Request, sceneId o
 		}
 	}
 
+	var selectTag string
+	var selectTagVersion int64
 	if params.SelectTag != nil {
 		t, ok := imageSource.GetTagByName(string(*params.SelectTag))
 		if !ok {
@@ -825,6 +1954,9 @@ func GetScenesSceneIdTilesImpl(w http.ResponseWriter, r *http.Request, sceneId o
 			return
 		}
 		rn.Selected = imageSource.GetTagImageIds(t.Id)
+		selectTag = t.Name
+		// Each update of a tag is a new version with a new id
+		selectTagVersion = int64(t.Id)
 	}
 
 	if params.DebugOverdraw != nil {
@@ -906,24 +2038,6 @@ func GetScenesSceneIdTilesImpl(w http.ResponseWriter, r *http.Request, sceneId o
 		encoder.Mem = codec.ImageMemPaletted
 	}
 
-	img, context := getPoolImage(&rn)
-	defer putPoolImage(&rn, img)
-
-	rn.CanvasImage = img
-	rn.Zoom = zoom
-	trace.WithRegion(ctx, "drawTile", func() {
-		drawTile(ctx, context, &rn, scene, zoom, x, y)
-	})
-
-	if incomplete {
-		w.Header().Add("Cache-Control", "no-cache")
-	} else {
-		w.Header().Add("Cache-Control", "max-age=86400") // 1 day
-	}
-
-	w.Header().Add("Content-Type", encoder.ContentType)
-	w.Header().Add("Vary", "Accept")
-
 	quality := mr.QualityParam()
 	if quality == 0 {
 		switch mr.Subtype {
@@ -937,11 +2051,69 @@ func GetScenesSceneIdTilesImpl(w http.ResponseWriter, r *http.Request, sceneId o
 		quality = 100
 	}
 
-	err = encoder.Func(w, img, quality)
+	if incomplete {
+		w.Header().Add("Cache-Control", "no-cache")
+	} else {
+		w.Header().Add("Cache-Control", "max-age=86400") // 1 day
+	}
+	w.Header().Add("Vary", "Accept")
+
+	key, cacheable := getTileKey(scene, &rn, params, selectTag, selectTagVersion, encoder, quality)
+	if cacheable {
+		etag := tileETag(key)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Add("Content-Type", encoder.ContentType)
+
+	cache := tileCache.Load()
+	if cacheable && cache != nil {
+		if b, ok := cache.Get(key); ok {
+			w.Write(b)
+			return
+		}
+	}
+
+	img, context := getPoolImage(&rn)
+	defer putPoolImage(&rn, img)
+
+	rn.CanvasImage = img
+	rn.Zoom = zoom
+	complete := false
+	trace.WithRegion(ctx, "drawTile", func() {
+		complete = drawTile(ctx, context, &rn, scene, zoom, x, y)
+	})
+
+	if !complete {
+		// Placeholders are drawn again once the thumbnails are available
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Del("ETag")
+	}
+
+	if !cacheable || cache == nil || !complete {
+		err = encoder.Func(w, img, quality)
+		if err != nil {
+			log.Printf("Error encoding image as %s: %v", mr.String(), err)
+			problem(w, r, http.StatusInternalServerError, "Error encoding image")
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	err = encoder.Func(&buf, img, quality)
 	if err != nil {
 		log.Printf("Error encoding image as %s: %v", mr.String(), err)
 		problem(w, r, http.StatusInternalServerError, "Error encoding image")
+		return
+	}
+	if err := cache.Put(key, buf.Bytes()); err != nil {
+		log.Printf("tile cache unable to store tile: %v", err)
 	}
+	w.Write(buf.Bytes())
 }
 
 func (*Api) GetScenesSceneIdFeatures(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdFeaturesParams) {
@@ -966,7 +2138,7 @@ func (*Api) GetScenesSceneIdFeatures(w http.ResponseWriter, r *http.Request, sce
 }
 
 func GetScenesSceneIdFeaturesImpl(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdFeaturesParams) {
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -1009,7 +2181,7 @@ func GetScenesSceneIdFeaturesImpl(w http.ResponseWriter, r *http.Request, sceneI
 }
 
 func (*Api) GetScenesSceneIdDates(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdDatesParams) {
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -1036,7 +2208,7 @@ func (*Api) GetScenesSceneIdDates(w http.ResponseWriter, r *http.Request, sceneI
 
 func (*Api) GetScenesSceneIdRegions(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdRegionsParams) {
 
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -1190,7 +2362,7 @@ func getRegionsFromBounds(scene *render.Scene, bounds render.Rect, limit int, mi
 
 func (*Api) GetScenesSceneIdRegionsId(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, id openapi.RegionId) {
 
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -1207,7 +2379,7 @@ func (*Api) GetScenesSceneIdRegionsId(w http.ResponseWriter, r *http.Request, sc
 
 func (*Api) GetScenesSceneIdSearchQueries(w http.ResponseWriter, r *http.Request, sceneId openapi.SceneId, params openapi.GetScenesSceneIdSearchQueriesParams) {
 
-	scene := sceneSource.GetSceneById(string(sceneId), imageSource)
+	scene := getAllowedSceneById(r, sceneId)
 	if scene == nil {
 		problem(w, r, http.StatusBadRequest, "Scene not found")
 		return
@@ -1222,12 +2394,19 @@ func (*Api) GetScenesSceneIdSearchQueries(w http.ResponseWriter, r *http.Request
 	tokens := q.Tokens()
 	apiTokens := make([]openapi.SearchToken, 0, len(tokens))
 	for _, t := range tokens {
-		apiTokens = append(apiTokens, openapi.SearchToken{
+		token := openapi.SearchToken{
 			Type:  t.Type,
 			Value: t.Value,
 			Start: t.Start,
 			End:   t.End,
-		})
+		}
+		if t.Not {
+			token.Not = &t.Not
+		}
+		if t.Depth > 0 {
+			token.Depth = &t.Depth
+		}
+		apiTokens = append(apiTokens, token)
 	}
 
 	// Ignore error as it's an aggregation of the `errors` field
@@ -1236,6 +2415,9 @@ func (*Api) GetScenesSceneIdSearchQueries(w http.ResponseWriter, r *http.Request
 	if !expression.Created.IsZero() {
 		expr["created"] = expression.Created
 	}
+	if expression.Condition != nil {
+		expr["condition"] = expression.Condition.String()
+	}
 	if expression.Errors != nil {
 		expr["errors"] = expression.Errors
 	}
@@ -1262,9 +2444,25 @@ func (*Api) GetTags(w http.ResponseWriter, r *http.Request, params openapi.GetTa
 		q = string(*params.Q)
 	}
 
+	var parentId tag.Id
+	if params.Parent != nil && *params.Parent != "" {
+		parent, ok := imageSource.GetTagByName(string(*params.Parent))
+		if !ok {
+			problem(w, r, http.StatusNotFound, "Parent tag not found")
+			return
+		}
+		parentId = parent.Id
+	}
+
 	tags := make([]tag.Tag, 0)
-	for t := range imageSource.ListTags(q, 100) {
-		tags = append(tags, t)
+	if params.Tree != nil && *params.Tree {
+		tags = imageSource.ListTagTree(parentId)
+	} else if params.Parent != nil {
+		tags = imageSource.ListTagChildren(parentId, 100)
+	} else {
+		for t := range imageSource.ListTags(q, 100) {
+			tags = append(tags, t)
+		}
 	}
 
 	respond(w, r, http.StatusOK, struct {
@@ -1302,6 +2500,12 @@ func (*Api) PostTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	collection := getCollectionById(string(*data.CollectionId))
+	if collection == nil || !canAccessCollection(r, collection) {
+		problem(w, r, http.StatusNotFound, "Collection not found")
+		return
+	}
+
 	t, err := tag.NewSelection(string(*data.CollectionId))
 	if err != nil {
 		problem(w, r, http.StatusInternalServerError, err.Error())
@@ -1315,78 +2519,336 @@ func (*Api) PostTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respond(w, r, http.StatusCreated, struct {
-		Id openapi.TagId `json:"id"`
-	}{
-		Id: openapi.TagId(tag.Name),
-	})
+	respond(w, r, http.StatusCreated, struct {
+		Id openapi.TagId `json:"id"`
+	}{
+		Id: openapi.TagId(tag.Name),
+	})
+}
+
+func (*Api) PostTagsIdFiles(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
+
+	data := &openapi.TagFilesPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t, err := imageSource.GetOrCreateTagFromName(string(id))
+	if err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ids, ok := selectedFileIds(w, r, data.SceneId, data.Bounds, data.FileId, data.TagId)
+	if !ok {
+		return
+	}
+
+	if ids.Len() > 0 {
+		switch data.Op {
+		case "ADD":
+			t.UpdatedAt = imageSource.AddTagIds(t.Id, ids)
+		case "SUBTRACT":
+			t.UpdatedAt = imageSource.RemoveTagIds(t.Id, ids)
+		case "INVERT":
+			t.UpdatedAt = imageSource.InvertTagIds(t.Id, ids)
+		default:
+			problem(w, r, http.StatusBadRequest, "Invalid op")
+			return
+		}
+		if !strings.HasPrefix(t.Name, "sys:") {
+			go writeXmpTags(ids)
+		}
+	}
+
+	respond(w, r, http.StatusOK, t)
+}
+
+// selectedFileIds returns the ids of the files within the bounds of the
+// scene, the single file, or the files with the tag, writing a problem and
+// returning false if none of them are provided.
+func selectedFileIds(w http.ResponseWriter, r *http.Request, sceneId *openapi.SceneId, bounds *openapi.Bounds, fileId *openapi.FileId, tagId *openapi.TagId) (image.Ids, bool) {
+	ids := image.NewIds()
+	if sceneId != nil && bounds != nil {
+		scene := getAllowedSceneById(r, *sceneId)
+		if scene == nil {
+			problem(w, r, http.StatusBadRequest, "Scene not found")
+			return nil, false
+		}
+
+		rect := render.Rect{
+			X: float64(bounds.X),
+			Y: float64(bounds.Y),
+			W: float64(bounds.W),
+			H: float64(bounds.H),
+		}
+
+		photos := scene.GetVisiblePhotos(rect)
+		for p := range photos {
+			ids.AddInt(int(p.Id))
+		}
+	} else if fileId != nil {
+		if !canAccessFile(r, image.ImageId(*fileId)) {
+			problem(w, r, http.StatusBadRequest, "File not found")
+			return nil, false
+		}
+		ids.AddInt(int(*fileId))
+	} else if tagId != nil {
+		t, err := imageSource.GetOrCreateTagFromName(string(*tagId))
+		if err != nil {
+			problem(w, r, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		ids = allowedFileIds(r, imageSource.GetTagImageIds(t.Id))
+	} else {
+		problem(w, r, http.StatusBadRequest, "Either scene_id+bounds or file_id required")
+		return nil, false
+	}
+	return ids, true
+}
+
+func (*Api) PostFilesMetadata(w http.ResponseWriter, r *http.Request) {
+
+	data := &openapi.FilesMetadataPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if data.Rating != nil && (*data.Rating < 0 || *data.Rating > image.MaxRating) {
+		problem(w, r, http.StatusBadRequest, fmt.Sprintf("Rating must be from 0 to %d", image.MaxRating))
+		return
+	}
+	if data.Label != nil && *data.Label != "" && !slices.Contains(search.LabelValues, string(*data.Label)) {
+		problem(w, r, http.StatusBadRequest, "Label must be one of "+strings.Join(search.LabelValues, ", "))
+		return
+	}
+
+	ids, ok := selectedFileIds(w, r, data.SceneId, data.Bounds, data.FileId, data.TagId)
+	if !ok {
+		return
+	}
+
+	if ids.Len() > 0 {
+		var err error
+		if data.Rating != nil && err == nil {
+			err = imageSource.SetRating(ids, int(*data.Rating))
+		}
+		if data.Label != nil && err == nil {
+			err = imageSource.SetLabel(ids, string(*data.Label))
+		}
+		if data.Favorite != nil && err == nil {
+			err = imageSource.SetFavorite(ids, *data.Favorite)
+		}
+		if err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	respond(w, r, http.StatusOK, struct {
+		FileCount int `json:"file_count"`
+	}{
+		FileCount: ids.Len(),
+	})
+}
+
+func (*Api) GetTagsIdFilesTags(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
+
+	t, ok := imageSource.GetTagByName(string(id))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	if _, restricted := restrictedUser(r); restricted {
+		ids := allowedFileIds(r, imageSource.GetTagImageIds(t.Id))
+		respond(w, r, http.StatusOK, struct {
+			Items []tag.Tag `json:"items"`
+			Count int       `json:"file_count"`
+		}{
+			Items: listTagsOfIds(ids, 10),
+			Count: ids.Len(),
+		})
+		return
+	}
+
+	count, ok := imageSource.GetTagFilesCount(t.Id)
+	if !ok {
+		problem(w, r, http.StatusInternalServerError, "Failed to count tag ids")
+		return
+	}
+
+	tags := make([]tag.Tag, 0)
+	for t := range imageSource.ListTagsOfTag(t.Id, 10) {
+		tags = append(tags, t)
+	}
+
+	respond(w, r, http.StatusOK, struct {
+		Items []tag.Tag `json:"items"`
+		Count int       `json:"file_count"`
+	}{
+		Items: tags,
+		Count: count,
+	})
+}
+
+// listTagsOfIds lists the tags of the files with the ids ordered by name,
+// counting the files of the ids in each, like ListTagsOfTag does for all
+// the files of a tag.
+func listTagsOfIds(ids image.Ids, limit int) []tag.Tag {
+	counts := make(map[tag.Id]*tag.Tag)
+	for _, id := range ids.IntSlice() {
+		for t := range imageSource.ListImageTags(image.ImageId(id)) {
+			c, ok := counts[t.Id]
+			if !ok {
+				c = &t
+				counts[t.Id] = c
+			}
+			c.FileCount++
+		}
+	}
+	tags := make([]tag.Tag, 0, len(counts))
+	for _, t := range counts {
+		tags = append(tags, *t)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags
+}
+
+func (*Api) PatchTagsId(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
+	data := &openapi.TagPatch{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t, ok := imageSource.GetTagByName(string(id))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	name := t.Name
+	if data.Name != nil && data.Parent != nil {
+		problem(w, r, http.StatusBadRequest, "Either name or parent supported")
+		return
+	} else if data.Name != nil {
+		name = *data.Name
+	} else if data.Parent != nil {
+		if i := strings.LastIndex(t.Name, tag.Separator); i >= 0 {
+			name = t.Name[i+1:]
+		}
+		if *data.Parent != "" {
+			name = *data.Parent + tag.Separator + name
+		}
+	}
+	if name != t.Name {
+		switch {
+		case !tag.ValidName(name):
+			problem(w, r, http.StatusBadRequest, "Invalid tag name")
+			return
+		case strings.HasPrefix(t.Name, "sys:"), strings.HasPrefix(t.Name, image.SuggestPrefix), strings.HasPrefix(name, "sys:"), strings.HasPrefix(name, image.SuggestPrefix):
+			problem(w, r, http.StatusBadRequest, "System and suggested tags cannot be renamed")
+			return
+		case strings.HasPrefix(name, t.Name+tag.Separator):
+			problem(w, r, http.StatusBadRequest, "Tags cannot be moved under themselves")
+			return
+		}
+		moved, err := imageSource.MoveTag(t, name)
+		if err == image.ErrExists {
+			problem(w, r, http.StatusConflict, "Tag already exists")
+			return
+		} else if err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		t = moved
+	}
+
+	if data.Auto != nil {
+		if *data.Auto && strings.HasPrefix(t.Name, image.SuggestPrefix) {
+			problem(w, r, http.StatusBadRequest, "Suggested tags cannot be auto tags")
+			return
+		}
+		if err := imageSource.SetTagAuto(t, *data.Auto); err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		t.Auto = *data.Auto
+	}
+
+	w.Header().Add("ETag", t.ETag())
+	respond(w, r, http.StatusOK, t)
 }
 
-func (*Api) PostTagsIdFiles(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
-
-	data := &openapi.TagFilesPost{}
-	if err := chirender.Decode(r, data); err != nil {
-		problem(w, r, http.StatusBadRequest, err.Error())
+func (*Api) GetTagsIdDownloadZip(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam, params openapi.GetTagsIdDownloadZipParams) {
+	tag, exists := imageSource.GetTagByName(string(id))
+	if !exists {
+		problem(w, r, http.StatusNotFound, "Tag not found")
 		return
 	}
-
-	t, err := imageSource.GetOrCreateTagFromName(string(id))
+	size, err := downloadSize(params.Size)
 	if err != nil {
 		problem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	ids := image.NewIds()
-	if data.SceneId != nil && data.Bounds != nil {
-		scene := sceneSource.GetSceneById(string(*data.SceneId), imageSource)
-		if scene == nil {
-			problem(w, r, http.StatusBadRequest, "Scene not found")
-			return
-		}
+	ids := allowedFileIds(r, imageSource.GetTagImageIds(tag.Id))
+	files := make([]image.ImageId, 0, ids.Len())
+	for _, id := range ids.IntSlice() {
+		files = append(files, image.ImageId(id))
+	}
+	if len(files) == 0 {
+		problem(w, r, http.StatusNotFound, "No files to download")
+		return
+	}
 
-		bounds := render.Rect{
-			X: float64(data.Bounds.X),
-			Y: float64(data.Bounds.Y),
-			W: float64(data.Bounds.W),
-			H: float64(data.Bounds.H),
-		}
+	writeZip(w, r, tagZipFilename(tag.Name), files, size)
+}
 
-		photos := scene.GetVisiblePhotos(bounds)
-		for p := range photos {
-			ids.AddInt(int(p.Id))
-		}
-	} else if data.FileId != nil {
-		ids.AddInt(int(*data.FileId))
-	} else if data.TagId != nil {
-		srct, err := imageSource.GetOrCreateTagFromName(string(*data.TagId))
-		if err != nil {
-			problem(w, r, http.StatusBadRequest, err.Error())
-			return
-		}
-		ids = imageSource.GetTagImageIds(srct.Id)
-	} else {
-		problem(w, r, http.StatusBadRequest, "Either scene_id+bounds or file_id required")
+func (*Api) GetTagsIdSuggestions(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam, params openapi.GetTagsIdSuggestionsParams) {
+	t, ok := imageSource.GetTagByName(string(id))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Tag not found")
 		return
 	}
 
-	if ids.Len() > 0 {
-		switch data.Op {
-		case "ADD":
-			t.UpdatedAt = imageSource.AddTagIds(t.Id, ids)
-		case "SUBTRACT":
-			t.UpdatedAt = imageSource.RemoveTagIds(t.Id, ids)
-		case "INVERT":
-			t.UpdatedAt = imageSource.InvertTagIds(t.Id, ids)
-		default:
-			problem(w, r, http.StatusBadRequest, "Invalid op")
-			return
+	limit := 100
+	if params.Limit != nil {
+		limit = int(*params.Limit)
+	}
+
+	suggestions := imageSource.ListTagSuggestions(t.Name, limit)
+	if _, restricted := restrictedUser(r); restricted {
+		allowed := make([]image.TagSuggestion, 0, len(suggestions))
+		for _, s := range suggestions {
+			if canAccessFile(r, s.FileId) {
+				allowed = append(allowed, s)
+			}
 		}
+		suggestions = allowed
 	}
 
-	respond(w, r, http.StatusOK, t)
+	respond(w, r, http.StatusOK, struct {
+		Items []image.TagSuggestion `json:"items"`
+	}{
+		Items: suggestions,
+	})
 }
 
-func (*Api) GetTagsIdFilesTags(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
+func (*Api) PostTagsIdSuggestions(w http.ResponseWriter, r *http.Request, id openapi.TagIdPathParam) {
+	data := &openapi.TagSuggestionsPost{}
+	if err := chirender.Decode(r, data); err != nil {
+		problem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	t, ok := imageSource.GetTagByName(string(id))
 	if !ok {
@@ -1394,30 +2856,51 @@ func (*Api) GetTagsIdFilesTags(w http.ResponseWriter, r *http.Request, id openap
 		return
 	}
 
-	count, ok := imageSource.GetTagFilesCount(t.Id)
-	if !ok {
-		problem(w, r, http.StatusInternalServerError, "Failed to count tag ids")
-		return
+	minConfidence := float32(0)
+	if data.MinConfidence != nil {
+		minConfidence = *data.MinConfidence
 	}
+	ids := imageSource.GetTagSuggestionIds(t.Name, minConfidence)
+	if data.FileIds != nil {
+		selected := image.NewIds()
+		for _, fileId := range *data.FileIds {
+			if ids.Contains(int(fileId)) {
+				selected.AddInt(int(fileId))
+			}
+		}
+		ids = selected
+	}
+	ids = allowedFileIds(r, ids)
 
-	tags := make([]tag.Tag, 0)
-	for t := range imageSource.ListTagsOfTag(t.Id, 10) {
-		tags = append(tags, t)
+	count := len(ids.IntSlice())
+	if count > 0 {
+		var err error
+		switch data.Op {
+		case openapi.TagSuggestionsPostOpACCEPT:
+			err = imageSource.AcceptTagSuggestions(t, ids)
+		case openapi.TagSuggestionsPostOpREJECT:
+			err = imageSource.RejectTagSuggestions(t, ids)
+		default:
+			problem(w, r, http.StatusBadRequest, "Invalid op")
+			return
+		}
+		if err != nil {
+			problem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
 	}
 
 	respond(w, r, http.StatusOK, struct {
-		Items []tag.Tag `json:"items"`
-		Count int       `json:"file_count"`
+		FileCount int `json:"file_count"`
 	}{
-		Items: tags,
-		Count: count,
+		FileCount: count,
 	})
 }
 
 func (*Api) GetFilesId(w http.ResponseWriter, r *http.Request, id openapi.FileIdPathParam) {
 
 	path, err := imageSource.GetImagePath(image.ImageId(id))
-	if err == image.ErrNotFound {
+	if err == image.ErrNotFound || !canAccessFile(r, image.ImageId(id)) {
 		problem(w, r, http.StatusNotFound, "File not found")
 		return
 	}
@@ -1428,7 +2911,7 @@ func (*Api) GetFilesId(w http.ResponseWriter, r *http.Request, id openapi.FileId
 func (*Api) GetFilesIdOriginalFilename(w http.ResponseWriter, r *http.Request, id openapi.FileIdPathParam, filename openapi.FilenamePathParam) {
 
 	path, err := imageSource.GetImagePath(image.ImageId(id))
-	if err == image.ErrNotFound {
+	if err == image.ErrNotFound || !canAccessFile(r, image.ImageId(id)) {
 		problem(w, r, http.StatusNotFound, "File not found")
 		return
 	}
@@ -1437,6 +2920,10 @@ func (*Api) GetFilesIdOriginalFilename(w http.ResponseWriter, r *http.Request, i
 }
 
 func (*Api) GetFilesIdVariantsSizeFilename(w http.ResponseWriter, r *http.Request, id openapi.FileIdPathParam, size openapi.SizePathParam, filename openapi.FilenamePathParam) {
+	if !canAccessFile(r, image.ImageId(id)) {
+		problem(w, r, http.StatusNotFound, "File not found")
+		return
+	}
 	imageSource.GetImageReader(image.ImageId(id), string(size), func(rs io.ReadSeeker, err error) {
 		if err != nil {
 			problem(w, r, http.StatusBadRequest, err.Error())
@@ -1451,7 +2938,7 @@ func (*Api) GetFilesIdPreviewsFilename(w http.ResponseWriter, r *http.Request, i
 
 	// Get file info
 	info := imageSource.GetInfo(image.ImageId(id))
-	if info.Width == 0 || info.Height == 0 {
+	if info.Width == 0 || info.Height == 0 || !canAccessFile(r, image.ImageId(id)) {
 		problem(w, r, http.StatusNotFound, "File not found")
 		return
 	}
@@ -1752,6 +3239,98 @@ func benchmarkSources(collection *collection.Collection, seed int64, sampleSize
 	bench.BenchmarkSources(seed, sources, samples, count)
 }
 
+// syncsXmp returns true if the file is included in a collection syncing
+// its tags with XMP.
+func syncsXmp(path string) bool {
+	return xmpSyncer(collections)(path)
+}
+
+// xmpSyncer returns a function returning true if the file is included in
+// one of the collections syncing their tags with XMP. The collections are
+// copied, so that it can be used by the pipeline while they are reloaded.
+func xmpSyncer(collections []collection.Collection) func(path string) bool {
+	type synced struct {
+		dirs   []string
+		filter image.PathFilter
+	}
+	syncs := make([]synced, 0)
+	for i := range collections {
+		c := &collections[i]
+		if !c.Xmp || c.SavedSearchId != 0 {
+			continue
+		}
+		syncs = append(syncs, synced{
+			dirs:   slices.Clone(c.Dirs),
+			filter: c.PathFilter(),
+		})
+	}
+	return func(path string) bool {
+		for _, s := range syncs {
+			if s.filter.MatchPath(s.dirs, path, false) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// includesPath returns true if the file or dir is included by the filters of
+// any of the collections with the path in their dirs, or is in none of them.
+func includesPath(path string, dir bool) bool {
+	found := false
+	for i := range collections {
+		c := &collections[i]
+		if c.SavedSearchId != 0 {
+			continue
+		}
+		inDirs := slices.ContainsFunc(c.Dirs, func(d string) bool {
+			return strings.HasPrefix(path+string(filepath.Separator), d)
+		})
+		if !inDirs {
+			continue
+		}
+		found = true
+		if c.PathFilter().MatchPath(c.Dirs, path, dir) {
+			return true
+		}
+	}
+	return !found
+}
+
+// xmpWriteMutex serializes the writes of the XMP sidecars. The tags are
+// read while holding it, so the last write has the latest tags even if the
+// writes of concurrent requests run out of order.
+var xmpWriteMutex sync.Mutex
+
+// writeXmpTags writes the tags of the files syncing with XMP to their XMP
+// sidecars, so that other photo managers see the same tags.
+func writeXmpTags(ids image.Ids) {
+	xmpWriteMutex.Lock()
+	defer xmpWriteMutex.Unlock()
+	written := 0
+	for _, id := range ids.IntSlice() {
+		path, err := imageSource.GetImagePath(image.ImageId(id))
+		if err != nil || !syncsXmp(path) {
+			continue
+		}
+		names := make([]string, 0)
+		for t := range imageSource.ListImageTags(image.ImageId(id)) {
+			if !image.SyncsXmpTag(t.Name) {
+				continue
+			}
+			names = append(names, t.Name)
+		}
+		if err := xmp.WriteSidecar(path, xmp.FromKeywords(names)); err != nil {
+			log.Printf("Unable to write xmp sidecar of %s: %s\n", path, err.Error())
+			continue
+		}
+		written++
+	}
+	if written > 0 {
+		log.Printf("xmp wrote tags of %d files", written)
+	}
+}
+
 func invalidateDirs(dirs []string) {
 	for i := range collections {
 		collection := &collections[i]
@@ -1768,7 +3347,7 @@ func invalidateDirs(dirs []string) {
 			}
 		}
 		if updated {
-			collection.Invalidate()
+			invalidateCollection(collection)
 		}
 	}
 }
@@ -1794,6 +3373,7 @@ func applyConfig(appConfig *AppConfig) {
 	defaultSceneConfig.Render = appConfig.Render
 	tileRequestConfig = appConfig.TileRequests
 	tagsEnabled = appConfig.Tags.Enable
+	authConfig = appConfig.Auth
 
 	var err error
 	globalGeo, err = geo.New(
@@ -1806,11 +3386,23 @@ func applyConfig(appConfig *AppConfig) {
 		log.Printf("%v", globalGeo.String())
 	}
 
+	// Stop indexing with the old source before closing it
+	collectionWatcher.Close()
+	collectionWatcher = nil
+	if collectionScheduler != nil {
+		collectionScheduler.Close()
+		collectionScheduler = nil
+	}
+	if pipelineCoordinator != nil {
+		pipelineCoordinator.Close()
+	}
+
 	oldSource := imageSource
 	imageSource = image.NewSource(appConfig.Media, migrations, globalGeo)
 	if oldSource != nil {
 		oldSource.Close()
 	}
+	ensureAdminUser()
 
 	if appConfig.AI.TextualHost() != "" {
 		log.Printf("ai textual (search) host: %s", appConfig.AI.TextualHost())
@@ -1825,9 +3417,6 @@ func applyConfig(appConfig *AppConfig) {
 	}
 
 	// Initialize pipeline coordinator
-	if pipelineCoordinator != nil {
-		pipelineCoordinator.Close()
-	}
 	// Convert thumbnail sources and generators to pipeline interface slices
 	rawThumbSources := imageSource.ThumbSources()
 	pipelineThumbSources := make([]pipeline.ThumbnailSource, len(rawThumbSources))
@@ -1840,26 +3429,39 @@ func applyConfig(appConfig *AppConfig) {
 		pipelineThumbGens[i] = g
 	}
 	pipelineCfg := pipeline.Config{
-		DB:                  imageSource.DB(),
-		MetadataExtractor:   imageSource.Decoder(),
-		EnableTags:          appConfig.Tags.Enable,
-		Extensions:          appConfig.Media.ListExtensions,
-		VideoExtensions:     appConfig.Media.Videos.Extensions,
-		ThumbnailSources:    pipelineThumbSources,
-		ThumbnailGenerators: pipelineThumbGens,
-		ThumbnailSink:       imageSource.ThumbSink(),
-		AIService:           imageSource.Clip,
-		FaceDetector:        imageSource.Clip,
-		MaxFaceFileSize:     appConfig.Media.MaxFaceFileSizeBytes(),
-		ImageDecoder:        imageSource.ThumbSink(),
-		MetadataWorkers:     appConfig.Media.ConcurrentMetaLoads,
-		ThumbnailWorkers:    appConfig.Media.ConcurrentColorLoads,
-		ContentsWorkers:     appConfig.Media.ConcurrentAILoads,
-		FaceWorkers:         appConfig.Media.ConcurrentMetaLoads,
+		DB:                   imageSource.DB(),
+		MetadataExtractor:    imageSource.Decoder(),
+		EnableTags:           appConfig.Tags.Enable,
+		SyncXmp:              xmpSyncer(collections),
+		Included:             includesPath,
+		Schedule:             indexScheduled,
+		Extensions:           appConfig.Media.ListExtensions,
+		VideoExtensions:      appConfig.Media.Videos.Extensions,
+		ContentHash:          appConfig.Media.ContentHash,
+		DuplicateSimilarity:  appConfig.Media.DuplicateSimilarity,
+		PersonSimilarity:     appConfig.Media.PersonSimilarity,
+		PersonMinFaces:       appConfig.Media.PersonMinFaces,
+		AutotagNeighbors:     appConfig.Tags.Autotag.Neighbors,
+		AutotagMinConfidence: appConfig.Tags.Autotag.MinConfidence,
+		ThumbnailSources:     pipelineThumbSources,
+		ThumbnailGenerators:  pipelineThumbGens,
+		ThumbnailSink:        imageSource.ThumbSink(),
+		AIService:            imageSource.Clip,
+		FaceDetector:         imageSource.Clip,
+		MaxFaceFileSize:      appConfig.Media.MaxFaceFileSizeBytes(),
+		ImageDecoder:         imageSource.ThumbSink(),
+		MetadataWorkers:      appConfig.Media.ConcurrentMetaLoads,
+		ThumbnailWorkers:     appConfig.Media.ConcurrentColorLoads,
+		ContentsWorkers:      appConfig.Media.ConcurrentAILoads,
+		FaceWorkers:          appConfig.Media.ConcurrentMetaLoads,
 	}
 	pipelineCoordinator = pipeline.NewCoordinator(context.Background(), pipelineCfg)
+	scheduleCollections()
+	watchCollections(pipelineCfg)
 
 	imageSource.HandleDirUpdates(invalidateDirs)
+	imageSource.HandleTagUpdates(publishTagUpdate)
+	tileCache.Store(updateTileCache(tileCache.Load(), appConfig.Media.DataDir, appConfig.Media.Caches.Tiles))
 	if tileRequestConfig.Concurrency > 0 {
 		log.Printf("request concurrency %v", tileRequestConfig.Concurrency)
 		requestsOut = make(chan struct{}, 10000)
@@ -1888,6 +3490,214 @@ func applyConfig(appConfig *AppConfig) {
 	}
 }
 
+// scheduleCollections indexes the collections with a schedule at their
+// scheduled times, replacing any previous schedules.
+func scheduleCollections() {
+	if collectionScheduler != nil {
+		collectionScheduler.Close()
+	}
+	jobs := make([]schedule.Job, 0)
+	for i := range collections {
+		c := &collections[i]
+		if c.Schedule == nil || c.Schedule.Index == "" {
+			continue
+		}
+		cron, err := schedule.Parse(c.Schedule.Index)
+		if err != nil {
+			log.Printf("collection %s schedule invalid: %v", c.Id, err)
+			continue
+		}
+		invalid := false
+		for _, stage := range c.Schedule.Stages {
+			if !slices.Contains(collection.ScheduleStages, stage) {
+				log.Printf("collection %s schedule stage %q invalid (use %s)", c.Id, stage, strings.Join(collection.ScheduleStages, ", "))
+				invalid = true
+			}
+		}
+		if invalid {
+			continue
+		}
+		id := c.Id
+		name := c.Name
+		jobs = append(jobs, schedule.Job{
+			Name: "index " + id,
+			Cron: cron,
+			Run: func() {
+				// Checking the dirs for changes can take a while, so it
+				// runs as a task to keep the other jobs on time
+				if pipelineCoordinator != nil {
+					pipelineCoordinator.AddSchedule(id, name)
+				}
+			},
+		})
+	}
+	collectionScheduler = schedule.New(jobs)
+}
+
+// indexScheduled enqueues the scheduled stages of a collection, unless none
+// of its dirs changed since it was last indexed. The collection is looked up
+// by id, as the collections may be reloaded since it was scheduled.
+func indexScheduled(id string) {
+	found := getCollectionById(id)
+	if found == nil || found.Schedule == nil || pipelineCoordinator == nil {
+		return
+	}
+	c := *found
+	stages := c.Schedule.Stages
+	if len(stages) == 0 {
+		stages = []string{"files", "metadata", "contents", "faces"}
+	}
+	if at, ok := stagesRunAt(c.Id, stages); ok && !c.ChangedSince(at) {
+		log.Printf("collection %s unchanged since %s, skipping scheduled index", id, at.Format(time.RFC3339))
+		return
+	}
+
+	tasks := make([]*inttask.Task, 0, len(stages))
+	for _, stage := range stages {
+		var pt *inttask.Task
+		switch stage {
+		case "files":
+			pt, _ = pipelineCoordinator.AddFiles(c.Id, c.Name, c.Dirs, c.IndexLimit)
+		case "metadata":
+			pt, _ = pipelineCoordinator.AddMetadata(c.Id, c.Name, c.Dirs, c.IndexLimit, false)
+		case "contents":
+			pt, _ = pipelineCoordinator.AddContents(c.Id, c.Name, c.Dirs, c.IndexLimit, false)
+		case "faces":
+			pt, _ = pipelineCoordinator.AddFaces(c.Id, c.Name, c.Dirs, c.IndexLimit, false)
+		case "duplicates":
+			pt, _ = pipelineCoordinator.AddDuplicates(c.Id, c.Name, c.Dirs)
+		default:
+			continue
+		}
+		tasks = append(tasks, pt)
+	}
+
+	go func() {
+		for _, t := range tasks {
+			<-t.Completed()
+		}
+		if c := getCollectionById(id); c != nil {
+			invalidateCollection(c)
+		}
+	}()
+}
+
+// scheduleTaskTypes are the task types of the stages of schedules.
+var scheduleTaskTypes = map[string]string{
+	"files":      inttask.TypeIndexFiles,
+	"metadata":   inttask.TypeIndexMetadata,
+	"contents":   inttask.TypeIndexContents,
+	"faces":      inttask.TypeIndexFaces,
+	"duplicates": inttask.TypeIndexDuplicates,
+}
+
+// stagesRunAt returns the start of the least recent successful run of the
+// stages for the collection, or false if any of them has not run yet.
+func stagesRunAt(collectionId string, stages []string) (time.Time, bool) {
+	var at time.Time
+	for _, stage := range stages {
+		taskType, ok := scheduleTaskTypes[stage]
+		if !ok {
+			continue
+		}
+		var last time.Time
+		for _, run := range imageSource.ListTaskRuns(image.TaskRunListOptions{
+			Finished:     true,
+			Type:         taskType,
+			CollectionId: collectionId,
+		}) {
+			if run.Error == "" {
+				last = run.StartedAt
+				break
+			}
+		}
+		if last.IsZero() {
+			return time.Time{}, false
+		}
+		if at.IsZero() || last.Before(at) {
+			at = last
+		}
+	}
+	return at, !at.IsZero()
+}
+
+// watchCollections indexes the files changed in the dirs of the watched
+// collections as they change, replacing any previous watcher.
+func watchCollections(cfg pipeline.Config) {
+	collectionWatcher.Close()
+	collectionWatcher = nil
+
+	dirs := make([]string, 0)
+	for _, c := range collections {
+		if !c.Watch {
+			continue
+		}
+		for _, dir := range c.Dirs {
+			dir = strings.TrimSuffix(dir, string(filepath.Separator))
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return
+	}
+
+	// Watch only the topmost dirs as the watches are recursive
+	sort.Strings(dirs)
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if len(roots) > 0 && strings.HasPrefix(dir, roots[len(roots)-1]+string(filepath.Separator)) {
+			continue
+		}
+		roots = append(roots, dir)
+	}
+
+	w, err := pipeline.NewWatcher(cfg, roots, 2*time.Second, indexWatched)
+	if err != nil {
+		log.Printf("unable to watch collections: %v", err)
+		return
+	}
+	log.Printf("watching %d dirs for changes", len(roots))
+	collectionWatcher = w
+}
+
+// indexWatched refreshes the watched collections with changed paths and
+// queues the extraction of the metadata and contents of any added files.
+func indexWatched(paths []string, added bool) {
+	for i := range collections {
+		c := &collections[i]
+		if !c.Watch {
+			continue
+		}
+		changed := false
+		for _, path := range paths {
+			for _, dir := range c.Dirs {
+				if strings.HasPrefix(path, dir) {
+					changed = true
+					break
+				}
+			}
+			if changed {
+				break
+			}
+		}
+		if !changed {
+			continue
+		}
+		invalidateCollection(c)
+		if added && pipelineCoordinator != nil {
+			pts, _ := pipelineCoordinator.AddAll(c.Id, c.Name, c.Dirs, c.IndexLimit, false)
+			go func() {
+				for _, t := range pts {
+					<-t.Completed()
+				}
+				invalidateCollection(c)
+			}()
+		}
+	}
+}
+
 func listenForShutdown() {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
@@ -2103,6 +3913,50 @@ func parseIntList(s string) ([]int, error) {
 	return result, nil
 }
 
+// exportUserData writes the user data of the database to the path, or to
+// stdout if the path is "-".
+func exportUserData(path string) error {
+	w := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	stats, err := imageSource.ExportUserData(bw)
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	log.Printf("exported %d tags and %d files", stats.Tags, stats.Files)
+	return nil
+}
+
+// importUserData reads the user data exported by exportUserData from the
+// path, or from stdin if the path is "-".
+func importUserData(path string) error {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	stats, err := imageSource.ImportUserData(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	log.Printf("imported %d tags and %d files, %d matched by hash, %d missing", stats.Tags, stats.Files, stats.Moved, stats.Missing)
+	return nil
+}
+
 // detectEncoderSupport logs which encoders are available at startup
 func detectEncoderSupport() {
 	log.Printf("encoders %s", codec.FastestEncoders.String())
@@ -2121,6 +3975,9 @@ func main() {
 	testing.Init()
 	versionFlag := flag.Bool("version", false, "print version and exit")
 	vacuumFlag := flag.Bool("vacuum", false, "clean database for smaller size and better performance, and exit")
+	backupPath := flag.String("backup", "", "write a consistent backup of the database to the specified path, and exit")
+	exportPath := flag.String("export", "", "export tags, selections, ratings, labels and favorites to the specified NDJSON file (- for stdout), and exit")
+	importPath := flag.String("import", "", "import tags, selections, ratings, labels and favorites from the specified NDJSON file (- for stdin), and exit")
 	benchFlag := flag.Bool("bench", false, "benchmark sources and exit")
 	benchCollectionId := flag.String("bench.collection", "vacation-photos", "id of the collection to benchmark")
 	benchSeed := flag.Int64("bench.seed", 123, "seed for random number generator")
@@ -2223,12 +4080,13 @@ func main() {
 		Debug:  fontFamily.Face(34, canvas.Black, canvas.FontRegular, canvas.FontNormal),
 	}
 	sceneSource.DefaultScene = defaultSceneConfig.Scene
+	handleEvents()
 
 	listenForShutdown()
 
 	detectEncoderSupport()
 
-	watchConfig(dataDir, func(appConfig *AppConfig) {
+	reloadCollections = watchConfig(dataDir, func(appConfig *AppConfig) {
 		applyConfig(appConfig)
 	})
 
@@ -2247,6 +4105,33 @@ func main() {
 		return
 	}
 
+	if *backupPath != "" {
+		err := imageSource.Backup(*backupPath)
+		if err != nil {
+			log.Fatalf("failed to back up database: %v", err)
+		}
+		imageSource.Close()
+		return
+	}
+
+	if *exportPath != "" {
+		err := exportUserData(*exportPath)
+		if err != nil {
+			log.Fatalf("failed to export user data: %v", err)
+		}
+		imageSource.Close()
+		return
+	}
+
+	if *importPath != "" {
+		err := importUserData(*importPath)
+		if err != nil {
+			log.Fatalf("failed to import user data: %v", err)
+		}
+		imageSource.Close()
+		return
+	}
+
 	if *benchFlag {
 		log.Printf("benchmark sources")
 
@@ -2322,19 +4207,25 @@ func main() {
 		if allowedOrigins != "" {
 			r.Use(cors.Handler(cors.Options{
 				AllowedOrigins: strings.Split(allowedOrigins, ","),
-				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 				AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 				MaxAge:         300, // Maximum value not ignored by any of major browsers
 			}))
 		}
 
+		r.Use(authMiddleware(apiPrefix))
+
 		var api Api
 		r.Mount("/", openapi.Handler(&api))
 		r.Mount("/metrics", promhttp.Handler())
 	})
 
-	r.Mount("/debug", middleware.Profiler())
-	r.Handle("/debug/fgprof", fgprof.Handler())
+	// Profiles and heap dumps are only available to admins
+	r.Route("/debug", func(r chi.Router) {
+		r.Use(authMiddleware(""))
+		r.Handle("/fgprof", fgprof.Handler())
+		r.Mount("/", middleware.Profiler())
+	})
 
 	msg := ""
 	if apiPrefix != "/" {
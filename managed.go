@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"photofield/internal/collection"
+	"photofield/internal/layout"
+	"photofield/internal/openapi"
+
+	"github.com/goccy/go-yaml"
+	"github.com/gosimple/slug"
+)
+
+var MANAGED_COLLECTIONS_FILENAME = "collections.managed.yaml"
+
+// ManagedCollections are the collections created, updated and deleted
+// through the API, merged over the collections of the config.
+type ManagedCollections struct {
+	// Collections are added after the configured ones, so they override the
+	// configured collections with the same id
+	Collections []collection.Collection `json:"collections"`
+	// Deleted are the ids of the configured collections deleted through the
+	// API
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// managedMutex guards the read-modify-write of the managed collections file
+var managedMutex sync.Mutex
+
+// reloadCollections reloads the config including the managed collections
+var reloadCollections func()
+
+func loadManagedCollections(dataDir string) (ManagedCollections, error) {
+	var managed ManagedCollections
+	path := filepath.Join(dataDir, MANAGED_COLLECTIONS_FILENAME)
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return managed, nil
+	}
+	if err != nil {
+		return managed, err
+	}
+	if err := yaml.Unmarshal(bytes, &managed); err != nil {
+		return managed, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	return managed, nil
+}
+
+func saveManagedCollections(dataDir string, managed ManagedCollections) error {
+	bytes, err := yaml.Marshal(managed)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dataDir, MANAGED_COLLECTIONS_FILENAME)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// managedCollectionIndex returns the index of the managed collection with
+// the id, or -1 if there is none. Expanded collections are not matched, as
+// their id is of their subdirs.
+func managedCollectionIndex(managed ManagedCollections, id string) int {
+	return slices.IndexFunc(managed.Collections, func(c collection.Collection) bool {
+		return !c.ExpandSubdirs && slug.Make(c.Name) == id
+	})
+}
+
+// collectionFromPost validates the posted collection.
+func collectionFromPost(data *openapi.CollectionPost) (collection.Collection, error) {
+	c := collection.Collection{
+		Name: strings.TrimSpace(data.Name),
+	}
+	if c.Name == "" {
+		return c, errors.New("name required")
+	}
+	if data.ExpandSubdirs != nil {
+		c.ExpandSubdirs = *data.ExpandSubdirs
+	}
+	if data.ExpandSort != nil {
+		c.ExpandSort = string(*data.ExpandSort)
+		if c.ExpandSort != "asc" && c.ExpandSort != "desc" {
+			return c, fmt.Errorf("invalid expand_sort %s", c.ExpandSort)
+		}
+	}
+	patch := openapi.CollectionPatch{
+		Dirs:       &data.Dirs,
+		Layout:     data.Layout,
+		Sort:       data.Sort,
+		Limit:      data.Limit,
+		Watch:      data.Watch,
+		Xmp:        data.Xmp,
+		Include:    data.Include,
+		Exclude:    data.Exclude,
+		SkipHidden: data.SkipHidden,
+		MaxDepth:   data.MaxDepth,
+		Allow:      data.Allow,
+	}
+	if err := patchCollection(&c, &patch); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// patchCollection validates and sets the provided fields of the patch.
+func patchCollection(c *collection.Collection, data *openapi.CollectionPatch) error {
+	if data.Dirs != nil {
+		if len(*data.Dirs) == 0 {
+			return errors.New("dirs required")
+		}
+		for _, dir := range *data.Dirs {
+			if !filepath.IsAbs(dir) {
+				return fmt.Errorf("dir %s is not absolute", dir)
+			}
+			info, err := os.Stat(dir)
+			if err != nil {
+				return fmt.Errorf("dir %s not found", dir)
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a dir", dir)
+			}
+		}
+		c.Dirs = slices.Clone(*data.Dirs)
+	}
+	if data.Layout != nil {
+		l := layout.Type(strings.ToUpper(string(*data.Layout)))
+		if l != "" && !slices.Contains(layout.Types, l) {
+			return fmt.Errorf("invalid layout %s", *data.Layout)
+		}
+		c.Layout = string(l)
+	}
+	if data.Sort != nil {
+		c.Sort = string(*data.Sort)
+	}
+	if data.Limit != nil {
+		if *data.Limit < 0 {
+			return errors.New("limit must not be negative")
+		}
+		// Derived from the limit again by MakeValid
+		if c.IndexLimit == c.Limit {
+			c.IndexLimit = 0
+		}
+		c.Limit = *data.Limit
+	}
+	if data.Watch != nil {
+		c.Watch = *data.Watch
+	}
+	if data.Xmp != nil {
+		c.Xmp = *data.Xmp
+	}
+	if data.Include != nil {
+		c.Include = slices.Clone(*data.Include)
+	}
+	if data.Exclude != nil {
+		c.Exclude = slices.Clone(*data.Exclude)
+	}
+	if data.SkipHidden != nil {
+		c.SkipHidden = *data.SkipHidden
+	}
+	if data.Allow != nil {
+		c.Allow = slices.Clone(*data.Allow)
+	}
+	if data.MaxDepth != nil {
+		if *data.MaxDepth < 0 {
+			return errors.New("max_depth must not be negative")
+		}
+		c.MaxDepth = int(*data.MaxDepth)
+	}
+	return nil
+}
+
+// isConfiguredCollection returns true if the collection can be managed
+// through the API, unlike the virtual collections of saved searches.
+func isConfiguredCollection(id string) bool {
+	_, saved := collection.ParseSavedSearchCollectionId(id)
+	return !saved || slices.ContainsFunc(collections, func(c collection.Collection) bool {
+		return c.Id == id
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"photofield/internal/collection"
+	"photofield/internal/openapi"
+)
+
+func TestManagedCollections(t *testing.T) {
+	tempDir := t.TempDir()
+	photos := filepath.Join(tempDir, "photos")
+	other := filepath.Join(tempDir, "other")
+	for _, dir := range []string{photos, other} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configContent := `
+collections:
+  - name: Photos
+    dirs: ["` + filepath.ToSlash(photos) + `"]
+  - name: Other
+    dirs: ["` + filepath.ToSlash(other) + `"]
+`
+	configPath := filepath.Join(tempDir, CONFIG_FILENAME)
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("unable to write config file: %v", err)
+	}
+
+	layout := openapi.LayoutType("timeline")
+	created, err := collectionFromPost(&openapi.CollectionPost{
+		Name:   "Created",
+		Dirs:   openapi.CollectionDirs{other},
+		Layout: &layout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	override := collection.Collection{
+		Name:   "Photos",
+		Dirs:   []string{photos},
+		Layout: "WALL",
+	}
+	err = saveManagedCollections(tempDir, ManagedCollections{
+		Collections: []collection.Collection{created, override},
+		Deleted:     []string{"other"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initDefaults()
+	appConfig, err := loadConfig(tempDir)
+	if err != nil {
+		t.Fatalf("unable to load configuration: %v", err)
+	}
+
+	layouts := make(map[string]string)
+	for _, c := range appConfig.Collections {
+		layouts[c.Id] = c.Layout
+	}
+	expected := map[string]string{
+		"photos":  "WALL",
+		"created": "TIMELINE",
+	}
+	if len(layouts) != len(expected) {
+		t.Errorf("collections %v, want %v", layouts, expected)
+	}
+	for id, layout := range expected {
+		if layouts[id] != layout {
+			t.Errorf("collection %s layout %q, want %q", id, layouts[id], layout)
+		}
+	}
+}
+
+func TestPatchCollectionValidation(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.jpg")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	invalidLayout := openapi.LayoutType("nope")
+	negative := -1
+	tests := []struct {
+		name  string
+		patch openapi.CollectionPatch
+		valid bool
+	}{
+		{"dir", openapi.CollectionPatch{Dirs: &openapi.CollectionDirs{dir}}, true},
+		{"no dirs", openapi.CollectionPatch{Dirs: &openapi.CollectionDirs{}}, false},
+		{"missing dir", openapi.CollectionPatch{Dirs: &openapi.CollectionDirs{filepath.Join(dir, "missing")}}, false},
+		{"file as dir", openapi.CollectionPatch{Dirs: &openapi.CollectionDirs{file}}, false},
+		{"relative dir", openapi.CollectionPatch{Dirs: &openapi.CollectionDirs{"photos"}}, false},
+		{"invalid layout", openapi.CollectionPatch{Layout: &invalidLayout}, false},
+		{"negative limit", openapi.CollectionPatch{Limit: &negative}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := collection.Collection{Name: "Test"}
+			err := patchCollection(&c, &tt.patch)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/collection"
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/task"
+)
+
+func TestCollectionPathFilter(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []string{
+		"a.jpg",
+		"b.lrprev.jpg",
+		".hidden.jpg",
+		"@eaDir/a.jpg",
+		".trash/c.jpg",
+		"2023/d.jpg",
+		"2023/exports/e.jpg",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := collection.Collection{
+		Name:       "Test",
+		Dirs:       []string{dir},
+		Exclude:    []string{"@eaDir", "*.lrprev.jpg", "2023/exports"},
+		SkipHidden: true,
+	}
+	c.MakeValid()
+
+	list := func(c collection.Collection) []string {
+		t.Helper()
+		infos, _ := db.List(c.Dirs, image.ListOptions{Filter: c.PathFilter()})
+		var paths []string
+		for info := range infos {
+			path, _ := db.GetPathFromId(info.Id)
+			rel, _ := filepath.Rel(dir, path)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		slices.Sort(paths)
+		return paths
+	}
+
+	// Indexed before the filter applied, hidden by listing with it
+	unfiltered := pipeline.Config{DB: db, Extensions: []string{".jpg"}}
+	index := func(cfg pipeline.Config) {
+		t.Helper()
+		tsk := task.NewFilesTask(c.Id, c.Name, c.Dirs, 0)
+		if err := pipeline.RunFiles(context.Background(), cfg, tsk); err != nil {
+			t.Fatalf("index files: %v", err)
+		}
+	}
+	index(unfiltered)
+
+	expected := []string{"2023/d.jpg", "a.jpg"}
+	if got := list(c); !slices.Equal(got, expected) {
+		t.Errorf("listed %v, want %v", got, expected)
+	}
+
+	// The limit applies to the files passing the filter
+	limited, _ := db.List(c.Dirs, image.ListOptions{Filter: c.PathFilter(), Limit: len(expected)})
+	n := 0
+	for range limited {
+		n++
+	}
+	if n != len(expected) {
+		t.Errorf("listed %d files with limit, want %d", n, len(expected))
+	}
+
+	count := func() int {
+		n := 0
+		for range db.ListIdPaths(c.Dirs, 0) {
+			n++
+		}
+		return n
+	}
+	if n := count(); n != len(files) {
+		t.Errorf("indexed %d files without filter, want %d", n, len(files))
+	}
+
+	filtered := unfiltered
+	filtered.Included = func(path string, dir bool) bool {
+		return c.PathFilter().MatchPath(c.Dirs, path, dir)
+	}
+	index(filtered)
+	if n := count(); n != len(expected) {
+		t.Errorf("indexed %d files with filter, want %d", n, len(expected))
+	}
+
+	c.Include = []string{"2023/**"}
+	c.MaxDepth = 2
+	expected = []string{"2023/d.jpg"}
+	if got := list(c); !slices.Equal(got, expected) {
+		t.Errorf("listed %v with include, want %v", got, expected)
+	}
+
+	// Collections without exclude patterns leave out the default ones
+	d := collection.Collection{Name: "Default", Dirs: []string{dir}}
+	d.MakeValid()
+	filter := d.PathFilter()
+	if filter.MatchPath(d.Dirs, filepath.Join(dir, "@eaDir", "a.jpg"), false) {
+		t.Error("@eaDir included without exclude")
+	}
+	if !filter.MatchPath(d.Dirs, filepath.Join(dir, ".trash", "c.jpg"), false) {
+		t.Error(".trash excluded without exclude")
+	}
+}
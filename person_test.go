@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/ai"
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/search"
+	"photofield/internal/task"
+)
+
+// testFaceDetector detects a face with the embedding keyed by the
+// contents of the file.
+type testFaceDetector map[string][]float32
+
+func (d testFaceDetector) DetectFaces(r io.Reader) ([]ai.Face, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := d[string(b)]
+	if !ok {
+		return nil, nil
+	}
+	norm := float32(0)
+	for _, f := range v {
+		norm += f * f
+	}
+	n := make([]float32, len(v))
+	for i, f := range v {
+		n[i] = f / float32(math.Sqrt(float64(norm)))
+	}
+	return []ai.Face{{W: 10, H: 10, Confidence: 90, Embedding: testEmbedding(n...).Byte()}}, nil
+}
+
+func TestIndexPersons(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	detector := testFaceDetector{
+		"alice-1": {1, 0.05, 0, 0},
+		"alice-2": {1, 0, 0.05, 0},
+		"alice-3": {1, 0, 0, 0.05},
+		"bob-1":   {0, 1, 0.05, 0},
+		"bob-2":   {0, 1, 0, 0.05},
+		"bob-3":   {0.05, 1, 0, 0},
+		"carol-1": {0, 0, 1, 0},
+		"alice-4": {1, 0.05, 0.05, 0},
+	}
+	write := func(names ...string) {
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name+".jpg"), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("alice-1", "alice-2", "alice-3", "bob-1", "bob-2", "bob-3", "carol-1")
+
+	dirs := []string{dir + string(filepath.Separator)}
+	cfg := pipeline.Config{
+		DB:               db,
+		Extensions:       []string{".jpg"},
+		FaceDetector:     detector,
+		MaxFaceFileSize:  1 << 20,
+		FaceWorkers:      1,
+		PersonSimilarity: 0.9,
+		PersonMinFaces:   3,
+	}
+	index := func() {
+		t.Helper()
+		if err := pipeline.RunFiles(context.Background(), cfg, task.NewFilesTask("test", "Test", dirs, 0)); err != nil {
+			t.Fatalf("index files: %v", err)
+		}
+		if err := pipeline.RunFaces(context.Background(), cfg, task.NewFacesTask("test", "Test", dirs, 0, false)); err != nil {
+			t.Fatalf("index faces: %v", err)
+		}
+	}
+	index()
+	if err := pipeline.RunPersons(context.Background(), cfg, task.NewPersonsTask("test", "Test", dirs)); err != nil {
+		t.Fatalf("index persons: %v", err)
+	}
+
+	persons := db.ListPersons(false)
+	if len(persons) != 2 || persons[0].FaceCount != 3 || persons[1].FaceCount != 3 {
+		t.Fatalf("persons = %+v, want two with 3 faces each", persons)
+	}
+
+	// Find out which person is which by the files of their faces
+	names := func(faces []image.FaceInfo) []string {
+		var names []string
+		for _, face := range faces {
+			path, _ := db.GetPathFromId(face.FileId)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+	alice, bob := persons[0], persons[1]
+	if names(db.ListPersonFaces(alice.Id))[0] != "alice-1.jpg" {
+		alice, bob = bob, alice
+	}
+	alice.Name = "Alice"
+	if _, err := db.UpdatePerson(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(q string) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression: %v", err)
+		}
+		results, _ := db.List(dirs, image.ListOptions{Expression: expr})
+		var names []string
+		for result := range results {
+			path, _ := db.GetPathFromId(result.Id)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got, want := query("person:alice"), []string{"alice-1.jpg", "alice-2.jpg", "alice-3.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("person:alice = %v, want %v", got, want)
+	}
+
+	// New faces join the persons they are similar to
+	write("alice-4")
+	index()
+	if got, want := query(`person:"Alice"`), []string{"alice-1.jpg", "alice-2.jpg", "alice-3.jpg", "alice-4.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("person:Alice after indexing = %v, want %v", got, want)
+	}
+
+	// Split and merge back
+	faces := db.ListPersonFaces(bob.Id)
+	split, err := db.SplitPerson(bob.Id, []int64{int64(faces[0].Id)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if split.FaceCount != 1 {
+		t.Errorf("split person has %d faces, want 1", split.FaceCount)
+	}
+	if _, err := db.SplitPerson(bob.Id, []int64{int64(faces[0].Id)}); err != image.ErrNotFound {
+		t.Errorf("splitting faces of another person = %v, want not found", err)
+	}
+	bob.Name = "Bob"
+	if _, err := db.UpdatePerson(bob); err != nil {
+		t.Fatal(err)
+	}
+	if got := query("person:bob"); len(got) != 2 {
+		t.Errorf("person:bob after split = %v, want 2 files", got)
+	}
+	merged, err := db.MergePersons(split.Id, []int64{bob.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Name != "Bob" || merged.FaceCount != 3 {
+		t.Errorf("merged = %+v, want Bob with 3 faces", merged)
+	}
+	if _, ok := db.GetPerson(bob.Id); ok {
+		t.Errorf("merged person still exists")
+	}
+
+	// Hidden persons are only listed on request
+	merged.Hidden = true
+	if _, err := db.UpdatePerson(merged); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(db.ListPersons(false)); got != 1 {
+		t.Errorf("listed %d persons, want 1 without hidden", got)
+	}
+	if got := len(db.ListPersons(true)); got != 2 {
+		t.Errorf("listed %d persons, want 2 with hidden", got)
+	}
+
+	// Clustering again keeps the edited persons
+	if err := pipeline.RunPersons(context.Background(), cfg, task.NewPersonsTask("test", "Test", dirs)); err != nil {
+		t.Fatalf("index persons: %v", err)
+	}
+	if got := db.ListPersons(true); len(got) != 2 {
+		t.Errorf("persons after clustering again = %+v, want 2", got)
+	}
+	if got := query("person:bob"); len(got) != 3 {
+		t.Errorf("person:bob after clustering again = %v, want 3 files", got)
+	}
+
+	// Detecting the faces again keeps them assigned
+	if err := pipeline.RunFaces(context.Background(), cfg, task.NewFacesTask("test", "Test", dirs, 0, true)); err != nil {
+		t.Fatalf("index faces: %v", err)
+	}
+	if got, want := query(`person:"Alice"`), []string{"alice-1.jpg", "alice-2.jpg", "alice-3.jpg", "alice-4.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("person:Alice after detecting again = %v, want %v", got, want)
+	}
+	if got := query("person:bob"); len(got) != 3 {
+		t.Errorf("person:bob after detecting again = %v, want 3 files", got)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/search"
+	"photofield/internal/task"
+)
+
+func TestRatingLabelFavorite(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	names := []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg", "e.jpg"}
+	for _, name := range names {
+		db.Write("/photos/"+name, image.Info{}, image.AppendPath)
+	}
+	<-db.CommitBarrier()
+
+	ids := make(map[string]image.ImageId)
+	for ip := range db.ListIdPaths([]string{"/photos"}, 0) {
+		ids[filepath.Base(ip.Path)] = ip.Id
+	}
+	idsOf := func(names ...string) image.Ids {
+		r := image.NewIds()
+		for _, name := range names {
+			r.AddInt(int(ids[name]))
+		}
+		return r
+	}
+
+	if err := db.SetRating(idsOf("a.jpg", "b.jpg", "c.jpg"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetRating(idsOf("c.jpg"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetRating(idsOf("d.jpg"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetRating(idsOf("e.jpg"), 6); err == nil {
+		t.Error("rating of 6 set, want error")
+	}
+	if err := db.SetLabel(idsOf("b.jpg", "d.jpg"), "red"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetFavorite(idsOf("a.jpg", "e.jpg"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(q string) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression: %v", err)
+		}
+		results, _ := db.List([]string{"/photos"}, image.ListOptions{Expression: expr})
+		var names []string
+		for result := range results {
+			path, _ := db.GetPathFromId(result.Id)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	testCases := []struct {
+		query  string
+		expect []string
+	}{
+		{"rating:>=4", []string{"a.jpg", "b.jpg"}},
+		{"rating:2..3", []string{"d.jpg"}},
+		{"rating:0", []string{"c.jpg", "e.jpg"}},
+		{"label:red", []string{"b.jpg", "d.jpg"}},
+		{"is:favorite", []string{"a.jpg", "e.jpg"}},
+		{"is:favorite rating:>=4", []string{"a.jpg"}},
+		{"NOT is:favorite label:red", []string{"b.jpg", "d.jpg"}},
+	}
+	for _, tc := range testCases {
+		if got := query(tc.query); !slices.Equal(got, tc.expect) {
+			t.Errorf("%s = %v, want %v", tc.query, got, tc.expect)
+		}
+	}
+
+	m, ok := db.GetFileMetadata(ids["b.jpg"])
+	if !ok {
+		t.Fatal("metadata of b.jpg not found")
+	}
+	if want := (image.FileMetadata{Rating: 4, Label: "red"}); m != want {
+		t.Errorf("metadata of b.jpg = %+v, want %+v", m, want)
+	}
+}
+
+func TestMetadataRating(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	sidecars := map[string]string{
+		"starred.jpg":  `xmp:Rating="5"`,
+		"rejected.jpg": `xmp:Rating="-1"`,
+	}
+	for _, name := range []string{"starred.jpg", "rejected.jpg", "plain.jpg"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		attr, ok := sidecars[name]
+		if !ok {
+			continue
+		}
+		sidecar := `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" ` + attr + `/>
+ </rdf:RDF>
+</x:xmpmeta>`
+		if err := os.WriteFile(path+".xmp", []byte(sidecar), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{dir + string(filepath.Separator)}
+	cfg := pipeline.Config{
+		DB:                db,
+		Extensions:        []string{".jpg"},
+		MetadataExtractor: corruptMetadataExtractor{},
+		MetadataWorkers:   1,
+		SyncXmp:           func(path string) bool { return true },
+	}
+	if err := pipeline.RunFiles(context.Background(), cfg, task.NewFilesTask("test", "Test", dirs, 0)); err != nil {
+		t.Fatalf("index files: %v", err)
+	}
+	if err := pipeline.RunMetadata(context.Background(), cfg, task.NewMetadataTask("test", "Test", dirs, 0, false)); err != nil {
+		t.Fatalf("index metadata: %v", err)
+	}
+	<-db.CommitBarrier()
+
+	expected := map[string]int{
+		"starred.jpg":  5,
+		"rejected.jpg": 0,
+		"plain.jpg":    0,
+	}
+	for ip := range db.ListIdPaths(dirs, 0) {
+		m, _ := db.GetFileMetadata(ip.Id)
+		name := filepath.Base(ip.Path)
+		if want := expected[name]; m.Rating != want {
+			t.Errorf("rating of %s = %d, want %d", name, m.Rating, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/collection"
+	"photofield/internal/image"
+	"photofield/internal/search"
+)
+
+func TestSavedSearchCrud(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+	<-db.CommitBarrier()
+
+	added, err := db.AddSavedSearch(image.SavedSearch{
+		Name:         "Night",
+		Search:       "iso:>1600",
+		CollectionId: "vacation",
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if added.Id == 0 || added.CreatedAt.IsZero() {
+		t.Fatalf("add returned %+v", added)
+	}
+
+	got, ok := db.GetSavedSearch(added.Id)
+	if !ok || got.Search != "iso:>1600" || got.CollectionId != "vacation" {
+		t.Fatalf("get = %+v, %v", got, ok)
+	}
+
+	updated, err := db.UpdateSavedSearch(image.SavedSearch{
+		Id:     added.Id,
+		Name:   "Night",
+		Search: "iso:>3200",
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.UpdatedAt.Before(added.UpdatedAt) || !updated.CreatedAt.Equal(added.CreatedAt) {
+		t.Fatalf("update returned %+v", updated)
+	}
+
+	list := db.ListSavedSearches()
+	if len(list) != 1 || list[0].Search != "iso:>3200" || list[0].CollectionId != "" {
+		t.Fatalf("list = %+v", list)
+	}
+
+	if err := db.DeleteSavedSearch(added.Id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := db.DeleteSavedSearch(added.Id); !errors.Is(err, image.ErrNotFound) {
+		t.Fatalf("delete again = %v, want not found", err)
+	}
+	if _, err := db.UpdateSavedSearch(added); !errors.Is(err, image.ErrNotFound) {
+		t.Fatalf("update deleted = %v, want not found", err)
+	}
+	if len(db.ListSavedSearches()) != 0 {
+		t.Fatalf("list after delete not empty")
+	}
+}
+
+func TestSavedSearchCollection(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := []struct {
+		path   string
+		camera *image.Camera
+	}{
+		{"/photos/night.jpg", &image.Camera{ISO: 6400, Lens: "RF35mm F1.8 MACRO IS STM"}},
+		{"/photos/street.jpg", &image.Camera{ISO: 3200, Lens: "RF24-105mm F4 L IS USM"}},
+		{"/photos/landscape.jpg", &image.Camera{ISO: 100, Lens: "RF24-105mm F4 L IS USM"}},
+	}
+	for _, f := range files {
+		if err := db.Write(f.path, image.Info{}, image.AppendPath); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+		if err := db.Write(f.path, image.Info{Camera: f.camera}, image.UpdateMeta); err != nil {
+			t.Fatalf("write %s: %v", f.path, err)
+		}
+	}
+	<-db.CommitBarrier()
+
+	bases := []*collection.Collection{
+		{Id: "photos", Dirs: []string{"/photos/"}, Sort: "-date"},
+	}
+	saved, err := db.AddSavedSearch(image.SavedSearch{
+		Name:   "Night",
+		Search: "iso:>1600 OR lens:*MACRO*",
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	c := collection.FromSavedSearch(saved, bases)
+	if c.Id != collection.SavedSearchCollectionId(saved.Id) {
+		t.Fatalf("id = %s", c.Id)
+	}
+	if id, ok := collection.ParseSavedSearchCollectionId(c.Id); !ok || id != saved.Id {
+		t.Fatalf("parse id %s = %d, %v", c.Id, id, ok)
+	}
+	configured := collection.Collection{Name: "Search 1"}
+	configured.MakeValid()
+	if _, ok := collection.ParseSavedSearchCollectionId(configured.Id); ok {
+		t.Fatalf("configured collection id %s parsed as a saved search", configured.Id)
+	}
+	if !slices.Equal(c.Dirs, bases[0].Dirs) || c.Sort != "-date" {
+		t.Fatalf("collection = %+v", c)
+	}
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect []string
+	}{
+		{
+			name:   "saved search only",
+			query:  "",
+			expect: []string{"/photos/night.jpg", "/photos/street.jpg"},
+		},
+		{
+			name:   "narrowed by scene search",
+			query:  "lens:*24-105*",
+			expect: []string{"/photos/street.jpg"},
+		},
+		{
+			name:   "narrowed by scene search with or",
+			query:  "lens:*50mm* OR lens:*24-105*",
+			expect: []string{"/photos/street.jpg"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			str := c.SearchWith(tc.query)
+			query, err := search.Parse(str)
+			if err != nil {
+				t.Fatalf("parse %q: %v", str, err)
+			}
+			expr, err := query.Expression()
+			if err != nil {
+				t.Fatalf("expression %q: %v", str, err)
+			}
+			paths := collectListedPaths(t, db, expr)
+			if !slices.Equal(paths, tc.expect) {
+				t.Fatalf("query %q paths = %v, want %v", str, paths, tc.expect)
+			}
+		})
+	}
+
+	updatedAt := c.UpdatedAt()
+	bases[0].Invalidate()
+	if !c.UpdatedAt().After(updatedAt) {
+		t.Fatalf("invalidating the base collection did not update the saved search collection")
+	}
+}
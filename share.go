@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"photofield/internal/auth"
+	"photofield/internal/image"
+	"photofield/internal/scene"
+)
+
+const SHARE_COOKIE_PREFIX = "photofield_share_"
+
+// shareToken returns the token of the share link the request is made with,
+// if any.
+func shareToken(r *http.Request) string {
+	if token := r.URL.Query().Get("share"); token != "" {
+		return token
+	}
+	return r.Header.Get("X-Share-Token")
+}
+
+func shareCookieName(share auth.Share) string {
+	return SHARE_COOKIE_PREFIX + strconv.FormatInt(share.Id, 10)
+}
+
+// shareUnlocked returns true if the share has no password or the request
+// has the cookie set by POST /share/unlock.
+func shareUnlocked(r *http.Request, share auth.Share, token string) bool {
+	if share.PasswordHash == "" {
+		return true
+	}
+	cookie, err := r.Cookie(shareCookieName(share))
+	return err == nil && cookie.Value == share.UnlockProof(token)
+}
+
+// serveShare serves the request made with the share link, limited to the
+// requests needed to browse the files of the share.
+func serveShare(w http.ResponseWriter, r *http.Request, next http.Handler, token string, path string) {
+	if imageSource == nil {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+	share, ok := imageSource.GetShare(auth.HashToken(token))
+	if !ok {
+		problem(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+	if !shareAllowsPath(share, r.Method, path) {
+		problem(w, r, http.StatusForbidden, "Not allowed with a share link")
+		return
+	}
+	unlocking := r.Method == http.MethodPost && path == "/share/unlock"
+	if !unlocking && !shareUnlocked(r, share, token) {
+		problem(w, r, http.StatusUnauthorized, "Share password required")
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(auth.WithShare(r.Context(), share)))
+}
+
+// shareAllowsPath returns true if the request can be made with the share
+// link. Original files can only be downloaded if the share allows it.
+func shareAllowsPath(share auth.Share, method string, path string) bool {
+	if method == http.MethodPost {
+		return path == "/scenes" || path == "/share/unlock"
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	switch {
+	case path == "/capabilities", path == "/share":
+		return true
+	case path == "/collections/"+share.CollectionId:
+		return true
+	case path == "/collections/"+share.CollectionId+"/download.zip":
+		return share.Download
+	case path == "/scenes", strings.HasPrefix(path, "/scenes/"):
+		return true
+	case strings.HasPrefix(path, "/files/"):
+		parts := strings.Split(path, "/")
+		original := len(parts) == 3 || parts[3] == "original"
+		return !original || share.Download
+	}
+	return false
+}
+
+// shareScene returns true if the scene config is of a scene showing the
+// files of the share.
+func shareScene(share auth.Share, config scene.SceneConfig) bool {
+	return config.Collection != nil &&
+		config.Collection.Id == share.CollectionId &&
+		config.Scene.Search == share.SceneSearch()
+}
+
+// shareContainsFile returns true if the file is in one of the scenes of
+// the share, as only those are browsed through the share link. Scenes still
+// loading are waited for, so that their files are reachable as soon as they
+// are shown.
+func shareContainsFile(r *http.Request, share auth.Share, id image.ImageId) bool {
+	scenes := sceneSource.GetScenesMatching(func(config scene.SceneConfig) bool {
+		return shareScene(share, config)
+	})
+	for _, s := range scenes {
+		if s.WaitLoaded(r.Context()) && s.ContainsFile(id) {
+			return true
+		}
+	}
+	return false
+}
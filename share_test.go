@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photofield/internal/auth"
+	"photofield/internal/image"
+)
+
+func TestShares(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+	<-db.CommitBarrier()
+
+	share, err := db.AddShare(auth.Share{
+		CollectionId: "vacation",
+		Search:       "beach",
+		Download:     true,
+		PasswordHash: "hash",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}, auth.HashToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !share.Password {
+		t.Error("share with password hash has no password")
+	}
+	if _, err := db.AddShare(auth.Share{
+		CollectionId: "vacation",
+		ExpiresAt:    time.Now().Add(-time.Hour),
+	}, auth.HashToken("expired")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := db.GetShare(auth.HashToken("token"))
+	if !ok {
+		t.Fatal("share not found")
+	}
+	if got.Id != share.Id || got.CollectionId != "vacation" || got.SceneSearch() != "beach" || !got.Download {
+		t.Errorf("share %+v, want %+v", got, share)
+	}
+	if _, ok := db.GetShare(auth.HashToken("expired")); ok {
+		t.Error("expired share found")
+	}
+	if _, ok := db.GetShare(auth.HashToken("other")); ok {
+		t.Error("share found by other token")
+	}
+	if got, ok := db.GetShareById(share.Id); !ok || got.CollectionId != "vacation" {
+		t.Errorf("share by id %+v, %v", got, ok)
+	}
+	if shares := db.ListShares(); len(shares) != 1 {
+		t.Errorf("shares %v, want 1", shares)
+	}
+
+	if err := db.DeleteShare(share.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.GetShare(auth.HashToken("token")); ok {
+		t.Error("deleted share found")
+	}
+	if err := db.DeleteShare(share.Id); err != image.ErrNotFound {
+		t.Errorf("delete error %v, want %v", err, image.ErrNotFound)
+	}
+}
+
+func TestShareAllowsPath(t *testing.T) {
+	share := auth.Share{CollectionId: "vacation"}
+	download := share
+	download.Download = true
+
+	tests := []struct {
+		share   auth.Share
+		method  string
+		path    string
+		allowed bool
+	}{
+		{share, http.MethodGet, "/share", true},
+		{share, http.MethodPost, "/share/unlock", true},
+		{share, http.MethodGet, "/collections/vacation", true},
+		{share, http.MethodGet, "/collections/other", false},
+		{share, http.MethodGet, "/collections", false},
+		{share, http.MethodPost, "/scenes", true},
+		{share, http.MethodGet, "/scenes/abc/tiles", true},
+		{share, http.MethodGet, "/scenes/abc/regions/1", true},
+		{share, http.MethodGet, "/files/1/variants/M/a.jpg", true},
+		{share, http.MethodGet, "/files/1/original/a.jpg", false},
+		{share, http.MethodGet, "/files/1", false},
+		{download, http.MethodGet, "/files/1/original/a.jpg", true},
+		{download, http.MethodGet, "/files/1", true},
+		{share, http.MethodGet, "/collections/vacation/download.zip", false},
+		{download, http.MethodGet, "/collections/vacation/download.zip", true},
+		{download, http.MethodGet, "/collections/other/download.zip", false},
+		{share, http.MethodPost, "/tags", false},
+		{share, http.MethodGet, "/tags", false},
+		{share, http.MethodGet, "/shares", false},
+		{share, http.MethodDelete, "/shares/1", false},
+	}
+	for _, tt := range tests {
+		if allowed := shareAllowsPath(tt.share, tt.method, tt.path); allowed != tt.allowed {
+			t.Errorf("%s %s allowed %v, want %v", tt.method, tt.path, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestShareSceneSearch(t *testing.T) {
+	share := auth.Share{TagId: "sys:select:col:vacation:abc"}
+	if search := share.SceneSearch(); search != "tag:sys:select:col:vacation:abc" {
+		t.Errorf("scene search %q, want the tag", search)
+	}
+
+	share.PasswordHash = "a"
+	proof := share.UnlockProof("token")
+	if proof == share.UnlockProof("other") {
+		t.Error("unlock proof independent of token")
+	}
+	share.PasswordHash = "b"
+	if proof == share.UnlockProof("token") {
+		t.Error("unlock proof unchanged by password change")
+	}
+}
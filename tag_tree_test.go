@@ -0,0 +1,131 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/search"
+	"photofield/internal/tag"
+)
+
+func TestTagTree(t *testing.T) {
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := map[string][]string{
+		"/photos/ljubljana.jpg": {"places/europe/slovenia"},
+		"/photos/paris.jpg":     {"places/europe/france"},
+		"/photos/tokyo.jpg":     {"places/asia/japan"},
+		"/photos/home.jpg":      {"places"},
+		"/photos/cat.jpg":       {"pets"},
+	}
+	for path := range files {
+		db.Write(path, image.Info{}, image.AppendPath)
+	}
+	<-db.CommitBarrier()
+	for ip := range db.ListIdPaths([]string{"/photos"}, 0) {
+		var tags []tag.Tag
+		for _, name := range files[ip.Path] {
+			tags = append(tags, tag.Tag{Name: name})
+		}
+		db.WriteTags(ip.Id, tags)
+	}
+	<-db.CommitBarrier()
+
+	query := func(q string) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression: %v", err)
+		}
+		results, _ := db.List([]string{"/photos"}, image.ListOptions{Expression: expr})
+		var names []string
+		for result := range results {
+			path, _ := db.GetPathFromId(result.Id)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	testCases := []struct {
+		query  string
+		expect []string
+	}{
+		{"tag:places/europe", []string{"ljubljana.jpg", "paris.jpg"}},
+		{"tag:places", []string{"home.jpg", "ljubljana.jpg", "paris.jpg", "tokyo.jpg"}},
+		{"tag:places/europe/slovenia", []string{"ljubljana.jpg"}},
+		{"tag:places/eur", nil},
+	}
+	for _, tc := range testCases {
+		if got := query(tc.query); !slices.Equal(got, tc.expect) {
+			t.Errorf("%s = %v, want %v", tc.query, got, tc.expect)
+		}
+	}
+
+	europe, ok := db.GetTagByName("places/europe")
+	if !ok {
+		t.Fatal("parent tag places/europe not added")
+	}
+	if europe.Parent != "places" {
+		t.Errorf("places/europe parent = %q, want places", europe.Parent)
+	}
+
+	names := func(tags []tag.Tag) []string {
+		var names []string
+		for _, t := range tags {
+			names = append(names, t.Name)
+		}
+		return names
+	}
+	roots := db.ListTagTree(0)
+	if got, want := names(roots), []string{"pets", "places"}; !slices.Equal(got, want) {
+		t.Fatalf("roots = %v, want %v", got, want)
+	}
+	if got, want := names(roots[1].Children), []string{"places/asia", "places/europe"}; !slices.Equal(got, want) {
+		t.Errorf("places children = %v, want %v", got, want)
+	}
+	if got, want := names(db.ListTagChildren(europe.Id, 10)), []string{"places/europe/france", "places/europe/slovenia"}; !slices.Equal(got, want) {
+		t.Errorf("places/europe children = %v, want %v", got, want)
+	}
+
+	// Parents stay linked to new versions of tags
+	slovenia, _ := db.GetTagByName("places/europe/slovenia")
+	db.AddTagIds(europe.Id, db.GetTagImageIds(slovenia.Id))
+	europe, _ = db.GetTagByName("places/europe")
+	if got, want := names(db.ListTagChildren(europe.Id, 10)), []string{"places/europe/france", "places/europe/slovenia"}; !slices.Equal(got, want) {
+		t.Errorf("places/europe children after update = %v, want %v", got, want)
+	}
+
+	// Moving a tag moves its descendants
+	moved, err := db.MoveTag(europe, "world/europe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Parent != "world" {
+		t.Errorf("moved parent = %q, want world", moved.Parent)
+	}
+	if got, want := query("tag:world"), []string{"ljubljana.jpg", "paris.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:world = %v, want %v", got, want)
+	}
+	if got, want := query("tag:places"), []string{"home.jpg", "tokyo.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:places after move = %v, want %v", got, want)
+	}
+	if _, ok := db.GetTagByName("world/europe/slovenia"); !ok {
+		t.Errorf("descendant world/europe/slovenia not moved")
+	}
+
+	pets, _ := db.GetTagByName("pets")
+	if _, err := db.MoveTag(pets, "world"); err != image.ErrExists {
+		t.Errorf("moving to an existing tag = %v, want exists", err)
+	}
+	if _, err := db.MoveTag(moved, "world/europe/west"); err == nil {
+		t.Errorf("moving a tag under itself succeeded")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/tag"
+	"photofield/internal/task"
+)
+
+type corruptMetadataExtractor struct{}
+
+func (corruptMetadataExtractor) DecodeInfo(path string, info *image.Info) ([]tag.Tag, error) {
+	if strings.Contains(path, "corrupt") {
+		return nil, errors.New("unexpected EOF")
+	}
+	info.Width = 100
+	info.Height = 100
+	return nil, nil
+}
+
+func TestTaskRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	for _, name := range []string{"good.jpg", "corrupt.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{dir + string(filepath.Separator)}
+	cfg := pipeline.Config{
+		DB:                db,
+		Extensions:        []string{".jpg"},
+		MetadataExtractor: corruptMetadataExtractor{},
+		MetadataWorkers:   1,
+	}
+	if err := pipeline.RunFiles(context.Background(), cfg, task.NewFilesTask("test", "Test", dirs, 0)); err != nil {
+		t.Fatalf("index files: %v", err)
+	}
+
+	coordinator := pipeline.NewCoordinator(context.Background(), cfg)
+	defer coordinator.Close()
+
+	pt, _ := coordinator.AddMetadata("test", "Test", dirs, 0, false)
+	<-pt.Completed()
+
+	runs := db.ListTaskRuns(image.TaskRunListOptions{Finished: true})
+	if len(runs) != 1 {
+		t.Fatalf("finished runs = %+v, want 1", runs)
+	}
+	run := runs[0]
+	if run.Id != pt.RunId() || run.TaskId != pt.Id || run.Type != task.TypeIndexMetadata {
+		t.Fatalf("run = %+v, task %s run %d", run, pt.Id, pt.RunId())
+	}
+	if !run.Finished() || run.Error != "" || run.Done != 1 || run.Total != 2 || run.ErrorCount != 1 {
+		t.Fatalf("run = %+v", run)
+	}
+
+	errs := db.ListTaskErrors(run.Id)
+	if len(errs) != 1 {
+		t.Fatalf("errors = %+v, want 1", errs)
+	}
+	if filepath.Base(errs[0].Path) != "corrupt.jpg" || errs[0].FileId == 0 || errs[0].Error != "metadata extract: unexpected EOF" {
+		t.Fatalf("error = %+v", errs[0])
+	}
+
+	// Runs left unfinished by a previous process are marked as interrupted
+	unfinished, err := db.AddTaskRun(image.TaskRun{TaskId: "index-contents-test", Type: task.TypeIndexContents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := db.ListTaskRuns(image.TaskRunListOptions{Finished: true}); len(runs) != 1 {
+		t.Fatalf("finished runs = %+v, want 1", runs)
+	}
+	if err := db.InterruptTaskRuns(); err != nil {
+		t.Fatal(err)
+	}
+	interrupted, ok := db.GetTaskRun(unfinished.Id)
+	if !ok || !interrupted.Finished() || interrupted.Error != "interrupted" {
+		t.Fatalf("interrupted run = %+v, %v", interrupted, ok)
+	}
+
+	// Only the most recent finished runs of a task are kept
+	for i := 0; i < 15; i++ {
+		r, err := db.AddTaskRun(image.TaskRun{TaskId: "index-faces-test", Type: task.TypeIndexFaces})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.FinishTaskRun(r.Id, 0, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runs := db.ListTaskRuns(image.TaskRunListOptions{TaskId: "index-faces-test"}); len(runs) != 10 {
+		t.Errorf("kept runs = %d, want 10", len(runs))
+	}
+	if _, ok := db.GetTaskRun(run.Id); !ok {
+		t.Error("run of another task removed")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"photofield/internal/codec"
+	"photofield/internal/image"
+	"photofield/internal/layout"
+	"photofield/internal/openapi"
+	"photofield/internal/render"
+	"photofield/internal/scene"
+	"photofield/internal/tilecache"
+
+	"github.com/docker/go-units"
+)
+
+// TILE_CACHE_VERSION is part of the keys of the cached tiles, increase it
+// when tiles are rendered differently to not reuse the old ones.
+const TILE_CACHE_VERSION = 1
+
+// tileCache is replaced on config reloads while tiles are requested
+var tileCache atomic.Pointer[tilecache.Cache]
+
+// TileKey contains everything a tile is rendered from. Tiles with the same
+// key are the same, even if they are of different scenes, e.g. as a scene
+// was recreated with the same config after a restart.
+type TileKey struct {
+	Version int `json:"version"`
+
+	// Scene
+	CollectionId     string           `json:"collection_id"`
+	Dirs             []string         `json:"dirs"`
+	PathFilter       image.PathFilter `json:"path_filter"`
+	CollectionSearch string           `json:"collection_search"`
+	Limit            int              `json:"limit"`
+	Layout           layout.Layout    `json:"layout"`
+	Search           string           `json:"search"`
+	LayoutHash       uint64           `json:"layout_hash"`
+	UpdatedAtMs      int64            `json:"updated_at_ms"`
+
+	// Tile
+	Zoom     int `json:"zoom"`
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	TileSize int `json:"tile_size"`
+
+	// Render options
+	Sources           []string  `json:"sources"`
+	SelectTag         string    `json:"select_tag"`
+	SelectTagVersion  int64     `json:"select_tag_version"`
+	DebugOverdraw     bool      `json:"debug_overdraw"`
+	DebugThumbnails   bool      `json:"debug_thumbnails"`
+	QualityPreset     int       `json:"quality_preset"`
+	MaxSolidPixelArea float64   `json:"max_solid_pixel_area"`
+	BackgroundColor   [4]uint32 `json:"background_color"`
+	Color             [4]uint32 `json:"color"`
+	TransparencyMask  bool      `json:"transparency_mask"`
+
+	// Encoder
+	Encoder string `json:"encoder"`
+	Quality int    `json:"quality"`
+	Mem     int    `json:"mem"`
+}
+
+// openTileCache opens the disk cache of rendered tiles in the data dir,
+// returning nil if it is disabled.
+func openTileCache(dataDir string, config image.CacheConfig) *tilecache.Cache {
+	maxSize := config.MaxSizeBytes()
+	if maxSize <= 0 {
+		log.Printf("tile cache disabled")
+		return nil
+	}
+	dir := filepath.Join(dataDir, "tiles")
+	c, err := tilecache.Open(dir, maxSize)
+	if err != nil {
+		log.Printf("tile cache disabled: %v", err)
+		return nil
+	}
+	log.Printf("tile cache %s, %s of %s used", dir, units.BytesSize(float64(c.Size())), units.BytesSize(float64(maxSize)))
+	return c
+}
+
+// updateTileCache returns the tile cache for the config, reusing the current
+// one if it is stored in the same dir, as caches of the same dir would remove
+// each other's tiles.
+func updateTileCache(current *tilecache.Cache, dataDir string, config image.CacheConfig) *tilecache.Cache {
+	maxSize := config.MaxSizeBytes()
+	if current != nil && maxSize > 0 && current.Dir() == filepath.Join(dataDir, "tiles") {
+		current.SetMaxSize(maxSize)
+		return current
+	}
+	return openTileCache(dataDir, config)
+}
+
+func colorKey(c color.Color) [4]uint32 {
+	r, g, b, a := c.RGBA()
+	return [4]uint32{r, g, b, a}
+}
+
+// getTileKey returns the key of the tile of the scene, or false if the tile
+// should not be cached as the scene is still loading or stale.
+func getTileKey(scene *render.Scene, rn *render.Render, params openapi.GetScenesSceneIdTilesParams, selectTag string, selectTagVersion int64, encoder codec.Encoder, quality int) (string, bool) {
+	if scene.Loading {
+		return "", false
+	}
+	updatedAt, ok := scene.DependenciesUpdatedAt()
+	if !ok {
+		return "", false
+	}
+	config, ok := sceneSource.GetSceneConfig(scene.Id)
+	if !ok || config.Collection == nil {
+		return "", false
+	}
+	return sceneTileKey(scene, config, updatedAt, rn, params, selectTag, selectTagVersion, encoder, quality)
+}
+
+// sceneTileKey returns the key of the tile of the loaded scene with the
+// config. The scene is keyed by its layout hash, as scenes with the same
+// config can be laid out differently, e.g. shuffled with another seed or
+// grouped by faces and duplicates found since.
+func sceneTileKey(scene *render.Scene, config scene.SceneConfig, updatedAt time.Time, rn *render.Render, params openapi.GetScenesSceneIdTilesParams, selectTag string, selectTagVersion int64, encoder codec.Encoder, quality int) (string, bool) {
+	key := TileKey{
+		Version:           TILE_CACHE_VERSION,
+		CollectionId:      config.Collection.Id,
+		Dirs:              config.Collection.Dirs,
+		PathFilter:        config.Collection.PathFilter(),
+		CollectionSearch:  config.Collection.Search,
+		Limit:             config.Collection.Limit,
+		Layout:            config.Layout,
+		Search:            scene.Search,
+		LayoutHash:        scene.LayoutHash,
+		UpdatedAtMs:       updatedAt.UnixMilli(),
+		Zoom:              params.Zoom,
+		X:                 int(params.X),
+		Y:                 int(params.Y),
+		TileSize:          rn.TileSize,
+		SelectTag:         selectTag,
+		SelectTagVersion:  selectTagVersion,
+		DebugOverdraw:     rn.DebugOverdraw,
+		DebugThumbnails:   rn.DebugThumbnails,
+		QualityPreset:     int(rn.QualityPreset),
+		MaxSolidPixelArea: rn.MaxSolidPixelArea,
+		BackgroundColor:   colorKey(rn.BackgroundColor),
+		Color:             colorKey(rn.Color),
+		TransparencyMask:  rn.TransparencyMask,
+		Encoder:           encoder.Type.String(),
+		Quality:           quality,
+		Mem:               int(encoder.Mem),
+	}
+	if params.Sources != nil {
+		key.Sources = *params.Sources
+	}
+	k, err := tilecache.Key(key)
+	if err != nil {
+		log.Printf("tile cache key: %v", err)
+		return "", false
+	}
+	return k, true
+}
+
+// tileETag returns the strong ETag of the tile with the key, as tiles with
+// the same key are the same.
+func tileETag(key string) string {
+	return `"` + key[:32] + `"`
+}
+
+// etagMatches returns true if the If-None-Match header of the request
+// matches the ETag, using the weak comparison of RFC 9110.
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
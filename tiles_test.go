@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image/color"
+	"math/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"photofield/internal/codec"
+	"photofield/internal/collection"
+	"photofield/internal/image"
+	"photofield/internal/layout"
+	"photofield/internal/openapi"
+	"photofield/internal/render"
+	"photofield/internal/scene"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := `"0123456789abcdef0123456789abcdef"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{etag, true},
+		{"W/" + etag, true},
+		{`"other", ` + etag, true},
+		{`"other"`, false},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/scenes/abc/tiles", nil)
+		if tt.header != "" {
+			r.Header.Set("If-None-Match", tt.header)
+		}
+		if got := etagMatches(r, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// shuffledScene lays out the photos in a grid in the order shuffled with the
+// seed, like a shuffled collection is laid out again once the seed changes.
+func shuffledScene(seed int64) *render.Scene {
+	ids := []image.ImageId{1, 2, 3, 4, 5, 6, 7, 8}
+	rand.New(rand.NewSource(seed)).Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+	s := &render.Scene{
+		Bounds:    render.Rect{W: 400, H: 200},
+		FileCount: len(ids),
+	}
+	for i, id := range ids {
+		photo := render.Photo{Id: id}
+		photo.Sprite.Rect = render.Rect{X: float64(i%4) * 100, Y: float64(i/4) * 100, W: 100, H: 100}
+		s.Photos = append(s.Photos, photo)
+	}
+	s.BuildLayoutHash()
+	return s
+}
+
+func TestSceneTileKeyShuffled(t *testing.T) {
+	config := scene.SceneConfig{
+		Collection: &collection.Collection{Id: "shuffled"},
+		Layout:     layout.Layout{Order: layout.ShuffleDaily},
+	}
+	rn := &render.Render{
+		TileSize:        256,
+		BackgroundColor: color.White,
+		Color:           color.Black,
+	}
+	params := openapi.GetScenesSceneIdTilesParams{TileSize: 256}
+	encoder := codec.Encoder{Type: codec.EncoderType{Subtype: "jpeg"}}
+	key := func(s *render.Scene) string {
+		t.Helper()
+		k, ok := sceneTileKey(s, config, time.Time{}, rn, params, "", 0, encoder, 80)
+		if !ok {
+			t.Fatal("tile not cacheable")
+		}
+		return k
+	}
+
+	first := key(shuffledScene(1))
+	if again := key(shuffledScene(1)); again != first {
+		t.Errorf("scene recreated with the same layout has key %s, want %s", again, first)
+	}
+	if reshuffled := key(shuffledScene(2)); reshuffled == first {
+		t.Error("reshuffled scene has the same key as the previous layout")
+	}
+}
@@ -400,5 +400,5 @@ export async function addTag(body) {
 }
 
 export async function postTagFiles(id, body) {
-  return await post(`/tags/${id}/files`, body);
+  return await post(`/tags/${encodeURIComponent(id)}/files`, body);
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"photofield/internal/image"
+)
+
+func TestExportImportUserData(t *testing.T) {
+	src := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer src.Close()
+
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		src.Write("/photos/"+name, image.Info{}, image.AppendPath)
+	}
+	<-src.CommitBarrier()
+
+	ids := make(map[string]image.ImageId)
+	for ip := range src.ListIdPaths([]string{"/photos"}, 0) {
+		ids[filepath.Base(ip.Path)] = ip.Id
+	}
+	src.WriteHash(ids["b.jpg"], image.ContentHash{Partial: -42})
+	<-src.CommitBarrier()
+
+	tagFiles := func(db *image.Database, name string, files ...image.ImageId) {
+		done, _ := db.AddTag(name)
+		<-done
+		tg, ok := db.GetTagByName(name)
+		if !ok {
+			t.Fatalf("tag %s not found", name)
+		}
+		tids := image.NewIds()
+		for _, id := range files {
+			tids.AddInt(int(id))
+		}
+		db.AddTagIds(tg.Id, tids)
+	}
+	tagFiles(src, "places/beach", ids["a.jpg"], ids["b.jpg"])
+	tagFiles(src, "empty")
+	one := image.NewIds()
+	one.AddInt(int(ids["b.jpg"]))
+	if err := src.SetRating(one, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetFavorite(one, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	stats, err := src.ExportUserData(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 {
+		t.Errorf("exported %d files, want 2", stats.Files)
+	}
+
+	// Rebuilt cache with b.jpg moved to another dir
+	dst := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer dst.Close()
+
+	dst.Write("/photos/a.jpg", image.Info{}, image.AppendPath)
+	dst.Write("/moved/b.jpg", image.Info{}, image.AppendPath)
+	<-dst.CommitBarrier()
+	moved, _ := dst.GetIdFromPath("/moved/b.jpg")
+	dst.WriteHash(moved, image.ContentHash{Partial: -42})
+	<-dst.CommitBarrier()
+
+	stats, err = dst.ImportUserData(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := image.UserDataStats{Tags: 3, Files: 2, Moved: 1}
+	if stats != expected {
+		t.Errorf("import stats %+v, want %+v", stats, expected)
+	}
+
+	if _, ok := dst.GetTagByName("empty"); !ok {
+		t.Error("tag without files not imported")
+	}
+	beach, ok := dst.GetTagByName("places/beach")
+	if !ok {
+		t.Fatal("places/beach not imported")
+	}
+	a, _ := dst.GetIdFromPath("/photos/a.jpg")
+	got := dst.GetTagImageIds(beach.Id).IntSlice()
+	want := []int{int(a), int(moved)}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("places/beach files %v, want %v", got, want)
+	}
+
+	meta, _ := dst.GetFileMetadata(moved)
+	if meta.Rating != 3 || !meta.Favorite {
+		t.Errorf("moved file metadata %+v, want rating 3 and favorite", meta)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(dir, "photofield.db"), migrations)
+	defer db.Close()
+
+	db.Write("/photos/a.jpg", image.Info{}, image.AppendPath)
+
+	path := filepath.Join(dir, "backup.db")
+	if err := db.Backup(path); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := image.NewDatabase(path, migrations)
+	defer backup.Close()
+	<-backup.CommitBarrier()
+	if _, ok := backup.GetIdFromPath("/photos/a.jpg"); !ok {
+		t.Error("file written before backup not found in backup")
+	}
+}
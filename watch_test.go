@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+)
+
+func TestWatchIndexesChanges(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+	<-db.CommitBarrier()
+
+	cfg := pipeline.Config{
+		DB:         db,
+		Extensions: []string{".jpg"},
+	}
+	changes := make(chan bool, 10)
+	w, err := pipeline.NewWatcher(cfg, []string{dir}, 100*time.Millisecond, func(paths []string, added bool) {
+		changes <- added
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	wait := func(wantAdded bool) {
+		t.Helper()
+		select {
+		case added := <-changes:
+			if added != wantAdded {
+				t.Fatalf("added = %v, want %v", added, wantAdded)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for changes")
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	if err := os.WriteFile(path("a.jpg"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(true)
+	id, ok := db.GetIdFromPath(path("a.jpg"))
+	if !ok {
+		t.Fatal("added file not indexed")
+	}
+
+	if err := os.Mkdir(path("sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wait(false)
+
+	if err := os.Rename(path("a.jpg"), path("sub/b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	wait(false)
+	if _, ok := db.GetIdFromPath(path("a.jpg")); ok {
+		t.Error("renamed file still indexed at old path")
+	}
+	if renamed, ok := db.GetIdFromPath(path("sub/b.jpg")); !ok || renamed != id {
+		t.Errorf("renamed file id = %v, %v, want %v", renamed, ok, id)
+	}
+
+	if err := os.Rename(path("sub"), path("moved")); err != nil {
+		t.Fatal(err)
+	}
+	wait(false)
+	if moved, ok := db.GetIdFromPath(path("moved/b.jpg")); !ok || moved != id {
+		t.Errorf("file in renamed dir id = %v, %v, want %v", moved, ok, id)
+	}
+
+	if err := os.Remove(path("moved/b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	wait(false)
+	if _, ok := db.GetIdFromPath(path("moved/b.jpg")); ok {
+		t.Error("removed file still indexed")
+	}
+}
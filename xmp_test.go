@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"photofield/internal/collection"
+	"photofield/internal/image"
+	"photofield/internal/image/pipeline"
+	"photofield/internal/search"
+	"photofield/internal/task"
+	"photofield/internal/xmp"
+)
+
+func TestMetadataXmp(t *testing.T) {
+	dir := t.TempDir()
+	db := image.NewDatabase(filepath.Join(t.TempDir(), "photofield.db"), migrations)
+	defer db.Close()
+
+	files := map[string]string{
+		"tagged.jpg": "places/europe/slovenia",
+		"other.jpg":  "places/asia/japan",
+	}
+	for name, keyword := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := xmp.WriteSidecar(path, xmp.FromKeywords([]string{keyword, "trip"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{dir + string(filepath.Separator)}
+	cfg := pipeline.Config{
+		DB:                db,
+		Extensions:        []string{".jpg"},
+		MetadataExtractor: corruptMetadataExtractor{},
+		MetadataWorkers:   1,
+		EnableTags:        true,
+		SyncXmp: func(path string) bool {
+			return !strings.HasSuffix(path, "other.jpg")
+		},
+	}
+	if err := pipeline.RunFiles(context.Background(), cfg, task.NewFilesTask("test", "Test", dirs, 0)); err != nil {
+		t.Fatalf("index files: %v", err)
+	}
+	if err := pipeline.RunMetadata(context.Background(), cfg, task.NewMetadataTask("test", "Test", dirs, 0, false)); err != nil {
+		t.Fatalf("index metadata: %v", err)
+	}
+	<-db.CommitBarrier()
+
+	query := func(q string) []string {
+		t.Helper()
+		query, err := search.Parse(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		expr, err := query.Expression()
+		if err != nil {
+			t.Fatalf("expression: %v", err)
+		}
+		results, _ := db.List(dirs, image.ListOptions{Expression: expr})
+		var names []string
+		for result := range results {
+			path, _ := db.GetPathFromId(result.Id)
+			names = append(names, filepath.Base(path))
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got, want := query("tag:places/europe"), []string{"tagged.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:places/europe = %v, want %v", got, want)
+	}
+	if got, want := query("tag:trip"), []string{"tagged.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:trip = %v, want %v", got, want)
+	}
+	if got := query("tag:places/asia"); len(got) != 0 {
+		t.Errorf("tag:places/asia = %v, want none of files not synced with xmp", got)
+	}
+
+	// Keywords removed in other photo managers are removed on reindex
+	tagged := filepath.Join(dir, "tagged.jpg")
+	if err := xmp.WriteSidecar(tagged, xmp.FromKeywords([]string{"places/europe/slovenia"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipeline.RunMetadata(context.Background(), cfg, task.NewMetadataTask("test", "Test", dirs, 0, true)); err != nil {
+		t.Fatalf("index metadata: %v", err)
+	}
+	<-db.CommitBarrier()
+	if got := query("tag:trip"); len(got) != 0 {
+		t.Errorf("tag:trip = %v, want none after the keyword was removed", got)
+	}
+	if got, want := query("tag:places/europe"), []string{"tagged.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:places/europe = %v, want %v", got, want)
+	}
+
+	// As well as when the sidecar is synced again, leaving out internal
+	// keywords
+	if err := xmp.WriteSidecar(tagged, xmp.FromKeywords([]string{"trip", "sys:leaked"})); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := db.GetIdFromPath(tagged)
+	if err := pipeline.SyncXmpFile(db, id, tagged); err != nil {
+		t.Fatal(err)
+	}
+	<-db.CommitBarrier()
+	if _, ok := db.GetTagId("sys:leaked"); ok {
+		t.Errorf("internal keyword added as a tag")
+	}
+	if got, want := query("tag:trip"), []string{"tagged.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("tag:trip = %v, want %v", got, want)
+	}
+	if got := query("tag:places/europe"); len(got) != 0 {
+		t.Errorf("tag:places/europe = %v, want none after the keyword was removed", got)
+	}
+}
+
+func TestSyncsXmpTag(t *testing.T) {
+	for name, want := range map[string]bool{
+		"places/europe":         true,
+		"trip":                  true,
+		"sys:select:col:test:1": false,
+		"exif:make:canon":       false,
+		"suggest:beach":         false,
+	} {
+		if got := image.SyncsXmpTag(name); got != want {
+			t.Errorf("syncs %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestXmpSyncer(t *testing.T) {
+	cs := []collection.Collection{
+		{Name: "Synced", Dirs: []string{"/photos"}, Xmp: true, Exclude: []string{"exports"}},
+		{Name: "Other", Dirs: []string{"/other"}},
+	}
+	for i := range cs {
+		cs[i].MakeValid()
+	}
+	syncs := xmpSyncer(cs)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/photos/a.jpg", true},
+		{"/photos/2023/b.jpg", true},
+		{"/photos/exports/c.jpg", false},
+		{"/photos-old/d.jpg", false},
+		{"/other/e.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := syncs(filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("syncs %s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}